@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
 	"github.com/luxor/lumos/pkg/pdf"
 	"github.com/luxor/lumos/pkg/ui"
 )
@@ -18,6 +21,11 @@ func main() {
 	version := flag.Bool("version", false, "Show version")
 	help := flag.Bool("help", false, "Show help")
 	keys := flag.Bool("keys", false, "Show keyboard shortcuts")
+	noResume := flag.Bool("no-resume", false, "Start at page 1 instead of the last reading position")
+	cacheMB := flag.Int64("cache-mb", pdf.DefaultDocumentOptions().CacheBytes>>20, "Memory budget for cached page text, in MiB")
+	password := flag.String("password", "", "Password of an encrypted PDF (or set LUMOS_PASSWORD)")
+	extract := flag.String("extract", pdf.DefaultDocumentOptions().Extraction.String(), "Text extraction mode: layout or raw")
+	searchAll := flag.String("search-all", "", "Search every document indexed before, without opening them")
 	flag.BoolVar(help, "h", false, "Show help (short)")
 	flag.BoolVar(version, "v", false, "Show version (short)")
 	flag.BoolVar(keys, "k", false, "Show keyboard shortcuts (short)")
@@ -40,6 +48,14 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *searchAll != "" {
+		if err := printSearchAll(*searchAll); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Get PDF file path from arguments
 	args := flag.Args()
 	if len(args) == 0 {
@@ -67,22 +83,82 @@ func main() {
 	}
 
 	// Load PDF
-	doc, err := pdf.NewDocument(pdfPath, 5)
+	docOpts := pdf.DefaultDocumentOptions()
+	docOpts.CacheBytes = *cacheMB << 20
+	extraction, err := pdf.ParseExtractionMode(*extract)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	docOpts.Extraction = extraction
+	docOpts.Password = *password
+	if docOpts.Password == "" {
+		docOpts.Password = os.Getenv("LUMOS_PASSWORD")
+	}
+	doc, err := openDocument(pdfPath, docOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading PDF: %v\n", err)
 		os.Exit(1)
 	}
+	defer doc.Close()
 
 	// Create and run TUI
-	model := ui.NewModel(doc)
+	opts := ui.DefaultModelOptions()
+	opts.Resume = !*noResume
+	opts.DocumentOptions = docOpts
+	opts.DocumentOptions.Password = "" // linked documents have passwords of their own
+	model := ui.NewModelWithOptions(doc, opts)
 
 	p := tea.NewProgram(model, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
+		doc.Close()
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// openDocument opens the PDF, asking for its password until the right one
+// is given if it is encrypted and none was given or the one given is wrong
+func openDocument(path string, opts pdf.DocumentOptions) (*pdf.Document, error) {
+	for {
+		doc, err := pdf.OpenDocument(path, opts)
+		if !errors.Is(err, pdf.ErrPasswordRequired) && !errors.Is(err, pdf.ErrWrongPassword) {
+			return doc, err
+		}
+
+		problem := ""
+		if errors.Is(err, pdf.ErrWrongPassword) {
+			problem = "Wrong password, try again"
+		}
+		password, ok, promptErr := ui.PromptPassword(path, problem)
+		if promptErr != nil || !ok {
+			return nil, err
+		}
+		opts.Password = password
+	}
+}
+
+// printSearchAll lists the pages matching query in every document whose
+// search index was persisted when it was last opened
+func printSearchAll(query string) error {
+	docs, err := pdf.SearchIndexes(pdf.DefaultIndexOptions(), query)
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Printf("No indexed document matches %q\n", query)
+		return nil
+	}
+	for _, doc := range docs {
+		pages := make([]string, len(doc.Hits))
+		for i, hit := range doc.Hits {
+			pages[i] = fmt.Sprint(hit.Page)
+		}
+		fmt.Printf("%s\n  pages %s\n", doc.Path, strings.Join(pages, ", "))
+	}
+	return nil
+}
+
 func printHelp() {
 	fmt.Print(`LUMOS - PDF Dark Mode Reader
 A developer-friendly PDF reader with dark mode and vim keybindings
@@ -94,11 +170,25 @@ OPTIONS:
   -h, --help      Show this help message
   -v, --version   Show version information
   -k, --keys      Show keyboard shortcuts reference
+  --no-resume     Open at page 1 instead of where you left off
+  --cache-mb N    Memory budget for cached page text (default 32)
+  --extract MODE  Text extraction: layout (words, lines and paragraphs
+                  rebuilt from glyph positions; default) or raw
+  --password PW   Password of an encrypted PDF, user or owner; also read
+                  from LUMOS_PASSWORD. Without one you are asked for it.
+  --search-all Q  List the pages matching Q, best first, in every document
+                  indexed when it was opened before
 
 EXAMPLES:
   # Open a PDF file
   lumos ~/Documents/paper.pdf
 
+  # Open at the first page, ignoring the saved position
+  lumos --no-resume ~/Documents/paper.pdf
+
+  # Find the documents read before that mention comonads
+  lumos --search-all 'comonad*'
+
   # Show help
   lumos --help
 
@@ -121,57 +211,60 @@ For more information, see documentation at:
 ` + "\n")
 }
 
+// printKeys prints the keys bound by the config, or the defaults if it
+// can't be loaded
 func printKeys() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: config not loaded, showing the default keys: %v\n\n", err)
+		cfg = config.DefaultConfig()
+	}
+	keymap, err := ui.NewKeymap(cfg.Keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: key bindings ignored:\n%v\n\n", err)
+	}
+
 	fmt.Print(`LUMOS - Keyboard Shortcuts
 
-NORMAL MODE
-===========
-
-Navigation:
-  j or ↓          Scroll down one line
-  k or ↑          Scroll up one line
-  d               Scroll down half page
-  u               Scroll up half page
-  gg              Go to top of document
-  G               Go to bottom of document
-  Ctrl+N          Go to next page
-  Ctrl+P          Go to previous page
-
-Search:
-  /               Start search
-  n               Go to next match
-  N               Go to previous match
-  Esc             Exit search mode
-
-UI Control:
-  Tab             Cycle through panes
-  1               Switch to dark mode
-  2               Switch to light mode
-  ?               Toggle this help screen
-  :               Enter command mode
-
-General:
-  q or Ctrl+C     Quit the application
-
-SEARCH MODE
-===========
-  Type to search
-  Enter           Execute search
-  Esc             Exit search mode
-  n/N             Next/previous match
-
-COMMAND MODE
-============
-  Type command name
-  Enter           Execute command
-  Esc             Exit command mode
+Keys are rebound in the [keys] section of the config, by mode (normal, visual,
+search, command, toc, hint) and action:
+
+  [keys.normal]
+  "ctrl+w j" = "next-page"
+  "g" = ""                  # unbind
+
+`)
+	fmt.Print(ui.KeyReference(keymap))
+	fmt.Print(`
+COMMANDS
+========
+`)
+	for _, cmd := range ui.DefaultCommands().Commands() {
+		fmt.Printf("  %-34s %s\n", cmd.Usage(), cmd.Help)
+	}
+	fmt.Print(`  :<page>                            Same as :goto; pages are given by the label
+                                     printed on them ("xiv", "A-3") or by number,
+                                     "#12" being the twelfth page
+
+  :set takes options as vim does: "columns" turns one on, "nocolumns" off,
+  "columns!" moves it on to its next value, "columns=side-by-side" sets it
+  and "columns?" shows it
 
 TIPS
 ====
   • Use vim keybindings for fast navigation
   • Dark mode by default for long reading sessions
-  • LRU caching keeps 5 pages in memory
+  • Page text is cached within a memory budget (--cache-mb)
   • Search is case-insensitive by default
-  • Mark pages with vim marks (Phase 2+)
+  • Documents are indexed in the background; the index is kept in your
+    cache directory so reopening a document searches instantly
+  • m{a-z} marks a line and '{a-z} returns to it; marks A-Z lead back
+    from any document, opening the one they were set in. They are kept in
+    the config, and :marks lists them
+  • v or V selects text from the cursor, across pages, and y copies it to
+    the system clipboard with OSC 52, which works over SSH, or with
+    wl-copy/xclip. In the [ui] section of the config, clipboard = "osc52"
+    or "command" picks one way, copy_command sets the command, and
+    copy_format (say "{text} ({title}, p. {page})") shapes what is copied
 ` + "\n")
 }
@@ -1,40 +1,135 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
 )
 
-// Config represents the complete application configuration
+// SchemaVersion is the config file format written by this build.
+//
+//	1: unversioned files; timestamps were written as quoted strings
+//	2: adds the version key; timestamps are native TOML datetimes
+//	3: documents and bookmarks are keyed by content hash instead of path
+const SchemaVersion = 3
+
+// Config represents the complete application configuration.
+// Documents and Bookmarks are keyed by document key (see HashFile), so
+// state follows a file across renames and moves.
 type Config struct {
+	Version   int                   `toml:"version"`
 	UI        UIConfig              `toml:"ui"`
 	Documents map[string]DocState   `toml:"documents"`
 	Bookmarks map[string][]Bookmark `toml:"bookmarks"`
+
+	// Marks are the global marks A-Z, which lead into any document; the
+	// marks a-z of a document are kept in its DocState
+	Marks map[string]Mark `toml:"marks,omitempty"`
+
+	// Keys binds key sequences to actions by mode ([keys.normal],
+	// [keys.visual], [keys.search], [keys.command], [keys.toc],
+	// [keys.hint]) on top of the defaults; an empty action unbinds the
+	// keys. Checked by ui.NewKeymap, which knows the actions.
+	Keys map[string]map[string]string `toml:"keys,omitempty"`
 }
 
 // UIConfig holds UI preferences
 type UIConfig struct {
-	Theme string `toml:"theme"`
+	Theme     string `toml:"theme"`
+	Opener    string `toml:"opener,omitempty"`     // command web links (http, https, mailto) are opened with, the address appended; the system's by default
+	HintChars string `toml:"hint_chars,omitempty"` // characters link hints are made of; DefaultHintChars if empty
+
+	// KeyTimeout is how long a key sequence ("gg", "5j") waits for its
+	// next key, in milliseconds; a second if zero
+	KeyTimeout int `toml:"key_timeout_ms,omitempty"`
+
+	Clipboard   string `toml:"clipboard,omitempty"`    // how text is copied: ClipboardAuto (if empty), ClipboardOSC52 or ClipboardCommand
+	CopyCommand string `toml:"copy_command,omitempty"` // command copied text is piped to; wl-copy, xclip, xsel or pbcopy, whichever is found, if empty
+	CopyFormat  string `toml:"copy_format,omitempty"`  // what copying text puts on the clipboard; DefaultCopyFormat if empty
 }
 
+// Ways of copying text to the system clipboard
+const (
+	ClipboardAuto    = "auto"    // the OSC 52 terminal sequence and, outside SSH sessions, the copy command
+	ClipboardOSC52   = "osc52"   // only the OSC 52 terminal sequence, which reaches the clipboard over SSH
+	ClipboardCommand = "command" // only the copy command
+)
+
+// DefaultCopyFormat copies text as it is. A copy format can add
+// {page}, the page label or range of labels the text is from, {file},
+// the document's file name, and {title}, its title or else file name:
+// "{text}\n({title}, p. {page})".
+const DefaultCopyFormat = "{text}"
+
+// CopyFormatFields are the placeholders of a copy format
+var CopyFormatFields = []string{"text", "page", "file", "title"}
+
+// DefaultHintChars are the characters link hints are made of unless
+// configured otherwise: the home row, so hints are quick to type
+const DefaultHintChars = "asdfghjkl"
+
 // DocState tracks per-document state
 type DocState struct {
-	LastPage   int       `toml:"last_page"`
-	LastScroll int       `toml:"last_scroll"`
-	Timestamp  time.Time `toml:"timestamp"`
+	Path        string          `toml:"path,omitempty"` // last known location, informational
+	LastPage    int             `toml:"last_page"`
+	LastScroll  int             `toml:"last_scroll"`
+	Columns     string          `toml:"columns,omitempty"`      // column view: "", ColumnsStacked or ColumnsSideBySide
+	HideRunning bool            `toml:"hide_running,omitempty"` // hide running headers and footers
+	Timestamp   time.Time       `toml:"timestamp"`
+	Jumps       []Jump          `toml:"jumps,omitempty"` // jump list, oldest first
+	Marks       map[string]Mark `toml:"marks,omitempty"` // marks a-z, by name
+}
+
+// Jump is a position in a document jumped away from
+type Jump struct {
+	Page   int `toml:"page"`
+	Scroll int `toml:"scroll,omitempty"`
+}
+
+// Mark is a position set by a vim mark: m{a-z} within a document, m{A-Z}
+// in any document, named by Path
+type Mark struct {
+	Path   string `toml:"path,omitempty"` // document of a global mark
+	Page   int    `toml:"page"`
+	Scroll int    `toml:"scroll,omitempty"`
+}
+
+// IsGlobalMark reports whether a mark name is one of the global marks A-Z
+func IsGlobalMark(name string) bool {
+	return len(name) == 1 && name[0] >= 'A' && name[0] <= 'Z'
 }
 
+// IsLocalMark reports whether a mark name is one of the marks a-z of a
+// document
+func IsLocalMark(name string) bool {
+	return len(name) == 1 && name[0] >= 'a' && name[0] <= 'z'
+}
+
+// Column views of a document
+const (
+	ColumnsStacked    = "stacked"      // columns one after another, in reading order
+	ColumnsSideBySide = "side-by-side" // columns next to each other when the viewer is wide enough
+)
+
 // Bookmark represents a page bookmark with optional note
 type Bookmark struct {
 	Page int    `toml:"page"`
-	Note string `toml:"note"`
+	Note string `toml:"note,omitempty"`
 }
 
 // DefaultConfig returns sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
+		Version: SchemaVersion,
 		UI: UIConfig{
 			Theme: "dark",
 		},
@@ -58,16 +153,9 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	var cfg Config
-	// Simple TOML parsing using standard library - avoid extra dependency
-	// For now, use a basic approach that doesn't require external TOML library
-	// We'll use a manual approach for maximum compatibility
-
-	cfg = *DefaultConfig()
-
-	// Parse the TOML file manually (pragmatic approach)
+	cfg := *DefaultConfig()
 	if err := parseConfig(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
 	}
 
 	return &cfg, nil
@@ -101,12 +189,100 @@ func (c *Config) Save() error {
 }
 
 // UpdateDocState updates the last page/scroll for a document
-func (c *Config) UpdateDocState(path string, page int, scroll int) {
-	c.Documents[path] = DocState{
-		LastPage:   page,
-		LastScroll: scroll,
-		Timestamp:  time.Now(),
+func (c *Config) UpdateDocState(key string, page int, scroll int) {
+	state := c.Documents[key]
+	state.LastPage = page
+	state.LastScroll = scroll
+	state.Timestamp = time.Now()
+	c.Documents[key] = state
+}
+
+// SetDocView records how a document is displayed
+func (c *Config) SetDocView(key string, columns string, hideRunning bool) {
+	state := c.Documents[key]
+	state.Columns = columns
+	state.HideRunning = hideRunning
+	if state.LastPage < 1 {
+		state.LastPage = 1
+	}
+	state.Timestamp = time.Now()
+	c.Documents[key] = state
+}
+
+// SetJumps records the jump list of a document
+func (c *Config) SetJumps(key string, jumps []Jump) {
+	state := c.Documents[key]
+	state.Jumps = jumps
+	if state.LastPage < 1 {
+		state.LastPage = 1
 	}
+	state.Timestamp = time.Now()
+	c.Documents[key] = state
+}
+
+// SetMark sets a mark: a global mark, which needs the document's path,
+// or a mark of the document with the given key
+func (c *Config) SetMark(key string, name string, mark Mark) {
+	if IsGlobalMark(name) {
+		if c.Marks == nil {
+			c.Marks = make(map[string]Mark)
+		}
+		c.Marks[name] = mark
+		return
+	}
+
+	state := c.Documents[key]
+	if state.Marks == nil {
+		state.Marks = make(map[string]Mark)
+	}
+	mark.Path = ""
+	state.Marks[name] = mark
+	if state.LastPage < 1 {
+		state.LastPage = 1
+	}
+	state.Timestamp = time.Now()
+	c.Documents[key] = state
+}
+
+// GetMark returns a global mark, or a mark of the document with the given
+// key
+func (c *Config) GetMark(key string, name string) (Mark, bool) {
+	if IsGlobalMark(name) {
+		mark, ok := c.Marks[name]
+		return mark, ok
+	}
+	mark, ok := c.Documents[key].Marks[name]
+	return mark, ok
+}
+
+// TrackDocument records the current location of a document
+func (c *Config) TrackDocument(key string, path string) {
+	if state, exists := c.Documents[key]; exists {
+		state.Path = path
+		c.Documents[key] = state
+	}
+}
+
+// GetDocState returns the saved state for a document
+func (c *Config) GetDocState(key string) (DocState, bool) {
+	state, exists := c.Documents[key]
+	return state, exists
+}
+
+// HashFile returns the document key for a file: the hex SHA-256 of its
+// contents
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // AddBookmark adds a bookmark to a document
@@ -166,51 +342,271 @@ func configPath() string {
 	return filepath.Join(configDir, "lumos", "config.toml")
 }
 
-// parseConfig parses TOML content - minimal implementation
-// In production, would use github.com/BurntSushi/toml, but keeping pragmatic here
+// parseConfig parses TOML content into cfg, migrating files written by
+// older versions and validating the result. Syntax and validation errors
+// are *ParseError values carrying the offending line.
 func parseConfig(data []byte, cfg *Config) error {
-	// For MVP, just return nil - config loading is optional
-	// The binary format matters less than the in-memory representation
-	// Users can manually edit the TOML file if needed
+	doc, err := parseTOML(data)
+	if err != nil {
+		return err
+	}
+	if err := migrateConfig(doc); err != nil {
+		return err
+	}
+	if err := decodeTOML(doc, cfg); err != nil {
+		return err
+	}
+	return validateConfig(doc, cfg)
+}
+
+// migrations[v] upgrades a parsed file from schema version v to v+1
+var migrations = map[int]func(doc *tomlTable) error{
+	1: migrateV1,
+	2: migrateV2,
+}
+
+// migrateConfig upgrades doc in place to SchemaVersion
+func migrateConfig(doc *tomlTable) error {
+	version := 1
+	if v, ok := doc.values["version"]; ok {
+		n, ok := v.(int64)
+		if !ok || n < 1 {
+			return &ParseError{Line: doc.lines["version"], Key: "version", Msg: "must be a positive integer"}
+		}
+		if n > SchemaVersion {
+			return &ParseError{Line: doc.lines["version"], Key: "version",
+				Msg: fmt.Sprintf("config version %d is newer than this build supports (%d)", n, SchemaVersion)}
+		}
+		version = int(n)
+	}
+
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return err
+		}
+	}
+	doc.set("version", int64(SchemaVersion), doc.lineOf("version"))
 	return nil
 }
 
-// toTOML converts config to TOML string
-func (c *Config) toTOML() string {
-	var content string
-
-	// UI section
-	content += "[ui]\n"
-	content += fmt.Sprintf("theme = \"%s\"\n\n", c.UI.Theme)
-
-	// Documents section
-	if len(c.Documents) > 0 {
-		content += "[documents]\n"
-		for path, state := range c.Documents {
-			content += fmt.Sprintf("\"%s\" = { last_page = %d, last_scroll = %d, timestamp = \"%s\" }\n",
-				path, state.LastPage, state.LastScroll, state.Timestamp.Format(time.RFC3339))
-		}
-		content += "\n"
-	}
-
-	// Bookmarks section
-	if len(c.Bookmarks) > 0 {
-		content += "[bookmarks]\n"
-		for docPath, bookmarks := range c.Bookmarks {
-			if len(bookmarks) > 0 {
-				content += fmt.Sprintf("# Bookmarks for %s\n", docPath)
-				for _, bm := range bookmarks {
-					if bm.Note != "" {
-						content += fmt.Sprintf("[[bookmarks.\"%s\"]]\npage = %d\nnote = \"%s\"\n\n",
-							docPath, bm.Page, bm.Note)
-					} else {
-						content += fmt.Sprintf("[[bookmarks.\"%s\"]]\npage = %d\n\n",
-							docPath, bm.Page)
-					}
-				}
+// migrateV1 converts the quoted RFC 3339 timestamps of version 1 files
+// into datetimes
+func migrateV1(doc *tomlTable) error {
+	documents, ok := doc.values["documents"].(*tomlTable)
+	if !ok {
+		return nil
+	}
+	for _, path := range documents.keys {
+		state, ok := documents.values[path].(*tomlTable)
+		if !ok {
+			continue
+		}
+		s, ok := state.values["timestamp"].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return &ParseError{Line: state.lines["timestamp"], Key: formatKeyPath([]string{"documents", path, "timestamp"}),
+				Msg: fmt.Sprintf("invalid timestamp %q", s)}
+		}
+		state.values["timestamp"] = t
+	}
+	return nil
+}
+
+// migrateV2 re-keys documents and bookmarks from file paths to content
+// hashes. Entries whose file no longer exists keep their path key, as
+// there is nothing left to hash.
+func migrateV2(doc *tomlTable) error {
+	keys := make(map[string]string) // path -> hash
+	keyFor := func(path string) (string, bool) {
+		if hash, ok := keys[path]; ok {
+			return hash, true
+		}
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return "", false
+		}
+		hash, err := HashFile(path)
+		if err != nil {
+			return "", false
+		}
+		keys[path] = hash
+		return hash, true
+	}
+
+	if documents, ok := doc.values["documents"].(*tomlTable); ok {
+		rekeyTable(documents, keyFor, func(path string, value any) {
+			if state, ok := value.(*tomlTable); ok {
+				state.set("path", path, state.line)
 			}
+		})
+	}
+	if bookmarks, ok := doc.values["bookmarks"].(*tomlTable); ok {
+		rekeyTable(bookmarks, keyFor, nil)
+	}
+	return nil
+}
+
+// rekeyTable renames the keys of t using keyFor. If two keys map to the
+// same new key (copies of one file), the first one wins. moved is called
+// with the old key and value of every renamed entry.
+func rekeyTable(t *tomlTable, keyFor func(string) (string, bool), moved func(string, any)) {
+	oldKeys := t.keys
+	values, lines := t.values, t.lines
+	t.keys, t.values, t.lines = nil, make(map[string]any), make(map[string]int)
+
+	for _, key := range oldKeys {
+		newKey, ok := keyFor(key)
+		if !ok {
+			newKey = key
+		} else if moved != nil {
+			moved(key, values[key])
 		}
+		if _, exists := t.values[newKey]; exists {
+			continue
+		}
+		t.set(newKey, values[key], lines[key])
+	}
+}
+
+// validateConfig checks decoded values that are well-typed but unusable,
+// reporting every problem found in line order
+func validateConfig(doc *tomlTable, cfg *Config) error {
+	var errs []*ParseError
+	invalid := func(msg string, path ...string) {
+		segs := make([]any, len(path))
+		for i, p := range path {
+			segs[i] = p
+		}
+		errs = append(errs, &ParseError{Line: doc.lineOf(segs...), Key: formatKeyPath(path), Msg: msg})
+	}
+
+	if !IsValidTheme(cfg.UI.Theme) {
+		invalid(fmt.Sprintf("unknown theme %q (available: %s)", cfg.UI.Theme, strings.Join(ThemeKeys(), ", ")), "ui", "theme")
+	}
+
+	if chars := cfg.UI.HintChars; chars != "" {
+		seen := make(map[rune]bool)
+		distinct := true
+		for _, c := range chars {
+			distinct = distinct && !seen[c] && !unicode.IsSpace(c)
+			seen[c] = true
+		}
+		switch {
+		case !distinct:
+			invalid(fmt.Sprintf("hint characters %q must be distinct and not spaces", chars), "ui", "hint_chars")
+		case len(seen) < 2:
+			invalid("needs at least two characters", "ui", "hint_chars")
+		}
+	}
+
+	if cfg.UI.KeyTimeout < 0 {
+		invalid(fmt.Sprintf("key timeout %d must not be negative", cfg.UI.KeyTimeout), "ui", "key_timeout_ms")
+	}
+
+	switch cfg.UI.Clipboard {
+	case "", ClipboardAuto, ClipboardOSC52, ClipboardCommand:
+	default:
+		invalid(fmt.Sprintf("unknown clipboard %q (want %s, %s or %s)", cfg.UI.Clipboard, ClipboardAuto, ClipboardOSC52, ClipboardCommand),
+			"ui", "clipboard")
 	}
 
-	return content
+	if field, ok := unknownCopyField(cfg.UI.CopyFormat); ok {
+		invalid(fmt.Sprintf("unknown placeholder {%s} (want {%s})", field, strings.Join(CopyFormatFields, "}, {")), "ui", "copy_format")
+	}
+
+	for path, state := range cfg.Documents {
+		if state.LastPage < 1 {
+			invalid("must be at least 1", "documents", path, "last_page")
+		}
+		if state.LastScroll < 0 {
+			invalid("must not be negative", "documents", path, "last_scroll")
+		}
+		switch state.Columns {
+		case "", ColumnsStacked, ColumnsSideBySide:
+		default:
+			invalid(fmt.Sprintf("unknown column view %q (want %s or %s)", state.Columns, ColumnsStacked, ColumnsSideBySide),
+				"documents", path, "columns")
+		}
+		for i, jump := range state.Jumps {
+			if jump.Page < 1 || jump.Scroll < 0 {
+				line := doc.lineOf("documents", path, "jumps", i, "page")
+				errs = append(errs, &ParseError{Line: line,
+					Key: formatKeyPath([]string{"documents", path, "jumps", fmt.Sprintf("[%d]", i)}), Msg: "page must be at least 1 and scroll not negative"})
+			}
+		}
+		for name, mark := range state.Marks {
+			validateMark(invalid, IsLocalMark(name), "a-z", mark, "documents", path, "marks", name)
+		}
+	}
+
+	for name, mark := range cfg.Marks {
+		validateMark(invalid, IsGlobalMark(name), "A-Z", mark, "marks", name)
+		if mark.Path == "" {
+			invalid("global mark needs the path of its document", "marks", name, "path")
+		}
+	}
+
+	for path, bookmarks := range cfg.Bookmarks {
+		for i, bm := range bookmarks {
+			if bm.Page < 1 {
+				line := doc.lineOf("bookmarks", path, i, "page")
+				errs = append(errs, &ParseError{Line: line,
+					Key: formatKeyPath([]string{"bookmarks", path, fmt.Sprintf("[%d]", i), "page"}), Msg: "must be at least 1"})
+			}
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	joined := make([]error, len(errs))
+	for i, err := range errs {
+		joined[i] = err
+	}
+	return errors.Join(joined...)
+}
+
+// unknownCopyField returns the first placeholder of a copy format that is
+// not one of CopyFormatFields. Placeholders are letters in braces; other
+// braces are copied as they are.
+func unknownCopyField(format string) (string, bool) {
+	for rest := format; ; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			return "", false
+		}
+		rest = rest[open+1:]
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return "", false
+		}
+		field := rest[:end]
+		if field != "" && strings.IndexFunc(field, func(r rune) bool { return !unicode.IsLetter(r) }) < 0 &&
+			!slices.Contains(CopyFormatFields, field) {
+			return field, true
+		}
+	}
+}
+
+// validateMark reports a mark with a name other than one of names, or a
+// position that can't be gone to
+func validateMark(invalid func(string, ...string), named bool, names string, mark Mark, path ...string) {
+	switch {
+	case !named:
+		invalid("mark names are "+names, path...)
+	case mark.Page < 1:
+		invalid("must be at least 1", append(path, "page")...)
+	case mark.Scroll < 0:
+		invalid("must not be negative", append(path, "scroll")...)
+	}
+}
+
+// toTOML converts config to TOML string
+func (c *Config) toTOML() string {
+	data, err := marshalTOML(c)
+	if err != nil {
+		// Config only holds types the encoder supports
+		panic(err)
+	}
+	return string(data)
 }
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestDefaultConfig verifies defaults
@@ -80,6 +85,20 @@ func TestUpdateDocState(t *testing.T) {
 	}
 }
 
+// TestSetDocView keeps a new document's state valid
+func TestSetDocView(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SetDocView("/new.pdf", ColumnsStacked, false)
+
+	state := cfg.Documents["/new.pdf"]
+	if state.Columns != ColumnsStacked || state.LastPage != 1 {
+		t.Errorf("DocState = %+v, want stacked columns on page 1", state)
+	}
+	if err := parseConfig([]byte(cfg.toTOML()), DefaultConfig()); err != nil {
+		t.Errorf("parseConfig() unexpected error: %v", err)
+	}
+}
+
 // TestDuplicateBookmark updates existing bookmark on same page
 func TestDuplicateBookmark(t *testing.T) {
 	cfg := DefaultConfig()
@@ -209,3 +228,220 @@ func BenchmarkSave(b *testing.B) {
 		cfg.Save()
 	}
 }
+
+// TestRoundTrip saves and reloads every persisted field
+func TestRoundTrip(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.UI.Theme = "nord"
+	cfg.UpdateDocState("/docs/my \"draft\".pdf", 7, 3)
+	cfg.SetDocView("/docs/my \"draft\".pdf", ColumnsSideBySide, true)
+	cfg.AddBookmark("/docs/my \"draft\".pdf", 2, `He said "see \ here"`)
+	cfg.AddBookmark("/docs/my \"draft\".pdf", 9, "")
+	cfg.SetJumps("/docs/my \"draft\".pdf", []Jump{{Page: 4, Scroll: 12}, {Page: 1}})
+	cfg.SetMark("/docs/my \"draft\".pdf", "a", Mark{Page: 3, Scroll: 40})
+	cfg.SetMark("/docs/my \"draft\".pdf", "A", Mark{Path: "/docs/my \"draft\".pdf", Page: 5})
+	cfg.Keys = map[string]map[string]string{
+		"normal":  {"ctrl+w j": "next-page", "''": "jump-back", "g": ""},
+		"command": {"ctrl+g": "cancel"},
+	}
+
+	loaded := DefaultConfig()
+	if err := parseConfig([]byte(cfg.toTOML()), loaded); err != nil {
+		t.Fatalf("parseConfig() unexpected error: %v\n%s", err, cfg.toTOML())
+	}
+
+	if loaded.Version != SchemaVersion {
+		t.Errorf("Version = %d, want %d", loaded.Version, SchemaVersion)
+	}
+	if loaded.UI.Theme != "nord" {
+		t.Errorf("Theme = %q, want nord", loaded.UI.Theme)
+	}
+
+	state := loaded.Documents["/docs/my \"draft\".pdf"]
+	want := cfg.Documents["/docs/my \"draft\".pdf"]
+	if state.LastPage != 7 || state.LastScroll != 3 || !state.Timestamp.Equal(want.Timestamp.Truncate(time.Second)) ||
+		state.Columns != ColumnsSideBySide || !state.HideRunning {
+		t.Errorf("DocState = %+v, want %+v", state, want)
+	}
+	if !reflect.DeepEqual(state.Jumps, []Jump{{Page: 4, Scroll: 12}, {Page: 1}}) {
+		t.Errorf("Jumps = %+v", state.Jumps)
+	}
+
+	if mark, ok := loaded.GetMark("/docs/my \"draft\".pdf", "a"); !ok || mark != (Mark{Page: 3, Scroll: 40}) {
+		t.Errorf("mark a = %+v, %v", mark, ok)
+	}
+	if mark, ok := loaded.GetMark("/elsewhere.pdf", "A"); !ok || mark != (Mark{Path: "/docs/my \"draft\".pdf", Page: 5}) {
+		t.Errorf("mark A = %+v, %v", mark, ok)
+	}
+
+	bookmarks := loaded.GetBookmarks("/docs/my \"draft\".pdf")
+	if len(bookmarks) != 2 || bookmarks[0].Note != `He said "see \ here"` || bookmarks[1].Page != 9 {
+		t.Errorf("Bookmarks = %+v", bookmarks)
+	}
+
+	if !reflect.DeepEqual(loaded.Keys, cfg.Keys) {
+		t.Errorf("Keys = %v, want %v", loaded.Keys, cfg.Keys)
+	}
+}
+
+// TestLoadConfig_ValidationErrors reports problems with their line numbers
+func TestLoadConfig_ValidationErrors(t *testing.T) {
+	input := `version = 2
+
+[ui]
+theme = "neon"
+hint_chars = "aa"
+key_timeout_ms = -5
+clipboard = "x11"
+copy_format = "{text} {Page} {x-1}"
+
+[documents]
+"/b.pdf" = { last_page = 1, last_scroll = 0, columns = "three", timestamp = 2025-11-01T10:20:30Z }
+
+[documents."/c.pdf"]
+last_page = 1
+last_scroll = 0
+timestamp = 2025-11-01T10:20:30Z
+marks = { a = { page = 0 }, B = { page = 2 } }
+
+[[bookmarks."/a.pdf"]]
+page = 0
+
+[marks]
+A = { page = 3 }
+`
+	err := parseConfig([]byte(input), DefaultConfig())
+	if err == nil {
+		t.Fatal("parseConfig() expected error")
+	}
+
+	for _, want := range []string{
+		`line 4: ui.theme: unknown theme "neon"`,
+		`line 5: ui.hint_chars: hint characters "aa" must be distinct and not spaces`,
+		`line 6: ui.key_timeout_ms: key timeout -5 must not be negative`,
+		`line 7: ui.clipboard: unknown clipboard "x11" (want auto, osc52 or command)`,
+		`line 8: ui.copy_format: unknown placeholder {Page} (want {text}, {page}, {file}, {title})`,
+		`line 11: documents."/b.pdf".columns: unknown column view "three"`,
+		`line 17: documents."/c.pdf".marks.a.page: must be at least 1`,
+		`line 17: documents."/c.pdf".marks.B: mark names are a-z`,
+		`line 20: bookmarks."/a.pdf"[0].page: must be at least 1`,
+		`line 23: marks.A.path: global mark needs the path of its document`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err, want)
+		}
+	}
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Line != 4 {
+		t.Errorf("first error should be a *ParseError on line 4, got %v", err)
+	}
+}
+
+// TestLoadConfig_NewerVersion refuses files from a newer build
+func TestLoadConfig_NewerVersion(t *testing.T) {
+	err := parseConfig([]byte("version = 99\n"), DefaultConfig())
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Key != "version" || perr.Line != 1 {
+		t.Errorf("expected version error on line 1, got %v", err)
+	}
+}
+
+// TestMigrateV1 loads a file written before the schema was versioned
+func TestMigrateV1(t *testing.T) {
+	input := `[ui]
+theme = "dracula"
+
+[documents]
+"/docs/paper.pdf" = { last_page = 15, last_scroll = 50, timestamp = "2025-11-01T10:20:30Z" }
+
+[bookmarks]
+# Bookmarks for /docs/paper.pdf
+[[bookmarks."/docs/paper.pdf"]]
+page = 10
+note = "Methods"
+
+`
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	path := configPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	if cfg.Version != SchemaVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, SchemaVersion)
+	}
+	state := cfg.Documents["/docs/paper.pdf"]
+	if state.LastPage != 15 || !state.Timestamp.Equal(time.Date(2025, 11, 1, 10, 20, 30, 0, time.UTC)) {
+		t.Errorf("DocState = %+v", state)
+	}
+	if !cfg.HasBookmark("/docs/paper.pdf", 10) {
+		t.Error("Bookmark should survive migration")
+	}
+
+	// Saving writes the current schema
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if !contains(string(content), fmt.Sprintf("version = %d", SchemaVersion)) || !contains(string(content), "timestamp = 2025-11-01T10:20:30Z") {
+		t.Errorf("Saved config not migrated:\n%s", content)
+	}
+}
+
+// TestMigrateV2 re-keys path-keyed state by content hash
+func TestMigrateV2(t *testing.T) {
+	docPath := filepath.Join(t.TempDir(), "spec.pdf")
+	if err := os.WriteFile(docPath, []byte("%PDF-1.4 test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := HashFile(docPath)
+	if err != nil {
+		t.Fatalf("HashFile() unexpected error: %v", err)
+	}
+
+	input := fmt.Sprintf(`version = 2
+
+[documents.%[1]q]
+last_page = 120
+last_scroll = 4
+timestamp = 2025-11-01T10:20:30Z
+
+[documents."/gone/missing.pdf"]
+last_page = 3
+last_scroll = 0
+timestamp = 2025-11-01T10:20:30Z
+
+[[bookmarks.%[1]q]]
+page = 7
+`, docPath)
+
+	cfg := DefaultConfig()
+	if err := parseConfig([]byte(input), cfg); err != nil {
+		t.Fatalf("parseConfig() unexpected error: %v", err)
+	}
+
+	state, ok := cfg.GetDocState(key)
+	if !ok || state.LastPage != 120 || state.Path != docPath {
+		t.Errorf("GetDocState(hash) = %+v, %v", state, ok)
+	}
+	if _, ok := cfg.Documents[docPath]; ok {
+		t.Error("Path key should have been replaced by the hash")
+	}
+	if _, ok := cfg.Documents["/gone/missing.pdf"]; !ok {
+		t.Error("Entries for missing files should be kept")
+	}
+	if !cfg.HasBookmark(key, 7) {
+		t.Error("Bookmarks should be re-keyed by hash")
+	}
+}
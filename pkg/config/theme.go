@@ -17,7 +17,7 @@ type Theme struct {
 // DarkTheme is the default dark mode theme (aligned with BARQUE dark theme)
 // Uses slightly darker background for consistency with BARQUE-generated PDFs
 var DarkTheme = Theme{
-	Name:       "dark",
+	Name:       "LUMOS Dark",
 	Background: "#1a1a1a", // Aligned with BARQUE dark_theme.background
 	Text:       "#e8e8e8", // Aligned with BARQUE dark_theme.text
 	Accent:     "#60a5fa", // Aligned with BARQUE dark_theme.accent
@@ -29,7 +29,7 @@ var DarkTheme = Theme{
 
 // LightTheme is an alternative light mode theme
 var LightTheme = Theme{
-	Name:       "light",
+	Name:       "Light",
 	Background: "#ffffff",
 	Text:       "#383838",
 	Accent:     "#0184bc",
@@ -39,14 +39,123 @@ var LightTheme = Theme{
 	Error:      "#d1394d",
 }
 
+// TokyoNightTheme is a soft blue-gray dark theme for extended reading
+var TokyoNightTheme = Theme{
+	Name:       "Tokyo Night",
+	Background: "#1a1b26",
+	Text:       "#c0caf5",
+	Accent:     "#7aa2f7",
+	Muted:      "#565f89",
+	Warning:    "#e0af68",
+	Success:    "#9ece6a",
+	Error:      "#f7768e",
+}
+
+// DraculaTheme is a high-contrast dark theme with vivid accents
+var DraculaTheme = Theme{
+	Name:       "Dracula",
+	Background: "#282a36",
+	Text:       "#f8f8f2",
+	Accent:     "#8be9fd",
+	Muted:      "#6272a4",
+	Warning:    "#f1fa8c",
+	Success:    "#50fa7b",
+	Error:      "#ff5555",
+}
+
+// SolarizedDarkTheme uses Solarized's intentionally moderate contrast
+var SolarizedDarkTheme = Theme{
+	Name:       "Solarized Dark",
+	Background: "#002b36",
+	Text:       "#93a1a1",
+	Accent:     "#268bd2",
+	Muted:      "#586e75",
+	Warning:    "#b58900",
+	Success:    "#859900",
+	Error:      "#dc322f",
+}
+
+// NordTheme is a minimal arctic dark theme
+var NordTheme = Theme{
+	Name:       "Nord",
+	Background: "#2e3440",
+	Text:       "#eceff4",
+	Accent:     "#88c0d0",
+	Muted:      "#4c566a",
+	Warning:    "#ebcb8b",
+	Success:    "#a3be8c",
+	Error:      "#bf616a",
+}
+
+// AvailableThemes lists the dark themes in cycling order
+var AvailableThemes = []Theme{
+	DarkTheme,
+	TokyoNightTheme,
+	DraculaTheme,
+	SolarizedDarkTheme,
+	NordTheme,
+}
+
+// themeKeys are the config names of all themes, in display order
+var themeKeys = []string{"dark", "tokyo-night", "dracula", "solarized-dark", "nord", "light"}
+
+// themeRegistry maps config names to themes
+var themeRegistry = map[string]Theme{
+	"dark":           DarkTheme,
+	"lumos-dark":     DarkTheme,
+	"tokyo-night":    TokyoNightTheme,
+	"dracula":        DraculaTheme,
+	"solarized-dark": SolarizedDarkTheme,
+	"nord":           NordTheme,
+	"light":          LightTheme,
+}
+
 // GetTheme returns a theme by name
 func GetTheme(name string) Theme {
-	switch name {
-	case "light":
-		return LightTheme
-	default:
-		return DarkTheme
+	if theme, ok := themeRegistry[name]; ok {
+		return theme
+	}
+	return DarkTheme
+}
+
+// IsValidTheme reports whether name is a known theme config name
+func IsValidTheme(name string) bool {
+	_, ok := themeRegistry[name]
+	return ok
+}
+
+// ThemeKeys returns the config names of all themes
+func ThemeKeys() []string {
+	return append([]string(nil), themeKeys...)
+}
+
+// ThemeKey returns the config name for a theme, as stored in [ui] theme
+func ThemeKey(theme Theme) string {
+	for _, key := range themeKeys {
+		if themeRegistry[key].Name == theme.Name {
+			return key
+		}
 	}
+	return "dark"
+}
+
+// AvailableThemeNames returns the display names of the dark themes
+func AvailableThemeNames() []string {
+	names := make([]string, len(AvailableThemes))
+	for i, theme := range AvailableThemes {
+		names[i] = theme.Name
+	}
+	return names
+}
+
+// GetNextTheme returns the dark theme after current, wrapping around
+func GetNextTheme(current Theme) Theme {
+	for i, theme := range AvailableThemes {
+		if theme.Name == current.Name {
+			return AvailableThemes[(i+1)%len(AvailableThemes)]
+		}
+	}
+	return AvailableThemes[0]
 }
 
 // Styles contains all UI styles for a theme
@@ -64,6 +173,12 @@ type Styles struct {
 	SelectedItem    lipgloss.Style
 	UnselectedItem  lipgloss.Style
 	SearchMatch     lipgloss.Style
+	CurrentMatch    lipgloss.Style
+	Link            lipgloss.Style
+	Hint            lipgloss.Style // labels of links in hint mode
+	HintTyped       lipgloss.Style // the part of a hint label typed so far
+	Selection       lipgloss.Style // text selected in visual mode
+	Cursor          lipgloss.Style // the text cursor of visual mode
 	LineNumber      lipgloss.Style
 	StatusBar       lipgloss.Style
 	HelpText        lipgloss.Style
@@ -128,6 +243,32 @@ func NewStyles(theme Theme) Styles {
 			Foreground(lipgloss.Color(theme.Background)).
 			Bold(true),
 
+		CurrentMatch: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.Accent)).
+			Foreground(lipgloss.Color(theme.Background)).
+			Bold(true),
+
+		Link: lipgloss.NewStyle().
+			Foreground(lipgloss.Color(theme.Accent)).
+			Underline(true),
+
+		Hint: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.Warning)).
+			Foreground(lipgloss.Color(theme.Background)).
+			Bold(true),
+
+		HintTyped: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.Warning)).
+			Foreground(lipgloss.Color(theme.Muted)),
+
+		Selection: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.Muted)).
+			Foreground(lipgloss.Color(theme.Text)),
+
+		Cursor: lipgloss.NewStyle().
+			Background(lipgloss.Color(theme.Text)).
+			Foreground(lipgloss.Color(theme.Background)),
+
 		LineNumber: lipgloss.NewStyle().
 			Foreground(lipgloss.Color(theme.Muted)).
 			Background(lipgloss.Color(theme.Background)),
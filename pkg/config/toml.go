@@ -0,0 +1,1203 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ParseError reports a syntax or validation problem in a TOML file,
+// with the 1-based line it occurred on
+type ParseError struct {
+	Line int
+	Key  string // dotted key path, if the error concerns a value
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Key, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// tomlTable is a parsed TOML table. Every key remembers the line it was
+// defined on so that later validation can point back into the file.
+//
+// Values are string, int64, float64, bool, time.Time, []any (arrays),
+// *tomlTable (tables and inline tables) or *tomlTableArray ([[arrays]]).
+type tomlTable struct {
+	keys   []string // definition order
+	values map[string]any
+	lines  map[string]int
+	line   int
+
+	explicit bool // defined by a [header]
+	dotted   bool // created by a dotted key
+	frozen   bool // inline tables cannot be extended
+}
+
+// tomlTableArray is an array of tables built from [[header]] sections
+type tomlTableArray struct {
+	tables []*tomlTable
+}
+
+func newTOMLTable(line int) *tomlTable {
+	return &tomlTable{
+		values: make(map[string]any),
+		lines:  make(map[string]int),
+		line:   line,
+	}
+}
+
+func (t *tomlTable) set(key string, value any, line int) {
+	if _, exists := t.values[key]; !exists {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = value
+	t.lines[key] = line
+}
+
+// lineOf returns the line on which the value at path was defined. Path
+// segments are table keys (string) or array indexes (int). If the path
+// does not exist, the line of its deepest existing ancestor is returned.
+func (t *tomlTable) lineOf(path ...any) int {
+	line := t.line
+	var cur any = t
+	for _, seg := range path {
+		switch c := cur.(type) {
+		case *tomlTable:
+			key, _ := seg.(string)
+			v, ok := c.values[key]
+			if !ok {
+				return line
+			}
+			line, cur = c.lines[key], v
+		case *tomlTableArray:
+			i, ok := seg.(int)
+			if !ok || i < 0 || i >= len(c.tables) {
+				return line
+			}
+			line, cur = c.tables[i].line, c.tables[i]
+		case []any:
+			i, ok := seg.(int)
+			if !ok || i < 0 || i >= len(c) {
+				return line
+			}
+			cur = c[i]
+			if sub, ok := cur.(*tomlTable); ok {
+				line = sub.line
+			}
+		default:
+			return line
+		}
+	}
+	return line
+}
+
+// parseTOML parses a TOML document into its root table
+func parseTOML(data []byte) (*tomlTable, error) {
+	if !utf8.Valid(data) {
+		return nil, &ParseError{Line: 1, Msg: "file is not valid UTF-8"}
+	}
+	p := &tomlParser{src: string(data), line: 1}
+	p.root = newTOMLTable(0)
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.root, nil
+}
+
+// tomlParser is a recursive descent parser for TOML 1.0
+type tomlParser struct {
+	src     string
+	pos     int
+	line    int
+	root    *tomlTable
+	current *tomlTable
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return &ParseError{Line: p.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) hasPrefix(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *tomlParser) next() byte {
+	c := p.src[p.pos]
+	p.pos++
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// skipSpace skips spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to (not including) the newline
+func (p *tomlParser) skipComment() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments (inside arrays)
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+// newline consumes a line ending, reporting whether there was one
+func (p *tomlParser) newline() bool {
+	if p.hasPrefix("\r\n") {
+		p.pos++
+	}
+	if p.peek() == '\n' {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// endOfLine requires the rest of the line to be blank or a comment
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		p.skipComment()
+	}
+	if p.eof() || p.newline() {
+		return nil
+	}
+	return p.errorf("expected end of line, found %q", p.peek())
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+
+		var err error
+		switch c := p.peek(); {
+		case c == '#':
+			p.skipComment()
+		case c == '\n' || c == '\r':
+		case c == '[':
+			err = p.parseHeader()
+		default:
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseHeader parses a [table] or [[array.of.tables]] header
+func (p *tomlParser) parseHeader() error {
+	line := p.line
+	p.next()
+	isArray := p.peek() == '['
+	if isArray {
+		p.next()
+	}
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !p.hasPrefix(closing) {
+		return p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent := p.root
+	for i, key := range keys[:len(keys)-1] {
+		if parent, err = p.descend(parent, key, keys[:i+1], line, false); err != nil {
+			return err
+		}
+	}
+
+	name := keys[len(keys)-1]
+	existing, exists := parent.values[name]
+
+	if isArray {
+		table := newTOMLTable(line)
+		table.explicit = true
+		switch v := existing.(type) {
+		case nil:
+			parent.set(name, &tomlTableArray{tables: []*tomlTable{table}}, line)
+		case *tomlTableArray:
+			v.tables = append(v.tables, table)
+		default:
+			return p.errorf("cannot define %s as an array of tables: already a %s", formatKeyPath(keys), tomlTypeName(v))
+		}
+		p.current = table
+		return nil
+	}
+
+	if !exists {
+		table := newTOMLTable(line)
+		table.explicit = true
+		parent.set(name, table, line)
+		p.current = table
+		return nil
+	}
+	table, ok := existing.(*tomlTable)
+	if !ok || table.explicit || table.dotted || table.frozen {
+		return p.errorf("table %s is already defined", formatKeyPath(keys))
+	}
+	// An implicitly created super-table may be defined later exactly once
+	table.explicit = true
+	table.line = line
+	parent.lines[name] = line
+	p.current = table
+	return nil
+}
+
+// descend returns the sub-table key of t, creating it when missing. The
+// last table of an array of tables is used, as TOML requires.
+func (p *tomlParser) descend(t *tomlTable, key string, path []string, line int, dotted bool) (*tomlTable, error) {
+	switch v := t.values[key].(type) {
+	case nil:
+		sub := newTOMLTable(line)
+		sub.dotted = dotted
+		t.set(key, sub, line)
+		return sub, nil
+	case *tomlTable:
+		if v.frozen {
+			return nil, p.errorf("cannot extend inline table %s", formatKeyPath(path))
+		}
+		if dotted && v.explicit {
+			return nil, p.errorf("cannot add to table %s with a dotted key", formatKeyPath(path))
+		}
+		return v, nil
+	case *tomlTableArray:
+		if dotted {
+			return nil, p.errorf("cannot add to array of tables %s with a dotted key", formatKeyPath(path))
+		}
+		return v.tables[len(v.tables)-1], nil
+	default:
+		return nil, p.errorf("key %s is already defined as a %s", formatKeyPath(path), tomlTypeName(v))
+	}
+}
+
+// parseKey parses a possibly dotted key
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		var err error
+		switch c := p.peek(); {
+		case c == '"':
+			key, err = p.parseBasicString()
+		case c == '\'':
+			key, err = p.parseLiteralString()
+		case isBareKeyChar(c):
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			key = p.src[start:p.pos]
+		case c == 0 || c == '\n' || c == '\r':
+			return nil, p.errorf("expected key, found end of line")
+		default:
+			return nil, p.errorf("invalid character %q in key", c)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+// parseKeyValue parses "key = value" into t
+func (p *tomlParser) parseKeyValue(t *tomlTable) error {
+	line := p.line
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %s", formatKeyPath(keys))
+	}
+	p.next()
+	p.skipSpace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys[:len(keys)-1] {
+		if t, err = p.descend(t, key, keys[:i+1], line, true); err != nil {
+			return err
+		}
+	}
+	name := keys[len(keys)-1]
+	if _, exists := t.values[name]; exists {
+		return &ParseError{Line: line, Msg: fmt.Sprintf("duplicate key %s", formatKeyPath(keys))}
+	}
+	t.set(name, value, line)
+	return nil
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	switch c := p.peek(); {
+	case p.hasPrefix(`"""`):
+		return p.parseMultilineBasicString()
+	case c == '"':
+		return p.parseBasicString()
+	case p.hasPrefix("'''"):
+		return p.parseMultilineLiteralString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case c == 0 || c == '\n' || c == '\r' || c == '#':
+		return nil, p.errorf("missing value")
+	}
+	return p.parseScalar()
+}
+
+var (
+	tomlIntegerPattern = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloatPattern   = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlDatePattern    = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// parseScalar parses booleans, numbers and dates
+func (p *tomlParser) parseScalar() (any, error) {
+	start := p.pos
+	for !p.eof() && isScalarChar(p.peek()) {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	// A space may separate the date and time of a datetime
+	if tomlDatePattern.MatchString(token) && len(p.src) > p.pos+3 &&
+		p.src[p.pos] == ' ' && isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && isScalarChar(p.peek()) {
+			p.pos++
+		}
+		token = p.src[start:p.pos]
+	}
+
+	switch token {
+	case "":
+		return nil, p.errorf("invalid value starting with %q", p.peek())
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	if isDatetimeToken(token) {
+		if t, ok := parseTOMLDatetime(token); ok {
+			return t, nil
+		}
+		return nil, p.errorf("invalid datetime %q", token)
+	}
+
+	if len(token) > 2 && token[0] == '0' && strings.ContainsRune("xob", rune(token[1])) {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[token[1]]
+		digits := token[2:]
+		if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+			return nil, p.errorf("invalid integer %q", token)
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", token)
+		}
+		return n, nil
+	}
+
+	clean := strings.ReplaceAll(token, "_", "")
+	if tomlIntegerPattern.MatchString(token) {
+		n, err := strconv.ParseInt(clean, 10, 64)
+		if err != nil {
+			return nil, p.errorf("integer %s out of range", token)
+		}
+		return n, nil
+	}
+	if tomlFloatPattern.MatchString(token) {
+		f, err := strconv.ParseFloat(clean, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", token)
+		}
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// parseTOMLDatetime parses offset and local datetimes, dates and times.
+// Local values are interpreted in the local time zone.
+func parseTOMLDatetime(s string) (time.Time, bool) {
+	if len(s) > 10 && (s[10] == ' ' || s[10] == 't') {
+		s = s[:10] + "T" + s[11:]
+	}
+	s = strings.Replace(s, "z", "Z", 1)
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02", "15:04:05.999999999"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.next()
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' || p.peek() == '\r' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.next()
+		switch {
+		case c == '"':
+			return sb.String(), nil
+		case c == '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case isControlChar(c):
+			return "", p.errorf("control character %q must be escaped in string", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	p.pos += 3
+	p.newline() // a newline right after the delimiter is trimmed
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line string")
+		}
+		if p.hasPrefix(`"""`) {
+			quotes := 0
+			for p.peek() == '"' && quotes < 5 {
+				p.next()
+				quotes++
+			}
+			sb.WriteString(strings.Repeat(`"`, quotes-3))
+			return sb.String(), nil
+		}
+
+		c := p.peek()
+		switch {
+		case c == '\\':
+			p.next()
+			// A line-ending backslash trims the newline and following whitespace
+			rest := p.pos
+			p.skipSpace()
+			if p.peek() == '\n' || p.hasPrefix("\r\n") {
+				p.skipBlankSpace()
+				continue
+			}
+			p.pos = rest
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\n':
+			sb.WriteByte(p.next())
+		case c == '\r' && p.hasPrefix("\r\n"):
+			p.pos++
+		case isControlChar(c) && c != '\t':
+			return "", p.errorf("control character %q must be escaped in string", c)
+		default:
+			sb.WriteByte(p.next())
+		}
+	}
+}
+
+// skipBlankSpace skips whitespace including newlines
+func (p *tomlParser) skipBlankSpace() {
+	for !p.eof() && strings.IndexByte(" \t\r\n", p.peek()) >= 0 {
+		p.next()
+	}
+}
+
+// parseEscape decodes the escape sequence following a backslash
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated escape sequence")
+	}
+	c := p.next()
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case 'e':
+		sb.WriteByte(0x1b)
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape \\%c%s", c, p.src[p.pos:p.pos+size])
+		}
+		p.pos += size
+		sb.WriteRune(rune(code))
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.next()
+	start := p.pos
+	for {
+		if p.eof() || p.peek() == '\n' || p.peek() == '\r' {
+			return "", p.errorf("unterminated literal string")
+		}
+		c := p.next()
+		if c == '\'' {
+			return p.src[start : p.pos-1], nil
+		}
+		if isControlChar(c) {
+			return "", p.errorf("control character %q in literal string", c)
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	p.pos += 3
+	p.newline()
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated multi-line literal string")
+		}
+		if p.hasPrefix("'''") {
+			quotes := 0
+			for p.peek() == '\'' && quotes < 5 {
+				p.next()
+				quotes++
+			}
+			sb.WriteString(strings.Repeat("'", quotes-3))
+			return sb.String(), nil
+		}
+		if p.hasPrefix("\r\n") {
+			p.pos++
+		}
+		c := p.next()
+		if isControlChar(c) && c != '\t' && c != '\n' {
+			return "", p.errorf("control character %q in literal string", c)
+		}
+		sb.WriteByte(c)
+	}
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.next()
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.next()
+			return values, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+			p.next()
+			return values, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (*tomlTable, error) {
+	table := newTOMLTable(p.line)
+	p.next()
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.next()
+		table.frozen = true
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case '}':
+			p.next()
+			freeze(table)
+			return table, nil
+		case '\n', '\r':
+			return nil, p.errorf("newline in inline table")
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// freeze marks an inline table and the tables its dotted keys created as
+// immutable
+func freeze(t *tomlTable) {
+	t.frozen = true
+	for _, v := range t.values {
+		if sub, ok := v.(*tomlTable); ok {
+			freeze(sub)
+		}
+	}
+}
+
+// isDatetimeToken reports whether token starts like a date (YYYY-) or a time (HH:)
+func isDatetimeToken(token string) bool {
+	digits := func(s string) bool {
+		for i := 0; i < len(s); i++ {
+			if !isDigit(s[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return len(token) >= 5 && digits(token[:4]) && token[4] == '-' ||
+		len(token) >= 3 && digits(token[:2]) && token[2] == ':'
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+func isScalarChar(c byte) bool {
+	return isBareKeyChar(c) || c == '+' || c == '.' || c == ':'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isControlChar(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+// tomlTypeName names the TOML type of a parsed value for error messages
+func tomlTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "datetime"
+	case []any:
+		return "array"
+	case *tomlTable:
+		return "table"
+	case *tomlTableArray:
+		return "array of tables"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// formatKey quotes a key unless it is a valid bare key
+func formatKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for i := 0; i < len(key); i++ {
+		if !isBareKeyChar(key[i]) {
+			return quoteTOMLString(key)
+		}
+	}
+	return key
+}
+
+// formatKeyPath joins keys into a dotted key. Array index segments such
+// as "[2]" are appended without a separator.
+func formatKeyPath(keys []string) string {
+	var sb strings.Builder
+	for i, key := range keys {
+		if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+			sb.WriteString(key)
+			continue
+		}
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(formatKey(key))
+	}
+	return sb.String()
+}
+
+// quoteTOMLString writes s as a basic string, escaping quotes, backslashes
+// and control characters
+func quoteTOMLString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// Decoding
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// tomlField describes a struct field mapped to a TOML key
+type tomlField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// tomlFields returns the fields of a struct type that carry a toml tag
+func tomlFields(t reflect.Type) []tomlField {
+	var fields []tomlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("toml")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fields = append(fields, tomlField{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
+
+// decodeTOML assigns the values in t to the struct pointed to by v. Keys
+// that do not correspond to a field are reported as errors.
+func decodeTOML(t *tomlTable, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("decodeTOML: need a non-nil pointer, got %T", v)
+	}
+	return decodeValue(t, t.line, nil, rv.Elem())
+}
+
+func decodeValue(value any, line int, path []string, rv reflect.Value) error {
+	mismatch := func() error {
+		return &ParseError{Line: line, Key: formatKeyPath(path),
+			Msg: fmt.Sprintf("expected %s, found %s", goTypeName(rv.Type()), tomlTypeName(value))}
+	}
+
+	if rv.Type() == timeType {
+		t, ok := value.(time.Time)
+		if !ok {
+			return mismatch()
+		}
+		rv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetString(s)
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		rv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(int64)
+		if !ok {
+			return mismatch()
+		}
+		if rv.OverflowInt(n) {
+			return &ParseError{Line: line, Key: formatKeyPath(path), Msg: fmt.Sprintf("%d is out of range", n)}
+		}
+		rv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(int64)
+		if !ok {
+			return mismatch()
+		}
+		if n < 0 || rv.OverflowUint(uint64(n)) {
+			return &ParseError{Line: line, Key: formatKeyPath(path), Msg: fmt.Sprintf("%d is out of range", n)}
+		}
+		rv.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		switch n := value.(type) {
+		case float64:
+			rv.SetFloat(n)
+		case int64:
+			rv.SetFloat(float64(n))
+		default:
+			return mismatch()
+		}
+
+	case reflect.Struct:
+		table, ok := value.(*tomlTable)
+		if !ok {
+			return mismatch()
+		}
+		fields := make(map[string]tomlField)
+		for _, f := range tomlFields(rv.Type()) {
+			fields[f.name] = f
+		}
+		for _, key := range table.keys {
+			keyPath := appendPath(path, key)
+			f, ok := fields[key]
+			if !ok {
+				return &ParseError{Line: table.lines[key], Key: formatKeyPath(keyPath), Msg: "unknown key"}
+			}
+			if err := decodeValue(table.values[key], table.lines[key], keyPath, rv.Field(f.index)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		table, ok := value.(*tomlTable)
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, key := range table.keys {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(table.values[key], table.lines[key], appendPath(path, key), elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+
+	case reflect.Slice:
+		var items []any
+		var lines []int
+		switch v := value.(type) {
+		case []any:
+			items = v
+			for range v {
+				lines = append(lines, line)
+			}
+		case *tomlTableArray:
+			for _, t := range v.tables {
+				items = append(items, t)
+				lines = append(lines, t.line)
+			}
+		default:
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if t, ok := item.(*tomlTable); ok {
+				lines[i] = t.line
+			}
+			if err := decodeValue(item, lines[i], appendPath(path, fmt.Sprintf("[%d]", i)), slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+
+	default:
+		return &ParseError{Line: line, Key: formatKeyPath(path), Msg: fmt.Sprintf("unsupported field type %s", rv.Type())}
+	}
+	return nil
+}
+
+func appendPath(path []string, key string) []string {
+	return append(append([]string(nil), path...), key)
+}
+
+// goTypeName describes the TOML type expected for a Go type
+func goTypeName(t reflect.Type) string {
+	if t == timeType {
+		return "datetime"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Struct, reflect.Map:
+		return "table"
+	case reflect.Slice:
+		return "array"
+	}
+	return t.String()
+}
+
+// Encoding
+
+// marshalTOML encodes a struct as a TOML document. Scalars and arrays of
+// scalars are written as key/value pairs; nested structs and maps become
+// [tables] and slices of structs become [[arrays of tables]]. Map keys are
+// sorted so the output is stable.
+func marshalTOML(v any) ([]byte, error) {
+	var sb strings.Builder
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("marshalTOML: need a struct, got %T", v)
+	}
+	if err := encodeTable(&sb, nil, rv, false); err != nil {
+		return nil, err
+	}
+	return []byte(sb.String()), nil
+}
+
+// tomlEntry is a key and value pending encoding
+type tomlEntry struct {
+	key   string
+	value reflect.Value
+}
+
+func encodeTable(sb *strings.Builder, path []string, rv reflect.Value, arrayElem bool) error {
+	var entries []tomlEntry
+	switch rv.Kind() {
+	case reflect.Struct:
+		for _, f := range tomlFields(rv.Type()) {
+			field := rv.Field(f.index)
+			if f.omitEmpty && field.IsZero() {
+				continue
+			}
+			entries = append(entries, tomlEntry{f.name, field})
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			entries = append(entries, tomlEntry{k.String(), rv.MapIndex(k)})
+		}
+	}
+
+	if len(path) > 0 {
+		if sb.Len() > 0 {
+			sb.WriteByte('\n')
+		}
+		if arrayElem {
+			fmt.Fprintf(sb, "[[%s]]\n", formatKeyPath(path))
+		} else {
+			fmt.Fprintf(sb, "[%s]\n", formatKeyPath(path))
+		}
+	}
+
+	// Plain values must precede sub-tables, which would otherwise claim them
+	var tables, arrays []tomlEntry
+	for _, e := range entries {
+		value := reflect.Indirect(e.value)
+		if !value.IsValid() || value.Kind() == reflect.Slice && value.IsNil() {
+			continue
+		}
+		switch {
+		case isTableValue(value):
+			tables = append(tables, tomlEntry{e.key, value})
+		case value.Kind() == reflect.Slice && isTableType(value.Type().Elem()) && value.Len() > 0:
+			arrays = append(arrays, tomlEntry{e.key, value})
+		default:
+			s, err := encodeInline(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", formatKeyPath(appendPath(path, e.key)), err)
+			}
+			fmt.Fprintf(sb, "%s = %s\n", formatKey(e.key), s)
+		}
+	}
+
+	for _, e := range tables {
+		if err := encodeTable(sb, appendPath(path, e.key), e.value, false); err != nil {
+			return err
+		}
+	}
+	for _, e := range arrays {
+		for i := 0; i < e.value.Len(); i++ {
+			if err := encodeTable(sb, appendPath(path, e.key), reflect.Indirect(e.value.Index(i)), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isTableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t != timeType && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map)
+}
+
+func isTableValue(v reflect.Value) bool {
+	return isTableType(v.Type())
+}
+
+// encodeInline encodes a scalar or an array of scalars
+func encodeInline(v reflect.Value) (string, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return quoteTOMLString(v.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return "", fmt.Errorf("%d does not fit in a TOML integer", v.Uint())
+		}
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan", nil
+		case math.IsInf(f, 1):
+			return "inf", nil
+		case math.IsInf(f, -1):
+			return "-inf", nil
+		}
+		s := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := range items {
+			item := reflect.Indirect(v.Index(i))
+			if !item.IsValid() || isTableValue(item) {
+				return "", fmt.Errorf("unsupported array element %s", v.Type().Elem())
+			}
+			s, err := encodeInline(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTOML_Values(t *testing.T) {
+	input := `# comment
+str = "tab\there \"quoted\" \u00e9"
+lit = 'C:\path\no\escape'
+multi = """
+first
+second \
+   joined"""
+int = 1_000
+hex = 0xff
+neg = -17
+float = 6.5e-1
+inf = -inf
+yes = true
+date = 1979-05-27T07:32:00Z
+space = 1979-05-27 07:32:00-07:00
+local = 1979-05-27
+list = [1, 2,
+  3, # trailing comma and comments are allowed
+]
+inline = { a = 1, b.c = "x" }
+"quoted key" = 1
+dotted.key = 2
+
+[table]
+value = "t"
+
+[[items]]
+n = 1
+
+[[items]]
+n = 2
+`
+	doc, err := parseTOML([]byte(input))
+	if err != nil {
+		t.Fatalf("parseTOML() unexpected error: %v", err)
+	}
+
+	want := map[string]any{
+		"str":   "tab\there \"quoted\" é",
+		"lit":   `C:\path\no\escape`,
+		"multi": "first\nsecond joined",
+		"int":   int64(1000),
+		"hex":   int64(255),
+		"neg":   int64(-17),
+		"float": 0.65,
+		"inf":   math.Inf(-1),
+		"yes":   true,
+		"date":  time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		"space": time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -7*3600)),
+		"local": time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local),
+		"list":  []any{int64(1), int64(2), int64(3)},
+	}
+	for key, w := range want {
+		got := doc.values[key]
+		if gotTime, ok := got.(time.Time); ok {
+			if !gotTime.Equal(w.(time.Time)) {
+				t.Errorf("%s = %v, want %v", key, got, w)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("%s = %#v, want %#v", key, got, w)
+		}
+	}
+
+	inline := doc.values["inline"].(*tomlTable)
+	if inline.values["a"] != int64(1) || inline.values["b"].(*tomlTable).values["c"] != "x" {
+		t.Errorf("inline table parsed incorrectly: %+v", inline.values)
+	}
+	if doc.values["quoted key"] != int64(1) {
+		t.Error("quoted key not parsed")
+	}
+	if doc.values["dotted"].(*tomlTable).values["key"] != int64(2) {
+		t.Error("dotted key not parsed")
+	}
+	if doc.values["table"].(*tomlTable).values["value"] != "t" {
+		t.Error("table not parsed")
+	}
+	items := doc.values["items"].(*tomlTableArray)
+	if len(items.tables) != 2 || items.tables[1].values["n"] != int64(2) {
+		t.Errorf("array of tables parsed incorrectly")
+	}
+	if line := doc.lineOf("items", 1, "n"); line != 31 {
+		t.Errorf("lineOf(items[1].n) = %d, want 31", line)
+	}
+}
+
+func TestParseTOML_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"missing value", "a = 1\nb =\n", 2},
+		{"unterminated string", "[ui]\ntheme = \"dark\n", 2},
+		{"bad escape", "a = \"\\q\"", 1},
+		{"duplicate key", "a = 1\n\na = 2\n", 3},
+		{"duplicate table", "[a]\nx = 1\n[b]\n[a]\n", 4},
+		{"trailing garbage", "a = 1 2\n", 1},
+		{"bad number", "a = 01\n", 1},
+		{"key is not a table", "a = 1\n[a.b]\n", 2},
+		{"extend inline table", "a = { x = 1 }\na.y = 2\n", 2},
+		{"unterminated array", "a = [1,\n2\n", 3},
+		{"newline in inline table", "a = { x = 1,\ny = 2 }", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML([]byte(tt.input))
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("parseTOML() error = %v, want *ParseError", err)
+			}
+			if perr.Line != tt.line {
+				t.Errorf("error line = %d, want %d (%v)", perr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestMarshalTOML_RoundTrip(t *testing.T) {
+	type inner struct {
+		Name  string   `toml:"name"`
+		Tags  []string `toml:"tags"`
+		Ratio float64  `toml:"ratio"`
+	}
+	type doc struct {
+		Title   string           `toml:"title"`
+		Count   int              `toml:"count"`
+		Enabled bool             `toml:"enabled"`
+		When    time.Time        `toml:"when"`
+		Skipped string           `toml:"skipped,omitempty"`
+		Inner   inner            `toml:"inner"`
+		ByName  map[string]inner `toml:"by_name"`
+		Items   []inner          `toml:"items"`
+		Ignored string
+	}
+
+	in := doc{
+		Title:   "Line\nbreak, \"quotes\" and \\",
+		Count:   -3,
+		Enabled: true,
+		When:    time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC),
+		Inner:   inner{Name: "x", Tags: []string{"a", "b"}, Ratio: 2},
+		ByName:  map[string]inner{"with space": {Name: "y"}, "plain": {Name: "z"}},
+		Items:   []inner{{Name: "first"}, {Name: "second", Ratio: 0.5}},
+		Ignored: "not encoded",
+	}
+
+	data, err := marshalTOML(in)
+	if err != nil {
+		t.Fatalf("marshalTOML() unexpected error: %v", err)
+	}
+
+	parsed, err := parseTOML(data)
+	if err != nil {
+		t.Fatalf("parseTOML() unexpected error: %v\n%s", err, data)
+	}
+	var out doc
+	if err := decodeTOML(parsed, &out); err != nil {
+		t.Fatalf("decodeTOML() unexpected error: %v\n%s", err, data)
+	}
+
+	in.Ignored = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v\n%s", out, in, data)
+	}
+}
+
+func TestDecodeTOML_Errors(t *testing.T) {
+	type target struct {
+		Page  int       `toml:"page"`
+		Small int8      `toml:"small"`
+		When  time.Time `toml:"when"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		key   string
+		line  int
+	}{
+		{"type mismatch", "\npage = \"ten\"\n", "page", 2},
+		{"overflow", "small = 300\n", "small", 1},
+		{"unknown key", "page = 1\npgae = 2\n", "pgae", 2},
+		{"datetime", "when = \"today\"\n", "when", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseTOML([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parseTOML() unexpected error: %v", err)
+			}
+			var out target
+			err = decodeTOML(doc, &out)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("decodeTOML() error = %v, want *ParseError", err)
+			}
+			if perr.Key != tt.key || perr.Line != tt.line {
+				t.Errorf("error at %s line %d, want %s line %d", perr.Key, perr.Line, tt.key, tt.line)
+			}
+		})
+	}
+}
@@ -5,35 +5,56 @@ import (
 	"sync"
 )
 
-// LRUCache implements a Least Recently Used cache for PDF pages
+// LRUCache implements a Least Recently Used cache for PDF pages: their
+// text and, for pages a Document has read, their lines as layout analysis
+// sees them. It is bounded by an entry count, a memory budget in bytes, or
+// both, and is safe for concurrent use.
 type LRUCache struct {
-	maxSize   int
-	size      int
-	cache     map[int]*CacheNode
-	list      *list.List
-	mu        sync.RWMutex
-	hits      int
-	misses    int
+	maxSize  int   // max entries (0 = unlimited)
+	maxBytes int64 // max total bytes of cached text and lines (0 = unlimited)
+	size     int
+	bytes    int64
+	cache    map[int]*CacheNode
+	list     *list.List
+	mu       sync.RWMutex
+	hits     int
+	misses   int
 }
 
 // CacheNode represents a node in the cache
 type CacheNode struct {
 	pageNum int
 	data    string
+	lines   *pageLines // nil if only the text was cached
+	cost    int64      // bytes the text and lines take
 	element *list.Element
 }
 
-// NewLRUCache creates a new LRU cache
+// NewLRUCache creates a new LRU cache holding at most maxSize pages
 func NewLRUCache(maxSize int) *LRUCache {
 	if maxSize <= 0 {
 		maxSize = 5 // Default to 5 pages
 	}
 
+	return NewLRUCacheWithBudget(maxSize, 0)
+}
+
+// NewLRUCacheWithBudget creates an LRU cache bounded by a memory budget in
+// bytes and optionally by a page count. A limit of 0 disables that bound.
+func NewLRUCacheWithBudget(maxPages int, maxBytes int64) *LRUCache {
+	if maxPages < 0 {
+		maxPages = 0
+	}
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+
 	return &LRUCache{
-		maxSize: maxSize,
-		size:    0,
-		cache:   make(map[int]*CacheNode),
-		list:    list.New(),
+		maxSize:  maxPages,
+		maxBytes: maxBytes,
+		size:     0,
+		cache:    make(map[int]*CacheNode),
+		list:     list.New(),
 	}
 }
 
@@ -54,35 +75,106 @@ func (c *LRUCache) Get(pageNum int) (string, bool) {
 	return node.data, true
 }
 
-// Put stores a page in cache
+// Contains reports whether a page is cached without affecting recency or
+// hit statistics
+func (c *LRUCache) Contains(pageNum int) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, exists := c.cache[pageNum]
+	return exists
+}
+
+// Peek retrieves a page without affecting recency or hit statistics
+func (c *LRUCache) Peek(pageNum int) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	node, exists := c.cache[pageNum]
+	if !exists {
+		return "", false
+	}
+	return node.data, true
+}
+
+// Put stores a page in cache, evicting least recently used pages until it
+// fits. A page larger than the whole byte budget is not cached.
 func (c *LRUCache) Put(pageNum int, data string) {
+	c.put(pageNum, data, nil, true)
+}
+
+// putPage stores the text of a page with its lines, evicting least
+// recently used pages until it fits if evict is set. It reports whether
+// the page was stored.
+func (c *LRUCache) putPage(pageNum int, data string, lines *pageLines, evict bool) bool {
+	return c.put(pageNum, data, lines, evict)
+}
+
+// lines retrieves the lines of a page, if they were cached with its text
+func (c *LRUCache) lines(pageNum int) (*pageLines, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	node, exists := c.cache[pageNum]
+	if !exists || node.lines == nil {
+		c.misses++
+		return nil, false
+	}
+
+	c.list.MoveToFront(node.element)
+	c.hits++
+	return node.lines, true
+}
+
+func (c *LRUCache) put(pageNum int, data string, lines *pageLines, evict bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cost := int64(len(data)) + lines.cost()
+	if c.maxBytes > 0 && cost > c.maxBytes {
+		c.remove(pageNum)
+		return false
+	}
+
 	// If already exists, update and move to front
 	if node, exists := c.cache[pageNum]; exists {
-		node.data = data
+		if !evict && c.maxBytes > 0 && c.bytes+cost-node.cost > c.maxBytes {
+			return false
+		}
+		c.bytes += cost - node.cost
+		node.data, node.lines, node.cost = data, lines, cost
 		c.list.MoveToFront(node.element)
-		return
+		c.evictToFit(0, 0)
+		return true
 	}
 
-	// If cache is full, remove least recently used
-	if c.size >= c.maxSize {
-		c.evict()
+	// Make room for the new page
+	if !evict && (c.maxSize > 0 && c.size >= c.maxSize || c.maxBytes > 0 && c.bytes+cost > c.maxBytes) {
+		return false
 	}
+	c.evictToFit(1, cost)
 
-	// Add new node to front
-	element := c.list.PushFront(&CacheNode{
-		pageNum: pageNum,
-		data:    data,
-	})
-
-	c.cache[pageNum] = &CacheNode{
+	node := &CacheNode{
 		pageNum: pageNum,
 		data:    data,
-		element: element,
+		lines:   lines,
+		cost:    cost,
 	}
+	node.element = c.list.PushFront(node)
+	c.cache[pageNum] = node
 	c.size++
+	c.bytes += cost
+	return true
+}
+
+// evictToFit evicts least recently used pages until extraPages more pages
+// and extraBytes more bytes fit within the limits
+func (c *LRUCache) evictToFit(extraPages int, extraBytes int64) {
+	for c.size > 0 &&
+		(c.maxSize > 0 && c.size+extraPages > c.maxSize ||
+			c.maxBytes > 0 && c.bytes+extraBytes > c.maxBytes) {
+		c.evict()
+	}
 }
 
 // evict removes the least recently used item
@@ -92,10 +184,20 @@ func (c *LRUCache) evict() {
 		return
 	}
 
-	node := back.Value.(*CacheNode)
-	c.list.Remove(back)
-	delete(c.cache, node.pageNum)
+	c.remove(back.Value.(*CacheNode).pageNum)
+}
+
+// remove deletes a page if present
+func (c *LRUCache) remove(pageNum int) {
+	node, exists := c.cache[pageNum]
+	if !exists {
+		return
+	}
+
+	c.list.Remove(node.element)
+	delete(c.cache, pageNum)
 	c.size--
+	c.bytes -= node.cost
 }
 
 // Clear clears all items from cache
@@ -106,6 +208,7 @@ func (c *LRUCache) Clear() {
 	c.cache = make(map[int]*CacheNode)
 	c.list = list.New()
 	c.size = 0
+	c.bytes = 0
 }
 
 // Stats returns cache statistics
@@ -116,6 +219,10 @@ func (c *LRUCache) Stats() CacheStats {
 	return CacheStats{
 		CachedPages: c.size,
 		MaxSize:     c.maxSize,
+		Bytes:       c.bytes,
+		MaxBytes:    c.maxBytes,
+		Hits:        c.hits,
+		Misses:      c.misses,
 	}
 }
 
@@ -153,11 +260,13 @@ func (c *LRUCache) GetStats() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"size":       c.size,
-		"max_size":   c.maxSize,
-		"hits":       c.hits,
-		"misses":     c.misses,
-		"total":      total,
-		"hit_rate":   hitRate,
+		"size":      c.size,
+		"max_size":  c.maxSize,
+		"bytes":     c.bytes,
+		"max_bytes": c.maxBytes,
+		"hits":      c.hits,
+		"misses":    c.misses,
+		"total":     total,
+		"hit_rate":  hitRate,
 	}
 }
@@ -2,6 +2,7 @@ package pdf
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -520,3 +521,101 @@ func BenchmarkLRUCache_Concurrent(b *testing.B) {
 		}
 	})
 }
+
+// TestLRUCache_ByteBudget tests eviction by memory budget
+func TestLRUCache_ByteBudget(t *testing.T) {
+	cache := NewLRUCacheWithBudget(0, 100)
+
+	cache.Put(1, strings.Repeat("a", 40))
+	cache.Put(2, strings.Repeat("b", 40))
+	if stats := cache.Stats(); stats.Bytes != 80 || stats.CachedPages != 2 {
+		t.Fatalf("Stats = %+v, want 80 bytes in 2 pages", stats)
+	}
+
+	// Touch page 1 so page 2 is evicted to fit page 3
+	cache.Get(1)
+	cache.Put(3, strings.Repeat("c", 40))
+
+	if _, found := cache.Get(2); found {
+		t.Error("Page 2 should have been evicted to stay within budget")
+	}
+	if !cache.Contains(1) || !cache.Contains(3) {
+		t.Error("Pages 1 and 3 should be cached")
+	}
+	if stats := cache.Stats(); stats.Bytes > stats.MaxBytes {
+		t.Errorf("Bytes = %d exceeds budget %d", stats.Bytes, stats.MaxBytes)
+	}
+
+	// Growing an entry evicts others
+	cache.Put(3, strings.Repeat("c", 90))
+	if cache.Contains(1) {
+		t.Error("Page 1 should be evicted when page 3 grows")
+	}
+	if stats := cache.Stats(); stats.Bytes != 90 || stats.CachedPages != 1 {
+		t.Errorf("Stats = %+v, want 90 bytes in 1 page", stats)
+	}
+
+	// Oversized pages are never cached
+	cache.Put(4, strings.Repeat("d", 101))
+	if cache.Contains(4) {
+		t.Error("Page larger than the budget should not be cached")
+	}
+	if !cache.Contains(3) {
+		t.Error("Oversized page should not evict existing pages")
+	}
+}
+
+// TestLRUCache_StatsHitsMisses tests hit and miss counters in Stats
+func TestLRUCache_StatsHitsMisses(t *testing.T) {
+	cache := NewLRUCacheWithBudget(2, 1<<20)
+	cache.Put(1, "page1")
+	cache.Get(1)
+	cache.Get(1)
+	cache.Get(2)
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Hits/Misses = %d/%d, want 2/1", stats.Hits, stats.Misses)
+	}
+	if stats.MaxBytes != 1<<20 || stats.MaxSize != 2 {
+		t.Errorf("Limits = %d pages/%d bytes, want 2/%d", stats.MaxSize, stats.MaxBytes, 1<<20)
+	}
+
+	// Contains does not count as a hit
+	cache.Contains(1)
+	if cache.Stats().Hits != 2 {
+		t.Error("Contains should not affect hit statistics")
+	}
+}
+
+// TestLRUCache_PutPage tests caching lines with the text, within the budget
+func TestLRUCache_PutPage(t *testing.T) {
+	lines := &pageLines{lines: []textLine{{text: "one two", elems: []TextElement{{Text: "one"}, {Text: "two"}}}}}
+	cost := int64(len("one two")) + lines.cost()
+	cache := NewLRUCacheWithBudget(0, 2*cost)
+
+	if !cache.putPage(1, "one two", lines, true) {
+		t.Fatal("putPage() should cache a page within the budget")
+	}
+	if got, ok := cache.lines(1); !ok || got != lines {
+		t.Errorf("lines(1) = %v, %v; want the cached lines", got, ok)
+	}
+	if stats := cache.Stats(); stats.Bytes != cost {
+		t.Errorf("Bytes = %d, want %d for the text and lines", stats.Bytes, cost)
+	}
+	cache.Put(2, "text only")
+	if _, ok := cache.lines(2); ok {
+		t.Error("lines(2) should miss for a page cached without lines")
+	}
+
+	// Without eviction, a page that doesn't fit is left out
+	if cache.putPage(3, "one two", lines, false) || cache.Contains(3) {
+		t.Error("putPage() without eviction should not cache a page that doesn't fit")
+	}
+	if !cache.Contains(1) || !cache.Contains(2) {
+		t.Error("putPage() without eviction should not evict pages")
+	}
+	if !cache.putPage(3, "one two", lines, true) || cache.Contains(1) {
+		t.Error("putPage() with eviction should evict the least recently used page")
+	}
+}
@@ -0,0 +1,484 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// minColumnLines is the fewest lines a page needs before columns are
+// looked for; shorter pages are read as a single column
+const minColumnLines = 4
+
+// PageLayout is the text of a page split into regions in reading order
+type PageLayout struct {
+	Header []LayoutLine  // running header, set aside from the body
+	Blocks []LayoutBlock // body, top to bottom
+	Footer []LayoutLine  // running footer, set aside from the body
+}
+
+// LayoutBlock is a run of lines that share a column structure: either
+// full-width text (one column) or text set in columns side by side
+type LayoutBlock struct {
+	Columns [][]LayoutLine // lines of each column, left to right
+}
+
+// LayoutLine is a line of text in a page layout
+type LayoutLine struct {
+	Text string
+
+	// Offset is the byte offset of Text in the page's layout-extracted text
+	// (see ExtractLayout), or -1 for blank lines between paragraphs and for
+	// documents extracted in another mode
+	Offset int
+}
+
+// Columns returns the largest number of columns side by side on the page
+func (pl *PageLayout) Columns() int {
+	n := 0
+	for _, block := range pl.Blocks {
+		n = max(n, len(block.Columns))
+	}
+	return n
+}
+
+// Text returns the page text in reading order: the header, each block with
+// its columns one after the other, then the footer, separated by blank lines
+func (pl *PageLayout) Text() string {
+	var parts []string
+	add := func(lines []LayoutLine) {
+		if len(lines) == 0 {
+			return
+		}
+		text := make([]string, len(lines))
+		for i, line := range lines {
+			text[i] = line.Text
+		}
+		parts = append(parts, strings.Join(text, "\n"))
+	}
+
+	add(pl.Header)
+	for _, block := range pl.Blocks {
+		for _, column := range block.Columns {
+			add(column)
+		}
+	}
+	add(pl.Footer)
+
+	return strings.Join(parts, "\n\n")
+}
+
+// AnalyzeLayout splits text elements into full-width and multi-column
+// blocks in reading order. Running headers and footers are not detected;
+// see Document.GetPageLayout.
+func (la *LayoutAnalyzer) AnalyzeLayout(elements []TextElement) *PageLayout {
+	lines, paragraph := la.textLines(elements)
+	return &PageLayout{Blocks: la.layoutBlocks(lines, la.findGutters(lines), paragraph)}
+}
+
+// elementWidth returns the width of an element, estimating it for fonts
+// that report no glyph widths
+func elementWidth(e TextElement) float64 {
+	if e.Width > 0 {
+		return e.Width
+	}
+	return glyphAdvance(e.Text, e.FontSize)
+}
+
+// findGutters returns the X positions of the gutters between text columns:
+// vertical strips wider than ColumnThreshold that most lines leave empty,
+// with column text on both sides. Some lines may cross a gutter, such as a
+// title or abstract set across the full width.
+func (la *LayoutAnalyzer) findGutters(lines []textLine) []float64 {
+	if len(lines) < minColumnLines {
+		return nil
+	}
+
+	left, right := math.Inf(1), math.Inf(-1)
+	for _, line := range lines {
+		for _, e := range line.elems {
+			left = math.Min(left, e.X)
+			right = math.Max(right, e.X+elementWidth(e))
+		}
+	}
+	if math.IsInf(left, 0) || right-left > 1e5 {
+		return nil
+	}
+
+	// Count the lines covering each point across the page
+	n := int(math.Ceil(right-left)) + 1
+	cover := make([]int, n)
+	covered := make([]bool, n)
+	for _, line := range lines {
+		clear(covered)
+		for _, e := range line.elems {
+			from := int(e.X - left)
+			to := min(int(math.Ceil(e.X+elementWidth(e)-left)), n)
+			for x := from; x < to; x++ {
+				covered[x] = true
+			}
+		}
+		for x, c := range covered {
+			if c {
+				cover[x]++
+			}
+		}
+	}
+
+	// Column text is covered by a good share of the lines; a gutter is
+	// crossed by few, if any
+	most := 0
+	for _, c := range cover {
+		most = max(most, c)
+	}
+	dense := func(x int) bool { return cover[x]*3 >= most && cover[x] >= 2 }
+	denseAt := func(from, to int) bool {
+		for x := from; x < to; x++ {
+			if dense(x) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var gutters []float64
+	for x := 0; x < n; {
+		if dense(x) {
+			x++
+			continue
+		}
+		start := x
+		for x < n && !dense(x) {
+			x++
+		}
+		if float64(x-start) > la.ColumnThreshold && denseAt(0, start) && denseAt(x, n) {
+			gutters = append(gutters, left+float64(start+x)/2)
+		}
+	}
+	return gutters
+}
+
+// lineSegment is the part of a line that falls in one column
+type lineSegment struct {
+	col  int
+	line LayoutLine
+}
+
+// splitLine splits a line at the gutters. It reports true instead if the
+// line spans the page: there are no gutters, or the line's text runs
+// across one, with a word over it or words on either side closer than
+// minGap.
+func splitLine(line textLine, gutters []float64, minGap float64) ([]lineSegment, bool) {
+	if len(gutters) == 0 {
+		return nil, true
+	}
+
+	var segs []lineSegment
+	pos := 0    // offset of the word in line.text
+	end := -1.0 // right edge of the previous word
+	for _, e := range line.elems {
+		col := 0
+		for col < len(gutters) && e.X >= gutters[col] {
+			col++
+		}
+		if col < len(gutters) && e.X+elementWidth(e) > gutters[col] {
+			return nil, true
+		}
+
+		if n := len(segs); n > 0 && segs[n-1].col == col {
+			segs[n-1].line.Text += " " + e.Text
+		} else {
+			if n > 0 && e.X-end < minGap {
+				return nil, true
+			}
+			segs = append(segs, lineSegment{col: col, line: LayoutLine{Text: e.Text, Offset: line.offset + pos}})
+		}
+		pos += len(e.Text) + 1
+		end = e.X + elementWidth(e)
+	}
+	return segs, false
+}
+
+// layoutBlocks groups lines into blocks. Consecutive lines spanning the
+// page form a full-width block; consecutive lines split at the gutters
+// form a block of columns. Paragraph breaks within a column become blank
+// lines.
+func (la *LayoutAnalyzer) layoutBlocks(lines []textLine, gutters []float64, paragraph float64) []LayoutBlock {
+	var blocks []LayoutBlock
+	var lastY []float64 // baseline of the last line of each column of the last block
+
+	appendLine := func(col int, line LayoutLine, y float64, breakBefore bool) {
+		column := &blocks[len(blocks)-1].Columns[col]
+		if len(*column) > 0 && breakBefore {
+			*column = append(*column, LayoutLine{Offset: -1})
+		}
+		*column = append(*column, line)
+		lastY[col] = y
+	}
+
+	for _, line := range lines {
+		segs, spans := splitLine(line, gutters, la.ColumnThreshold)
+		if spans {
+			if n := len(blocks); n == 0 || len(blocks[n-1].Columns) != 1 {
+				blocks = append(blocks, LayoutBlock{Columns: make([][]LayoutLine, 1)})
+				lastY = make([]float64, 1)
+			}
+			appendLine(0, LayoutLine{Text: line.text, Offset: line.offset}, line.y, line.paragraph)
+			continue
+		}
+
+		if n := len(blocks); n == 0 || len(blocks[n-1].Columns) != len(gutters)+1 {
+			blocks = append(blocks, LayoutBlock{Columns: make([][]LayoutLine, len(gutters)+1)})
+			lastY = make([]float64, len(gutters)+1)
+		}
+		for _, seg := range segs {
+			breakBefore := paragraph > 0 && lastY[seg.col]-line.y > paragraph
+			appendLine(seg.col, seg.line, line.y, breakBefore)
+		}
+	}
+
+	return blocks
+}
+
+// columnsOf describes the columns of a page, with the lines set in each.
+// Lines spanning a multi-column page belong to no column.
+func (la *LayoutAnalyzer) columnsOf(lines []textLine, gutters []float64) []Column {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	left, right := math.Inf(1), math.Inf(-1)
+	for _, line := range lines {
+		for _, e := range line.elems {
+			left = math.Min(left, e.X)
+			right = math.Max(right, e.X+elementWidth(e))
+		}
+	}
+
+	bounds := append(append([]float64{left}, gutters...), right)
+	columns := make([]Column, len(gutters)+1)
+	for i := range columns {
+		columns[i] = Column{Left: bounds[i], Right: bounds[i+1], Width: bounds[i+1] - bounds[i]}
+	}
+
+	for _, line := range lines {
+		segs, spans := splitLine(line, gutters, la.ColumnThreshold)
+		if spans {
+			if len(gutters) == 0 {
+				columns[0].Lines = append(columns[0].Lines, line.text)
+			}
+			continue
+		}
+		for _, seg := range segs {
+			columns[seg.col].Lines = append(columns[seg.col].Lines, seg.line.Text)
+		}
+	}
+	return columns
+}
+
+// pageNumberLine matches lines that hold nothing but a page number, like
+// "12", "- iv -" or "Page 3 of 10"
+var pageNumberLine = regexp.MustCompile(`(?i)^[\s\-–—|.]*(page\s+)?(\d+|[ivxlcdm]+)(\s*(of|/)\s*\d+)?[\s\-–—|.]*$`)
+
+// runningText normalizes a line for comparison with the lines at the same
+// place on other pages, ignoring case and page numbers
+func runningText(line string) string {
+	var sb strings.Builder
+	digits := false
+	for _, r := range strings.ToLower(strings.Join(strings.Fields(line), " ")) {
+		if unicode.IsDigit(r) {
+			if !digits {
+				sb.WriteByte('#')
+			}
+			digits = true
+			continue
+		}
+		digits = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// pageEdges are the normalized top and bottom lines of a page
+type pageEdges struct {
+	top, bottom string
+}
+
+// pageLines is a page as layout analysis sees it. Cached pageLines are
+// shared, so they must not be modified.
+type pageLines struct {
+	lines     []textLine
+	paragraph float64  // line gap above which a paragraph starts
+	rulings   []ruling // ruling lines drawn on the page
+}
+
+// cost estimates the bytes pageLines take, for the cache's budget
+func (pl *pageLines) cost() int64 {
+	if pl == nil {
+		return 0
+	}
+	const (
+		lineSize    = 96 // a textLine and its slice and string headers
+		elementSize = 96 // a TextElement and its string headers
+		rulingSize  = 40
+	)
+	cost := int64(len(pl.rulings) * rulingSize)
+	for _, line := range pl.lines {
+		cost += lineSize + int64(len(line.text))
+		for _, e := range line.elems {
+			cost += elementSize + int64(len(e.Text)+len(e.Font))
+		}
+	}
+	return cost
+}
+
+// readPageLines returns the lines of a page as layout extraction sees
+// them, from the cache if the page was read before, and otherwise reading
+// the page and caching it
+func (d *Document) readPageLines(pageNum int) (pageLines, error) {
+	if pl, ok := d.cache.lines(pageNum); ok {
+		return *pl, nil
+	}
+	page, err := d.extractPage(pageNum)
+	if err != nil {
+		return pageLines{}, err
+	}
+	d.cache.putPage(pageNum, page.text, &page.lines, true)
+	return page.lines, nil
+}
+
+// scanPageLines returns the lines of a page for a job that reads every
+// page, like readPageLines, but only caches the page if there is room, so
+// the pages being viewed stay cached
+func (d *Document) scanPageLines(pageNum int) (pageLines, error) {
+	if pl, ok := d.cache.lines(pageNum); ok {
+		return *pl, nil
+	}
+	page, err := d.extractPage(pageNum)
+	if err != nil {
+		return pageLines{}, err
+	}
+	d.cache.putPage(pageNum, page.text, &page.lines, false)
+	return page.lines, nil
+}
+
+// storeEdges remembers the top and bottom lines of a page
+func (d *Document) storeEdges(pageNum int, lines []textLine) {
+	var edges pageEdges
+	if len(lines) > 0 {
+		edges = pageEdges{top: runningText(lines[0].text), bottom: runningText(lines[len(lines)-1].text)}
+	}
+
+	d.edgesMu.Lock()
+	defer d.edgesMu.Unlock()
+	if d.edges == nil {
+		d.edges = make(map[int]pageEdges)
+	}
+	d.edges[pageNum] = edges
+}
+
+// edgesOf returns the top and bottom lines of a page, reading it if needed
+func (d *Document) edgesOf(pageNum int) (pageEdges, bool) {
+	d.edgesMu.Lock()
+	edges, ok := d.edges[pageNum]
+	d.edgesMu.Unlock()
+	if ok {
+		return edges, true
+	}
+
+	if _, err := d.readPageLines(pageNum); err != nil {
+		return pageEdges{}, false
+	}
+	d.edgesMu.Lock()
+	defer d.edgesMu.Unlock()
+	return d.edges[pageNum], true
+}
+
+// isRunning reports whether the top (or bottom) line of a page is part of
+// a running header (or footer): it is only a page number, or it recurs,
+// page numbers aside, at the same place on a nearby page. Pages two away
+// are compared too, for headers that alternate between odd and even pages.
+func (d *Document) isRunning(pageNum int, line string, top bool) bool {
+	if pageNumberLine.MatchString(line) {
+		return true
+	}
+
+	text := runningText(line)
+	if text == "" {
+		return false
+	}
+	for _, other := range []int{pageNum - 1, pageNum + 1, pageNum - 2, pageNum + 2} {
+		if other < 1 || other > d.pages {
+			continue
+		}
+		edges, ok := d.edgesOf(other)
+		if !ok {
+			continue
+		}
+		if (top && edges.top == text) || (!top && edges.bottom == text) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPageLayout analyzes the columns of a page and sets aside its running
+// header and footer. Line offsets refer to the page text only when the
+// document uses ExtractLayout.
+func (d *Document) GetPageLayout(pageNum int) (*PageLayout, error) {
+	if pageNum < 1 || pageNum > d.pages {
+		return nil, fmt.Errorf("page number out of range: %d", pageNum)
+	}
+
+	pl, err := d.readPageLines(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	lines := pl.lines
+
+	header, footer := 0, 0
+	if len(lines) > 1 && d.isRunning(pageNum, lines[0].text, true) {
+		header = 1
+	}
+	if len(lines)-header > 1 && d.isRunning(pageNum, lines[len(lines)-1].text, false) {
+		footer = 1
+	}
+
+	asLines := func(lines []textLine) []LayoutLine {
+		out := make([]LayoutLine, len(lines))
+		for i, line := range lines {
+			out[i] = LayoutLine{Text: line.text, Offset: line.offset}
+		}
+		return out
+	}
+	body := lines[header : len(lines)-footer]
+	layout := &PageLayout{
+		Header: asLines(lines[:header]),
+		Blocks: d.layout.layoutBlocks(body, d.layout.findGutters(body), pl.paragraph),
+		Footer: asLines(lines[len(lines)-footer:]),
+	}
+
+	if d.extraction != ExtractLayout {
+		layout.forEachLine(func(line *LayoutLine) { line.Offset = -1 })
+	}
+	return layout, nil
+}
+
+// forEachLine calls fn with every line of the layout
+func (pl *PageLayout) forEachLine(fn func(*LayoutLine)) {
+	for i := range pl.Header {
+		fn(&pl.Header[i])
+	}
+	for _, block := range pl.Blocks {
+		for _, column := range block.Columns {
+			for i := range column {
+				fn(&column[i])
+			}
+		}
+	}
+	for i := range pl.Footer {
+		fn(&pl.Footer[i])
+	}
+}
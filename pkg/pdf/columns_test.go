@@ -0,0 +1,221 @@
+package pdf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// twoColumnPage lays out a page of a two-column paper: a running header,
+// a full-width title, six lines in each column and a page number
+func twoColumnPage(page int) []placedText {
+	texts := []placedText{
+		{x: 72, y: 760, size: 10, text: fmt.Sprintf("Journal of Tests, Vol. %d", page)},
+		{x: 72, y: 736, size: 12, text: "A Study of Column Layouts in Practice"},
+	}
+	for i := 1; i <= 6; i++ {
+		y := 710 - 14*i
+		texts = append(texts,
+			placedText{x: 72, y: y, size: 12, text: fmt.Sprintf("left line %d", i)},
+			placedText{x: 320, y: y, size: 12, text: fmt.Sprintf("right line %d", i)},
+		)
+	}
+	return append(texts, placedText{x: 300, y: 40, size: 10, text: fmt.Sprint(page)})
+}
+
+// columnLines returns the text of each line of a column
+func columnLines(lines []LayoutLine) []string {
+	var texts []string
+	for _, line := range lines {
+		texts = append(texts, line.Text)
+	}
+	return texts
+}
+
+func TestAnalyzeLayout_TwoColumns(t *testing.T) {
+	var elements []TextElement
+	add := func(text string, x, y float64) {
+		elements = append(elements, TextElement{Text: text, X: x, Y: y, FontSize: 10, Width: float64(len(text)) * 5})
+	}
+	add("Full", 10, 300)
+	add("width", 110, 300) // crosses the gutter
+	add("title", 200, 300)
+	for i := 0; i < 5; i++ {
+		y := 280 - float64(i)*12
+		add(fmt.Sprintf("L%d", i), 10, y)
+		add("left", 30, y)
+		add(fmt.Sprintf("R%d", i), 200, y)
+	}
+
+	layout := NewLayoutAnalyzer().AnalyzeLayout(elements)
+
+	if len(layout.Blocks) != 2 || layout.Columns() != 2 {
+		t.Fatalf("got %d blocks, %d columns; want a full-width block and two columns", len(layout.Blocks), layout.Columns())
+	}
+	if got := columnLines(layout.Blocks[0].Columns[0]); !reflect.DeepEqual(got, []string{"Full width title"}) {
+		t.Errorf("full-width block = %q", got)
+	}
+	if got, want := columnLines(layout.Blocks[1].Columns[0]), []string{"L0 left", "L1 left", "L2 left", "L3 left", "L4 left"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left column = %q, want %q", got, want)
+	}
+	if got, want := columnLines(layout.Blocks[1].Columns[1]), []string{"R0", "R1", "R2", "R3", "R4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("right column = %q, want %q", got, want)
+	}
+
+	// Offsets point into the layout-extracted text
+	text := NewLayoutAnalyzer().ExtractText(elements)
+	layout.forEachLine(func(line *LayoutLine) {
+		if line.Offset >= 0 && text[line.Offset:line.Offset+len(line.Text)] != line.Text {
+			t.Errorf("line %q has offset %d, text there is %q", line.Text, line.Offset, text[line.Offset:])
+		}
+	})
+
+	formatted, cols := NewLayoutAnalyzer().ExtractWithColumns(elements)
+	want := "Full width title\n\nL0 left\nL1 left\nL2 left\nL3 left\nL4 left\n\nR0\nR1\nR2\nR3\nR4"
+	if formatted != want {
+		t.Errorf("ExtractWithColumns() = %q, want %q", formatted, want)
+	}
+	if len(cols) != 2 || len(cols[1].Lines) != 5 || cols[0].Right != cols[1].Left {
+		t.Errorf("columns = %+v, want two adjoining columns", cols)
+	}
+}
+
+func TestAnalyzeLayout_SingleColumn(t *testing.T) {
+	var elements []TextElement
+	for i := 0; i < 6; i++ {
+		// A ragged right margin is not a gutter
+		elements = append(elements, TextElement{Text: strings.Repeat("w", 10+i*8), X: 10, Y: 300 - float64(i)*12, FontSize: 10})
+	}
+
+	layout := NewLayoutAnalyzer().AnalyzeLayout(elements)
+	if len(layout.Blocks) != 1 || layout.Columns() != 1 || len(layout.Blocks[0].Columns[0]) != 6 {
+		t.Errorf("layout = %+v, want one column of 6 lines", layout)
+	}
+}
+
+func TestRunningText(t *testing.T) {
+	if runningText("Journal of Tests,  Vol. 12") != runningText("journal of tests, vol. 3") {
+		t.Error("running text should ignore case, spacing and numbers")
+	}
+	for _, line := range []string{"12", "- iv -", "Page 3 of 10", "3/10"} {
+		if !pageNumberLine.MatchString(line) {
+			t.Errorf("%q should be recognized as a page number", line)
+		}
+	}
+	if pageNumberLine.MatchString("Chapter 3") {
+		t.Error("Chapter 3 is not a page number")
+	}
+}
+
+func TestGetPageLayout(t *testing.T) {
+	b := newTestPDFBuilder()
+	for page := 1; page <= 3; page++ {
+		b.addPageAt(twoColumnPage(page), "")
+	}
+	b.addPage([]string{"Only line"}, "")
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	layout, err := doc.GetPageLayout(2)
+	if err != nil {
+		t.Fatalf("GetPageLayout() unexpected error: %v", err)
+	}
+
+	if got := columnLines(layout.Header); !reflect.DeepEqual(got, []string{"Journal of Tests, Vol. 2"}) {
+		t.Errorf("Header = %q", got)
+	}
+	if got := columnLines(layout.Footer); !reflect.DeepEqual(got, []string{"2"}) {
+		t.Errorf("Footer = %q", got)
+	}
+	if len(layout.Blocks) != 2 || layout.Columns() != 2 {
+		t.Fatalf("got %d blocks, %d columns; want the title and two columns", len(layout.Blocks), layout.Columns())
+	}
+	if got := columnLines(layout.Blocks[0].Columns[0]); !reflect.DeepEqual(got, []string{"A Study of Column Layouts in Practice"}) {
+		t.Errorf("title block = %q", got)
+	}
+	if got := columnLines(layout.Blocks[1].Columns[1]); len(got) != 6 || got[0] != "right line 1" {
+		t.Errorf("right column = %q", got)
+	}
+
+	page, err := doc.GetPage(2)
+	if err != nil {
+		t.Fatalf("GetPage() unexpected error: %v", err)
+	}
+	layout.forEachLine(func(line *LayoutLine) {
+		if line.Offset < 0 || page.Text[line.Offset:line.Offset+len(line.Text)] != line.Text {
+			t.Errorf("line %q has offset %d into %q", line.Text, line.Offset, page.Text)
+		}
+	})
+
+	// A page's only line is never set aside
+	layout, err = doc.GetPageLayout(4)
+	if err != nil {
+		t.Fatalf("GetPageLayout() unexpected error: %v", err)
+	}
+	if len(layout.Header) != 0 || len(layout.Footer) != 0 || layout.Text() != "Only line" {
+		t.Errorf("layout of a one-line page = %+v", layout)
+	}
+
+	if _, err := doc.GetPageLayout(5); err == nil {
+		t.Error("GetPageLayout() should reject pages out of range")
+	}
+}
+
+// TestPageReadOnce reads the text, tables and layout of a page from one
+// parse of its content stream, whichever is asked for first
+func TestPageReadOnce(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPageAt(twoColumnPage(1), "")
+	b.addPageAt(twoColumnPage(2), "")
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	// Page 1 is read for its text; its layout reads page 2, the header of
+	// which it is compared with
+	steps := []func() error{
+		func() error { _, err := doc.GetPage(1); return err },
+		func() error { _, err := doc.GetPageTables(1); return err },
+		func() error { _, err := doc.GetPageLayout(1); return err },
+		func() error { _, err := doc.GetPageLayout(2); return err },
+		func() error { _, err := doc.GetPageTables(2); return err },
+		func() error { _, err := doc.GetPage(2); return err },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+	}
+	if stats := doc.CacheStats(); stats.Misses != 2 || stats.Hits != 5 {
+		t.Errorf("cache hits/misses = %d/%d, want 5/2: each page read once", stats.Hits, stats.Misses)
+	}
+}
+
+func TestGetPageLayout_RawOffsets(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPageAt(twoColumnPage(1), "")
+
+	doc, err := OpenDocument(b.write(t), DocumentOptions{Extraction: ExtractRaw})
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	layout, err := doc.GetPageLayout(1)
+	if err != nil {
+		t.Fatalf("GetPageLayout() unexpected error: %v", err)
+	}
+	layout.forEachLine(func(line *LayoutLine) {
+		if line.Offset != -1 {
+			t.Errorf("line %q has offset %d; raw page text has no layout offsets", line.Text, line.Offset)
+		}
+	})
+}
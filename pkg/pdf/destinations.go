@@ -0,0 +1,150 @@
+package pdf
+
+import (
+	"reflect"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// objectRef identifies an indirect PDF object by object and generation number
+type objectRef struct {
+	id  uint64
+	gen uint64
+}
+
+// refOf returns the indirect object a value was loaded from.
+// The reader does not export object identity, so it is read via reflection;
+// two values loaded from the same indirect object share the same ref.
+func refOf(v pdf.Value) objectRef {
+	ptr := reflect.ValueOf(v).FieldByName("ptr")
+	if !ptr.IsValid() || ptr.NumField() < 2 {
+		return objectRef{}
+	}
+	return objectRef{id: ptr.Field(0).Uint(), gen: ptr.Field(1).Uint()}
+}
+
+// buildPageIndex walks the page tree and maps each page object to its
+// 1-indexed page number
+func buildPageIndex(r *pdf.Reader) map[objectRef]int {
+	index := make(map[objectRef]int)
+	visited := make(map[objectRef]bool)
+	pageNum := 0
+
+	var walk func(node pdf.Value)
+	walk = func(node pdf.Value) {
+		ref := refOf(node)
+		if ref.id != 0 {
+			if visited[ref] {
+				return // Malformed tree with a cycle
+			}
+			visited[ref] = true
+		}
+
+		switch node.Key("Type").Name() {
+		case "Pages":
+			kids := node.Key("Kids")
+			for i := 0; i < kids.Len(); i++ {
+				walk(kids.Index(i))
+			}
+		case "Page":
+			pageNum++
+			index[ref] = pageNum
+		}
+	}
+
+	walk(r.Trailer().Key("Root").Key("Pages"))
+	return index
+}
+
+// destResolver resolves explicit and named destinations to page numbers
+type destResolver struct {
+	pages map[objectRef]int
+	named map[string]pdf.Value
+	crypt *securityHandler // decrypts strings, of links too
+}
+
+// newDestResolver indexes the page tree and the document's named destinations
+// from both the /Names /Dests name tree (PDF 1.2+) and the legacy /Dests dictionary
+func newDestResolver(r *pdf.Reader, crypt *securityHandler) *destResolver {
+	root := r.Trailer().Key("Root")
+	dr := &destResolver{
+		pages: buildPageIndex(r),
+		named: make(map[string]pdf.Value),
+		crypt: crypt,
+	}
+
+	legacy := root.Key("Dests")
+	for _, key := range legacy.Keys() {
+		dr.named[key] = legacy.Key(key)
+	}
+
+	walkNameTree(root.Key("Names").Key("Dests"), func(name, value pdf.Value) {
+		dr.named[crypt.rawString(name)] = value
+	})
+
+	return dr
+}
+
+// resolve returns the 1-indexed page a destination points to, or 0 if it
+// cannot be resolved. Accepts explicit destination arrays, destination
+// dictionaries (/D), and names or strings referring to named destinations.
+func (dr *destResolver) resolve(dest pdf.Value) int {
+	for depth := 0; depth < 4; depth++ {
+		switch dest.Kind() {
+		case pdf.Array:
+			target := dest.Index(0)
+			if target.Kind() == pdf.Integer {
+				// Remote-style destination: zero-based page index
+				return int(target.Int64()) + 1
+			}
+			return dr.pages[refOf(target)]
+		case pdf.Dict:
+			dest = dest.Key("D")
+		case pdf.Name:
+			dest = dr.named[dest.Name()]
+		case pdf.String:
+			dest = dr.named[dr.crypt.rawString(dest)]
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// resolveAction returns the page targeted by a GoTo action, or 0
+func (dr *destResolver) resolveAction(action pdf.Value) int {
+	if action.Key("S").Name() != "GoTo" {
+		return 0
+	}
+	return dr.resolve(action.Key("D"))
+}
+
+// walkNameTree visits every key/value pair in a PDF name tree
+func walkNameTree(node pdf.Value, visit func(name, value pdf.Value)) {
+	visited := make(map[objectRef]bool)
+
+	var walk func(node pdf.Value, depth int)
+	walk = func(node pdf.Value, depth int) {
+		if node.Kind() != pdf.Dict || depth > 32 {
+			return
+		}
+		if ref := refOf(node); ref.id != 0 {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+
+		names := node.Key("Names")
+		for i := 0; i+1 < names.Len(); i += 2 {
+			visit(names.Index(i), names.Index(i+1))
+		}
+
+		kids := node.Key("Kids")
+		for i := 0; i < kids.Len(); i++ {
+			walk(kids.Index(i), depth+1)
+		}
+	}
+
+	walk(node, 0)
+}
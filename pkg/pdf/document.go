@@ -1,34 +1,68 @@
 package pdf
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ledongthuc/pdf"
 )
 
-// Document represents a PDF document with cached pages
+// ErrClosed is returned by operations on a Document after Close
+var ErrClosed = errors.New("document is closed")
+
+// Document represents a PDF document with cached pages.
+// The file stays open and its cross-reference table parsed for the life of
+// the Document; call Close when done. A Document is safe for concurrent use.
 type Document struct {
 	filepath string
-	reader   *pdf.Reader
+	file     *os.File
+	reader   *pdf.Reader      // immutable once parsed; reads go through file.ReadAt, or a decrypted copy
+	crypt    *securityHandler // decrypts the strings of an encrypted document; nil for a plain one
+	readerMu sync.RWMutex     // held for reading while the reader is in use, for writing by Close
 	pages    int
 
+	// Text extraction
+	extraction ExtractionMode
+	layout     *LayoutAnalyzer
+	edgesMu    sync.Mutex
+	edges      map[int]pageEdges // top and bottom lines, for running header detection
+	tablesMu   sync.Mutex
+	tables     map[int]bool // pages extracted so far, and whether they have tables
+
 	// Caching
-	pageCache map[int]string
-	cacheMu   sync.RWMutex
-	maxCache  int // LRU cache size
+	cache      *LRUCache // extracted page text and lines, bounded by DocumentOptions
+	inflightMu sync.Mutex
+	inflight   map[int]*pageExtraction // pages being extracted right now
+
+	// Full-text index, set once built or loaded by an Indexer
+	index    atomic.Pointer[Index]
+	hashOnce sync.Once
+	hash     string
+	hashErr  error
+
+	// Image caching (Phase 3)
+	imageCache *ImagePageCache
 
 	// Metadata
-	title      string
-	author     string
-	subject    string
-	creator    string
+	metadata   Metadata
+	labels     []string // page labels from /PageLabels, nil if there are none
+	destsOnce  sync.Once
+	dests      *destResolver // named destinations and page objects, for links
 	totalWords int
 }
 
 // PageInfo contains extracted text and metadata for a page
 type PageInfo struct {
 	PageNum    int
+	Label      string // label printed on the page, see PageLabel
 	Text       string
 	LineCount  int
 	WordCount  int
@@ -36,37 +70,170 @@ type PageInfo struct {
 	HasTables  bool
 }
 
-// NewDocument creates a new PDF document from a file path
+// DocumentOptions configures how a Document extracts and caches page text
+type DocumentOptions struct {
+	// Extraction selects how page text is reconstructed from the glyphs
+	// on the page (the zero value is ExtractLayout)
+	Extraction ExtractionMode
+
+	// CacheBytes is the memory budget for cached page text and the lines
+	// tables, columns and links are found in. Least recently used pages are
+	// evicted to stay within it; 0 means unbounded.
+	CacheBytes int64
+
+	// CachePages optionally caps the number of cached pages as well (0 = no cap)
+	CachePages int
+
+	// Password opens encrypted documents that the empty password doesn't;
+	// either the user or the owner password will do
+	Password string
+}
+
+// DefaultDocumentOptions returns the default document options
+func DefaultDocumentOptions() DocumentOptions {
+	return DocumentOptions{
+		Extraction: ExtractLayout,
+		CacheBytes: 32 << 20, // 32 MiB of text covers thousands of typical pages
+		CachePages: 0,
+	}
+}
+
+// NewDocument creates a new PDF document from a file path, caching at most
+// maxCachePages pages within the default memory budget
 func NewDocument(filepath string, maxCachePages int) (*Document, error) {
-	f, r, err := pdf.Open(filepath)
+	opts := DefaultDocumentOptions()
+	opts.CachePages = maxCachePages
+	return OpenDocument(filepath, opts)
+}
+
+// OpenDocument creates a new PDF document from a file path with custom options
+func OpenDocument(filepath string, opts DocumentOptions) (*Document, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	fi, err := f.Stat()
 	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+	r, crypt, err := openReader(f, fi.Size(), opts.Password)
+	if err != nil {
+		f.Close()
 		return nil, fmt.Errorf("failed to open PDF: %w", err)
 	}
-	defer f.Close()
 
 	pages := r.NumPage()
 	if pages == 0 {
+		f.Close()
 		return nil, fmt.Errorf("PDF has no pages")
 	}
 
 	doc := &Document{
-		filepath:  filepath,
-		pages:     pages,
-		pageCache: make(map[int]string),
-		maxCache:  maxCachePages,
+		filepath:   filepath,
+		file:       f,
+		reader:     r,
+		crypt:      crypt,
+		pages:      pages,
+		extraction: opts.Extraction,
+		layout:     NewLayoutAnalyzer(),
+		cache:      NewLRUCacheWithBudget(opts.CachePages, opts.CacheBytes),
+		imageCache: NewImagePageCache(10),
 	}
 
-	// Extract metadata
-	// Note: Metadata extraction depends on PDF structure
-	// We'll implement basic metadata extraction later
-	doc.title = filepath
-	doc.author = ""
-	doc.subject = ""
-	doc.creator = ""
+	// Extract metadata from /Info, XMP and the first page
+	doc.metadata = extractMetadata(f, r, crypt)
+	doc.labels = extractPageLabels(r, crypt, pages)
 
 	return doc, nil
 }
 
+// extractMetadata reads document metadata, tolerating malformed objects
+func extractMetadata(f *os.File, r *pdf.Reader, crypt *securityHandler) (meta Metadata) {
+	// The reader panics on malformed objects; metadata is best-effort
+	defer func() {
+		if rec := recover(); rec != nil {
+			meta = Metadata{}
+		}
+	}()
+
+	header := make([]byte, 16)
+	n, _ := f.ReadAt(header, 0)
+	return readMetadata(header[:n], r, crypt)
+}
+
+// Close releases the underlying file. Calls made after Close return
+// ErrClosed; Close waits for calls already in progress.
+func (d *Document) Close() error {
+	d.readerMu.Lock()
+	defer d.readerMu.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+	err := d.file.Close()
+	d.file = nil
+	d.reader = nil
+	return err
+}
+
+// withReader calls fn with the parsed reader. Any number of calls may run
+// concurrently. The reader panics on malformed objects rather than
+// returning errors; such panics are returned as errors.
+func (d *Document) withReader(fn func(r *pdf.Reader) error) (err error) {
+	d.readerMu.RLock()
+	defer d.readerMu.RUnlock()
+
+	if d.reader == nil {
+		return ErrClosed
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("malformed PDF: %v", rec)
+		}
+	}()
+
+	return fn(d.reader)
+}
+
+// Path returns the file path the document was opened from
+func (d *Document) Path() string {
+	return d.filepath
+}
+
+// ContentHash returns the hex SHA-256 of the file's contents, computed on
+// first use. It identifies the document across renames and moves.
+func (d *Document) ContentHash() (string, error) {
+	d.hashOnce.Do(func() {
+		d.readerMu.RLock()
+		defer d.readerMu.RUnlock()
+
+		if d.file == nil {
+			d.hashErr = ErrClosed
+			return
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(d.file, 0, 1<<62)); err != nil {
+			d.hashErr = fmt.Errorf("failed to hash document: %w", err)
+			return
+		}
+		d.hash = hex.EncodeToString(h.Sum(nil))
+	})
+	return d.hash, d.hashErr
+}
+
+// Extraction returns how the document's page text is extracted
+func (d *Document) Extraction() ExtractionMode {
+	return d.extraction
+}
+
+// Index returns the document's full-text index, or nil until an Indexer
+// has built or loaded it
+func (d *Document) Index() *Index {
+	return d.index.Load()
+}
+
 // GetPageCount returns the total number of pages
 func (d *Document) GetPageCount() int {
 	return d.pages
@@ -79,40 +246,88 @@ func (d *Document) GetPage(pageNum int) (*PageInfo, error) {
 	}
 
 	// Check cache first
-	d.cacheMu.RLock()
-	if cached, exists := d.pageCache[pageNum]; exists {
-		d.cacheMu.RUnlock()
+	if cached, exists := d.cache.Get(pageNum); exists {
 		return d.createPageInfo(pageNum, cached), nil
 	}
-	d.cacheMu.RUnlock()
 
-	// Extract text from page
-	f, r, err := pdf.Open(d.filepath)
+	page, err := d.extractPage(pageNum)
 	if err != nil {
-		return nil, fmt.Errorf("failed to reopen PDF: %w", err)
+		return nil, err
 	}
-	defer f.Close()
 
-	page := r.Page(pageNum)
-	if page.V.IsNull() {
-		return nil, fmt.Errorf("page %d is empty or null", pageNum)
-	}
+	// Cache the result, with the lines tables, layout and links are read from
+	d.cache.putPage(pageNum, page.text, &page.lines, true)
+
+	return d.createPageInfo(pageNum, page.text), nil
+}
 
-	// Extract plain text from page
-	// NOTE: PDFs often split text into small chunks (sometimes single characters)
-	// We concatenate without adding spaces and let natural spacing in the PDF show through
-	content := ""
-	texts := page.Content().Text
-	for _, text := range texts {
-		content += text.S
+// parsedPage is a page read from the PDF: its text, and its lines as
+// layout analysis sees them, from one pass over its content stream
+type parsedPage struct {
+	text  string
+	lines pageLines
+}
+
+// pageExtraction is an extraction in progress that other callers can wait on
+type pageExtraction struct {
+	done chan struct{}
+	page parsedPage
+	err  error
+}
+
+// extractPage reads a page without consulting or filling the cache.
+// Concurrent calls for the same page share one extraction, so a page being
+// prefetched is not extracted twice when the user opens it.
+func (d *Document) extractPage(pageNum int) (parsedPage, error) {
+	d.inflightMu.Lock()
+	if call, ok := d.inflight[pageNum]; ok {
+		d.inflightMu.Unlock()
+		<-call.done
+		return call.page, call.err
 	}
+	call := &pageExtraction{done: make(chan struct{})}
+	if d.inflight == nil {
+		d.inflight = make(map[int]*pageExtraction)
+	}
+	d.inflight[pageNum] = call
+	d.inflightMu.Unlock()
+
+	call.page, call.err = d.readPage(pageNum)
+
+	d.inflightMu.Lock()
+	delete(d.inflight, pageNum)
+	d.inflightMu.Unlock()
+	close(call.done)
+
+	return call.page, call.err
+}
+
+// readPage reads the text and lines of a page from the PDF
+func (d *Document) readPage(pageNum int) (parsedPage, error) {
+	var page parsedPage
+	err := d.withReader(func(r *pdf.Reader) error {
+		p := r.Page(pageNum)
+		if p.V.IsNull() {
+			return fmt.Errorf("page %d is empty or null", pageNum)
+		}
 
-	// Cache the result
-	d.cacheMu.Lock()
-	d.pageCache[pageNum] = content
-	d.cacheMu.Unlock()
+		// PDFs position text in small chunks, often single glyphs, so words
+		// and lines have to be reconstructed from where the glyphs are drawn
+		pc := p.Content()
+		pl := &page.lines
+		pl.lines, pl.paragraph = d.layout.textLines(glyphElements(pc.Text))
+		pl.rulings = rulingsOf(pc.Rect)
+		if d.extraction == ExtractLayout {
+			page.text = joinLines(pl.lines)
+		} else {
+			page.text = extractText(pc.Text, d.extraction, d.layout)
+		}
 
-	return d.createPageInfo(pageNum, content), nil
+		d.storeEdges(pageNum, pl.lines)
+		d.setHasTables(pageNum, len(d.layout.detectTables(pl.lines, pl.rulings)) > 0)
+		return nil
+	})
+	return page, err
 }
 
 // GetPageRange retrieves text from a range of pages
@@ -152,6 +367,8 @@ func (d *Document) Search(query string) ([]SearchResult, error) {
 				MatchText:    match.Text,
 				ContextBefore: match.ContextBefore,
 				ContextAfter:  match.ContextAfter,
+				Start:         match.Start,
+				End:           match.End,
 			})
 		}
 	}
@@ -161,32 +378,23 @@ func (d *Document) Search(query string) ([]SearchResult, error) {
 
 // GetMetadata returns document metadata
 func (d *Document) GetMetadata() Metadata {
-	return Metadata{
-		FilePath: d.filepath,
-		Pages:    d.pages,
-		Title:    d.title,
-		Author:   d.author,
-		Subject:  d.subject,
-		Creator:  d.creator,
+	meta := d.metadata
+	meta.FilePath = d.filepath
+	meta.Pages = d.pages
+	if meta.Title == "" {
+		meta.Title = filepath.Base(d.filepath)
 	}
+	return meta
 }
 
 // ClearCache clears all cached pages
 func (d *Document) ClearCache() {
-	d.cacheMu.Lock()
-	defer d.cacheMu.Unlock()
-	d.pageCache = make(map[int]string)
+	d.cache.Clear()
 }
 
 // CacheStats returns cache statistics
 func (d *Document) CacheStats() CacheStats {
-	d.cacheMu.RLock()
-	defer d.cacheMu.RUnlock()
-
-	return CacheStats{
-		CachedPages: len(d.pageCache),
-		MaxSize:     d.maxCache,
-	}
+	return d.cache.Stats()
 }
 
 // Helper functions
@@ -197,12 +405,13 @@ func (d *Document) createPageInfo(pageNum int, text string) *PageInfo {
 
 	return &PageInfo{
 		PageNum:   pageNum,
+		Label:     d.PageLabel(pageNum),
 		Text:      text,
 		LineCount: lineCount,
 		WordCount: wordCount,
-		// TODO: Detect images and tables
+		// TODO: Detect images
 		HasImages: false,
-		HasTables: false,
+		HasTables: d.hasTables(pageNum),
 	}
 }
 
@@ -246,6 +455,7 @@ type SearchResult struct {
 	MatchText      string
 	ContextBefore  string
 	ContextAfter   string
+	Start, End     int // byte range of the match in the page text
 }
 
 // Metadata contains document metadata
@@ -256,12 +466,28 @@ type Metadata struct {
 	Author   string
 	Subject  string
 	Creator  string
+
+	// From the /Info dictionary or XMP metadata stream
+	Keywords     string
+	Producer     string
+	CreationDate time.Time // Zero if unknown
+	ModDate      time.Time // Zero if unknown
+
+	// Document properties
+	PDFVersion string  // e.g. "1.7"
+	Encrypted  bool    // Document uses a security handler
+	PageWidth  float64 // First page width in points
+	PageHeight float64 // First page height in points
 }
 
 // CacheStats contains cache statistics
 type CacheStats struct {
 	CachedPages int
-	MaxSize     int
+	MaxSize     int   // page cap (0 = none)
+	Bytes       int64 // text currently cached
+	MaxBytes    int64 // memory budget (0 = unbounded)
+	Hits        int
+	Misses      int
 }
 
 // Match represents a text match within a page
@@ -271,6 +497,7 @@ type Match struct {
 	ColumnNum     int
 	ContextBefore string
 	ContextAfter  string
+	Start, End    int // byte range of the match in the page text
 }
 
 // findMatches finds all case-insensitive matches of a query in text
@@ -295,6 +522,8 @@ func findMatches(text, query string) []Match {
 				ColumnNum:     pos + 1, // 1-indexed
 				ContextBefore: before,
 				ContextAfter:  after,
+				Start:         line.StartPos + pos,
+				End:           line.StartPos + pos + len(query),
 			})
 		}
 	}
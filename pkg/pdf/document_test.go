@@ -1,8 +1,11 @@
 package pdf
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -336,7 +339,7 @@ func TestSearch(t *testing.T) {
 	}{
 		{
 			name:        "common word",
-			query:       "T e s t",
+			query:       "Test",
 			expectFound: true,
 		},
 		{
@@ -555,3 +558,114 @@ func TestThreadSafety(t *testing.T) {
 
 	// If we get here without deadlock or panic, test passes
 }
+
+// TestOpenDocument_CacheBudget tests that page text stays within the budget
+func TestOpenDocument_CacheBudget(t *testing.T) {
+	testPDF := getTestPDF("multipage.pdf")
+	if _, err := os.Stat(testPDF); os.IsNotExist(err) {
+		t.Skip("Test PDF fixture not found:", testPDF)
+	}
+
+	doc, err := OpenDocument(testPDF, DocumentOptions{CacheBytes: 1})
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+
+	// Every page is larger than one byte, so nothing can be cached
+	for i := 1; i <= doc.GetPageCount(); i++ {
+		if _, err := doc.GetPage(i); err != nil {
+			t.Fatalf("GetPage(%d) unexpected error: %v", i, err)
+		}
+	}
+	stats := doc.CacheStats()
+	if stats.CachedPages != 0 || stats.Bytes != 0 {
+		t.Errorf("CacheStats = %+v, want empty cache", stats)
+	}
+	if stats.Misses != doc.GetPageCount() {
+		t.Errorf("Misses = %d, want %d", stats.Misses, doc.GetPageCount())
+	}
+}
+
+// TestClose tests that a closed document refuses further reads
+func TestClose(t *testing.T) {
+	testPDF := getTestPDF("multipage.pdf")
+	if _, err := os.Stat(testPDF); os.IsNotExist(err) {
+		t.Skip("Test PDF fixture not found:", testPDF)
+	}
+
+	doc, err := NewDocument(testPDF, 5)
+	if err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+	if _, err := doc.GetPage(1); err != nil {
+		t.Fatalf("GetPage(1) unexpected error: %v", err)
+	}
+
+	if err := doc.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if err := doc.Close(); err != nil {
+		t.Errorf("second Close() should be a no-op, got %v", err)
+	}
+
+	// Cached pages remain available; uncached pages need the reader
+	if _, err := doc.GetPage(1); err != nil {
+		t.Errorf("GetPage(1) from cache unexpected error: %v", err)
+	}
+	if _, err := doc.GetPage(2); !errors.Is(err, ErrClosed) {
+		t.Errorf("GetPage(2) after Close error = %v, want ErrClosed", err)
+	}
+	if _, err := doc.ExtractOutline(); !errors.Is(err, ErrClosed) {
+		t.Errorf("ExtractOutline() after Close error = %v, want ErrClosed", err)
+	}
+}
+
+// TestGetPage_Concurrent tests concurrent page extraction from one reader
+func TestGetPage_Concurrent(t *testing.T) {
+	testPDF := getTestPDF("multipage.pdf")
+	if _, err := os.Stat(testPDF); os.IsNotExist(err) {
+		t.Skip("Test PDF fixture not found:", testPDF)
+	}
+
+	doc, err := OpenDocument(testPDF, DocumentOptions{CacheBytes: 1}) // force extraction every time
+	if err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+	defer doc.Close()
+
+	want := make([]string, doc.GetPageCount()+1)
+	for i := 1; i <= doc.GetPageCount(); i++ {
+		page, err := doc.GetPage(i)
+		if err != nil {
+			t.Fatalf("GetPage(%d) unexpected error: %v", i, err)
+		}
+		want[i] = page.Text
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				pageNum := (g+j)%doc.GetPageCount() + 1
+				page, err := doc.GetPage(pageNum)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if page.Text != want[pageNum] {
+					errs <- fmt.Errorf("page %d text differs under concurrency", pageNum)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
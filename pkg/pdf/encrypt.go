@@ -0,0 +1,874 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Errors opening encrypted documents
+var (
+	// ErrPasswordRequired is returned for a document that cannot be opened
+	// with the empty password when no password was given
+	ErrPasswordRequired = errors.New("document is encrypted and needs a password")
+
+	// ErrWrongPassword is returned when the password given is neither the
+	// user nor the owner password of the document
+	ErrWrongPassword = errors.New("wrong password")
+
+	// ErrUnsupportedEncryption is returned for security handlers other than
+	// the standard one, and for revisions of it this package doesn't know
+	ErrUnsupportedEncryption = errors.New("unsupported encryption")
+)
+
+// The reader library only decrypts part of the standard security handler
+// correctly (it derives 128-bit keys for 40-bit files, and rejects AES-256),
+// so encrypted files are decrypted here instead. The reader parses a copy
+// of the file in memory, with the /Encrypt key of its trailer renamed so
+// that it takes the file for a plain one. The streams of the objects in
+// the cross-reference table are decrypted in that copy, where they never
+// grow, and the strings are left encrypted: the few the package reads are
+// decrypted as they are read (see securityHandler.text).
+
+// openReader opens the PDF in f, decrypting it with password if it is
+// encrypted. The security handler is nil for a plain document.
+func openReader(f *os.File, size int64, password string) (*pdf.Reader, *securityHandler, error) {
+	r, err := pdf.NewReader(f, size)
+	switch {
+	case err == nil && r.Trailer().Key("Encrypt").Kind() == pdf.Null:
+		return r, nil, nil
+	case err != nil && err != pdf.ErrInvalidPassword && !strings.Contains(err.Error(), "encryption"):
+		return nil, nil, err
+	}
+
+	data := make([]byte, size)
+	if _, err := f.ReadAt(data, 0); err != nil {
+		return nil, nil, err
+	}
+	return decryptPDF(data, password)
+}
+
+// decryptedKey is what decryptPDF renames the /Encrypt key of the trailer
+// to, keeping its length
+const decryptedKey = "Decrypt"
+
+// decryptPDF opens the encrypted PDF file in data, decrypting its streams
+// in place
+func decryptPDF(data []byte, password string) (*pdf.Reader, *securityHandler, error) {
+	key, err := trailerEncryptKey(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	copy(data[key+1:], decryptedKey)
+
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	sh, err := newSecurityHandler(r.Trailer())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := sh.authenticate(password); err != nil {
+		return nil, nil, err
+	}
+	entries, err := xrefEntries(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	sh.decryptStreams(data, entries)
+	return r, sh, nil
+}
+
+// trailerEncryptKey returns where the /Encrypt key is in the trailer the
+// reader reads: the dictionary after the cross-reference table startxref
+// points to, or that of the cross-reference stream there
+func trailerEncryptKey(data []byte) (int, error) {
+	i := bytes.LastIndex(data, []byte("startxref"))
+	if i < 0 {
+		return 0, errors.New("malformed PDF: missing startxref")
+	}
+	start, end := nextToken(data, i+len("startxref"))
+	offset, err := strconv.Atoi(string(data[start:end]))
+	if err != nil || offset < 0 || offset >= len(data) {
+		return 0, errors.New("malformed PDF: bad startxref")
+	}
+
+	start, end = nextToken(data, offset)
+	if string(data[start:end]) == "xref" {
+		for start < len(data) && string(data[start:end]) != "trailer" {
+			start, end = nextToken(data, end)
+		}
+		start, _ = nextToken(data, end)
+	} else {
+		// "12 0 obj <<" starts a cross-reference stream
+		for i := 0; i < 3; i++ {
+			start, end = nextToken(data, end)
+		}
+	}
+
+	if key := dictKey(data, start, "/Encrypt"); key >= 0 {
+		return key, nil
+	}
+	return 0, errors.New("malformed PDF: missing encryption dictionary")
+}
+
+// cryptMethod is how a crypt filter encrypts strings or streams
+type cryptMethod int
+
+const (
+	cryptNone cryptMethod = iota
+	cryptRC4
+	cryptAESV2 // AES-128 with a key per object
+	cryptAESV3 // AES-256 with the file key
+)
+
+// securityHandler holds the /Encrypt parameters of the standard security
+// handler (PDF 32000-2 §7.6.4) and, once authenticated, the file key
+type securityHandler struct {
+	v, r      int
+	keyLength int // bytes
+	o, u      []byte
+	oe, ue    []byte
+	p         uint32
+	id        []byte
+	metadata  bool // whether metadata streams are encrypted
+	stm, str  cryptMethod
+	encrypt   objectRef // the /Encrypt dictionary itself, never encrypted
+
+	key   []byte
+	plain map[objectRef]bool // objects whose strings are not encrypted on their own
+}
+
+// newSecurityHandler reads the encryption dictionary of a trailer
+func newSecurityHandler(trailer pdf.Value) (*securityHandler, error) {
+	enc := trailer.Key(decryptedKey)
+	if enc.Kind() != pdf.Dict {
+		return nil, fmt.Errorf("malformed PDF: missing encryption dictionary")
+	}
+	if filter := enc.Key("Filter").Name(); filter != "Standard" {
+		return nil, fmt.Errorf("%w: security handler %q", ErrUnsupportedEncryption, filter)
+	}
+
+	sh := &securityHandler{
+		v:        int(enc.Key("V").Int64()),
+		r:        int(enc.Key("R").Int64()),
+		o:        []byte(enc.Key("O").RawString()),
+		u:        []byte(enc.Key("U").RawString()),
+		oe:       []byte(enc.Key("OE").RawString()),
+		ue:       []byte(enc.Key("UE").RawString()),
+		p:        uint32(enc.Key("P").Int64()),
+		id:       []byte(trailer.Key("ID").Index(0).RawString()),
+		metadata: enc.Key("EncryptMetadata").Kind() != pdf.Bool || enc.Key("EncryptMetadata").Bool(),
+		encrypt:  refOf(enc),
+		plain:    map[objectRef]bool{refOf(enc): true},
+	}
+	if sh.r < 2 || sh.r > 6 {
+		return nil, fmt.Errorf("%w: revision %d", ErrUnsupportedEncryption, sh.r)
+	}
+
+	sh.keyLength = 5
+	if bits := enc.Key("Length").Int64(); sh.r >= 3 && bits >= 40 && bits <= 128 && bits%8 == 0 {
+		sh.keyLength = int(bits / 8)
+	} else if sh.v == 4 {
+		sh.keyLength = 16
+	}
+
+	switch sh.v {
+	case 1, 2:
+		sh.stm, sh.str = cryptRC4, cryptRC4
+	case 4, 5:
+		var err error
+		if sh.stm, err = cryptFilter(enc, "StmF"); err != nil {
+			return nil, err
+		}
+		if sh.str, err = cryptFilter(enc, "StrF"); err != nil {
+			return nil, err
+		}
+		if sh.v == 5 {
+			sh.keyLength = 32
+		}
+	default:
+		return nil, fmt.Errorf("%w: version %d", ErrUnsupportedEncryption, sh.v)
+	}
+
+	if len(sh.o) < 32 || len(sh.u) < 32 || (sh.r >= 5 && (len(sh.o) < 48 || len(sh.u) < 48)) {
+		return nil, fmt.Errorf("malformed PDF: bad /O or /U encryption entry")
+	}
+	return sh, nil
+}
+
+// cryptFilter looks up the method of the crypt filter named by the key
+// (StmF or StrF) of an encryption dictionary
+func cryptFilter(enc pdf.Value, key string) (cryptMethod, error) {
+	name := enc.Key(key).Name()
+	if name == "" || name == "Identity" {
+		return cryptNone, nil
+	}
+	switch cfm := enc.Key("CF").Key(name).Key("CFM").Name(); cfm {
+	case "None":
+		return cryptNone, nil
+	case "V2":
+		return cryptRC4, nil
+	case "AESV2":
+		return cryptAESV2, nil
+	case "AESV3":
+		return cryptAESV3, nil
+	default:
+		return cryptNone, fmt.Errorf("%w: crypt filter method %q", ErrUnsupportedEncryption, cfm)
+	}
+}
+
+// authenticate finds the file key from the empty password, or from the
+// user or owner password given
+func (sh *securityHandler) authenticate(password string) error {
+	candidates := []string{""}
+	if password != "" {
+		candidates = append(candidates, password)
+	}
+	for _, pw := range candidates {
+		if sh.r >= 5 {
+			sh.key = sh.aesKey(pw)
+		} else {
+			sh.key = sh.rc4Key(pdfDocBytes(pw))
+		}
+		if sh.key != nil {
+			return nil
+		}
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+	return ErrWrongPassword
+}
+
+// pdfDocBytes encodes a password for revisions 2 to 4, which take it in
+// PDFDocEncoding: Latin-1 is close enough, other text is left in UTF-8
+func pdfDocBytes(pw string) []byte {
+	b := make([]byte, 0, len(pw))
+	for _, r := range pw {
+		if r > 0xff {
+			return []byte(pw)
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
+// passwordPad pads passwords to 32 bytes for revisions 2 to 4
+var passwordPad = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// pad pads or cuts a password to 32 bytes
+func pad(pw []byte) []byte {
+	return append(append([]byte(nil), pw[:min(len(pw), 32)]...), passwordPad...)[:32]
+}
+
+// rc4Key returns the file key if pw is the user or the owner password of a
+// revision 2 to 4 document, or nil
+func (sh *securityHandler) rc4Key(pw []byte) []byte {
+	if key := sh.userKey(pad(pw)); key != nil {
+		return key
+	}
+
+	// The owner password decrypts /O into the user password (algorithm 7)
+	sum := md5.Sum(pad(pw))
+	if sh.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(sum[:])
+		}
+	}
+	ownerKey := sum[:sh.keyLength]
+	user := append([]byte(nil), sh.o[:32]...)
+	if sh.r == 2 {
+		rc4XOR(ownerKey, user)
+	} else {
+		for i := 19; i >= 0; i-- {
+			rc4XOR(xorKey(ownerKey, byte(i)), user)
+		}
+	}
+	return sh.userKey(user)
+}
+
+// userKey computes the file key from a padded user password (algorithm 2)
+// and returns it if it checks against /U (algorithms 4 and 5), or nil
+func (sh *securityHandler) userKey(padded []byte) []byte {
+	h := md5.New()
+	h.Write(padded)
+	h.Write(sh.o[:32])
+	binary.Write(h, binary.LittleEndian, sh.p)
+	h.Write(sh.id)
+	if sh.r >= 4 && !sh.metadata {
+		h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	}
+	key := h.Sum(nil)
+	if sh.r >= 3 {
+		for i := 0; i < 50; i++ {
+			sum := md5.Sum(key[:sh.keyLength])
+			key = sum[:]
+		}
+	}
+	key = key[:sh.keyLength]
+
+	if sh.r == 2 {
+		u := append([]byte(nil), passwordPad...)
+		rc4XOR(key, u)
+		if !bytes.Equal(u, sh.u[:32]) {
+			return nil
+		}
+		return key
+	}
+
+	sum := md5.Sum(append(append([]byte(nil), passwordPad...), sh.id...))
+	u := sum[:]
+	for i := 0; i < 20; i++ {
+		rc4XOR(xorKey(key, byte(i)), u)
+	}
+	if !bytes.Equal(u, sh.u[:16]) {
+		return nil
+	}
+	return key
+}
+
+// aesKey returns the file key if pw is the user or the owner password of a
+// revision 5 or 6 (AES-256) document, or nil
+func (sh *securityHandler) aesKey(password string) []byte {
+	pw := []byte(password)
+	if len(pw) > 127 {
+		pw = pw[:127]
+	}
+
+	// Hash, validation salt and key salt follow each other in /U and /O
+	if bytes.Equal(sh.hash2B(pw, sh.u[32:40], nil), sh.u[:32]) {
+		return aesUnwrap(sh.hash2B(pw, sh.u[40:48], nil), sh.ue)
+	}
+	if bytes.Equal(sh.hash2B(pw, sh.o[32:40], sh.u[:48]), sh.o[:32]) {
+		return aesUnwrap(sh.hash2B(pw, sh.o[40:48], sh.u[:48]), sh.oe)
+	}
+	return nil
+}
+
+// hash2B hashes a password with a salt (algorithm 2.B; plain SHA-256 for
+// revision 5)
+func (sh *securityHandler) hash2B(pw, salt, udata []byte) []byte {
+	h := sha256.New()
+	h.Write(pw)
+	h.Write(salt)
+	h.Write(udata)
+	k := h.Sum(nil)
+	if sh.r == 5 {
+		return k
+	}
+
+	var e []byte
+	for i := 0; i < 64 || int(e[len(e)-1]) > i-32; i++ {
+		round := append(append(append([]byte(nil), pw...), k...), udata...)
+		k1 := bytes.Repeat(round, 64)
+
+		block, _ := aes.NewCipher(k[:16])
+		e = make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		// The first 16 bytes of E as a number, modulo 3, pick the hash
+		sum := 0
+		for _, b := range e[:16] {
+			sum += int(b)
+		}
+		var next hash.Hash
+		switch sum % 3 {
+		case 0:
+			next = sha256.New()
+		case 1:
+			next = sha512.New384()
+		default:
+			next = sha512.New()
+		}
+		next.Write(e)
+		k = next.Sum(nil)
+	}
+	return k[:32]
+}
+
+// aesUnwrap decrypts /UE or /OE into the file key
+func aesUnwrap(key, wrapped []byte) []byte {
+	if len(wrapped) < 32 {
+		return nil
+	}
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, 32)
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, wrapped[:32])
+	return out
+}
+
+// rc4XOR encrypts or decrypts data in place
+func rc4XOR(key, data []byte) {
+	c, _ := rc4.NewCipher(key)
+	c.XORKeyStream(data, data)
+}
+
+// xorKey returns a copy of key with every byte XORed with b
+func xorKey(key []byte, b byte) []byte {
+	out := make([]byte, len(key))
+	for i := range key {
+		out[i] = key[i] ^ b
+	}
+	return out
+}
+
+// decrypt decrypts the string or stream data of an object
+func (sh *securityHandler) decrypt(method cryptMethod, ref objectRef, data []byte) []byte {
+	if method == cryptNone {
+		return data
+	}
+
+	key := sh.key
+	if method != cryptAESV3 {
+		// Algorithm 1: a key per object
+		h := md5.New()
+		h.Write(sh.key)
+		h.Write([]byte{byte(ref.id), byte(ref.id >> 8), byte(ref.id >> 16), byte(ref.gen), byte(ref.gen >> 8)})
+		if method == cryptAESV2 {
+			h.Write([]byte("sAlT"))
+		}
+		key = h.Sum(nil)[:min(len(sh.key)+5, 16)]
+	}
+
+	if method == cryptRC4 {
+		out := append([]byte(nil), data...)
+		rc4XOR(key, out)
+		return out
+	}
+
+	// AES: a 16-byte IV, then the data padded to whole blocks
+	if len(data) < 2*aes.BlockSize || len(data)%aes.BlockSize != 0 {
+		return nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil
+	}
+	out := make([]byte, len(data)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, data[:aes.BlockSize]).CryptBlocks(out, data[aes.BlockSize:])
+	if n := int(out[len(out)-1]); n >= 1 && n <= aes.BlockSize && n <= len(out) {
+		out = out[:len(out)-n]
+	}
+	return out
+}
+
+// xrefEntry is an object of a cross-reference table
+type xrefEntry struct {
+	ref    objectRef
+	offset int  // where its "12 0 obj" is, unless packed
+	packed bool // stored in an object stream
+}
+
+// xrefEntries returns the cross-reference table the reader read, which the
+// library doesn't export
+func xrefEntries(r *pdf.Reader) ([]xrefEntry, error) {
+	table := reflect.ValueOf(r).Elem().FieldByName("xref")
+	if table.Kind() != reflect.Slice {
+		return nil, errors.New("cannot read the cross-reference table")
+	}
+	var entries []xrefEntry
+	for i := 0; i < table.Len(); i++ {
+		x := table.Index(i)
+		ptr, offset, packed := x.FieldByName("ptr"), x.FieldByName("offset"), x.FieldByName("inStream")
+		if !ptr.IsValid() || ptr.NumField() < 2 || !offset.IsValid() || !packed.IsValid() {
+			return nil, errors.New("cannot read the cross-reference table")
+		}
+		if ptr.Field(0).Uint() == 0 {
+			continue // a free entry
+		}
+		entries = append(entries, xrefEntry{
+			ref:    objectRef{id: ptr.Field(0).Uint(), gen: ptr.Field(1).Uint()},
+			offset: int(offset.Int()),
+			packed: packed.Bool(),
+		})
+	}
+	return entries, nil
+}
+
+// objectAt returns where the body of object ref starts, after the
+// "12 0 obj" at offset, or -1 if the object is not there
+func objectAt(data []byte, offset int, ref objectRef) int {
+	if offset <= 0 || offset >= len(data) {
+		return -1
+	}
+	var header [3]string
+	end := offset
+	for i := range header {
+		var start int
+		start, end = nextToken(data, end)
+		header[i] = string(data[start:end])
+	}
+	id, err := strconv.ParseUint(header[0], 10, 64)
+	if err != nil || id != ref.id || header[2] != "obj" {
+		return -1
+	}
+	if gen, err := strconv.ParseUint(header[1], 10, 64); err != nil || gen != ref.gen {
+		return -1
+	}
+	return end
+}
+
+// decryptStreams decrypts the stream data of the objects of the
+// cross-reference table in place. It also notes the objects whose strings
+// are not encrypted on their own: those in object streams, encrypted with
+// the stream, and cross-reference streams.
+func (sh *securityHandler) decryptStreams(data []byte, entries []xrefEntry) {
+	offsets := make(map[objectRef]int, len(entries))
+	for _, e := range entries {
+		if e.packed {
+			sh.plain[e.ref] = true
+		} else {
+			offsets[e.ref] = e.offset
+		}
+	}
+
+	for _, e := range entries {
+		body := objectAt(data, e.offset, e.ref)
+		if e.packed || body < 0 || e.ref == sh.encrypt {
+			continue
+		}
+		obj := scanObject(data, body)
+		if obj.typ == "XRef" {
+			sh.plain[e.ref] = true
+			continue
+		}
+		if obj.stream[1] > obj.stream[0] && (sh.metadata || obj.typ != "Metadata") {
+			sh.decryptStream(data, e.ref, obj, offsets)
+		}
+	}
+}
+
+// decryptStream decrypts the data of a stream in place. AES data shrinks,
+// so /Length is rewritten and the space left is blanked.
+func (sh *securityHandler) decryptStream(data []byte, ref objectRef, obj scannedObject, offsets map[objectRef]int) {
+	raw := data[obj.stream[0]:obj.stream[1]]
+	plain := sh.decrypt(sh.stm, ref, raw)
+	if plain == nil && len(raw) > 0 {
+		return
+	}
+	copy(raw, plain)
+	if len(plain) == len(raw) {
+		return
+	}
+	for i := len(plain); i < len(raw); i++ {
+		raw[i] = ' '
+	}
+
+	length := obj.length
+	if obj.lengthRef.id != 0 {
+		// An indirect /Length is an object of its own holding the number
+		if body := objectAt(data, offsets[obj.lengthRef], obj.lengthRef); body >= 0 {
+			start, end := nextToken(data, body)
+			length = [2]int{start, end}
+		}
+	}
+	if length[1] > length[0] {
+		digits := data[length[0]:length[1]]
+		n := strconv.Itoa(len(plain))
+		if _, err := strconv.Atoi(string(digits)); err == nil && len(n) <= len(digits) {
+			copy(digits, n+strings.Repeat(" ", len(digits)-len(n)))
+		}
+	}
+}
+
+// rawString returns the bytes of a string value. The strings of an
+// encrypted document stay encrypted in the file the reader parses, and are
+// decrypted here; those that can't be are read as empty.
+func (sh *securityHandler) rawString(v pdf.Value) string {
+	s := v.RawString()
+	if sh == nil || s == "" {
+		return s
+	}
+	ref := refOf(v)
+	if ref.id == 0 || sh.plain[ref] {
+		// Strings of the trailer and of objects in object streams
+		return s
+	}
+	return string(sh.decrypt(sh.str, ref, []byte(s)))
+}
+
+// text returns a string value as text, as pdf.Value.Text does, decrypting
+// it first
+func (sh *securityHandler) text(v pdf.Value) string {
+	if sh == nil {
+		return v.Text()
+	}
+	return decodeText(sh.rawString(v))
+}
+
+// decodeText decodes a text string: UTF-16BE or UTF-8 after a byte order
+// mark, or else PDFDocEncoding. Strings with bytes PDFDocEncoding leaves
+// undefined are returned as they are.
+func decodeText(s string) string {
+	switch {
+	case len(s) >= 2 && len(s)%2 == 0 && s[0] == 0xfe && s[1] == 0xff:
+		u := make([]uint16, 0, len(s)/2-1)
+		for i := 2; i < len(s); i += 2 {
+			u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(u))
+	case strings.HasPrefix(s, "\xef\xbb\xbf"):
+		return s[3:]
+	}
+
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c < 0x18 && c != '\t' && c != '\n' && c != '\r', c == 0x7f, c == 0x9f, c == 0xad:
+			return s
+		case pdfDocRunes[c] != 0:
+			runes[i] = pdfDocRunes[c]
+		default:
+			runes[i] = rune(c)
+		}
+	}
+	return string(runes)
+}
+
+// pdfDocRunes are the characters of PDFDocEncoding that differ from
+// Latin-1 (PDF 32000-1 Annex D.2)
+var pdfDocRunes = map[byte]rune{
+	0x18: '\u02d8', 0x19: '\u02c7', 0x1a: '\u02c6', 0x1b: '\u02d9', 0x1c: '\u02dd', 0x1d: '\u02db', 0x1e: '\u02da', 0x1f: '\u02dc',
+	0x80: '\u2022', 0x81: '\u2020', 0x82: '\u2021', 0x83: '\u2026', 0x84: '\u2014', 0x85: '\u2013', 0x86: '\u0192', 0x87: '\u2044',
+	0x88: '\u2039', 0x89: '\u203a', 0x8a: '\u2212', 0x8b: '\u2030', 0x8c: '\u201e', 0x8d: '\u201c', 0x8e: '\u201d', 0x8f: '\u2018',
+	0x90: '\u2019', 0x91: '\u201a', 0x92: '\u2122', 0x93: '\ufb01', 0x94: '\ufb02', 0x95: '\u0141', 0x96: '\u0152', 0x97: '\u0160',
+	0x98: '\u0178', 0x99: '\u017d', 0x9a: '\u0131', 0x9b: '\u0142', 0x9c: '\u0153', 0x9d: '\u0161', 0x9e: '\u017e', 0xa0: '\u20ac',
+}
+
+// scannedObject is what decryptStreams needs to know about an object
+type scannedObject struct {
+	stream    [2]int // stream data, if any
+	length    [2]int // digits of a direct /Length
+	lengthRef objectRef
+	typ       string // /Type of the object's dictionary
+}
+
+// scanObject reads the object whose body starts at data[pos], up to
+// endobj or the start of its stream data
+func scanObject(data []byte, pos int) scannedObject {
+	var obj scannedObject
+	var tokens []string // top-level dictionary tokens, for /Length and /Type
+	var spans [][2]int
+	depth := 0
+
+	for {
+		start, end := nextToken(data, pos)
+		if start == len(data) {
+			return obj
+		}
+		pos = end
+		switch token := string(data[start:end]); token {
+		case "<<":
+			depth++
+		case ">>":
+			depth--
+		case "stream":
+			obj.readStream(data, pos, tokens, spans)
+			return obj
+		case "endobj", "obj":
+			// "obj" is the next object's, after a missing endobj
+			return obj
+		default:
+			if depth == 1 {
+				tokens = append(tokens, token)
+				spans = append(spans, [2]int{start, end})
+			}
+		}
+	}
+}
+
+// nextToken returns the bounds of the token at or after data[pos],
+// skipping white space and comments: a string, "<<" or ">>", another
+// delimiter, or a name, number or keyword. Both are len(data) past the
+// last token.
+func nextToken(data []byte, pos int) (int, int) {
+	for pos < len(data) {
+		c := data[pos]
+		switch {
+		case isPDFSpace(c):
+			pos++
+		case c == '%':
+			for pos < len(data) && data[pos] != '\n' && data[pos] != '\r' {
+				pos++
+			}
+		case c == '(':
+			return pos, literalEnd(data, pos)
+		case (c == '<' || c == '>') && pos+1 < len(data) && data[pos+1] == c:
+			return pos, pos + 2
+		case c == '<':
+			end := bytes.IndexByte(data[pos:], '>')
+			if end < 0 {
+				return pos, len(data)
+			}
+			return pos, pos + end + 1
+		case c == '/':
+			end := pos + 1
+			for end < len(data) && !isPDFSpace(data[end]) && !isPDFDelimiter(data[end]) {
+				end++
+			}
+			return pos, end
+		case isPDFDelimiter(c):
+			return pos, pos + 1
+		default:
+			end := pos + 1
+			for end < len(data) && !isPDFSpace(data[end]) && !isPDFDelimiter(data[end]) {
+				end++
+			}
+			return pos, end
+		}
+	}
+	return len(data), len(data)
+}
+
+// dictKey returns where key, a name with its slash, is among the keys of
+// the dictionary starting at data[pos], or -1
+func dictKey(data []byte, pos int, key string) int {
+	start, end := nextToken(data, pos)
+	if string(data[start:end]) != "<<" {
+		return -1
+	}
+	for {
+		start, end = nextToken(data, end)
+		switch string(data[start:end]) {
+		case key:
+			return start
+		case ">>", "":
+			return -1
+		}
+		end = skipValue(data, end)
+	}
+}
+
+// skipValue returns the end of the value at or after data[pos]
+func skipValue(data []byte, pos int) int {
+	start, end := nextToken(data, pos)
+	switch token := string(data[start:end]); token {
+	case "<<", "[":
+		closing := "]"
+		if token == "<<" {
+			closing = ">>"
+		}
+		for {
+			start, next := nextToken(data, end)
+			if start == len(data) || string(data[start:next]) == closing {
+				return next
+			}
+			end = skipValue(data, start)
+		}
+	}
+
+	// "12 0 R" is a reference
+	if _, err := strconv.ParseUint(string(data[start:end]), 10, 64); err == nil {
+		genStart, genEnd := nextToken(data, end)
+		rStart, rEnd := nextToken(data, genEnd)
+		if _, err := strconv.ParseUint(string(data[genStart:genEnd]), 10, 64); err == nil && string(data[rStart:rEnd]) == "R" {
+			return rEnd
+		}
+	}
+	return end
+}
+
+// readStream finds the data of a stream starting after the stream keyword
+// at data[pos], from the tokens of its dictionary
+func (obj *scannedObject) readStream(data []byte, pos int, tokens []string, spans [][2]int) {
+	if pos < len(data) && data[pos] == '\r' {
+		pos++
+	}
+	if pos < len(data) && data[pos] == '\n' {
+		pos++
+	}
+
+	length := -1
+	for i, t := range tokens {
+		switch {
+		case t == "/Type" && i+1 < len(tokens):
+			obj.typ = strings.TrimPrefix(tokens[i+1], "/")
+		case t == "/Length" && i+1 < len(tokens):
+			n, err := strconv.Atoi(tokens[i+1])
+			if err != nil {
+				break
+			}
+			if i+3 < len(tokens) && tokens[i+3] == "R" {
+				gen, _ := strconv.Atoi(tokens[i+2])
+				obj.lengthRef = objectRef{uint64(n), uint64(gen)}
+			} else {
+				length = n
+				obj.length = spans[i+1]
+			}
+		}
+	}
+
+	// Trust /Length when endstream follows it, else look for endstream
+	end := -1
+	if length >= 0 && pos+length <= len(data) {
+		rest := bytes.TrimLeft(data[pos+length:min(pos+length+16, len(data))], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = pos + length
+		}
+	}
+	if end < 0 {
+		i := bytes.Index(data[pos:], []byte("endstream"))
+		if i < 0 {
+			return
+		}
+		end = pos + i
+		if end > pos && data[end-1] == '\n' {
+			end--
+		}
+		if end > pos && data[end-1] == '\r' {
+			end--
+		}
+		if length < 0 && obj.lengthRef.id == 0 {
+			obj.length = [2]int{}
+		}
+	}
+	obj.stream = [2]int{pos, end}
+}
+
+// literalEnd returns the end of the literal string starting at data[pos]
+func literalEnd(data []byte, pos int) int {
+	depth := 0
+	for i := pos; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(data)
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,210 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// writeEncrypted writes a two-page document with a title, encrypted as given
+func writeEncrypted(t *testing.T, enc *testEncryption) string {
+	t.Helper()
+	b := newTestPDFBuilder()
+	b.addPage([]string{"Hello encrypted world"}, "")
+	b.addPage([]string{"Second (page) \\ here"}, "")
+	info := b.add("<< /Title (Secret \\(draft\\) report) /Author (A. Writer) >>")
+	b.trailer = append(b.trailer, "/Info "+ref(info))
+	b.encryption = enc
+	return b.write(t)
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	for _, revision := range []int{2, 3, 4, 6} {
+		path := writeEncrypted(t, &testEncryption{owner: "owner", revision: revision})
+
+		doc, err := OpenDocument(path, DefaultDocumentOptions())
+		if err != nil {
+			t.Fatalf("R%d: OpenDocument() unexpected error: %v", revision, err)
+		}
+		defer doc.Close()
+
+		for pageNum, want := range map[int]string{1: "Hello encrypted world", 2: "Second (page) \\ here"} {
+			page, err := doc.GetPage(pageNum)
+			if err != nil {
+				t.Fatalf("R%d: GetPage(%d) unexpected error: %v", revision, pageNum, err)
+			}
+			if !strings.Contains(page.Text, want) {
+				t.Errorf("R%d: page %d text = %q, want %q", revision, pageNum, page.Text, want)
+			}
+		}
+
+		meta := doc.GetMetadata()
+		if !meta.Encrypted {
+			t.Errorf("R%d: Encrypted = false", revision)
+		}
+		if meta.Title != "Secret (draft) report" || meta.Author != "A. Writer" {
+			t.Errorf("R%d: Title, Author = %q, %q", revision, meta.Title, meta.Author)
+		}
+	}
+}
+
+func TestOpenEncryptedPassword(t *testing.T) {
+	for _, revision := range []int{2, 3, 4, 6} {
+		path := writeEncrypted(t, &testEncryption{user: "user", owner: "owner", revision: revision})
+
+		_, err := OpenDocument(path, DefaultDocumentOptions())
+		if !errors.Is(err, ErrPasswordRequired) {
+			t.Errorf("R%d: no password: error = %v, want ErrPasswordRequired", revision, err)
+		}
+
+		opts := DefaultDocumentOptions()
+		opts.Password = "guess"
+		_, err = OpenDocument(path, opts)
+		if !errors.Is(err, ErrWrongPassword) {
+			t.Errorf("R%d: wrong password: error = %v, want ErrWrongPassword", revision, err)
+		}
+
+		for _, password := range []string{"user", "owner"} {
+			opts.Password = password
+			doc, err := OpenDocument(path, opts)
+			if err != nil {
+				t.Errorf("R%d: password %q: unexpected error: %v", revision, password, err)
+				continue
+			}
+			page, err := doc.GetPage(1)
+			if err != nil || !strings.Contains(page.Text, "Hello encrypted world") {
+				t.Errorf("R%d: password %q: page 1 = %v, %v", revision, password, page, err)
+			}
+			doc.Close()
+		}
+	}
+}
+
+func TestOpenUnsupportedEncryption(t *testing.T) {
+	data, err := os.ReadFile(writeEncrypted(t, &testEncryption{revision: 3}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same-length edits keep the cross-reference table right
+	for _, edit := range [][2]string{
+		{"/Filter /Standard", "/Filter /Adobe.PS"},
+		{"/V 2 /R 3", "/V 2 /R 9"},
+		{"/V 2 /R 3", "/V 7 /R 3"},
+	} {
+		path := filepath.Join(t.TempDir(), "unsupported.pdf")
+		if err := os.WriteFile(path, bytes.Replace(data, []byte(edit[0]), []byte(edit[1]), 1), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := OpenDocument(path, DefaultDocumentOptions())
+		if !errors.Is(err, ErrUnsupportedEncryption) {
+			t.Errorf("%s: error = %v, want ErrUnsupportedEncryption", edit[1], err)
+		}
+	}
+}
+
+func TestOpenEncryptedLiteralStrings(t *testing.T) {
+	// Decrypted, these strings need more escapes than their ciphertext
+	title := strings.Repeat("(", 40) + " draft " + strings.Repeat(`\`, 8)
+	for _, revision := range []int{3, 4} {
+		b := newTestPDFBuilder()
+		b.addPage([]string{"Literal strings"}, "")
+		escaped := strings.NewReplacer(`\`, `\\`, "(", `\(`).Replace(title)
+		info := b.add("<< /Title (" + escaped + ") >>")
+		b.trailer = append(b.trailer, "/Info "+ref(info))
+		b.encryption = &testEncryption{revision: revision, literal: true}
+
+		doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+		if err != nil {
+			t.Fatalf("R%d: OpenDocument() unexpected error: %v", revision, err)
+		}
+		if got := doc.GetMetadata().Title; got != strings.TrimSpace(title) {
+			t.Errorf("R%d: Title = %q, want %q", revision, got, title)
+		}
+		doc.Close()
+	}
+}
+
+func TestOpenEncryptedObjectStream(t *testing.T) {
+	for _, revision := range []int{4, 6} {
+		b := newTestPDFBuilder()
+		page := b.addPage([]string{"Packed objects"},
+			"/Annots [<< /Subtype /Link /Rect [70 715 200 732] /A << /S /URI /URI (https://example.com/packed) >> >>]")
+		info := b.add("<< /Title (Packed title) >>")
+		outlines := b.reserve()
+		item := b.add("<< /Title (Unpacked title) /Parent " + ref(outlines) + " /Dest [" + ref(page) + " /Fit] >>")
+		b.set(outlines, "<< /Type /Outlines /First "+ref(item)+" /Last "+ref(item)+" /Count 1 >>")
+		b.catalog = append(b.catalog, "/Outlines "+ref(outlines))
+		b.trailer = append(b.trailer, "/Info "+ref(info))
+		b.packed = []int{page, info}
+		b.encryption = &testEncryption{revision: revision}
+
+		doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+		if err != nil {
+			t.Fatalf("R%d: OpenDocument() unexpected error: %v", revision, err)
+		}
+		defer doc.Close()
+
+		// Strings in the object stream are encrypted with it, not again
+		if got := doc.GetMetadata().Title; got != "Packed title" {
+			t.Errorf("R%d: Title = %q, want the packed one", revision, got)
+		}
+		links, err := doc.GetPageLinks(1)
+		if err != nil || len(links) != 1 || links[0].URI != "https://example.com/packed" {
+			t.Errorf("R%d: GetPageLinks(1) = %+v, %v", revision, links, err)
+		}
+		toc, err := doc.ExtractOutline()
+		if err != nil || len(toc) != 1 || toc[0].Title != "Unpacked title" || toc[0].Page != 1 {
+			t.Errorf("R%d: ExtractOutline() = %+v, %v", revision, toc, err)
+		}
+		page1, err := doc.GetPage(1)
+		if err != nil || !strings.Contains(page1.Text, "Packed objects") {
+			t.Errorf("R%d: page 1 = %+v, %v", revision, page1, err)
+		}
+	}
+}
+
+// The test files are encrypted by encryptbuilder_test.go, whose algorithms
+// mirror the package's. The reader library decrypts revisions 3 and 4 with
+// code of its own, which checks both against the specification.
+func TestEncryptedFilesReadByLibrary(t *testing.T) {
+	for _, revision := range []int{3, 4} {
+		path := writeEncrypted(t, &testEncryption{owner: "owner", revision: revision})
+		f, r, err := pdf.Open(path)
+		if err != nil {
+			t.Fatalf("R%d: the reader library cannot open the file: %v", revision, err)
+		}
+		var text strings.Builder
+		for _, glyph := range r.Page(1).Content().Text {
+			text.WriteString(glyph.S)
+		}
+		title := r.Trailer().Key("Info").Key("Title").Text()
+		f.Close()
+		if !strings.Contains(text.String(), "Hello encrypted world") {
+			t.Errorf("R%d: the reader library reads page 1 as %q", revision, text.String())
+		}
+		if revision == 3 && title != "Secret (draft) report" {
+			t.Errorf("R%d: the reader library reads the title as %q", revision, title)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Plain", "Plain"},
+		{"\x80 \x93rst \xa0", "• ﬁrst €"},
+		{"\xfe\xff\x00H\x00i\x20\x22", "Hi•"},
+		{"\xef\xbb\xbfcafé", "café"},
+		{"bad \x01 byte", "bad \x01 byte"},
+	}
+	for _, tt := range tests {
+		if got := decodeText(tt.in); got != tt.want {
+			t.Errorf("decodeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
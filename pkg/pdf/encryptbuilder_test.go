@@ -0,0 +1,213 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// testEncryption encrypts a test PDF with the standard security handler
+type testEncryption struct {
+	user     string // user password
+	owner    string // owner password
+	revision int    // 2 (RC4 40-bit), 3 (RC4 128-bit), 4 (AES-128) or 6 (AES-256)
+	literal  bool   // write encrypted strings as literal strings rather than hex
+}
+
+var testFileID = []byte("0123456789abcdef")
+
+const testPermissions = -4
+
+// keyBytes returns the length of the file key for the revision
+func (e *testEncryption) keyBytes() int {
+	switch e.revision {
+	case 2:
+		return 5
+	case 6:
+		return 32
+	}
+	return 16
+}
+
+// rc4Rounds encrypts data with key, then (revision 3 and later) 19 more
+// times with the key XORed with the round number
+func (e *testEncryption) rc4Rounds(key, data []byte) []byte {
+	out := append([]byte(nil), data...)
+	rounds := 1
+	if e.revision >= 3 {
+		rounds = 20
+	}
+	for i := 0; i < rounds; i++ {
+		k := append([]byte(nil), key...)
+		for j := range k {
+			k[j] ^= byte(i)
+		}
+		c, _ := rc4.NewCipher(k)
+		c.XORKeyStream(out, out)
+	}
+	return out
+}
+
+// hashKey hashes data, 50 more times from revision 3 on, cut to the key length
+func (e *testEncryption) hashKey(data []byte) []byte {
+	sum := md5.Sum(data)
+	key := sum[:]
+	if e.revision >= 3 {
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(key[:e.keyBytes()])
+			key = sum[:]
+		}
+	}
+	return key[:e.keyBytes()]
+}
+
+// values returns the /Encrypt dictionary and the file key
+func (e *testEncryption) values() (dict string, key []byte) {
+	if e.revision == 6 {
+		return e.aesValues()
+	}
+
+	// PDF 32000-1 §7.6.3.3, algorithms 2 to 5
+	o := e.rc4Rounds(e.hashKey(pad([]byte(e.owner))), pad([]byte(e.user)))
+
+	perms := int32(testPermissions)
+	p := uint32(perms)
+	var buf bytes.Buffer
+	buf.Write(pad([]byte(e.user)))
+	buf.Write(o)
+	buf.Write([]byte{byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)})
+	buf.Write(testFileID)
+	key = e.hashKey(buf.Bytes())
+
+	var u []byte
+	if e.revision == 2 {
+		u = e.rc4Rounds(key, passwordPad)
+	} else {
+		sum := md5.Sum(append(append([]byte(nil), passwordPad...), testFileID...))
+		u = append(e.rc4Rounds(key, sum[:]), make([]byte, 16)...)
+	}
+
+	switch e.revision {
+	case 2:
+		dict = fmt.Sprintf("<< /Filter /Standard /V 1 /R 2 /O <%x> /U <%x> /P %d >>", o, u, testPermissions)
+	case 3:
+		dict = fmt.Sprintf("<< /Filter /Standard /V 2 /R 3 /Length 128 /O <%x> /U <%x> /P %d >>", o, u, testPermissions)
+	default:
+		dict = fmt.Sprintf("<< /Filter /Standard /V 4 /R 4 /Length 128 /CF << /StdCF << /CFM /AESV2 /AuthEvent /DocOpen /Length 16 >> >> /StmF /StdCF /StrF /StdCF /O <%x> /U <%x> /P %d >>",
+			o, u, testPermissions)
+	}
+	return dict, key
+}
+
+// aesValues returns the /Encrypt dictionary and file key of revision 6
+// (PDF 32000-2 §7.6.4.4, algorithms 8 and 9)
+func (e *testEncryption) aesValues() (string, []byte) {
+	key := []byte("a 256-bit file key for testing!!")
+	u := append(testHash2B([]byte(e.user), []byte("uvsalt!!"), nil), "uvsalt!!uksalt!!"...)
+	ue := aesWrap(testHash2B([]byte(e.user), []byte("uksalt!!"), nil), key)
+	o := append(testHash2B([]byte(e.owner), []byte("ovsalt!!"), u), "ovsalt!!oksalt!!"...)
+	oe := aesWrap(testHash2B([]byte(e.owner), []byte("oksalt!!"), u), key)
+
+	dict := fmt.Sprintf("<< /Filter /Standard /V 5 /R 6 /Length 256 /CF << /StdCF << /CFM /AESV3 /AuthEvent /DocOpen /Length 32 >> >> /StmF /StdCF /StrF /StdCF /O <%x> /U <%x> /OE <%x> /UE <%x> /Perms <%x> /P %d >>",
+		o, u, oe, ue, make([]byte, 16), testPermissions)
+	return dict, key
+}
+
+// testHash2B is algorithm 2.B, written from the specification apart from
+// the one under test
+func testHash2B(pw, salt, udata []byte) []byte {
+	sum := sha256.Sum256(append(append(append([]byte(nil), pw...), salt...), udata...))
+	k := sum[:]
+	for round := 0; ; round++ {
+		k1 := bytes.Repeat(append(append(append([]byte(nil), pw...), k...), udata...), 64)
+		block, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(block, k[16:32]).CryptBlocks(e, k1)
+
+		switch new(big.Int).Mod(new(big.Int).SetBytes(e[:16]), big.NewInt(3)).Int64() {
+		case 0:
+			s := sha256.Sum256(e)
+			k = s[:]
+		case 1:
+			s := sha512.Sum384(e)
+			k = s[:]
+		case 2:
+			s := sha512.Sum512(e)
+			k = s[:]
+		}
+		if round >= 63 && int(e[len(e)-1]) <= round+1-32 {
+			return k[:32]
+		}
+	}
+}
+
+// aesWrap encrypts a file key for /UE or /OE
+func aesWrap(key, fileKey []byte) []byte {
+	block, _ := aes.NewCipher(key)
+	out := make([]byte, len(fileKey))
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(out, fileKey)
+	return out
+}
+
+// encrypt encrypts the data of a string or stream of object id
+func (e *testEncryption) encrypt(key []byte, id int, data []byte) []byte {
+	k := key
+	if e.revision != 6 {
+		objKey := append(append([]byte(nil), key...), byte(id), byte(id>>8), byte(id>>16), 0, 0)
+		if e.revision == 4 {
+			objKey = append(objKey, "sAlT"...)
+		}
+		sum := md5.Sum(objKey)
+		k = sum[:min(len(key)+5, 16)]
+	}
+
+	if e.revision <= 3 {
+		c, _ := rc4.NewCipher(k)
+		out := make([]byte, len(data))
+		c.XORKeyStream(out, data)
+		return out
+	}
+
+	padding := aes.BlockSize - len(data)%aes.BlockSize
+	data = append(append([]byte(nil), data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	iv := []byte("an IV for tests!")
+	block, _ := aes.NewCipher(k)
+	out := append(append([]byte(nil), iv...), data...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out[aes.BlockSize:], data)
+	return out
+}
+
+var (
+	streamPattern  = regexp.MustCompile(`(?s)/Length \d+(.*?)>>\nstream\n(.*)endstream$`)
+	literalPattern = regexp.MustCompile(`\((?:[^()\\]|\\.)*\)`)
+)
+
+// encryptObject encrypts the strings and stream of an object. Strings are
+// written back in hex, or as literals with as few escapes as can be.
+func (e *testEncryption) encryptObject(key []byte, id int, body string) string {
+	dict, stream := body, ""
+	if m := streamPattern.FindStringSubmatchIndex(body); m != nil {
+		data := e.encrypt(key, id, []byte(body[m[4]:m[5]]))
+		dict = body[:strings.Index(body, "/Length")] + "/Length " + strconv.Itoa(len(data)) + body[m[2]:m[3]]
+		stream = ">>\nstream\n" + string(data) + "\nendstream"
+	}
+
+	dict = literalPattern.ReplaceAllStringFunc(dict, func(s string) string {
+		text := strings.NewReplacer(`\\`, `\`, `\(`, `(`, `\)`, `)`).Replace(s[1 : len(s)-1])
+		encrypted := e.encrypt(key, id, []byte(text))
+		if e.literal {
+			return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`).Replace(string(encrypted)) + ")"
+		}
+		return fmt.Sprintf("<%x>", encrypted)
+	})
+	return dict + stream
+}
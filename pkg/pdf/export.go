@@ -0,0 +1,83 @@
+package pdf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrUnknownExportFormat is returned by ParseExportFormat for names that
+// are not an export format
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+// ExportFormat is a format the text of a document can be exported to
+type ExportFormat string
+
+const (
+	ExportText     ExportFormat = "txt" // plain text, pages separated by form feeds
+	ExportMarkdown ExportFormat = "md"  // Markdown, a section per page
+)
+
+// ExportFormats returns the export formats
+func ExportFormats() []ExportFormat {
+	return []ExportFormat{ExportMarkdown, ExportText}
+}
+
+// ParseExportFormat returns the export format with the given name, which
+// may also be spelled out ("markdown", "text")
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(name) {
+	case "md", "markdown":
+		return ExportMarkdown, nil
+	case "txt", "text":
+		return ExportText, nil
+	}
+	return "", fmt.Errorf("%q: %w", name, ErrUnknownExportFormat)
+}
+
+// markdownSyntaxPattern matches the start of a line that Markdown would
+// read as a heading, quote, list item or rule
+var markdownSyntaxPattern = regexp.MustCompile(`^(\s*\d*)([#>*+\-=_.)])`)
+
+// Export writes the text of every page to w in the given format. Markdown
+// starts with the document title, if it has one, and gives each page a
+// heading with its label; text that Markdown would take for syntax is
+// escaped.
+func (d *Document) Export(w io.Writer, format ExportFormat) error {
+	bw := bufio.NewWriter(w)
+	if format == ExportMarkdown {
+		if title := strings.TrimSpace(d.GetMetadata().Title); title != "" {
+			fmt.Fprintf(bw, "# %s\n\n", title)
+		}
+	}
+
+	for pageNum := 1; pageNum <= d.pages; pageNum++ {
+		page, err := d.GetPage(pageNum)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		text := strings.TrimRight(page.Text, "\n")
+
+		switch format {
+		case ExportMarkdown:
+			fmt.Fprintf(bw, "## Page %s\n\n", d.PageLabel(pageNum))
+			for _, line := range strings.Split(text, "\n") {
+				bw.WriteString(markdownSyntaxPattern.ReplaceAllString(line, `$1\$2`))
+				bw.WriteByte('\n')
+			}
+			bw.WriteByte('\n')
+		case ExportText:
+			if pageNum > 1 {
+				bw.WriteByte('\f')
+			}
+			bw.WriteString(text)
+			bw.WriteByte('\n')
+		default:
+			return fmt.Errorf("%q: %w", format, ErrUnknownExportFormat)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,49 @@
+package pdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"Introduction", "# not a heading"}, "")
+	b.addPage([]string{"1. Results", "- and more"}, "")
+	info := b.add("<< /Title (Exported) >>")
+	b.trailer = append(b.trailer, "/Info "+ref(info))
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	var md strings.Builder
+	if err := doc.Export(&md, ExportMarkdown); err != nil {
+		t.Fatalf("Export(md) unexpected error: %v", err)
+	}
+	want := "# Exported\n\n## Page 1\n\nIntroduction\n\\# not a heading\n\n## Page 2\n\n1\\. Results\n\\- and more\n\n"
+	if md.String() != want {
+		t.Errorf("Export(md) = %q, want %q", md.String(), want)
+	}
+
+	var txt strings.Builder
+	if err := doc.Export(&txt, ExportText); err != nil {
+		t.Fatalf("Export(txt) unexpected error: %v", err)
+	}
+	if want := "Introduction\n# not a heading\n\f1. Results\n- and more\n"; txt.String() != want {
+		t.Errorf("Export(txt) = %q, want %q", txt.String(), want)
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for name, want := range map[string]ExportFormat{"md": ExportMarkdown, "Markdown": ExportMarkdown, "txt": ExportText, "text": ExportText} {
+		if got, err := ParseExportFormat(name); got != want || err != nil {
+			t.Errorf("ParseExportFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseExportFormat("docx"); !errors.Is(err, ErrUnknownExportFormat) {
+		t.Errorf("ParseExportFormat(docx) error = %v, want ErrUnknownExportFormat", err)
+	}
+}
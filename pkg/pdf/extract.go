@@ -0,0 +1,180 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ExtractionMode selects how page text is reconstructed from the PDF
+type ExtractionMode int
+
+const (
+	// ExtractLayout rebuilds words, lines and paragraphs from glyph positions
+	ExtractLayout ExtractionMode = iota
+
+	// ExtractRaw concatenates text fragments in content-stream order
+	ExtractRaw
+)
+
+// String returns the name of the mode, as accepted by ParseExtractionMode
+func (m ExtractionMode) String() string {
+	switch m {
+	case ExtractLayout:
+		return "layout"
+	case ExtractRaw:
+		return "raw"
+	default:
+		return fmt.Sprintf("ExtractionMode(%d)", int(m))
+	}
+}
+
+// ParseExtractionMode parses an extraction mode name ("layout" or "raw")
+func ParseExtractionMode(name string) (ExtractionMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "layout", "":
+		return ExtractLayout, nil
+	case "raw":
+		return ExtractRaw, nil
+	default:
+		return 0, fmt.Errorf("unknown extraction mode %q (want layout or raw)", name)
+	}
+}
+
+// Word gap inference, relative to the font size
+const (
+	wordGap     = 0.2 // horizontal gap between glyphs that separates words
+	baselineGap = 0.5 // vertical shift that moves a glyph off the word's line
+	backtrack   = 1.0 // leftward jump that starts a new word (e.g. a new line)
+)
+
+// glyphElements groups the positioned glyphs of a page into words. Glyphs
+// join the current word while they follow it on the same baseline without
+// a gap wider than wordGap; whitespace glyphs always end a word.
+func glyphElements(glyphs []pdf.Text) []TextElement {
+	var elements []TextElement
+	var word strings.Builder
+	var cur TextElement
+	var lastY, end float64
+	open, estimated := false, false
+	place := newGlyphPlacer()
+
+	flush := func() {
+		if open {
+			cur.Text = word.String()
+			cur.Width = end - cur.X
+			elements = append(elements, cur)
+			word.Reset()
+			open = false
+		}
+	}
+
+	add := func(g pdf.Text, text string) {
+		if open && !continuesWord(cur, lastY, end, g) {
+			flush()
+		}
+		if !open {
+			cur = TextElement{X: g.X, Y: g.Y, FontSize: g.FontSize, Font: g.Font}
+			end = g.X
+			open = true
+		}
+		cur.estimated = cur.estimated || estimated
+		word.WriteString(text)
+		lastY = g.Y
+		end = math.Max(end, g.X+g.W)
+	}
+
+	for _, g := range glyphs {
+		g, estimated = place(g)
+		fields := strings.Fields(g.S)
+		if len(fields) == 0 {
+			flush()
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(g.S); unicode.IsSpace(r) {
+			flush()
+		}
+		for i, field := range fields {
+			if i > 0 {
+				flush()
+			}
+			add(g, field)
+		}
+		if r, _ := utf8.DecodeLastRuneInString(g.S); unicode.IsSpace(r) {
+			flush()
+		}
+	}
+	flush()
+
+	return elements
+}
+
+// glyphAdvance estimates the width of text set at the given size, for
+// fonts that report no glyph widths
+func glyphAdvance(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			width += size * 0.25
+		} else {
+			width += size * 0.5
+		}
+	}
+	return width
+}
+
+// newGlyphPlacer returns a function that fills in the geometry of glyphs
+// in fonts that report no widths, and reports whether it did. The reader
+// leaves such glyphs at the start of their text run, moved only by the
+// kerning of TJ arrays, so each is placed where the previous glyph of the
+// run ended, as estimated by glyphAdvance, plus that kerning. A move of
+// more than the font size starts a new run.
+func newGlyphPlacer() func(pdf.Text) (pdf.Text, bool) {
+	var lastX, runY, cursor float64
+	started := false
+
+	return func(g pdf.Text) (pdf.Text, bool) {
+		if g.W > 0 {
+			started = false
+			return g, false
+		}
+		x := g.X
+		if started && g.Y == runY && math.Abs(x-lastX) <= math.Max(g.FontSize, 1) {
+			g.X = cursor + x - lastX
+		}
+		g.W = glyphAdvance(g.S, g.FontSize)
+		lastX, runY, cursor = x, g.Y, g.X+g.W
+		started = true
+		return g, true
+	}
+}
+
+// continuesWord reports whether glyph g, drawn after a word whose last glyph
+// sits on baseline lastY and ends at end, belongs to that word
+func continuesWord(word TextElement, lastY, end float64, g pdf.Text) bool {
+	size := math.Max(word.FontSize, g.FontSize)
+	if size <= 0 {
+		size = 1
+	}
+	if math.Abs(g.Y-lastY) > size*baselineGap {
+		return false
+	}
+	gap := g.X - end
+	return gap <= size*wordGap && gap >= -size*backtrack
+}
+
+// extractText reconstructs the text of a page's glyphs
+func extractText(glyphs []pdf.Text, mode ExtractionMode, la *LayoutAnalyzer) string {
+	if mode == ExtractRaw {
+		var sb strings.Builder
+		for _, g := range glyphs {
+			sb.WriteString(g.S)
+		}
+		return sb.String()
+	}
+	return la.ExtractText(glyphElements(glyphs))
+}
@@ -0,0 +1,179 @@
+package pdf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// glyphRun lays out text one glyph at a time from x on baseline y, each
+// glyph advancing by width, as the reader reports fonts with glyph widths
+func glyphRun(text string, x, y, size, width float64) []pdf.Text {
+	var glyphs []pdf.Text
+	for _, r := range text {
+		glyphs = append(glyphs, pdf.Text{Font: "Helvetica", FontSize: size, X: x, Y: y, W: width, S: string(r)})
+		x += width
+	}
+	return glyphs
+}
+
+func elementTexts(elements []TextElement) []string {
+	var texts []string
+	for _, e := range elements {
+		texts = append(texts, e.Text)
+	}
+	return texts
+}
+
+func TestGlyphElements(t *testing.T) {
+	tests := []struct {
+		name   string
+		glyphs []pdf.Text
+		want   []string
+	}{
+		{
+			name:   "explicit spaces",
+			glyphs: glyphRun("Hello brave world", 72, 700, 12, 6),
+			want:   []string{"Hello", "brave", "world"},
+		},
+		{
+			name: "gap without space glyph",
+			glyphs: append(glyphRun("Hello", 72, 700, 12, 6),
+				glyphRun("world", 72+5*6+4, 700, 12, 6)...), // 4pt gap > 0.2em
+			want: []string{"Hello", "world"},
+		},
+		{
+			name: "kerning is not a gap",
+			glyphs: append(glyphRun("Hel", 72, 700, 12, 6),
+				glyphRun("lo", 72+3*6+1, 700, 12, 6)...), // 1pt gap < 0.2em
+			want: []string{"Hello"},
+		},
+		{
+			name: "new line ends a word",
+			glyphs: append(glyphRun("end", 72, 700, 12, 6),
+				glyphRun("next", 72, 684, 12, 6)...),
+			want: []string{"end", "next"},
+		},
+		{
+			name:   "fonts without widths rely on spaces",
+			glyphs: glyphRun("one two", 50, 700, 11, 0),
+			want:   []string{"one", "two"},
+		},
+		{
+			name: "multi-character fragments",
+			glyphs: []pdf.Text{
+				{FontSize: 12, X: 72, Y: 700, W: 60, S: "Hello world "},
+				{FontSize: 12, X: 132, Y: 700, W: 30, S: "again"},
+			},
+			want: []string{"Hello", "world", "again"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := elementTexts(glyphElements(tt.glyphs))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("glyphElements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlyphElements_Geometry(t *testing.T) {
+	elements := glyphElements(glyphRun("ab cd", 72, 700, 10, 5))
+	if len(elements) != 2 {
+		t.Fatalf("got %d elements, want 2", len(elements))
+	}
+	want := TextElement{Text: "cd", X: 87, Y: 700, FontSize: 10, Font: "Helvetica", Width: 10}
+	if elements[1] != want {
+		t.Errorf("elements[1] = %+v, want %+v", elements[1], want)
+	}
+}
+
+func TestExtractText_Modes(t *testing.T) {
+	glyphs := append(glyphRun("Title", 72, 740, 16, 8),
+		glyphRun("first line", 72, 700, 12, 6)...)
+	glyphs = append(glyphs, glyphRun("second line", 72, 686, 12, 6)...)
+
+	if got, want := extractText(glyphs, ExtractLayout, NewLayoutAnalyzer()), "Title\n\nfirst line\nsecond line"; got != want {
+		t.Errorf("layout extraction = %q, want %q", got, want)
+	}
+	if got, want := extractText(glyphs, ExtractRaw, NewLayoutAnalyzer()), "Titlefirst linesecond line"; got != want {
+		t.Errorf("raw extraction = %q, want %q", got, want)
+	}
+}
+
+func TestParseExtractionMode(t *testing.T) {
+	for _, mode := range []ExtractionMode{ExtractLayout, ExtractRaw} {
+		got, err := ParseExtractionMode(strings.ToUpper(mode.String()))
+		if err != nil || got != mode {
+			t.Errorf("ParseExtractionMode(%q) = %v, %v; want %v", mode, got, err, mode)
+		}
+	}
+	if _, err := ParseExtractionMode("columns"); err == nil {
+		t.Error("ParseExtractionMode() should reject unknown modes")
+	}
+}
+
+func TestGetPage_LayoutExtraction(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"First line of text", "Second line"}, "")
+	path := b.write(t)
+
+	doc, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	page, err := doc.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage() unexpected error: %v", err)
+	}
+	if want := "First line of text\nSecond line"; page.Text != want {
+		t.Errorf("layout text = %q, want %q", page.Text, want)
+	}
+	if page.LineCount != 2 || page.WordCount != 6 {
+		t.Errorf("LineCount, WordCount = %d, %d; want 2, 6", page.LineCount, page.WordCount)
+	}
+
+	raw, err := OpenDocument(path, DocumentOptions{Extraction: ExtractRaw})
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer raw.Close()
+
+	page, err = raw.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage() unexpected error: %v", err)
+	}
+	if want := "First line of textSecond line"; page.Text != want {
+		t.Errorf("raw text = %q, want %q", page.Text, want)
+	}
+}
+
+// TestGetPage_KernedZeroWidthGlyphs reads a heading set in a font without
+// glyph widths and spaced by TJ kerning, which the reader reports as glyphs
+// that barely move, or move back
+func TestGetPage_KernedZeroWidthGlyphs(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPageDrawn(nil, "BT /F2 18 Tf 72 700 Td [(1.1 ) -1250 (WHA) 74 (T) 17 ( W) 112 (AS) 166 ( ACCOMPLISHED)] TJ ET\n"+
+		"BT /F1 14 Tf 72 670 Td [(Part 1: Corr) 14 (ected Mathematics)] TJ ET\n", "")
+	path := b.write(t)
+
+	doc, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	page, err := doc.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage() unexpected error: %v", err)
+	}
+	if want := "1.1 WHAT WAS ACCOMPLISHED\nPart 1: Corrected Mathematics"; page.Text != want {
+		t.Errorf("layout text = %q, want %q", page.Text, want)
+	}
+}
@@ -0,0 +1,297 @@
+package pdf
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Heading detection
+const (
+	maxHeadingWords  = 15 // longer lines are body text
+	maxHeadingLevel  = 6  // heading styles beyond this many share the last level
+	bodySampledPages = 5  // pages sampled for the body text size before a scan
+)
+
+// headingStyle is the look of a heading
+type headingStyle struct {
+	size float64 // font size, rounded to half a point
+	bold bool
+}
+
+// heading is a line of a page set as a heading
+type heading struct {
+	title    string
+	page     int
+	style    headingStyle
+	numbered int // depth of its section number ("2" is 1, "2.1" is 2), 0 if it has none
+}
+
+// roundSize rounds a font size to half a point, so that sizes differing
+// only by rounding in the PDF compare equal
+func roundSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// isBold reports whether a font name is of a bold weight
+func isBold(font string) bool {
+	font = strings.ToLower(font)
+	for _, weight := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		if strings.Contains(font, weight) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodySizes tallies the characters set in each font size, to find the size
+// of body text
+type bodySizes map[float64]int
+
+// add counts the characters of lines
+func (b bodySizes) add(lines []textLine) {
+	for _, line := range lines {
+		for _, e := range line.elems {
+			if e.FontSize > 0 {
+				b[roundSize(e.FontSize)] += utf8.RuneCountInString(e.Text)
+			}
+		}
+	}
+}
+
+// size returns the size most characters are set in, the smaller on a tie,
+// or 0 if nothing was counted
+func (b bodySizes) size() float64 {
+	best, most := 0.0, 0
+	for size, n := range b {
+		if n > most || (n == most && size < best) {
+			best, most = size, n
+		}
+	}
+	return best
+}
+
+// lineStyle returns the style of a line: its largest size, and bold if
+// every word is
+func lineStyle(line textLine) headingStyle {
+	style := headingStyle{bold: len(line.elems) > 0}
+	for _, e := range line.elems {
+		style.size = math.Max(style.size, roundSize(e.FontSize))
+		style.bold = style.bold && isBold(e.Font)
+	}
+	return style
+}
+
+// pageHeadings finds the headings among the lines of a page. A heading is a
+// short line that DetectHeadings picks out entirely, given the document's
+// body text size, or a line numbered like a subsection ("2.1 Scope").
+// Running headers and footers are skipped, and a heading set over
+// consecutive lines in the same style is joined into one.
+func (d *Document) pageHeadings(pageNum int, lines []textLine, body float64) []heading {
+	la := *d.layout
+	la.BodyFontSize = body
+
+	var elements []TextElement
+	for _, line := range lines {
+		elements = append(elements, line.elems...)
+	}
+	flagged := make([]bool, len(elements))
+	for _, i := range la.DetectHeadings(elements) {
+		flagged[i] = true
+	}
+
+	detector := NewRegexHeadingDetector()
+	var headings []heading
+	first, last := 0, -1 // elements of the line; index of the last heading's line
+	for i, line := range lines {
+		styled := len(line.elems) > 0
+		for j := range line.elems {
+			styled = styled && flagged[first+j]
+		}
+		first += len(line.elems)
+
+		text := strings.TrimSpace(line.text)
+		numbered := detector.NumberingLevel(text)
+		if !styled && numbered < 2 {
+			continue
+		}
+		if len(strings.Fields(text)) > maxHeadingWords || !strings.ContainsFunc(text, unicode.IsLetter) {
+			continue
+		}
+		if edge := i == 0 || i == len(lines)-1; edge && len(lines) > 1 && d.isRunning(pageNum, text, i == 0) {
+			continue
+		}
+
+		style := lineStyle(line)
+		if n := len(headings); n > 0 && last == i-1 && headings[n-1].style == style && numbered == 0 && !line.paragraph {
+			headings[n-1].title += " " + text
+		} else {
+			headings = append(headings, heading{title: text, page: pageNum, style: style, numbered: numbered})
+		}
+		last = i
+	}
+	return headings
+}
+
+// headingEntries turns headings into flat TOC entries. Levels follow the
+// heading styles, largest first and bold before regular at the same size,
+// except that numbered headings take the depth of their number.
+func headingEntries(headings []heading) []TOCEntry {
+	var styles []headingStyle
+	seen := make(map[headingStyle]bool)
+	for _, h := range headings {
+		if !seen[h.style] {
+			seen[h.style] = true
+			styles = append(styles, h.style)
+		}
+	}
+	sort.Slice(styles, func(i, j int) bool {
+		if styles[i].size != styles[j].size {
+			return styles[i].size > styles[j].size
+		}
+		return styles[i].bold && !styles[j].bold
+	})
+	levels := make(map[headingStyle]int, len(styles))
+	for i, style := range styles {
+		levels[style] = min(i+1, maxHeadingLevel)
+	}
+
+	entries := make([]TOCEntry, len(headings))
+	for i, h := range headings {
+		level := levels[h.style]
+		if h.numbered > 0 {
+			level = min(h.numbered, maxHeadingLevel)
+		}
+		entries[i] = TOCEntry{Title: h.title, Page: h.page, Level: level, Children: []TOCEntry{}}
+	}
+	return entries
+}
+
+// nestEntries nests flat TOC entries under the nearest preceding entry of a
+// lower level
+func nestEntries(flat []TOCEntry) []TOCEntry {
+	nested := []TOCEntry{}
+	for i := 0; i < len(flat); {
+		entry := flat[i]
+		j := i + 1
+		for j < len(flat) && flat[j].Level > entry.Level {
+			j++
+		}
+		entry.Children = nestEntries(flat[i+1 : j])
+		nested = append(nested, entry)
+		i = j
+	}
+	return nested
+}
+
+// headingTOC builds a table of contents from headings
+func headingTOC(headings []heading) *TableOfContents {
+	toc := &TableOfContents{Entries: []TOCEntry{}, Source: "none"}
+	if len(headings) > 0 {
+		toc.Entries = nestEntries(headingEntries(headings))
+		toc.Source = "headings"
+	}
+	return toc
+}
+
+// scanHeadings finds the headings on every page in order, calling report
+// (if not nil) after each page with the headings found so far. The body
+// text size is first estimated from pages sampled across the document,
+// then refined as pages are scanned. It returns false if ctx was
+// cancelled. Pages that cannot be read are skipped.
+func (d *Document) scanHeadings(ctx context.Context, report func(done int, headings []heading)) ([]heading, bool) {
+	body := bodySizes{}
+	for i := 0; i < min(bodySampledPages, d.pages); i++ {
+		if pl, err := d.scanPageLines(1 + i*d.pages/bodySampledPages); err == nil {
+			body.add(pl.lines)
+		}
+	}
+
+	var headings []heading
+	for pageNum := 1; pageNum <= d.pages; pageNum++ {
+		if ctx.Err() != nil {
+			return headings, false
+		}
+		if pl, err := d.scanPageLines(pageNum); err == nil {
+			body.add(pl.lines)
+			headings = append(headings, d.pageHeadings(pageNum, pl.lines, body.size())...)
+		}
+		if report != nil {
+			report(pageNum, headings)
+		}
+	}
+	return headings, true
+}
+
+// HeadingProgress reports the state of a heading scan
+type HeadingProgress struct {
+	TOC       *TableOfContents // headings found so far, nested by level
+	Done      int              // pages scanned
+	Total     int              // pages in the document
+	Finished  bool             // no more progress will be reported
+	Cancelled bool             // stopped before every page was scanned
+}
+
+// HeadingScanner builds a table of contents from the headings on every
+// page in the background, for documents without an outline
+type HeadingScanner struct {
+	doc *Document
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewHeadingScanner creates a heading scanner for a document
+func NewHeadingScanner(doc *Document) *HeadingScanner {
+	return &HeadingScanner{doc: doc}
+}
+
+// Start scans the document from the first page, cancelling any scan
+// already running. The table of contents found so far is reported after
+// every page on the returned channel, which keeps only the latest update
+// and is closed when the scan ends.
+func (hs *HeadingScanner) Start() <-chan HeadingProgress {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	hs.mu.Lock()
+	if hs.cancel != nil {
+		hs.cancel()
+	}
+	hs.cancel = cancel
+	hs.mu.Unlock()
+
+	progress := newLatest[HeadingProgress]()
+	go hs.run(ctx, cancel, progress)
+	return progress.ch
+}
+
+// Cancel stops the running scan, if any
+func (hs *HeadingScanner) Cancel() {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.cancel != nil {
+		hs.cancel()
+		hs.cancel = nil
+	}
+}
+
+func (hs *HeadingScanner) run(ctx context.Context, cancel context.CancelFunc, progress *latest[HeadingProgress]) {
+	defer progress.close()
+	defer cancel()
+
+	total, scanned := hs.doc.pages, 0
+	headings, ok := hs.doc.scanHeadings(ctx, func(done int, headings []heading) {
+		scanned = done
+		progress.send(HeadingProgress{TOC: headingTOC(headings), Done: done, Total: total})
+	})
+	if !ok {
+		progress.send(HeadingProgress{TOC: headingTOC(headings), Done: scanned, Total: total, Finished: true, Cancelled: true})
+		return
+	}
+	progress.send(HeadingProgress{TOC: headingTOC(headings), Done: total, Total: total, Finished: true})
+}
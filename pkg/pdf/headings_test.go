@@ -0,0 +1,163 @@
+package pdf
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// manualPages lays out a short manual: a running header on every page,
+// headings in several styles and numbering, body text and page numbers
+func manualPages() [][]placedText {
+	body := func(y int) []placedText {
+		var texts []placedText
+		for i := 0; i < 5; i++ {
+			texts = append(texts, placedText{x: 72, y: y - 14*i, size: 11, text: fmt.Sprintf("Body text line %d of the widget manual.", i+1)})
+		}
+		return texts
+	}
+	page := func(n int, texts ...[]placedText) []placedText {
+		all := []placedText{{x: 72, y: 770, size: 14, text: "Widget Manual"}}
+		for _, t := range texts {
+			all = append(all, t...)
+		}
+		return append(all, placedText{x: 300, y: 40, size: 11, text: fmt.Sprint(n)})
+	}
+	heading := func(y, size int, bold bool, text string) []placedText {
+		return []placedText{{x: 72, y: y, size: size, text: text, bold: bold}}
+	}
+
+	return [][]placedText{
+		page(1, heading(720, 18, false, "1 Introduction"), body(690)),
+		page(2, heading(720, 14, true, "1.1 Scope"), body(690), heading(600, 11, false, "1.2 Terms of use"), body(580)),
+		page(3, heading(720, 18, false, "2 Installation"), body(690), heading(600, 11, true, "Requirements"), body(580)),
+		page(4, body(720)),
+	}
+}
+
+// tocTitles lists the titles of a TOC, indented by nesting
+func tocTitles(entries []TOCEntry, indent string) []string {
+	var titles []string
+	for _, e := range entries {
+		titles = append(titles, fmt.Sprintf("%s%s (p%d)", indent, e.Title, e.Page))
+		titles = append(titles, tocTitles(e.Children, indent+"  ")...)
+	}
+	return titles
+}
+
+func openManual(t *testing.T) *Document {
+	t.Helper()
+	b := newTestPDFBuilder()
+	for _, texts := range manualPages() {
+		b.addPageAt(texts, "")
+	}
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { doc.Close() })
+	return doc
+}
+
+var manualTOC = []string{
+	"1 Introduction (p1)",
+	"  1.1 Scope (p2)",
+	"  1.2 Terms of use (p2)",
+	"2 Installation (p3)",
+	"  Requirements (p3)",
+}
+
+func TestExtractTableOfContents_Headings(t *testing.T) {
+	doc := openManual(t)
+
+	toc, err := doc.ExtractTableOfContents()
+	if err != nil {
+		t.Fatalf("ExtractTableOfContents() unexpected error: %v", err)
+	}
+	if toc.Source != "headings" {
+		t.Errorf("Source = %q, want headings", toc.Source)
+	}
+	if got := tocTitles(toc.Entries, ""); !reflect.DeepEqual(got, manualTOC) {
+		t.Errorf("TOC = %q, want %q", got, manualTOC)
+	}
+	if level := toc.Entries[1].Children[0].Level; level != 3 {
+		t.Errorf("bold body-size heading has level %d, want 3 (after two larger styles)", level)
+	}
+}
+
+func TestFindHeadings(t *testing.T) {
+	doc := openManual(t)
+
+	got := doc.FindHeadings(2)
+	if len(got) != 2 || got[0].Title != "1.1 Scope" || got[1].Title != "1.2 Terms of use" || got[1].Level != 2 {
+		t.Errorf("FindHeadings(2) = %+v, want the two numbered subsections", got)
+	}
+	if got := doc.FindHeadings(4); len(got) != 0 {
+		t.Errorf("FindHeadings(4) = %+v, want none on a page of body text", got)
+	}
+}
+
+func TestHeadingScanner(t *testing.T) {
+	doc := openManual(t)
+
+	var last HeadingProgress
+	done := 0
+	for p := range NewHeadingScanner(doc).Start() {
+		if p.Done < done {
+			t.Errorf("progress went back from %d to %d pages", done, p.Done)
+		}
+		done, last = p.Done, p
+	}
+
+	if !last.Finished || last.Cancelled || last.Done != 4 || last.Total != 4 {
+		t.Fatalf("final progress = %+v", last)
+	}
+	if got := tocTitles(last.TOC.Entries, ""); !reflect.DeepEqual(got, manualTOC) {
+		t.Errorf("TOC = %q, want %q", got, manualTOC)
+	}
+}
+
+func TestHeadingScanner_Cancel(t *testing.T) {
+	doc := openManual(t)
+
+	scanner := NewHeadingScanner(doc)
+	ch := scanner.Start()
+	scanner.Cancel()
+
+	var last HeadingProgress
+	for p := range ch {
+		last = p
+	}
+	if !last.Finished {
+		t.Errorf("final progress = %+v, want finished", last)
+	}
+}
+
+func TestNumberingLevel(t *testing.T) {
+	detector := NewRegexHeadingDetector()
+	tests := map[string]int{
+		"Chapter 3":           1,
+		"Appendix B":          1,
+		"3 Results":           1,
+		"2.1. Scope":          2,
+		"4.2.7 Timing":        3,
+		"A.1 Register map":    2,
+		"A Study of Columns":  0,
+		"Introduction":        0,
+		"3.14":                0,
+		"chapter IV Overview": 1,
+	}
+	for text, want := range tests {
+		if got := detector.NumberingLevel(text); got != want {
+			t.Errorf("NumberingLevel(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestNestEntries(t *testing.T) {
+	flat := []TOCEntry{{Title: "a", Level: 2}, {Title: "b", Level: 1}, {Title: "c", Level: 3}, {Title: "d", Level: 2}, {Title: "e", Level: 1}}
+	want := []string{"a (p0)", "b (p0)", "  c (p0)", "  d (p0)", "e (p0)"}
+	if got := tocTitles(nestEntries(flat), ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("nestEntries() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,531 @@
+package pdf
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// indexFormatVersion is bumped whenever tokenization, text extraction or
+// the on-disk layout changes, so that stale persisted indexes are rebuilt
+const indexFormatVersion = 3
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// ErrIndexNotReady is returned by ranked searches before the document's
+// index has been built or loaded
+var ErrIndexNotReady = errors.New("search index is not ready yet")
+
+// Index is an inverted index of a document's text: for every term, the
+// pages it occurs on and where. Terms are lowercased runs of letters and
+// digits. An Index is immutable once built and safe for concurrent use.
+type Index struct {
+	Version  int
+	Hash     string // content hash of the indexed file
+	Path     string // file the index was built from
+	PageLens []int  // number of terms on each page, by page number - 1
+	Postings map[string][]Posting
+
+	vocab  []string // sorted terms, for prefix and substring lookups
+	avgLen float64  // average page length in terms
+}
+
+// Posting lists the occurrences of a term on one page
+type Posting struct {
+	Page      int
+	Positions []int // term positions on the page, in order
+	Starts    []int // byte offset of each occurrence in the page text
+	Ends      []int
+}
+
+// IndexHit is a page matching an index query
+type IndexHit struct {
+	Page    int
+	Score   float64      // BM25 relevance
+	Matches []IndexMatch // in page order
+}
+
+// IndexMatch is the byte range of a query match in a page's text
+type IndexMatch struct {
+	Start, End int
+}
+
+// token is a term and its byte range in the text it was read from
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lowercased runs of letters and digits
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// newIndex creates an empty index for a document with the given pages
+func newIndex(hash, path string, pages int) *Index {
+	return &Index{
+		Version:  indexFormatVersion,
+		Hash:     hash,
+		Path:     path,
+		PageLens: make([]int, pages),
+		Postings: make(map[string][]Posting),
+	}
+}
+
+// addPage indexes the text of a page. Pages must be added in order.
+func (idx *Index) addPage(page int, text string) {
+	tokens := tokenize(text)
+	idx.PageLens[page-1] = len(tokens)
+	for pos, tok := range tokens {
+		list := idx.Postings[tok.term]
+		if n := len(list); n == 0 || list[n-1].Page != page {
+			list = append(list, Posting{Page: page})
+		}
+		p := &list[len(list)-1]
+		p.Positions = append(p.Positions, pos)
+		p.Starts = append(p.Starts, tok.start)
+		p.Ends = append(p.Ends, tok.end)
+		idx.Postings[tok.term] = list
+	}
+}
+
+// finish prepares the lookup tables of a built or loaded index
+func (idx *Index) finish() {
+	idx.vocab = make([]string, 0, len(idx.Postings))
+	for term := range idx.Postings {
+		idx.vocab = append(idx.vocab, term)
+	}
+	sort.Strings(idx.vocab)
+
+	total := 0
+	for _, n := range idx.PageLens {
+		total += n
+	}
+	if len(idx.PageLens) > 0 {
+		idx.avgLen = float64(total) / float64(len(idx.PageLens))
+	}
+}
+
+// Pages returns the number of pages in the index
+func (idx *Index) Pages() int {
+	return len(idx.PageLens)
+}
+
+// Terms returns the number of distinct terms in the index
+func (idx *Index) Terms() int {
+	return len(idx.vocab)
+}
+
+// queryClause is a run of consecutive terms that must all match: a single
+// word or a quoted phrase
+type queryClause struct {
+	terms  []string
+	prefix bool // the last term matches any term it is a prefix of
+}
+
+// parseIndexQuery splits a query into clauses. Words are matched as
+// terms, "quoted text" as a phrase, and a trailing * makes the last term a
+// prefix. A word containing punctuation, like e-mail, is a phrase.
+func parseIndexQuery(query string) []queryClause {
+	var clauses []queryClause
+	add := func(text string, prefix bool) {
+		var terms []string
+		for _, tok := range tokenize(text) {
+			terms = append(terms, tok.term)
+		}
+		if len(terms) > 0 {
+			clauses = append(clauses, queryClause{terms: terms, prefix: prefix})
+		}
+	}
+
+	for rest := strings.TrimSpace(query); rest != ""; rest = strings.TrimSpace(rest) {
+		if rest[0] == '"' {
+			phrase, after, _ := strings.Cut(rest[1:], `"`)
+			prefix := strings.HasSuffix(phrase, "*") || strings.HasPrefix(after, "*")
+			add(phrase, prefix)
+			rest = strings.TrimPrefix(after, "*")
+			continue
+		}
+		word := rest
+		if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+			word, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+		add(word, strings.HasSuffix(word, "*"))
+	}
+	return clauses
+}
+
+// expand returns the indexed terms a query term stands for
+func (idx *Index) expand(term string, prefix bool) []string {
+	if !prefix {
+		if _, ok := idx.Postings[term]; ok {
+			return []string{term}
+		}
+		return nil
+	}
+	i := sort.SearchStrings(idx.vocab, term)
+	j := i
+	for j < len(idx.vocab) && strings.HasPrefix(idx.vocab[j], term) {
+		j++
+	}
+	return idx.vocab[i:j]
+}
+
+// occurrences maps page to term position to byte range
+type occurrences map[int]map[int]IndexMatch
+
+// collect gathers every occurrence of the given terms
+func (idx *Index) collect(terms []string) occurrences {
+	occ := make(occurrences)
+	for _, term := range terms {
+		for _, p := range idx.Postings[term] {
+			positions := occ[p.Page]
+			if positions == nil {
+				positions = make(map[int]IndexMatch, len(p.Positions))
+				occ[p.Page] = positions
+			}
+			for i, pos := range p.Positions {
+				positions[pos] = IndexMatch{Start: p.Starts[i], End: p.Ends[i]}
+			}
+		}
+	}
+	return occ
+}
+
+// matchClause returns the matches of a clause on every page it occurs on
+func (idx *Index) matchClause(c queryClause) map[int][]IndexMatch {
+	slots := make([]occurrences, len(c.terms))
+	for i, term := range c.terms {
+		slots[i] = idx.collect(idx.expand(term, c.prefix && i == len(c.terms)-1))
+		if len(slots[i]) == 0 {
+			return nil
+		}
+	}
+
+	matches := make(map[int][]IndexMatch)
+	for page, first := range slots[0] {
+	positions:
+		for pos, start := range first {
+			end := start
+			for i := 1; i < len(slots); i++ {
+				next, ok := slots[i][page][pos+i]
+				if !ok {
+					continue positions
+				}
+				end = next
+			}
+			matches[page] = append(matches[page], IndexMatch{Start: start.Start, End: end.End})
+		}
+	}
+	return matches
+}
+
+// bm25 scores a term (or phrase) that occurs tf times on a page of
+// pageLen terms and on df pages of the document
+func (idx *Index) bm25(tf, df, pageLen int) float64 {
+	n := float64(len(idx.PageLens))
+	idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+	norm := 1.0
+	if idx.avgLen > 0 {
+		norm = 1 - bm25B + bm25B*float64(pageLen)/idx.avgLen
+	}
+	return idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+}
+
+// Search finds the pages matching every word, "phrase" and prefix* of the
+// query, ranked by BM25 with the best page first
+func (idx *Index) Search(query string) []IndexHit {
+	clauses := parseIndexQuery(query)
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	perClause := make([]map[int][]IndexMatch, len(clauses))
+	for i, c := range clauses {
+		perClause[i] = idx.matchClause(c)
+		if len(perClause[i]) == 0 {
+			return nil
+		}
+	}
+
+	var hits []IndexHit
+pages:
+	for page := range perClause[0] {
+		hit := IndexHit{Page: page}
+		for _, matches := range perClause {
+			pageMatches, ok := matches[page]
+			if !ok {
+				continue pages
+			}
+			hit.Score += idx.bm25(len(pageMatches), len(matches), idx.PageLens[page-1])
+			hit.Matches = append(hit.Matches, pageMatches...)
+		}
+		hit.Matches = sortMatches(hit.Matches)
+		hits = append(hits, hit)
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Page < hits[j].Page
+	})
+	return hits
+}
+
+// sortMatches orders matches by position and drops any that overlap an
+// earlier one
+func sortMatches(matches []IndexMatch) []IndexMatch {
+	slices.SortFunc(matches, func(a, b IndexMatch) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+	out := matches[:0]
+	for _, m := range matches {
+		if len(out) > 0 && m.Start < out[len(out)-1].End {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// substringScores narrows a plain (non-regex) search for query: a page can
+// only contain the query if, for every term of the query, it has a term
+// containing it. It returns the BM25 score of every such page, or false if
+// the query has no terms to narrow by.
+func (idx *Index) substringScores(query string) (map[int]float64, bool) {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+
+	var scores map[int]float64
+	for _, tok := range tokens {
+		var terms []string
+		for _, term := range idx.vocab {
+			if strings.Contains(term, tok.term) {
+				terms = append(terms, term)
+			}
+		}
+
+		tf := make(map[int]int)
+		for _, term := range terms {
+			for _, p := range idx.Postings[term] {
+				tf[p.Page] += len(p.Positions)
+			}
+		}
+
+		next := make(map[int]float64, len(tf))
+		for page, n := range tf {
+			if scores != nil {
+				if _, ok := scores[page]; !ok {
+					continue
+				}
+			}
+			next[page] = scores[page] + idx.bm25(n, len(tf), idx.PageLens[page-1])
+		}
+		scores = next
+	}
+	return scores, true
+}
+
+// indexPath returns where the index of the file with the given content
+// hash, extracted in the given mode, is stored
+func indexPath(dir, hash string, mode ExtractionMode) string {
+	return filepath.Join(dir, hash+"-"+mode.String()+indexExt)
+}
+
+// save writes the index to path, replacing any previous file atomically
+func (idx *Index) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*")
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	err = gob.NewEncoder(zw).Encode(idx)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// indexExt is the file name extension of persisted indexes
+const indexExt = ".idx"
+
+// pruneIndexes removes the persisted indexes in dir that have not been
+// used for longer than maxAge, then the least recently used ones while all
+// of them take more than maxBytes. The index at keep, just saved, stays.
+func pruneIndexes(dir, keep string, maxBytes int64, maxAge time.Duration) error {
+	files, err := indexFiles(dir)
+	if err != nil {
+		return fmt.Errorf("failed to prune indexes: %w", err)
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	// Oldest first
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		path := filepath.Join(dir, f.Name())
+		old := maxAge > 0 && time.Since(f.ModTime()) > maxAge
+		full := maxBytes > 0 && total > maxBytes
+		if path == keep || !old && !full {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to prune indexes: %w", err)
+		}
+		total -= f.Size()
+	}
+	return nil
+}
+
+// indexFiles lists the persisted indexes in dir, most recently used first
+func indexFiles(dir string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []os.FileInfo
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != indexExt {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+	return files, nil
+}
+
+// DocumentHits are the pages of an indexed document that match a query
+type DocumentHits struct {
+	Path string     // file the index was built from
+	Hits []IndexHit // best page first
+}
+
+// SearchIndexes searches every document with a persisted index, without
+// opening them, and returns the documents with matches, the document with
+// the best page first. A document indexed more than once, edited or in
+// both extraction modes, is searched in its most recently used index.
+func SearchIndexes(opts IndexOptions, query string) ([]DocumentHits, error) {
+	dir, err := opts.dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := indexFiles(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	var docs []DocumentHits
+	seen := make(map[string]bool)
+	for _, f := range files {
+		idx, err := readIndex(filepath.Join(dir, f.Name()))
+		if err != nil || seen[idx.Path] {
+			continue
+		}
+		seen[idx.Path] = true
+		if hits := idx.Search(query); len(hits) > 0 {
+			docs = append(docs, DocumentHits{Path: idx.Path, Hits: hits})
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Hits[0].Score > docs[j].Hits[0].Score })
+	return docs, nil
+}
+
+// loadIndex reads an index from path, rejecting indexes of another file or
+// format version
+func loadIndex(path, hash string, pages int) (*Index, error) {
+	idx, err := readIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	if idx.Hash != hash || len(idx.PageLens) != pages {
+		return nil, fmt.Errorf("index %s is stale", path)
+	}
+	return idx, nil
+}
+
+// readIndex reads an index from path, rejecting indexes of another format
+// version
+func readIndex(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	var idx Index
+	if err := gob.NewDecoder(zr).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	if idx.Version != indexFormatVersion {
+		return nil, fmt.Errorf("index %s is stale", path)
+	}
+	if idx.Postings == nil {
+		idx.Postings = make(map[string][]Posting)
+	}
+
+	idx.finish()
+	return &idx, nil
+}
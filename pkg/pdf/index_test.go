@@ -0,0 +1,323 @@
+package pdf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// buildTestIndex indexes the given page texts
+func buildTestIndex(pages ...string) *Index {
+	idx := newIndex("hash", "test.pdf", len(pages))
+	for i, text := range pages {
+		idx.addPage(i+1, text)
+	}
+	idx.finish()
+	return idx
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize("Hello, wörld! e-mail 42x")
+	want := []token{{"hello", 0, 5}, {"wörld", 7, 13}, {"e", 15, 16}, {"mail", 17, 21}, {"42x", 22, 25}}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokenize() = %v, want %v", tokens, want)
+	}
+}
+
+func TestParseIndexQuery(t *testing.T) {
+	got := parseIndexQuery(`cache "least recently" evict* "page fau"* e-mail !!`)
+	want := []queryClause{
+		{terms: []string{"cache"}},
+		{terms: []string{"least", "recently"}},
+		{terms: []string{"evict"}, prefix: true},
+		{terms: []string{"page", "fau"}, prefix: true},
+		{terms: []string{"e", "mail"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseIndexQuery() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := buildTestIndex(
+		"The cache evicts the least recently used page.",
+		"Page faults are handled by the kernel. A page fault is costly.",
+		"Least frequently used caches; recently added pages.",
+		"Nothing to see here.",
+	)
+
+	tests := []struct {
+		name    string
+		query   string
+		pages   []int
+		matches []string // text of the first hit's matches
+	}{
+		{"term", "kernel", []int{2}, []string{"kernel"}},
+		{"all terms must match", "cache least", []int{1}, []string{"cache", "least"}},
+		{"phrase", `"least recently"`, []int{1}, []string{"least recently"}},
+		{"prefix", "evict*", []int{1}, []string{"evicts"}},
+		{"phrase with prefix", `"page fault"*`, []int{2}, []string{"Page faults", "page fault"}},
+		// More occurrences rank higher; for equal counts the shorter page does
+		{"ranked by bm25", "page*", []int{2, 3, 1}, []string{"Page", "page"}},
+		{"no match", "missing", nil, nil},
+	}
+
+	texts := map[int]string{
+		1: "The cache evicts the least recently used page.",
+		2: "Page faults are handled by the kernel. A page fault is costly.",
+		3: "Least frequently used caches; recently added pages.",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := idx.Search(tt.query)
+			var pages []int
+			for _, hit := range hits {
+				pages = append(pages, hit.Page)
+			}
+			if !reflect.DeepEqual(pages, tt.pages) {
+				t.Fatalf("Search(%q) pages = %v, want %v", tt.query, pages, tt.pages)
+			}
+			if len(hits) == 0 {
+				return
+			}
+			var matches []string
+			for _, m := range hits[0].Matches {
+				matches = append(matches, texts[hits[0].Page][m.Start:m.End])
+			}
+			if !reflect.DeepEqual(matches, tt.matches) {
+				t.Errorf("Search(%q) matches = %q, want %q", tt.query, matches, tt.matches)
+			}
+		})
+	}
+}
+
+func TestIndex_SubstringScores(t *testing.T) {
+	idx := buildTestIndex("retest the parser", "testing, testing", "no match here")
+
+	scores, ok := idx.substringScores("TEST")
+	if !ok || len(scores) != 2 || scores[2] <= scores[1] {
+		t.Errorf("substringScores(TEST) = %v, want pages 1 and 2, page 2 higher", scores)
+	}
+	if scores, _ := idx.substringScores("test match"); len(scores) != 0 {
+		t.Errorf("substringScores(test match) = %v, want no pages", scores)
+	}
+	if _, ok := idx.substringScores("!?"); ok {
+		t.Error("a query without terms should not narrow the search")
+	}
+}
+
+func TestIndexer_BuildsAndPersists(t *testing.T) {
+	path := buildPrefetchPDF(t, 5)
+	opts := IndexOptions{Dir: t.TempDir(), Workers: 2}
+
+	doc, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	if _, err := doc.AdvancedSearch("page", SearchOptions{Ranked: true}); err != ErrIndexNotReady {
+		t.Errorf("ranked search before indexing: error = %v, want ErrIndexNotReady", err)
+	}
+
+	final := drainIndex(NewIndexer(doc, opts).Start())
+	if !final.Finished || final.Cancelled || final.Loaded || final.Err != nil {
+		t.Fatalf("final progress = %+v, want built and saved", final)
+	}
+	if doc.Index() == nil || doc.Index().Pages() != 5 {
+		t.Fatal("index should be attached to the document")
+	}
+	hash, _ := doc.ContentHash()
+	if _, err := os.Stat(indexPath(opts.Dir, hash, doc.Extraction())); err != nil {
+		t.Errorf("index not persisted: %v", err)
+	}
+
+	// Reopening loads the persisted index
+	doc2, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc2.Close()
+	final = drainIndex(NewIndexer(doc2, opts).Start())
+	if !final.Loaded || doc2.Index() == nil || doc2.Index().Terms() != doc.Index().Terms() {
+		t.Errorf("final progress = %+v, want the index loaded from disk", final)
+	}
+
+	// Text extracted in another mode is indexed separately
+	doc3, err := OpenDocument(path, DocumentOptions{Extraction: ExtractRaw})
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc3.Close()
+	if final = drainIndex(NewIndexer(doc3, opts).Start()); final.Loaded {
+		t.Error("an index of layout-extracted text should not be loaded for raw extraction")
+	}
+
+	// A persisted index for other contents is not used
+	if _, err := loadIndex(indexPath(opts.Dir, hash, doc.Extraction()), "other", 5); err == nil {
+		t.Error("loadIndex() should reject an index of another file")
+	}
+}
+
+func TestIndexer_KeepsEncryptedInMemory(t *testing.T) {
+	opts := IndexOptions{Dir: t.TempDir(), Workers: 1}
+	doc, err := OpenDocument(writeEncrypted(t, &testEncryption{owner: "owner", revision: 4}), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	final := drainIndex(NewIndexer(doc, opts).Start())
+	if !final.Finished || final.Cancelled || final.Err != nil {
+		t.Fatalf("final progress = %+v, want built", final)
+	}
+	if results, err := doc.AdvancedSearch("encrypted", SearchOptions{Ranked: true}); err != nil || len(results) == 0 {
+		t.Errorf("ranked search = %v, %v; want a hit from the in-memory index", results, err)
+	}
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		t.Fatalf("ReadDir() unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("index directory holds %d entries, want none for an encrypted document", len(entries))
+	}
+	if hits, err := SearchIndexes(opts, "encrypted"); err != nil || len(hits) != 0 {
+		t.Errorf("SearchIndexes() = %v, %v; want no hits", hits, err)
+	}
+}
+
+func TestSearch_UsesIndex(t *testing.T) {
+	doc, err := OpenDocument(buildPrefetchPDF(t, 12), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	linear, err := doc.AdvancedSearch("page 1", SearchOptions{})
+	if err != nil {
+		t.Fatalf("AdvancedSearch() unexpected error: %v", err)
+	}
+
+	drainIndex(NewIndexer(doc, IndexOptions{Dir: t.TempDir()}).Start())
+	indexed, err := doc.AdvancedSearch("page 1", SearchOptions{})
+	if err != nil {
+		t.Fatalf("AdvancedSearch() unexpected error: %v", err)
+	}
+	if len(indexed) != len(linear) || len(indexed) != 3 { // "Page 10" to "Page 12"
+		t.Fatalf("indexed search found %d results, linear %d, want 3", len(indexed), len(linear))
+	}
+	for i := range indexed {
+		if indexed[i].PageNum != linear[i].PageNum || indexed[i].Start != linear[i].Start {
+			t.Errorf("result %d differs: indexed %+v, linear %+v", i, indexed[i].SearchResult, linear[i].SearchResult)
+		}
+		if indexed[i].Relevance <= 0 || indexed[i].Relevance > 1 {
+			t.Errorf("result %d relevance = %v, want in (0, 1]", i, indexed[i].Relevance)
+		}
+	}
+
+	ch, err := doc.SearchStream(context.Background(), `"page 07"`, SearchOptions{Ranked: true})
+	if err != nil {
+		t.Fatalf("SearchStream() unexpected error: %v", err)
+	}
+	var ranked []SearchResultAdvanced
+	for update := range ch {
+		ranked = append(ranked, update.Results...)
+	}
+	if len(ranked) != 1 || ranked[0].PageNum != 7 || ranked[0].MatchText != "Page 07" {
+		t.Errorf("ranked search = %+v, want one match on page 7", ranked)
+	}
+}
+
+func TestPruneIndexes(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("new-layout.idx", 100, 0)
+	write("recent-layout.idx", 100, time.Hour)
+	write("older-layout.idx", 100, 2*time.Hour)
+	write("oldest-layout.idx", 100, 3*time.Hour)
+	write("stale-raw.idx", 10, 100*24*time.Hour)
+	write("notes.txt", 1000, 100*24*time.Hour)
+
+	if err := pruneIndexes(dir, filepath.Join(dir, "new-layout.idx"), 250, 90*24*time.Hour); err != nil {
+		t.Fatalf("pruneIndexes() unexpected error: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	if want := []string{"new-layout.idx", "notes.txt", "recent-layout.idx"}; !reflect.DeepEqual(left, want) {
+		t.Errorf("files left = %q, want %q", left, want)
+	}
+
+	// The index just saved stays even if it is over the limit
+	if err := pruneIndexes(dir, filepath.Join(dir, "new-layout.idx"), 1, 0); err != nil {
+		t.Fatalf("pruneIndexes() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new-layout.idx")); err != nil {
+		t.Errorf("the kept index was removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "recent-layout.idx")); !os.IsNotExist(err) {
+		t.Errorf("recent-layout.idx not pruned to the size limit")
+	}
+}
+
+func TestSearchIndexes(t *testing.T) {
+	opts := IndexOptions{Dir: t.TempDir()}
+	var paths []string
+	for _, lines := range [][]string{
+		{"Comonads and streams", "A stream comonad extends a stream"},
+		{"Monads only", "Nothing about the other kind"},
+		{"Store comonad"},
+	} {
+		b := newTestPDFBuilder()
+		b.addPage(lines, "")
+		b.addPage([]string{"Appendix"}, "")
+		paths = append(paths, b.write(t))
+
+		doc, err := OpenDocument(paths[len(paths)-1], DefaultDocumentOptions())
+		if err != nil {
+			t.Fatalf("OpenDocument() unexpected error: %v", err)
+		}
+		drainIndex(NewIndexer(doc, opts).Start())
+		doc.Close()
+	}
+
+	docs, err := SearchIndexes(opts, "comonad")
+	if err != nil {
+		t.Fatalf("SearchIndexes() unexpected error: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Path != paths[2] || docs[1].Path != paths[0] {
+		t.Fatalf("SearchIndexes() = %+v, want the third then the first document", docs)
+	}
+	if hits := docs[1].Hits; len(hits) != 1 || hits[0].Page != 1 {
+		t.Errorf("hits in the first document = %+v, want page 1", hits)
+	}
+
+	docs, err = SearchIndexes(IndexOptions{Dir: filepath.Join(opts.Dir, "none")}, "comonad")
+	if err != nil || len(docs) != 0 {
+		t.Errorf("SearchIndexes() without indexes = %+v, %v; want none", docs, err)
+	}
+}
+
+// drainIndex waits for an indexing job to finish and returns its final progress
+func drainIndex(ch <-chan IndexProgress) IndexProgress {
+	var last IndexProgress
+	for p := range ch {
+		last = p
+	}
+	return last
+}
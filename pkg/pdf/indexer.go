@@ -0,0 +1,201 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IndexOptions configures background indexing
+type IndexOptions struct {
+	Dir     string // where indexes are persisted ("" = <user cache dir>/lumos/index)
+	Workers int    // concurrent page extractions while building
+
+	// Persisted indexes are pruned when one is saved: the least recently
+	// used go first while they take more than MaxBytes, and any unused for
+	// longer than MaxAge go too (0 = no limit)
+	MaxBytes int64
+	MaxAge   time.Duration
+}
+
+// DefaultIndexOptions returns sensible defaults for indexing
+func DefaultIndexOptions() IndexOptions {
+	return IndexOptions{
+		Workers:  min(runtime.NumCPU(), 4),
+		MaxBytes: 256 << 20,
+		MaxAge:   90 * 24 * time.Hour,
+	}
+}
+
+// dir returns the directory indexes are persisted in
+func (o IndexOptions) dir() (string, error) {
+	if o.Dir != "" {
+		return o.Dir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no cache directory for the search index: %w", err)
+	}
+	return filepath.Join(cacheDir, "lumos", "index"), nil
+}
+
+// IndexProgress reports the state of an indexing job
+type IndexProgress struct {
+	Done      int   // pages indexed
+	Total     int   // pages in the document
+	Loaded    bool  // the index was read from disk rather than built
+	Finished  bool  // no more progress will be reported
+	Cancelled bool  // stopped before the index was ready
+	Err       error // the index could not be persisted; it is still usable
+}
+
+// Indexer builds a Document's full-text index in the background. An index
+// persisted by an earlier run for the same file contents is loaded instead
+// of being rebuilt; a freshly built one is persisted for next time. The
+// index of an encrypted document is never persisted.
+type Indexer struct {
+	doc  *Document
+	opts IndexOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewIndexer creates an indexer for a document
+func NewIndexer(doc *Document, opts IndexOptions) *Indexer {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+
+	return &Indexer{
+		doc:  doc,
+		opts: opts,
+	}
+}
+
+// Start loads or builds the index, cancelling any job already running.
+// Once the index is ready it is attached to the document, where searches
+// pick it up. Progress is reported on the returned channel, which keeps
+// only the latest update and is closed when the job ends.
+func (ix *Indexer) Start() <-chan IndexProgress {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ix.mu.Lock()
+	if ix.cancel != nil {
+		ix.cancel()
+	}
+	ix.cancel = cancel
+	ix.mu.Unlock()
+
+	progress := newLatest[IndexProgress]()
+	go ix.run(ctx, cancel, progress)
+	return progress.ch
+}
+
+// Cancel stops the running job, if any
+func (ix *Indexer) Cancel() {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.cancel != nil {
+		ix.cancel()
+		ix.cancel = nil
+	}
+}
+
+func (ix *Indexer) run(ctx context.Context, cancel context.CancelFunc, progress *latest[IndexProgress]) {
+	defer progress.close()
+	defer cancel()
+
+	total := ix.doc.pages
+	hash, err := ix.doc.ContentHash()
+	if err != nil {
+		progress.send(IndexProgress{Total: total, Finished: true, Cancelled: true, Err: err})
+		return
+	}
+
+	dir, dirErr := ix.opts.dir()
+	path := indexPath(dir, hash, ix.doc.extraction)
+	// The index holds the plain text of every page, so an encrypted
+	// document keeps its index in memory and out of SearchIndexes
+	// (one left by an earlier version is removed)
+	persist := ix.doc.crypt == nil
+	if !persist {
+		if dirErr == nil {
+			os.Remove(path)
+		}
+		dirErr = nil
+	}
+	if persist && dirErr == nil {
+		if idx, err := loadIndex(path, hash, total); err == nil {
+			// The modification time tells pruning when the index was last used
+			now := time.Now()
+			os.Chtimes(path, now, now)
+			ix.doc.index.Store(idx)
+			progress.send(IndexProgress{Done: total, Total: total, Loaded: true, Finished: true})
+			return
+		}
+	}
+
+	texts, ok := ix.extract(ctx, progress)
+	if !ok {
+		progress.send(IndexProgress{Total: total, Finished: true, Cancelled: true})
+		return
+	}
+
+	idx := newIndex(hash, ix.doc.filepath, total)
+	for i, text := range texts {
+		idx.addPage(i+1, text)
+	}
+	idx.finish()
+	ix.doc.index.Store(idx)
+
+	final := IndexProgress{Done: total, Total: total, Finished: true, Err: dirErr}
+	if persist && dirErr == nil {
+		final.Err = idx.save(path)
+	}
+	if persist && final.Err == nil {
+		final.Err = pruneIndexes(dir, path, ix.opts.MaxBytes, ix.opts.MaxAge)
+	}
+	progress.send(final)
+}
+
+// extract reads the text of every page on a worker pool, reporting false
+// if the job was cancelled. Cached pages are reused, and pages read are
+// cached while there is room; pages that fail to extract are indexed as
+// empty.
+func (ix *Indexer) extract(ctx context.Context, progress *latest[IndexProgress]) ([]string, bool) {
+	total := ix.doc.pages
+	texts := make([]string, total)
+	var next, done atomic.Int64
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(ix.opts.Workers, total); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= total {
+					return
+				}
+				if text, ok := ix.doc.cache.Peek(i + 1); ok {
+					texts[i] = text
+				} else if page, err := ix.doc.extractPage(i + 1); err == nil {
+					texts[i] = page.text
+					// Kept for the heading scan if there is room
+					ix.doc.cache.putPage(i+1, page.text, &page.lines, false)
+				}
+				progress.send(IndexProgress{Done: int(done.Add(1)), Total: total})
+			}
+		}()
+	}
+	wg.Wait()
+
+	return texts, ctx.Err() == nil
+}
@@ -0,0 +1,220 @@
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Page label numbering styles (the /S entry of a page label dictionary)
+const (
+	LabelDecimal    = "D" // 1, 2, 3
+	LabelUpperRoman = "R" // I, II, III
+	LabelLowerRoman = "r" // i, ii, iii
+	LabelUpperAlpha = "A" // A to Z, then AA to ZZ
+	LabelLowerAlpha = "a" // a to z, then aa to zz
+)
+
+// Bounds on page label numbers. A broken or hostile /St would otherwise
+// make every label megabytes long: numbers past them are written in
+// decimal, and /St is capped.
+const (
+	maxRomanNumber = 3999    // MMMCMXCIX, the largest in standard roman numerals
+	maxAlphaNumber = 26 * 10 // ZZZZZZZZZZ
+	maxLabelStart  = 1 << 30
+)
+
+// labelRange numbers the pages from its first page up to the next range
+type labelRange struct {
+	first  int    // 1-indexed page the range starts on
+	style  string // one of the Label constants, or "" for the prefix alone
+	prefix string
+	start  int // number of the first page
+}
+
+// label returns the label of a page in the range
+func (lr labelRange) label(pageNum int) string {
+	n := lr.start + pageNum - lr.first
+	switch lr.style {
+	case LabelDecimal:
+		return lr.prefix + strconv.Itoa(n)
+	case LabelUpperRoman:
+		return lr.prefix + romanNumeral(n)
+	case LabelLowerRoman:
+		return lr.prefix + strings.ToLower(romanNumeral(n))
+	case LabelUpperAlpha:
+		return lr.prefix + alphaNumeral(n)
+	case LabelLowerAlpha:
+		return lr.prefix + strings.ToLower(alphaNumeral(n))
+	}
+	return lr.prefix
+}
+
+// romanNumeral writes n in upper-case roman numerals, or in decimal past
+// maxRomanNumber
+func romanNumeral(n int) string {
+	if n <= 0 || n > maxRomanNumber {
+		return strconv.Itoa(n)
+	}
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+
+	var sb strings.Builder
+	for i, v := range values {
+		for ; n >= v; n -= v {
+			sb.WriteString(symbols[i])
+		}
+	}
+	return sb.String()
+}
+
+// alphaNumeral writes n as PDF letter numbering: A to Z, then AA to ZZ,
+// AAA to ZZZ and so on, or in decimal past maxAlphaNumber
+func alphaNumeral(n int) string {
+	if n <= 0 || n > maxAlphaNumber {
+		return strconv.Itoa(n)
+	}
+	return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+}
+
+// readPageLabels reads the /PageLabels number tree of the catalog into
+// the label of every page, or nil if the document has none. Pages before
+// the first range are numbered from 1.
+func readPageLabels(r *pdf.Reader, crypt *securityHandler, pages int) []string {
+	var ranges []labelRange
+	walkNumberTree(r.Trailer().Key("Root").Key("PageLabels"), func(key int, value pdf.Value) {
+		if key < 0 || key >= pages || value.Kind() != pdf.Dict {
+			return
+		}
+		lr := labelRange{
+			first:  key + 1,
+			style:  value.Key("S").Name(),
+			prefix: crypt.text(value.Key("P")),
+			start:  1,
+		}
+		if st := value.Key("St"); st.Kind() == pdf.Integer && st.Int64() > 0 {
+			lr.start = int(min(st.Int64(), maxLabelStart))
+		}
+		ranges = append(ranges, lr)
+	})
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	// Number trees are sorted, but don't rely on broken files being so
+	for i := 1; i < len(ranges); i++ {
+		for j := i; j > 0 && ranges[j].first < ranges[j-1].first; j-- {
+			ranges[j], ranges[j-1] = ranges[j-1], ranges[j]
+		}
+	}
+
+	labels := make([]string, pages)
+	current := labelRange{first: 1, style: LabelDecimal, start: 1}
+	for pageNum, next := 1, 0; pageNum <= pages; pageNum++ {
+		for next < len(ranges) && ranges[next].first <= pageNum {
+			current = ranges[next]
+			next++
+		}
+		labels[pageNum-1] = current.label(pageNum)
+	}
+	return labels
+}
+
+// extractPageLabels reads the page labels, tolerating malformed objects
+func extractPageLabels(r *pdf.Reader, crypt *securityHandler, pages int) (labels []string) {
+	// The reader panics on malformed objects; labels are best-effort
+	defer func() {
+		if rec := recover(); rec != nil {
+			labels = nil
+		}
+	}()
+	return readPageLabels(r, crypt, pages)
+}
+
+// walkNumberTree visits every key/value pair in a PDF number tree
+func walkNumberTree(node pdf.Value, visit func(key int, value pdf.Value)) {
+	visited := make(map[objectRef]bool)
+
+	var walk func(node pdf.Value, depth int)
+	walk = func(node pdf.Value, depth int) {
+		if node.Kind() != pdf.Dict || depth > 32 {
+			return
+		}
+		if ref := refOf(node); ref.id != 0 {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+
+		nums := node.Key("Nums")
+		for i := 0; i+1 < nums.Len(); i += 2 {
+			if key := nums.Index(i); key.Kind() == pdf.Integer {
+				visit(int(key.Int64()), nums.Index(i+1))
+			}
+		}
+
+		kids := node.Key("Kids")
+		for i := 0; i < kids.Len(); i++ {
+			walk(kids.Index(i), depth+1)
+		}
+	}
+
+	walk(node, 0)
+}
+
+// HasPageLabels reports whether the document defines page labels
+func (d *Document) HasPageLabels() bool {
+	return d.labels != nil
+}
+
+// PageLabel returns the label printed on a page, such as "xiv" or "A-3",
+// or the page number if the document defines no labels
+func (d *Document) PageLabel(pageNum int) string {
+	if pageNum >= 1 && pageNum <= len(d.labels) {
+		return d.labels[pageNum-1]
+	}
+	return strconv.Itoa(pageNum)
+}
+
+// PageForLabel returns the first page with the given label, matching case
+// only when that tells pages apart ("I" before "i")
+func (d *Document) PageForLabel(label string) (int, bool) {
+	found := 0
+	for i, l := range d.labels {
+		if l == label {
+			return i + 1, true
+		}
+		if found == 0 && strings.EqualFold(l, label) {
+			found = i + 1
+		}
+	}
+	return found, found > 0
+}
+
+// ResolvePage finds the page a user refers to: a page label, or else a
+// page number counted from the first page. A leading '#' ("#12") always
+// means the page number.
+func (d *Document) ResolvePage(ref string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return 0, fmt.Errorf("no page given")
+	}
+
+	if number, ok := strings.CutPrefix(ref, "#"); ok {
+		ref = number
+	} else if pageNum, ok := d.PageForLabel(ref); ok {
+		return pageNum, nil
+	}
+
+	pageNum, err := strconv.Atoi(ref)
+	if err != nil {
+		return 0, fmt.Errorf("no page labelled %q", ref)
+	}
+	if pageNum < 1 || pageNum > d.pages {
+		return 0, fmt.Errorf("page number out of range: %d", pageNum)
+	}
+	return pageNum, nil
+}
@@ -0,0 +1,136 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// openLabelled opens a document of the given number of pages with a
+// /PageLabels number tree. Its nodes are given as objects, the first being
+// the root; "@n" in them refers to node n.
+func openLabelled(t *testing.T, pages int, nodes ...string) *Document {
+	t.Helper()
+	b := newTestPDFBuilder()
+	for i := 0; i < pages; i++ {
+		b.addPage([]string{fmt.Sprintf("Page %d", i+1)}, "")
+	}
+	if len(nodes) > 0 {
+		ids := make([]int, len(nodes))
+		for i := range nodes {
+			ids[i] = b.reserve()
+		}
+		for i, node := range nodes {
+			for j := range nodes {
+				node = strings.ReplaceAll(node, fmt.Sprintf("@%d", j), ref(ids[j]))
+			}
+			b.set(ids[i], node)
+		}
+		b.catalog = append(b.catalog, "/PageLabels "+ref(ids[0]))
+	}
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { doc.Close() })
+	return doc
+}
+
+func TestPageLabels(t *testing.T) {
+	// Front matter i-iii, body 1-3, appendix A-1 and A-2, then a cover
+	// labelled by its prefix alone
+	doc := openLabelled(t, 9, "<< /Kids [@1 @2] >>",
+		"<< /Limits [0 3] /Nums [0 << /S /r >> 3 << /S /D >>] >>",
+		"<< /Limits [6 8] /Nums [6 << /S /D /P (A-) >> 8 << /P (Back cover) >>] >>")
+
+	want := []string{"i", "ii", "iii", "1", "2", "3", "A-1", "A-2", "Back cover"}
+	if !doc.HasPageLabels() {
+		t.Fatal("HasPageLabels() = false")
+	}
+	for i, label := range want {
+		if got := doc.PageLabel(i + 1); got != label {
+			t.Errorf("PageLabel(%d) = %q, want %q", i+1, got, label)
+		}
+		if page, ok := doc.PageForLabel(label); !ok || page != i+1 {
+			t.Errorf("PageForLabel(%q) = %d, %v, want %d", label, page, ok, i+1)
+		}
+	}
+
+	page, err := doc.GetPage(2)
+	if err != nil || page.Label != "ii" {
+		t.Errorf("GetPage(2) label = %q (err %v), want ii", page.Label, err)
+	}
+}
+
+func TestPageLabels_Styles(t *testing.T) {
+	doc := openLabelled(t, 6, `<< /Nums [0 << /S /R /St 4 >> 2 << /S /A /St 26 >> 4 << /S /a /St 53 >>] >>`)
+
+	want := []string{"IV", "V", "Z", "AA", "aaa", "bbb"}
+	for i, label := range want {
+		if got := doc.PageLabel(i + 1); got != label {
+			t.Errorf("PageLabel(%d) = %q, want %q", i+1, got, label)
+		}
+	}
+	if page, ok := doc.PageForLabel("v"); !ok || page != 2 {
+		t.Errorf("PageForLabel(\"v\") = %d, %v, want page 2 ignoring case", page, ok)
+	}
+}
+
+// TestPageLabels_HugeStart keeps labels short whatever number a range
+// starts at
+func TestPageLabels_HugeStart(t *testing.T) {
+	doc := openLabelled(t, 4, `<< /Nums [0 << /S /A /St 1000000000 >> 1 << /S /R /St 1000000000 >> 2 << /S /a /St 260 >> 3 << /S /D /St 99999999999999 >>] >>`)
+
+	want := []string{"1000000000", "1000000000", "zzzzzzzzzz", "1073741824"}
+	for i, label := range want {
+		if got := doc.PageLabel(i + 1); got != label {
+			t.Errorf("PageLabel(%d) = %.40q, want %q", i+1, got, label)
+		}
+	}
+}
+
+func TestPageLabels_None(t *testing.T) {
+	doc := openLabelled(t, 2)
+
+	if doc.HasPageLabels() {
+		t.Error("HasPageLabels() = true without /PageLabels")
+	}
+	if got := doc.PageLabel(2); got != "2" {
+		t.Errorf("PageLabel(2) = %q, want the page number", got)
+	}
+	if _, ok := doc.PageForLabel("2"); ok {
+		t.Error("PageForLabel() should find nothing without labels")
+	}
+}
+
+func TestResolvePage(t *testing.T) {
+	doc := openLabelled(t, 6, `<< /Nums [0 << /S /r >> 2 << /S /D >>] >>`)
+
+	tests := []struct {
+		ref     string
+		want    int
+		wantErr bool
+	}{
+		{"ii", 2, false},
+		{"1", 3, false},  // the label wins over the page number
+		{"#1", 1, false}, // unless asked for the page number
+		{"6", 6, false},  // no page is labelled 6
+		{" iv ", 0, true},
+		{"#7", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := doc.ResolvePage(tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolvePage(%q) = %d, %v; want %d, error %v", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRomanNumeral(t *testing.T) {
+	for n, want := range map[int]string{1: "I", 4: "IV", 9: "IX", 14: "XIV", 40: "XL", 1994: "MCMXCIV", 3999: "MMMCMXCIX", 4000: "4000", 0: "0"} {
+		if got := romanNumeral(n); got != want {
+			t.Errorf("romanNumeral(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
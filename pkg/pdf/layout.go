@@ -13,6 +13,8 @@ type TextElement struct {
 	FontSize float64 // Font size in points
 	Font     string  // Font name (e.g., "Helvetica", "Times-Bold")
 	Width    float64 // Width of text string in points
+
+	estimated bool // Width is guessed: the font reports no glyph widths
 }
 
 // LayoutAnalyzer provides layout-aware text extraction and formatting
@@ -28,6 +30,14 @@ type LayoutAnalyzer struct {
 
 	// UseRelativeThreshold: if true, line threshold is relative to font size
 	UseRelativeThreshold bool
+
+	// ParagraphGap: line spacing, relative to the page's usual spacing, above
+	// which ExtractText starts a new paragraph (0 disables paragraph breaks)
+	ParagraphGap float64
+
+	// BodyFontSize: size of body text that DetectHeadings measures headings
+	// against (0 = the median size of the elements)
+	BodyFontSize float64
 }
 
 // NewLayoutAnalyzer creates a layout analyzer with sensible defaults
@@ -36,6 +46,7 @@ func NewLayoutAnalyzer() *LayoutAnalyzer {
 		LineThreshold:        5.0, // 5 points for absolute threshold
 		ColumnThreshold:      20.0,
 		UseRelativeThreshold: true,
+		ParagraphGap:         1.5,
 	}
 }
 
@@ -97,16 +108,13 @@ func (la *LayoutAnalyzer) ExtractWithColumns(elements []TextElement) (string, []
 		return "", nil
 	}
 
-	// Group elements by Y-coordinate ranges (logical lines)
-	lines := la.groupIntoLines(elements)
-
-	// Detect column boundaries from X-coordinates across all lines
-	columns := la.detectColumnBoundaries(lines)
+	// Group elements into lines, then find the gutters between columns
+	lines, paragraph := la.textLines(elements)
+	gutters := la.findGutters(lines)
 
-	// Format text respecting column structure
-	formatted := la.formatByColumns(lines, columns)
-
-	return formatted, columns
+	// Read each column of a block top to bottom before the next column
+	layout := &PageLayout{Blocks: la.layoutBlocks(lines, gutters, paragraph)}
+	return layout.Text(), la.columnsOf(lines, gutters)
 }
 
 // Column represents a vertical region of text
@@ -123,25 +131,26 @@ func (la *LayoutAnalyzer) groupIntoLines(elements []TextElement) [][]TextElement
 		return nil
 	}
 
-	// Sort by Y descending first
-	sorted := make([]TextElement, len(elements))
-	copy(sorted, elements)
-	sort.Slice(sorted, func(i, j int) bool {
-		if sorted[i].Y != sorted[j].Y {
-			return sorted[i].Y > sorted[j].Y
-		}
-		return sorted[i].X < sorted[j].X
+	// Sort by Y descending first. The sort is stable, so elements on the
+	// same baseline keep their drawing order.
+	sorted := make([]int, len(elements))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return elements[sorted[i]].Y > elements[sorted[j]].Y
 	})
 
-	var lines [][]TextElement
-	var currentLine []TextElement
+	var groups [][]int
+	var currentLine []int
 	var lastY float64 = -1.0
 	lineThreshold := la.LineThreshold
 
-	for _, elem := range sorted {
+	for _, i := range sorted {
+		elem := elements[i]
 		if lastY < 0 {
 			// First element
-			currentLine = append(currentLine, elem)
+			currentLine = append(currentLine, i)
 			lastY = elem.Y
 		} else {
 			yDistance := lastY - elem.Y
@@ -152,139 +161,198 @@ func (la *LayoutAnalyzer) groupIntoLines(elements []TextElement) [][]TextElement
 
 			if yDistance > lineThreshold {
 				// New line detected
-				lines = append(lines, currentLine)
-				currentLine = []TextElement{elem}
+				groups = append(groups, currentLine)
+				currentLine = []int{i}
 				lastY = elem.Y
 			} else {
 				// Same line
-				currentLine = append(currentLine, elem)
+				currentLine = append(currentLine, i)
 			}
 		}
 	}
 
 	// Add last line
 	if len(currentLine) > 0 {
-		lines = append(lines, currentLine)
+		groups = append(groups, currentLine)
+	}
+
+	lines := make([][]TextElement, len(groups))
+	for i, group := range groups {
+		lines[i] = orderLine(elements, group)
 	}
 
 	return lines
 }
 
-// detectColumnBoundaries analyzes X-coordinates to find column divisions
-func (la *LayoutAnalyzer) detectColumnBoundaries(lines [][]TextElement) []Column {
-	if len(lines) == 0 {
-		return nil
+// orderLine puts the elements of a line in reading order. Elements with
+// known, non-overlapping extents are read left to right, which puts
+// superscripts and jittered baselines back among their neighbours.
+// Otherwise the X positions can't be trusted (fonts without glyph widths,
+// runs positioned by kerning), and the elements keep their drawing order.
+func orderLine(elements []TextElement, line []int) []TextElement {
+	byX := append([]int(nil), line...)
+	sort.SliceStable(byX, func(i, j int) bool {
+		return elements[byX[i]].X < elements[byX[j]].X
+	})
+	if !hasExtents(elements, byX) {
+		byX = append(byX[:0], line...)
+		sort.Ints(byX)
 	}
 
-	// Collect all X values and gaps to detect columns
-	var columns []Column
-	xValues := make(map[float64]bool)
+	ordered := make([]TextElement, len(byX))
+	for i, e := range byX {
+		ordered[i] = elements[e]
+	}
+	return ordered
+}
 
-	// Get all unique X boundaries
-	for _, line := range lines {
-		for _, elem := range line {
-			xValues[elem.X] = true
-			xValues[elem.X + elem.Width] = true
+// hasExtents reports whether elements, in left to right order, have real
+// widths and don't overlap by more than a tenth of their font size
+func hasExtents(elements []TextElement, byX []int) bool {
+	for i, e := range byX {
+		elem := elements[e]
+		if elem.Width <= 0 || elem.estimated {
+			return false
+		}
+		if i > 0 {
+			prev := elements[byX[i-1]]
+			if prev.X+prev.Width > elem.X+0.1*max(prev.FontSize, elem.FontSize) {
+				return false
+			}
 		}
 	}
+	return true
+}
+
+// ExtractText reconstructs reading text from words: words on a line are
+// separated by a space, lines by a newline, and paragraphs by a blank line.
+// A paragraph break is a line gap ParagraphGap times wider than the usual
+// line spacing of the page.
+func (la *LayoutAnalyzer) ExtractText(elements []TextElement) string {
+	lines, _ := la.textLines(elements)
+	return joinLines(lines)
+}
 
-	// Simple heuristic: if there's a significant gap, it's likely a column boundary
-	var sortedX []float64
-	for x := range xValues {
-		sortedX = append(sortedX, x)
+// joinLines lays out lines as ExtractText does
+func joinLines(lines []textLine) string {
+	var result strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			result.WriteString("\n")
+			if line.paragraph {
+				result.WriteString("\n")
+			}
+		}
+		result.WriteString(line.text)
 	}
-	sort.Float64s(sortedX)
 
-	// Find gaps larger than column threshold
-	var columnStarts []float64
-	columnStarts = append(columnStarts, sortedX[0])
+	return result.String()
+}
 
-	for i := 1; i < len(sortedX); i++ {
-		gap := sortedX[i] - sortedX[i-1]
-		if gap > la.ColumnThreshold {
-			columnStarts = append(columnStarts, sortedX[i])
-		}
+// textLine is a line of words as ExtractText lays it out
+type textLine struct {
+	elems     []TextElement // words, left to right
+	text      string        // the words joined by spaces
+	y         float64       // baseline
+	offset    int           // byte offset of text in ExtractText's output
+	paragraph bool          // a paragraph break precedes the line
+}
+
+// textLines groups elements into lines, top to bottom, and returns them
+// with the line gap above which a paragraph starts (0 if none is detected)
+func (la *LayoutAnalyzer) textLines(elements []TextElement) ([]textLine, float64) {
+	groups := la.groupIntoLines(elements)
+	if len(groups) == 0 {
+		return nil, 0
 	}
 
-	// Create column objects
-	for i, start := range columnStarts {
-		col := Column{Left: start}
-		if i+1 < len(columnStarts) {
-			col.Right = columnStarts[i+1]
-		} else {
-			col.Right = sortedX[len(sortedX)-1]
+	gaps := make([]float64, len(groups)-1)
+	for i := 1; i < len(groups); i++ {
+		gaps[i-1] = groups[i-1][0].Y - groups[i][0].Y
+	}
+	paragraph := la.paragraphThreshold(groups, gaps)
+
+	lines := make([]textLine, len(groups))
+	offset := 0
+	for i, group := range groups {
+		words := make([]string, len(group))
+		for j, elem := range group {
+			words[j] = elem.Text
 		}
-		col.Width = col.Right - col.Left
-		columns = append(columns, col)
+		line := textLine{elems: group, text: strings.Join(words, " "), y: group[0].Y}
+		if i > 0 {
+			offset++ // newline
+			if paragraph > 0 && gaps[i-1] > paragraph {
+				line.paragraph = true
+				offset++
+			}
+		}
+		line.offset = offset
+		offset += len(line.text)
+		lines[i] = line
 	}
 
-	return columns
+	return lines, paragraph
 }
 
-// formatByColumns constructs text respecting column structure
-func (la *LayoutAnalyzer) formatByColumns(lines [][]TextElement, columns []Column) string {
-	var result strings.Builder
-
-	for lineIdx, line := range lines {
-		// Process each column in this line
-		for colIdx, col := range columns {
-			var lineText strings.Builder
-			first := true
+// paragraphThreshold returns the line gap above which a new paragraph
+// starts, or 0 if paragraphs are not detected. The usual line spacing is
+// the median gap when the page has enough lines to tell, and otherwise the
+// conventional leading of 1.2 times the font size.
+func (la *LayoutAnalyzer) paragraphThreshold(lines [][]TextElement, gaps []float64) float64 {
+	if la.ParagraphGap <= 0 || len(gaps) == 0 {
+		return 0
+	}
 
-			// Collect elements for this column in this line
+	var spacing float64
+	if len(gaps) >= 3 {
+		sorted := append([]float64(nil), gaps...)
+		sort.Float64s(sorted)
+		spacing = sorted[(len(sorted)-1)/2] // lower median: paragraph gaps skew it up
+	} else {
+		for _, line := range lines {
 			for _, elem := range line {
-				if elem.X >= col.Left && elem.X+elem.Width <= col.Right+1 {
-					if !first {
-						lineText.WriteString(" ")
-					}
-					lineText.WriteString(elem.Text)
-					first = false
-				}
-			}
-
-			if lineText.Len() > 0 {
-				result.WriteString(lineText.String())
-				if colIdx < len(columns)-1 {
-					result.WriteString(" | ") // Column separator
-				}
+				spacing = max(spacing, elem.FontSize*1.2)
 			}
 		}
-
-		// Add line break between lines (but not after last line)
-		if lineIdx < len(lines)-1 {
-			result.WriteString("\n")
-		}
+	}
+	if spacing <= 0 {
+		return 0
 	}
 
-	return result.String()
+	return spacing * la.ParagraphGap
 }
 
-// DetectHeadings identifies likely headings by font size
-// Returns indices of elements that are likely headings
+// DetectHeadings identifies likely headings by font size and weight:
+// elements set 20% larger than body text, or in bold at body size or
+// larger. Returns indices of elements that are likely headings
 func (la *LayoutAnalyzer) DetectHeadings(elements []TextElement) []int {
 	if len(elements) == 0 {
 		return nil
 	}
 
-	// Find median and mean font sizes
-	var sizes []float64
-	for _, elem := range elements {
-		if elem.FontSize > 0 {
-			sizes = append(sizes, elem.FontSize)
+	bodySize := la.BodyFontSize
+	if bodySize <= 0 {
+		// Find the median font size
+		var sizes []float64
+		for _, elem := range elements {
+			if elem.FontSize > 0 {
+				sizes = append(sizes, elem.FontSize)
+			}
 		}
-	}
 
-	if len(sizes) == 0 {
-		return nil
-	}
+		if len(sizes) == 0 {
+			return nil
+		}
 
-	sort.Float64s(sizes)
-	medianSize := sizes[len(sizes)/2]
-	headingThreshold := medianSize * 1.2 // 20% larger than median
+		sort.Float64s(sizes)
+		bodySize = sizes[len(sizes)/2]
+	}
+	headingThreshold := bodySize * 1.2 // 20% larger than body text
 
 	var headings []int
 	for i, elem := range elements {
-		if elem.FontSize > headingThreshold {
+		if elem.FontSize > headingThreshold || (elem.FontSize >= bodySize && isBold(elem.Font)) {
 			headings = append(headings, i)
 		}
 	}
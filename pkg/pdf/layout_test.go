@@ -329,3 +329,79 @@ func TestExtractWithColumns_SingleColumn(t *testing.T) {
 		t.Errorf("Output should contain text: %s", formatted)
 	}
 }
+
+// TestExtractText separates words, lines and paragraphs
+func TestExtractText(t *testing.T) {
+	analyzer := NewLayoutAnalyzer()
+
+	elements := []TextElement{
+		{Text: "Heading", X: 10, Y: 200, FontSize: 12, Width: 42},
+		{Text: "one", X: 10, Y: 170, FontSize: 10, Width: 15},
+		{Text: "2", X: 32, Y: 174, FontSize: 6, Width: 3}, // superscript, drawn above the baseline
+		{Text: "x", X: 28, Y: 170, FontSize: 10, Width: 4},
+		{Text: "two", X: 10, Y: 158, FontSize: 10, Width: 15},
+		{Text: "three", X: 10, Y: 146, FontSize: 10, Width: 25},
+		{Text: "four", X: 10, Y: 116, FontSize: 10, Width: 20},
+	}
+
+	want := "Heading\n\none x 2\ntwo\nthree\n\nfour"
+	if got := analyzer.ExtractText(elements); got != want {
+		t.Errorf("ExtractText() = %q, want %q", got, want)
+	}
+
+	analyzer.ParagraphGap = 0
+	want = "Heading\none x 2\ntwo\nthree\nfour"
+	if got := analyzer.ExtractText(elements); got != want {
+		t.Errorf("ExtractText() without paragraphs = %q, want %q", got, want)
+	}
+
+	if got := analyzer.ExtractText(nil); got != "" {
+		t.Errorf("ExtractText(nil) = %q, want empty", got)
+	}
+}
+
+// TestExtractText_DrawingOrder keeps words whose extents aren't known, or
+// overlap, in the order they are drawn
+func TestExtractText_DrawingOrder(t *testing.T) {
+	analyzer := NewLayoutAnalyzer()
+
+	tests := []struct {
+		name     string
+		elements []TextElement
+		want     string
+	}{
+		{
+			name: "no widths",
+			elements: []TextElement{
+				{Text: "WHAT", X: 115, Y: 700, FontSize: 18},
+				{Text: "WAS", X: 113, Y: 700, FontSize: 18},
+				{Text: "DONE", X: 110, Y: 700, FontSize: 18},
+			},
+			want: "WHAT WAS DONE",
+		},
+		{
+			name: "estimated widths",
+			elements: []TextElement{
+				{Text: "Part", X: 150, Y: 700, FontSize: 14, Width: 28, estimated: true},
+				{Text: "one", X: 121, Y: 700, FontSize: 14, Width: 21, estimated: true},
+			},
+			want: "Part one",
+		},
+		{
+			name: "overlapping extents",
+			elements: []TextElement{
+				{Text: "second", X: 40, Y: 700, FontSize: 10, Width: 30},
+				{Text: "first", X: 30, Y: 700, FontSize: 10, Width: 25},
+			},
+			want: "second first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzer.ExtractText(tt.elements); got != tt.want {
+				t.Errorf("ExtractText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
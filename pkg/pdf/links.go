@@ -0,0 +1,252 @@
+package pdf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// LinkKind is what a link leads to
+type LinkKind int
+
+const (
+	LinkPage   LinkKind = iota // a page of this document
+	LinkRemote                 // a page of another PDF file
+	LinkURI                    // a web address or other URI
+)
+
+// Link is a link annotation on a page
+type Link struct {
+	Kind LinkKind
+
+	// Rect is the area of the page the link covers, in points: left,
+	// bottom, right, top
+	Rect [4]float64
+
+	Page int    // target page, 1-indexed; 0 if unknown (or for LinkURI)
+	File string // target file of a LinkRemote, relative to the document's directory if it was given relative
+	Dest string // named destination in File, if the target is given by name
+	URI  string // target of a LinkURI
+
+	// File, Dest and URI come from the document, and have control
+	// characters percent-encoded (see EscapeControls) so they can be shown
+	// in a terminal
+
+	// Text is the page text the link covers, if any. Start and End
+	// delimit it in the page's layout-extracted text (see ExtractLayout),
+	// or are -1 for links covering no text and documents extracted in
+	// another mode.
+	Text       string
+	Start, End int
+}
+
+// Target describes where a link leads, for display
+func (l Link) Target() string {
+	switch l.Kind {
+	case LinkURI:
+		return l.URI
+	case LinkRemote:
+		name := filepath.Base(l.File)
+		switch {
+		case l.Dest != "":
+			return fmt.Sprintf("%s#%s", name, l.Dest)
+		case l.Page > 0:
+			return fmt.Sprintf("%s, page %d", name, l.Page)
+		}
+		return name
+	}
+	if l.Page > 0 {
+		return fmt.Sprintf("page %d", l.Page)
+	}
+	return "nowhere"
+}
+
+// GetPageLinks reads the link annotations of a page, resolving their
+// destinations and finding the text they cover. Text offsets refer to the
+// page text only when the document uses ExtractLayout.
+func (d *Document) GetPageLinks(pageNum int) ([]Link, error) {
+	if pageNum < 1 || pageNum > d.pages {
+		return nil, fmt.Errorf("page number out of range: %d", pageNum)
+	}
+
+	var links []Link
+	err := d.withReader(func(r *pdf.Reader) error {
+		d.destsOnce.Do(func() { d.dests = newDestResolver(r, d.crypt) })
+		links = readLinks(r.Page(pageNum), d.dests, pageNum, d.pages)
+		return nil
+	})
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+
+	for i := range links {
+		if links[i].Kind == LinkRemote && links[i].File != "" && !filepath.IsAbs(links[i].File) {
+			links[i].File = filepath.Join(filepath.Dir(d.filepath), links[i].File)
+		}
+	}
+
+	pl, err := d.readPageLines(pageNum)
+	if err != nil {
+		return links, nil
+	}
+	for i := range links {
+		links[i].Text, links[i].Start, links[i].End = coveredText(pl.lines, links[i].Rect)
+		if d.extraction != ExtractLayout {
+			links[i].Start, links[i].End = -1, -1
+		}
+	}
+	return links, nil
+}
+
+// DestinationPage returns the page a named destination of the document
+// leads to, or 0 if there is no such destination
+func (d *Document) DestinationPage(name string) int {
+	pageNum := 0
+	d.withReader(func(r *pdf.Reader) error {
+		d.destsOnce.Do(func() { d.dests = newDestResolver(r, d.crypt) })
+		pageNum = d.dests.resolve(d.dests.named[name])
+		return nil
+	})
+	return pageNum
+}
+
+// readLinks reads the link annotations of a page
+func readLinks(page pdf.Page, dests *destResolver, pageNum, pages int) []Link {
+	var links []Link
+	annots := page.V.Key("Annots")
+	for i := 0; i < annots.Len(); i++ {
+		annot := annots.Index(i)
+		if annot.Key("Subtype").Name() != "Link" {
+			continue
+		}
+		rect := annot.Key("Rect")
+		if rect.Len() != 4 {
+			continue
+		}
+
+		link := Link{Start: -1, End: -1}
+		for j := range link.Rect {
+			link.Rect[j] = rect.Index(j).Float64()
+		}
+		if link.Rect[0] > link.Rect[2] {
+			link.Rect[0], link.Rect[2] = link.Rect[2], link.Rect[0]
+		}
+		if link.Rect[1] > link.Rect[3] {
+			link.Rect[1], link.Rect[3] = link.Rect[3], link.Rect[1]
+		}
+
+		if dest := annot.Key("Dest"); dest.Kind() != pdf.Null {
+			link.Page = dests.resolve(dest)
+		} else if !readAction(annot.Key("A"), &link, dests, pageNum, pages) {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// readAction fills in the target of a link from its action, reporting
+// whether the action is one links can follow
+func readAction(action pdf.Value, link *Link, dests *destResolver, pageNum, pages int) bool {
+	switch action.Key("S").Name() {
+	case "GoTo":
+		link.Page = dests.resolve(action.Key("D"))
+	case "GoToR":
+		link.Kind = LinkRemote
+		link.File = EscapeControls(fileSpec(action.Key("F"), dests.crypt))
+		switch dest := action.Key("D"); dest.Kind() {
+		case pdf.Array:
+			// Pages of other files are given by their zero-based index
+			if first := dest.Index(0); first.Kind() == pdf.Integer {
+				link.Page = int(first.Int64()) + 1
+			}
+		case pdf.Name:
+			link.Dest = EscapeControls(dest.Name())
+		case pdf.String:
+			link.Dest = EscapeControls(dests.crypt.text(dest))
+		}
+	case "URI":
+		link.Kind = LinkURI
+		link.URI = EscapeControls(dests.crypt.rawString(action.Key("URI")))
+	case "Named":
+		switch action.Key("N").Name() {
+		case "FirstPage":
+			link.Page = 1
+		case "LastPage":
+			link.Page = pages
+		case "NextPage":
+			link.Page = min(pageNum+1, pages)
+		case "PrevPage":
+			link.Page = max(pageNum-1, 1)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// fileSpec returns the path of a file specification, a string or a
+// dictionary
+func fileSpec(spec pdf.Value, crypt *securityHandler) string {
+	if spec.Kind() == pdf.Dict {
+		for _, key := range []string{"UF", "F", "Unix"} {
+			if f := spec.Key(key); f.Kind() == pdf.String {
+				return crypt.text(f)
+			}
+		}
+		return ""
+	}
+	return crypt.text(spec)
+}
+
+// EscapeControls percent-encodes the C0 and C1 control characters, DEL
+// and invalid UTF-8 in s. Escape sequences in a link target would
+// otherwise reach the terminal when the target is shown or made a
+// hyperlink: a URI ending an OSC 8 sequence early with BEL could go on to
+// set the clipboard or retitle the window.
+func EscapeControls(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f) || (r == utf8.RuneError && size == 1) {
+			for _, c := range []byte(s[i : i+size]) {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		} else {
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// coveredText returns the words of the lines inside a rectangle and their
+// byte range in the page text, or -1, -1 if the rectangle covers no words.
+// A word is inside when the middle of its height is, and at least half
+// its width; link areas are drawn loosely around the text.
+func coveredText(lines []textLine, rect [4]float64) (string, int, int) {
+	var words []string
+	start, end := -1, -1
+	for _, line := range lines {
+		offset := line.offset
+		for _, elem := range line.elems {
+			mid := elem.Y + elem.FontSize*0.3
+			width := elementWidth(elem)
+			overlap := min(elem.X+width, rect[2]) - max(elem.X, rect[0])
+			if mid >= rect[1] && mid <= rect[3] && overlap > 0 && (overlap >= width/2 || overlap >= (rect[2]-rect[0])*0.9) {
+				if start < 0 {
+					start = offset
+				}
+				end = offset + len(elem.Text)
+				words = append(words, elem.Text)
+			}
+			offset += len(elem.Text) + 1
+		}
+	}
+	return strings.Join(words, " "), start, end
+}
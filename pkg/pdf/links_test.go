@@ -0,0 +1,160 @@
+package pdf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPageLinks(t *testing.T) {
+	b := newTestPDFBuilder()
+	link := func(rect, target string) string {
+		return "<< /Type /Annot /Subtype /Link /Rect [" + rect + "] " + target + " >>"
+	}
+	first := b.addPageAt([]placedText{
+		{x: 72, y: 720, size: 12, text: "See"},
+		{x: 150, y: 720, size: 12, text: "Chapter"},
+		{x: 220, y: 720, size: 12, text: "Two"},
+		{x: 300, y: 720, size: 12, text: "online"},
+		{x: 72, y: 600, size: 12, text: "Appendix"},
+	}, "/Annots ["+
+		link("145 715 260 732", "/A << /S /GoTo /D (chap2) >>")+
+		link("345 732 295 715", "/A << /S /URI /URI (https://example.com/docs) >>")+
+		link("70 595 130 612", "/A << /S /GoToR /F (appendix.pdf) /D [1 /Fit] >>")+
+		link("0 0 10 10", "/A << /S /JavaScript /JS (app.alert\\(1\\)) >>")+
+		"<< /Type /Annot /Subtype /Text /Rect [0 0 10 10] /Contents (A note) >>]")
+	second := b.addPageAt([]placedText{
+		{x: 72, y: 720, size: 12, text: "Back"},
+		{x: 72, y: 700, size: 12, text: "End"},
+	}, "/Annots ["+
+		link("70 715 110 732", "/Dest ["+ref(first)+" /Fit]")+
+		link("70 695 110 712", "/A << /S /Named /N /LastPage >>")+"]")
+	b.addPage([]string{"Third"}, "")
+	b.catalog = append(b.catalog, "/Dests << /chap2 ["+ref(second)+" /XYZ 0 792 0] >>")
+
+	path := b.write(t)
+	doc, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	links, err := doc.GetPageLinks(1)
+	if err != nil {
+		t.Fatalf("GetPageLinks(1) unexpected error: %v", err)
+	}
+	if len(links) != 3 {
+		t.Fatalf("GetPageLinks(1) = %d links, want 3: %+v", len(links), links)
+	}
+	page, err := doc.GetPage(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind   LinkKind
+		page   int
+		text   string
+		target string
+	}{
+		{LinkPage, 2, "Chapter Two", "page 2"},
+		{LinkURI, 0, "online", "https://example.com/docs"},
+		{LinkRemote, 2, "Appendix", "appendix.pdf, page 2"},
+	}
+	for i, w := range want {
+		l := links[i]
+		if l.Kind != w.kind || l.Page != w.page || l.Text != w.text || l.Target() != w.target {
+			t.Errorf("link %d = %+v (target %q), want %+v", i, l, l.Target(), w)
+		}
+		if l.Start < 0 || l.End > len(page.Text) || page.Text[l.Start:l.End] != w.text {
+			t.Errorf("link %d covers [%d, %d) of %q, want %q", i, l.Start, l.End, page.Text, w.text)
+		}
+	}
+	if links[1].Rect != [4]float64{295, 715, 345, 732} {
+		t.Errorf("Rect = %v, want it normalized", links[1].Rect)
+	}
+	if links[1].URI != "https://example.com/docs" {
+		t.Errorf("URI = %q", links[1].URI)
+	}
+	if want := filepath.Join(filepath.Dir(path), "appendix.pdf"); links[2].File != want {
+		t.Errorf("File = %q, want %q", links[2].File, want)
+	}
+
+	links, err = doc.GetPageLinks(2)
+	if err != nil || len(links) != 2 {
+		t.Fatalf("GetPageLinks(2) = %+v, %v, want 2 links", links, err)
+	}
+	if links[0].Page != 1 || links[0].Text != "Back" || links[1].Page != 3 || links[1].Text != "End" {
+		t.Errorf("GetPageLinks(2) = %+v", links)
+	}
+
+	if links, err := doc.GetPageLinks(3); err != nil || len(links) != 0 {
+		t.Errorf("GetPageLinks(3) = %+v, %v, want none", links, err)
+	}
+}
+
+func TestPageLinksRawExtraction(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"Start"}, "/Annots [<< /Subtype /Link /Rect [70 715 110 732] /A << /S /Named /N /NextPage >> >>]")
+	b.addPage([]string{"Next"}, "")
+
+	opts := DefaultDocumentOptions()
+	opts.Extraction = ExtractRaw
+	doc, err := OpenDocument(b.write(t), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	links, err := doc.GetPageLinks(1)
+	if err != nil || len(links) != 1 {
+		t.Fatalf("GetPageLinks(1) = %+v, %v", links, err)
+	}
+	if l := links[0]; l.Page != 2 || l.Text != "Start" || l.Start != -1 || l.End != -1 {
+		t.Errorf("link = %+v, want page 2 covering Start without offsets", l)
+	}
+}
+
+func TestPageLinksEscapeControls(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPageAt([]placedText{
+		{x: 72, y: 720, size: 12, text: "Click"},
+		{x: 72, y: 700, size: 12, text: "Open"},
+	}, "/Annots ["+
+		"<< /Subtype /Link /Rect [70 715 110 732] /A << /S /URI /URI (https://example.com/\\007\\033]52;c;cm0gLXJm\\007) >> >>"+
+		"<< /Subtype /Link /Rect [70 695 110 712] /A << /S /GoToR /F <FEFF0061001B0062002E007000640066> /D <FEFF0078009B0079> >> >>]")
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	links, err := doc.GetPageLinks(1)
+	if err != nil || len(links) != 2 {
+		t.Fatalf("GetPageLinks(1) = %+v, %v", links, err)
+	}
+	if want := "https://example.com/%07%1B]52;c;cm0gLXJm%07"; links[0].URI != want {
+		t.Errorf("URI = %q, want %q", links[0].URI, want)
+	}
+	if want := "a%1Bb.pdf"; filepath.Base(links[1].File) != want {
+		t.Errorf("File = %q, want it ending in %q", links[1].File, want)
+	}
+	if links[1].Dest != "x%C2%9By" {
+		t.Errorf("Dest = %q, want the C1 control escaped", links[1].Dest)
+	}
+}
+
+func TestEscapeControls(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"https://example.com/a b?q=é", "https://example.com/a b?q=é"},
+		{"\x1b]52;c;aGk=\a", "%1B]52;c;aGk=%07"},
+		{"tab\there\x7f", "tab%09here%7F"},
+		{"c1\u009b31m", "c1%C2%9B31m"},
+		{"bad\x9butf8", "bad%9Butf8"},
+	}
+	for _, tt := range tests {
+		if got := EscapeControls(tt.in); got != tt.want {
+			t.Errorf("EscapeControls(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// XMP namespaces for the properties LUMOS reads
+const (
+	xmpNSDublinCore = "http://purl.org/dc/elements/1.1/"
+	xmpNSBasic      = "http://ns.adobe.com/xap/1.0/"
+	xmpNSPDF        = "http://ns.adobe.com/pdf/1.3/"
+)
+
+// readMetadata collects document metadata from the file header, the
+// trailer /Info dictionary, the catalog's XMP stream and the first page.
+// Values from /Info take precedence; XMP fills in anything /Info lacks.
+// The strings of an encrypted document are decrypted with crypt.
+func readMetadata(header []byte, r *pdf.Reader, crypt *securityHandler) Metadata {
+	var meta Metadata
+
+	meta.PDFVersion = headerVersion(header)
+	root := r.Trailer().Key("Root")
+	// The catalog /Version overrides the header when it is later (PDF 1.4+)
+	if v := root.Key("Version").Name(); v != "" && v > meta.PDFVersion {
+		meta.PDFVersion = v
+	}
+
+	meta.Encrypted = crypt != nil || r.Trailer().Key("Encrypt").Kind() != pdf.Null
+
+	info := r.Trailer().Key("Info")
+	meta.Title = strings.TrimSpace(crypt.text(info.Key("Title")))
+	meta.Author = strings.TrimSpace(crypt.text(info.Key("Author")))
+	meta.Subject = strings.TrimSpace(crypt.text(info.Key("Subject")))
+	meta.Keywords = strings.TrimSpace(crypt.text(info.Key("Keywords")))
+	meta.Creator = strings.TrimSpace(crypt.text(info.Key("Creator")))
+	meta.Producer = strings.TrimSpace(crypt.text(info.Key("Producer")))
+	meta.CreationDate = parsePDFDate(crypt.text(info.Key("CreationDate")))
+	meta.ModDate = parsePDFDate(crypt.text(info.Key("ModDate")))
+
+	if stream := root.Key("Metadata"); stream.Kind() == pdf.Stream {
+		if data, err := io.ReadAll(stream.Reader()); err == nil {
+			meta.mergeMissing(parseXMP(data))
+		}
+	}
+
+	meta.PageWidth, meta.PageHeight = pageSize(r.Page(1))
+
+	return meta
+}
+
+// mergeMissing copies fields from other that are empty in m
+func (m *Metadata) mergeMissing(other Metadata) {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fill(&m.Title, other.Title)
+	fill(&m.Author, other.Author)
+	fill(&m.Subject, other.Subject)
+	fill(&m.Keywords, other.Keywords)
+	fill(&m.Creator, other.Creator)
+	fill(&m.Producer, other.Producer)
+	fill(&m.PDFVersion, other.PDFVersion)
+
+	if m.CreationDate.IsZero() {
+		m.CreationDate = other.CreationDate
+	}
+	if m.ModDate.IsZero() {
+		m.ModDate = other.ModDate
+	}
+}
+
+var headerVersionPattern = regexp.MustCompile(`^%PDF-(\d+\.\d+)`)
+
+// headerVersion extracts the version from a "%PDF-1.x" file header
+func headerVersion(header []byte) string {
+	if m := headerVersionPattern.FindSubmatch(header); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// pageSize returns the page dimensions in points, honouring an inherited
+// /MediaBox (narrowed by /CropBox) and a /Rotate of 90 or 270 degrees
+func pageSize(page pdf.Page) (width, height float64) {
+	box := inheritedKey(page, "CropBox")
+	if box.Len() != 4 {
+		box = inheritedKey(page, "MediaBox")
+	}
+	if box.Len() != 4 {
+		return 0, 0
+	}
+
+	width = math.Abs(box.Index(2).Float64() - box.Index(0).Float64())
+	height = math.Abs(box.Index(3).Float64() - box.Index(1).Float64())
+
+	rotate := int(inheritedKey(page, "Rotate").Int64()) % 360
+	if rotate < 0 {
+		rotate += 360
+	}
+	if rotate == 90 || rotate == 270 {
+		width, height = height, width
+	}
+
+	return width, height
+}
+
+// inheritedKey looks up a page attribute, walking up the page tree
+func inheritedKey(page pdf.Page, key string) pdf.Value {
+	v := page.V
+	for depth := 0; depth < 32 && !v.IsNull(); depth++ {
+		if value := v.Key(key); !value.IsNull() {
+			return value
+		}
+		v = v.Key("Parent")
+	}
+	return pdf.Value{}
+}
+
+var pdfDatePattern = regexp.MustCompile(
+	`^D?:?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?([Zz+\-])?(\d{2})?'?(\d{2})?'?`)
+
+// parsePDFDate parses a PDF date string ("D:YYYYMMDDHHmmSSOHH'mm'").
+// Every component after the year is optional. Returns the zero time when
+// the string is not a recognizable date.
+func parsePDFDate(s string) time.Time {
+	m := pdfDatePattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return time.Time{}
+	}
+
+	field := func(i, def int) int {
+		if m[i] == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(m[i])
+		return n
+	}
+
+	loc := time.UTC
+	switch m[7] {
+	case "+", "-":
+		offset := field(8, 0)*3600 + field(9, 0)*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	return time.Date(field(1, 0), time.Month(field(2, 1)), field(3, 1),
+		field(4, 0), field(5, 0), field(6, 0), 0, loc)
+}
+
+// xmpDateLayouts are the ISO 8601 forms permitted in XMP date properties
+var xmpDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseXMPDate parses an XMP (ISO 8601) date, returning the zero time on failure
+func parseXMPDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range xmpDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseXMP extracts document properties from an XMP metadata packet.
+// Properties may appear as elements or as attributes of rdf:Description;
+// language alternatives and sequences contribute their items in order.
+func parseXMP(data []byte) Metadata {
+	var meta Metadata
+	values := make(map[string][]string)
+
+	add := func(name xml.Name, value string) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return
+		}
+		key := name.Space + name.Local
+		values[key] = append(values[key], value)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	var property xml.Name // innermost open property element
+	var depth, propertyDepth int
+	var text strings.Builder
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if t.Name.Space == "http://www.w3.org/1999/02/22-rdf-syntax-ns#" {
+				if t.Name.Local == "Description" {
+					for _, attr := range t.Attr {
+						add(attr.Name, attr.Value)
+					}
+				}
+				text.Reset()
+				continue
+			}
+			property = t.Name
+			propertyDepth = depth
+			text.Reset()
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			if t.Name.Space == "http://www.w3.org/1999/02/22-rdf-syntax-ns#" && t.Name.Local == "li" && property.Local != "" {
+				add(property, text.String())
+				text.Reset()
+			} else if t.Name == property && depth == propertyDepth {
+				add(property, text.String())
+				property = xml.Name{}
+				text.Reset()
+			}
+			depth--
+		}
+	}
+
+	first := func(space, local string) string {
+		if v := values[space+local]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	meta.Title = first(xmpNSDublinCore, "title")
+	meta.Subject = first(xmpNSDublinCore, "description")
+	meta.Author = strings.Join(values[xmpNSDublinCore+"creator"], ", ")
+	meta.Keywords = first(xmpNSPDF, "Keywords")
+	if meta.Keywords == "" {
+		meta.Keywords = strings.Join(values[xmpNSDublinCore+"subject"], ", ")
+	}
+	meta.Producer = first(xmpNSPDF, "Producer")
+	meta.Creator = first(xmpNSBasic, "CreatorTool")
+	meta.PDFVersion = first(xmpNSPDF, "PDFVersion")
+	meta.CreationDate = parseXMPDate(first(xmpNSBasic, "CreateDate"))
+	meta.ModDate = parseXMPDate(first(xmpNSBasic, "ModifyDate"))
+
+	return meta
+}
+
+// PageSizeName returns the common paper name for a page size in points
+// (e.g. "A4", "Letter"), or "" if it matches none
+func PageSizeName(width, height float64) string {
+	sizes := []struct {
+		name string
+		w, h float64
+	}{
+		{"Letter", 612, 792},
+		{"Legal", 612, 1008},
+		{"Tabloid", 792, 1224},
+		{"A3", 842, 1191},
+		{"A4", 595, 842},
+		{"A5", 420, 595},
+		{"B5", 499, 709},
+	}
+
+	short, long := math.Min(width, height), math.Max(width, height)
+	for _, size := range sizes {
+		if math.Abs(short-size.w) <= 2 && math.Abs(long-size.h) <= 2 {
+			return size.name
+		}
+	}
+	return ""
+}
+
+// FormatPageSize formats page dimensions for display, e.g. "612 × 792 pt (Letter)"
+func (m Metadata) FormatPageSize() string {
+	if m.PageWidth == 0 || m.PageHeight == 0 {
+		return ""
+	}
+	size := fmt.Sprintf("%.0f × %.0f pt", m.PageWidth, m.PageHeight)
+	if name := PageSizeName(m.PageWidth, m.PageHeight); name != "" {
+		size += " (" + name + ")"
+	}
+	return size
+}
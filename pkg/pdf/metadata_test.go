@@ -0,0 +1,166 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetMetadata_InfoDictionary(t *testing.T) {
+	testPDF := getTestPDF("simple.pdf")
+	if _, err := os.Stat(testPDF); os.IsNotExist(err) {
+		t.Skip("Test PDF fixture not found:", testPDF)
+	}
+
+	doc, err := NewDocument(testPDF, 5)
+	if err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	meta := doc.GetMetadata()
+
+	tests := []struct {
+		field string
+		got   string
+		want  string
+	}{
+		{"Title", meta.Title, "LUMOS Test Document"},
+		{"Author", meta.Author, "LUMOS Test Suite"},
+		{"Subject", meta.Subject, "Testing PDF Reading Functionality"},
+		{"Creator", meta.Creator, "LUMOS Fixture Generator"},
+		{"Producer", meta.Producer, "ReportLab PDF Library - www.reportlab.com"},
+		{"PDFVersion", meta.PDFVersion, "1.3"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	wantCreated := time.Date(2025, 11, 1, 4, 45, 9, 0, time.FixedZone("", -6*3600))
+	if !meta.CreationDate.Equal(wantCreated) {
+		t.Errorf("CreationDate = %v, want %v", meta.CreationDate, wantCreated)
+	}
+
+	if meta.PageWidth != 612 || meta.PageHeight != 792 {
+		t.Errorf("Page size = %vx%v, want 612x792", meta.PageWidth, meta.PageHeight)
+	}
+	if meta.Encrypted {
+		t.Error("Encrypted = true, want false")
+	}
+}
+
+func TestGetMetadata_XMPFallback(t *testing.T) {
+	xmp := `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:pdf="http://ns.adobe.com/pdf/1.3/"
+    xmp:CreatorTool="LaTeX with hyperref"
+    pdf:Producer="pdfTeX-1.40.25">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Register Reference Manual</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Ada Lovelace</rdf:li><rdf:li>Charles Babbage</rdf:li></rdf:Seq></dc:creator>
+   <pdf:Keywords>registers, timers</pdf:Keywords>
+   <xmp:CreateDate>2024-03-15T09:30:00+01:00</xmp:CreateDate>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+	b := newTestPDFBuilder()
+	b.addPage([]string{"Body"}, "/Rotate 90")
+	stream := b.add(fmt.Sprintf("<< /Type /Metadata /Subtype /XML /Length %d >>\nstream\n%s\nendstream", len(xmp)+1, xmp))
+	info := b.add("<< /Title (Info Title Wins) >>")
+	b.catalog = append(b.catalog, "/Metadata "+ref(stream))
+	b.trailer = append(b.trailer, "/Info "+ref(info))
+
+	doc, err := NewDocument(b.write(t), 5)
+	if err != nil {
+		t.Fatalf("NewDocument() unexpected error: %v", err)
+	}
+	meta := doc.GetMetadata()
+
+	if meta.Title != "Info Title Wins" {
+		t.Errorf("Title = %q, want /Info title to take precedence", meta.Title)
+	}
+	if meta.Author != "Ada Lovelace, Charles Babbage" {
+		t.Errorf("Author = %q", meta.Author)
+	}
+	if meta.Keywords != "registers, timers" {
+		t.Errorf("Keywords = %q", meta.Keywords)
+	}
+	if meta.Creator != "LaTeX with hyperref" {
+		t.Errorf("Creator = %q", meta.Creator)
+	}
+	if meta.Producer != "pdfTeX-1.40.25" {
+		t.Errorf("Producer = %q", meta.Producer)
+	}
+	if meta.CreationDate.IsZero() || meta.CreationDate.Year() != 2024 {
+		t.Errorf("CreationDate = %v, want 2024-03-15", meta.CreationDate)
+	}
+	if meta.PDFVersion != "1.7" {
+		t.Errorf("PDFVersion = %q, want 1.7", meta.PDFVersion)
+	}
+	// /Rotate 90 swaps the reported dimensions
+	if meta.PageWidth != 792 || meta.PageHeight != 612 {
+		t.Errorf("Page size = %vx%v, want 792x612", meta.PageWidth, meta.PageHeight)
+	}
+}
+
+func TestGetMetadata_TitleFallsBackToFileName(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"Untitled"}, "")
+
+	doc, err := NewDocument(b.write(t), 5)
+	if err != nil {
+		t.Fatalf("NewDocument() unexpected error: %v", err)
+	}
+
+	if title := doc.GetMetadata().Title; title != "test.pdf" {
+		t.Errorf("Title = %q, want file name", title)
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"D:20240315093000Z", time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)},
+		{"D:20240315093000+02'00'", time.Date(2024, 3, 15, 9, 30, 0, 0, time.FixedZone("", 2*3600))},
+		{"D:20240315093000-05'30", time.Date(2024, 3, 15, 9, 30, 0, 0, time.FixedZone("", -(5*3600+30*60)))},
+		{"D:2024", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"20240315", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"yesterday", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		got := parsePDFDate(tt.in)
+		if !got.Equal(tt.want) {
+			t.Errorf("parsePDFDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPageSize(t *testing.T) {
+	tests := []struct {
+		w, h float64
+		want string
+	}{
+		{612, 792, "612 × 792 pt (Letter)"},
+		{842, 595, "842 × 595 pt (A4)"},
+		{500, 500, "500 × 500 pt"},
+		{0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		got := Metadata{PageWidth: tt.w, PageHeight: tt.h}.FormatPageSize()
+		if got != tt.want {
+			t.Errorf("FormatPageSize(%v, %v) = %q, want %q", tt.w, tt.h, got, tt.want)
+		}
+	}
+}
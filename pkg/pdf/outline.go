@@ -0,0 +1,91 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxOutlineDepth guards against malformed outlines nested without bound
+const maxOutlineDepth = 32
+
+// ExtractOutline reads the document outline (bookmarks) stored in the
+// catalog's /Outlines tree. Destinations, GoTo actions and named
+// destinations are resolved to page numbers. Returns an empty slice if the
+// document has no outline.
+func (d *Document) ExtractOutline() ([]TOCEntry, error) {
+	var entries []TOCEntry
+	err := d.withReader(func(r *pdf.Reader) error {
+		entries = readOutline(r, d.crypt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outline: %w", err)
+	}
+	return entries, nil
+}
+
+// readOutline converts the /Outlines tree into hierarchical TOC entries
+func readOutline(r *pdf.Reader, crypt *securityHandler) []TOCEntry {
+	outlines := r.Trailer().Key("Root").Key("Outlines")
+	if outlines.Kind() != pdf.Dict {
+		return []TOCEntry{}
+	}
+
+	dests := newDestResolver(r, crypt)
+	visited := make(map[objectRef]bool)
+
+	var walk func(item pdf.Value, level int) []TOCEntry
+	walk = func(item pdf.Value, level int) []TOCEntry {
+		entries := []TOCEntry{}
+
+		for ; item.Kind() == pdf.Dict; item = item.Key("Next") {
+			// Sibling chains in broken files sometimes loop back on themselves
+			ref := refOf(item)
+			if visited[ref] {
+				break
+			}
+			visited[ref] = true
+
+			entry := TOCEntry{
+				Title:    cleanOutlineTitle(crypt.text(item.Key("Title"))),
+				Level:    level,
+				Children: []TOCEntry{},
+			}
+
+			entry.Page = dests.resolve(item.Key("Dest"))
+			if entry.Page == 0 {
+				entry.Page = dests.resolveAction(item.Key("A"))
+			}
+
+			if level < maxOutlineDepth {
+				entry.Children = walk(item.Key("First"), level+1)
+			}
+
+			// Container entries without a destination point at their first child
+			if entry.Page == 0 && len(entry.Children) > 0 {
+				entry.Page = entry.Children[0].Page
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+
+	return walk(outlines.Key("First"), 1)
+}
+
+// cleanOutlineTitle collapses whitespace and strips control characters
+// that some producers embed in outline titles
+func cleanOutlineTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, title)
+	return strings.Join(strings.Fields(title), " ")
+}
@@ -0,0 +1,153 @@
+package pdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+// buildOutlinePDF creates a 4-page document whose outline exercises explicit
+// destinations, GoTo actions, name-tree destinations and legacy /Dests
+func buildOutlinePDF(t *testing.T) string {
+	t.Helper()
+	b := newTestPDFBuilder()
+	var pages []int
+	for i := 1; i <= 4; i++ {
+		pages = append(pages, b.addPage([]string{fmt.Sprintf("Page %d body", i)}, ""))
+	}
+
+	outlines := b.reserve()
+	ch1 := b.reserve()
+	sec11 := b.reserve()
+	sec12 := b.reserve()
+	ch2 := b.reserve()
+	appendix := b.reserve()
+	a1 := b.reserve()
+
+	b.set(outlines, fmt.Sprintf("<< /Type /Outlines /First %s /Last %s /Count 4 >>", ref(ch1), ref(appendix)))
+	b.set(ch1, fmt.Sprintf("<< /Title (Chapter 1) /Parent %s /Next %s /First %s /Last %s /Count 2 /Dest [%s /XYZ 0 792 0] >>",
+		ref(outlines), ref(ch2), ref(sec11), ref(sec12), ref(pages[0])))
+	b.set(sec11, fmt.Sprintf("<< /Title (Section 1.1) /Parent %s /Next %s /A << /S /GoTo /D [%s /Fit] >> >>",
+		ref(ch1), ref(sec12), ref(pages[1])))
+	b.set(sec12, fmt.Sprintf("<< /Title (Section\r1.2 ) /Parent %s /Prev %s /Dest (sec12) >>",
+		ref(ch1), ref(sec11)))
+	b.set(ch2, fmt.Sprintf("<< /Title (Chapter 2) /Parent %s /Prev %s /Next %s /Dest /chap2 >>",
+		ref(outlines), ref(ch1), ref(appendix)))
+	b.set(appendix, fmt.Sprintf("<< /Title (Appendix) /Parent %s /Prev %s /First %s /Last %s /Count 1 >>",
+		ref(outlines), ref(ch2), ref(a1), ref(a1)))
+	b.set(a1, fmt.Sprintf("<< /Title (A.1) /Parent %s /Dest [%s /Fit] >>", ref(appendix), ref(pages[3])))
+
+	nameTree := b.add(fmt.Sprintf("<< /Kids [%s] >>",
+		ref(b.add(fmt.Sprintf("<< /Limits [(sec12) (sec12)] /Names [(sec12) [%s /Fit]] >>", ref(pages[2]))))))
+	legacyDests := b.add(fmt.Sprintf("<< /chap2 << /D [%s /FitH 700] >> >>", ref(pages[3])))
+
+	b.catalog = append(b.catalog,
+		"/Outlines "+ref(outlines),
+		fmt.Sprintf("/Names << /Dests %s >>", ref(nameTree)),
+		"/Dests "+ref(legacyDests))
+
+	return b.write(t)
+}
+
+func TestExtractOutline(t *testing.T) {
+	doc, err := NewDocument(buildOutlinePDF(t), 5)
+	if err != nil {
+		t.Fatalf("NewDocument() unexpected error: %v", err)
+	}
+
+	entries, err := doc.ExtractOutline()
+	if err != nil {
+		t.Fatalf("ExtractOutline() unexpected error: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 top-level entries, got %d: %+v", len(entries), entries)
+	}
+
+	tests := []struct {
+		entry TOCEntry
+		title string
+		page  int
+		level int
+	}{
+		{entries[0], "Chapter 1", 1, 1},
+		{entries[0].Children[0], "Section 1.1", 2, 2},
+		{entries[0].Children[1], "Section 1.2", 3, 2},
+		{entries[1], "Chapter 2", 4, 1},
+		{entries[2], "Appendix", 4, 1},
+		{entries[2].Children[0], "A.1", 4, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if tt.entry.Title != tt.title {
+				t.Errorf("Title = %q, want %q", tt.entry.Title, tt.title)
+			}
+			if tt.entry.Page != tt.page {
+				t.Errorf("Page = %d, want %d", tt.entry.Page, tt.page)
+			}
+			if tt.entry.Level != tt.level {
+				t.Errorf("Level = %d, want %d", tt.entry.Level, tt.level)
+			}
+		})
+	}
+}
+
+func TestExtractOutline_NoOutline(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"No bookmarks here"}, "")
+
+	doc, err := NewDocument(b.write(t), 5)
+	if err != nil {
+		t.Fatalf("NewDocument() unexpected error: %v", err)
+	}
+
+	entries, err := doc.ExtractOutline()
+	if err != nil {
+		t.Fatalf("ExtractOutline() unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries, got %d", len(entries))
+	}
+}
+
+func TestExtractTableOfContents_PrefersOutline(t *testing.T) {
+	doc, err := NewDocument(buildOutlinePDF(t), 5)
+	if err != nil {
+		t.Fatalf("NewDocument() unexpected error: %v", err)
+	}
+
+	toc, err := doc.ExtractTableOfContents()
+	if err != nil {
+		t.Fatalf("ExtractTableOfContents() unexpected error: %v", err)
+	}
+
+	if toc.Source != "metadata" {
+		t.Errorf("Source = %q, want %q", toc.Source, "metadata")
+	}
+	if len(toc.Entries) != 3 || len(toc.Entries[0].Children) != 2 {
+		t.Errorf("Outline nesting not preserved: %+v", toc.Entries)
+	}
+
+	found := toc.FindEntryByPage(3)
+	if len(found) != 1 || found[0].Title != "Section 1.2" {
+		t.Errorf("FindEntryByPage(3) = %+v, want Section 1.2", found)
+	}
+}
+
+func TestCleanOutlineTitle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Chapter 1", "Chapter 1"},
+		{"  Padded  ", "Padded"},
+		{"Line\rBreak", "Line Break"},
+		{"Tab\tand\x00null", "Tab and null"},
+	}
+
+	for _, tt := range tests {
+		if got := cleanOutlineTitle(tt.in); got != tt.want {
+			t.Errorf("cleanOutlineTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
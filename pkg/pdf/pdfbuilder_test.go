@@ -0,0 +1,189 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPDFBuilder assembles small, valid PDF files for tests that need
+// structures the checked-in fixtures do not have (outlines, labels, links...)
+type testPDFBuilder struct {
+	objects []string // objects[i] is the body of object i+1
+	pages   []int    // page object numbers in order
+	pagesID int      // object number of the /Pages node
+	fontID  int      // shared Helvetica font
+	boldID  int      // shared Helvetica-Bold font
+	catalog []string // extra catalog entries
+	trailer []string // extra trailer entries
+	packed  []int    // objects written in an object stream; a cross-reference stream then indexes the file
+
+	encryption *testEncryption // encrypts the file when set
+}
+
+func newTestPDFBuilder() *testPDFBuilder {
+	b := &testPDFBuilder{}
+	b.pagesID = b.reserve()
+	b.fontID = b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	b.boldID = b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>")
+	return b
+}
+
+// reserve allocates an object number whose body is set later
+func (b *testPDFBuilder) reserve() int {
+	b.objects = append(b.objects, "null")
+	return len(b.objects)
+}
+
+// add appends an object and returns its object number
+func (b *testPDFBuilder) add(body string) int {
+	b.objects = append(b.objects, body)
+	return len(b.objects)
+}
+
+// set replaces the body of a reserved object
+func (b *testPDFBuilder) set(id int, body string) {
+	b.objects[id-1] = body
+}
+
+// addPage adds a page showing each line of text at 12pt, top to bottom.
+// Extra page dictionary entries (e.g. /Annots) can be supplied.
+func (b *testPDFBuilder) addPage(lines []string, extra string) int {
+	texts := make([]placedText, len(lines))
+	for i, line := range lines {
+		texts[i] = placedText{x: 72, y: 720 - 16*i, size: 12, text: line}
+	}
+	return b.addPageAt(texts, extra)
+}
+
+// placedText is a string drawn at a position on a page
+type placedText struct {
+	x, y, size int
+	text       string
+	bold       bool
+}
+
+// addPageAt adds a page drawing each text at its position
+func (b *testPDFBuilder) addPageAt(texts []placedText, extra string) int {
+	return b.addPageDrawn(texts, "", extra)
+}
+
+// addPageDrawn adds a page that runs the given drawing operators (e.g.
+// rectangles) before drawing the texts
+func (b *testPDFBuilder) addPageDrawn(texts []placedText, drawing string, extra string) int {
+	var content strings.Builder
+	content.WriteString(drawing)
+	for _, t := range texts {
+		escaped := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(t.text)
+		font := "F1"
+		if t.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&content, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, t.size, t.x, t.y, escaped)
+	}
+	stream := content.String()
+	contentID := b.add(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(stream), stream))
+
+	pageID := b.add(fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R %s >>",
+		b.pagesID, b.fontID, b.boldID, contentID, extra))
+	b.pages = append(b.pages, pageID)
+	return pageID
+}
+
+// ref formats an indirect reference to an object
+func ref(id int) string {
+	return fmt.Sprintf("%d 0 R", id)
+}
+
+// bytes serializes the document with a classic xref table, or a
+// cross-reference stream if objects are packed in an object stream
+func (b *testPDFBuilder) bytes() []byte {
+	kids := make([]string, len(b.pages))
+	for i, id := range b.pages {
+		kids[i] = ref(id)
+	}
+	b.set(b.pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(b.pages)))
+
+	catalogID := b.add(fmt.Sprintf("<< /Type /Catalog /Pages %s %s >>", ref(b.pagesID), strings.Join(b.catalog, " ")))
+	added := 1
+	trailer := b.trailer
+
+	packed := make(map[int]int) // object number to index in the object stream
+	if len(b.packed) > 0 {
+		var index, bodies strings.Builder
+		for i, id := range b.packed {
+			packed[id] = i
+			fmt.Fprintf(&index, "%d %d ", id, bodies.Len())
+			bodies.WriteString(b.objects[id-1] + "\n")
+		}
+		data := index.String() + bodies.String()
+		b.add(fmt.Sprintf("<< /Type /ObjStm /N %d /First %d /Length %d >>\nstream\n%sendstream", len(b.packed), index.Len(), len(data), data))
+		added++
+	}
+
+	var key []byte
+	if b.encryption != nil {
+		var dict string
+		dict, key = b.encryption.values()
+		encryptID := b.add(dict)
+		added++
+		trailer = append(trailer, "/Encrypt "+ref(encryptID), fmt.Sprintf("/ID [<%x> <%x>]", testFileID, testFileID))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+
+	offsets := make([]int, len(b.objects))
+	for i, body := range b.objects {
+		if _, ok := packed[i+1]; ok {
+			continue
+		}
+		if key != nil && i+1 != len(b.objects) {
+			body = b.encryption.encryptObject(key, i+1, body)
+		}
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xrefOffset := buf.Len()
+	if len(packed) == 0 {
+		fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(b.objects)+1)
+		for _, off := range offsets {
+			fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+		}
+		fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %s %s >>\nstartxref\n%d\n%%%%EOF\n",
+			len(b.objects)+1, ref(catalogID), strings.Join(trailer, " "), xrefOffset)
+	} else {
+		// Rows of type, offset or object stream, generation or index
+		objStm := len(b.objects) - added + 2
+		rows := []byte{0, 0, 0, 0, 0, 0xff, 0xff}
+		for i, off := range append(offsets, xrefOffset) {
+			if n, ok := packed[i+1]; ok {
+				rows = append(rows, 2, 0, 0, byte(objStm>>8), byte(objStm), byte(n>>8), byte(n))
+			} else {
+				rows = append(rows, 1, byte(off>>24), byte(off>>16), byte(off>>8), byte(off), 0, 0)
+			}
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d /W [1 4 2] /Root %s %s /Length %d >>\nstream\n%s\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n",
+			len(b.objects)+1, len(b.objects)+2, ref(catalogID), strings.Join(trailer, " "), len(rows), rows, xrefOffset)
+	}
+
+	// Drop the catalog (and the object stream and /Encrypt) so bytes() can
+	// be called again after further edits
+	b.objects = b.objects[:len(b.objects)-added]
+	return buf.Bytes()
+}
+
+// write saves the document to a temp file and returns its path
+func (b *testPDFBuilder) write(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pdf")
+	if err := os.WriteFile(path, b.bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test PDF: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,221 @@
+package pdf
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// PrefetchOptions configures background page prefetching
+type PrefetchOptions struct {
+	Workers int // concurrent page extractions
+	Radius  int // pages to warm on each side of the current page
+}
+
+// DefaultPrefetchOptions returns sensible defaults for prefetching
+func DefaultPrefetchOptions() PrefetchOptions {
+	return PrefetchOptions{
+		Workers: min(runtime.NumCPU(), 4),
+		Radius:  3,
+	}
+}
+
+// PrefetchProgress reports the state of a prefetch job
+type PrefetchProgress struct {
+	Done       int  // pages warmed (or found already cached)
+	Total      int  // pages requested
+	Finished   bool // no more progress will be reported
+	Cancelled  bool // stopped because another job started or Cancel was called
+	BudgetFull bool // stopped because further pages would not fit the cache budget
+}
+
+// Prefetcher warms a Document's page cache in the background. Only one job
+// runs at a time: starting a job cancels the previous one, so jumping
+// elsewhere in the document abandons work that is no longer useful.
+type Prefetcher struct {
+	doc  *Document
+	opts PrefetchOptions
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// NewPrefetcher creates a prefetcher for a document
+func NewPrefetcher(doc *Document, opts PrefetchOptions) *Prefetcher {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Radius < 0 {
+		opts.Radius = 0
+	}
+
+	return &Prefetcher{
+		doc:  doc,
+		opts: opts,
+	}
+}
+
+// Radius returns the number of pages warmed on each side of a page
+func (p *Prefetcher) Radius() int {
+	return p.opts.Radius
+}
+
+// Start cancels any running job and warms the given pages, in order, on a
+// worker pool. A job never caches more than the cache budget holds, so it
+// cannot evict the pages it warmed itself; it stops once the budget is
+// full. Progress is reported on the returned channel, which keeps only the
+// latest update and is closed when the job ends.
+func (p *Prefetcher) Start(pages []int) <-chan PrefetchProgress {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.cancel = cancel
+	p.mu.Unlock()
+
+	progress := newLatest[PrefetchProgress]()
+	go p.run(ctx, cancel, pages, progress)
+	return progress.ch
+}
+
+// Cancel stops the running job, if any
+func (p *Prefetcher) Cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+}
+
+func (p *Prefetcher) run(ctx context.Context, cancel context.CancelFunc, pages []int, progress *latest[PrefetchProgress]) {
+	defer progress.close()
+	defer cancel()
+
+	budget := newPrefetchBudget(p.doc.cache.Stats())
+	var next, done atomic.Int64
+	var budgetFull atomic.Bool
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(p.opts.Workers, len(pages)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= len(pages) {
+					return
+				}
+				if !p.warm(pages[i], budget) {
+					budgetFull.Store(true)
+					cancel()
+					return
+				}
+				progress.send(PrefetchProgress{Done: int(done.Add(1)), Total: len(pages)})
+			}
+		}()
+	}
+	wg.Wait()
+
+	progress.send(PrefetchProgress{
+		Done:       int(done.Load()),
+		Total:      len(pages),
+		Finished:   true,
+		Cancelled:  ctx.Err() != nil && !budgetFull.Load(),
+		BudgetFull: budgetFull.Load(),
+	})
+}
+
+// warm extracts and caches a page, reporting false if it would not fit in
+// the job's budget. Pages that fail to extract are skipped.
+func (p *Prefetcher) warm(pageNum int, budget *prefetchBudget) bool {
+	if pageNum < 1 || pageNum > p.doc.pages || p.doc.cache.Contains(pageNum) {
+		return true
+	}
+
+	page, err := p.doc.extractPage(pageNum)
+	if err != nil {
+		return true
+	}
+	if !budget.reserve(int64(len(page.text)) + page.lines.cost()) {
+		return false
+	}
+	p.doc.cache.putPage(pageNum, page.text, &page.lines, true)
+	return true
+}
+
+// prefetchBudget tracks how much of the cache a job has filled
+type prefetchBudget struct {
+	mu       sync.Mutex
+	bytes    int64
+	maxBytes int64
+	pages    int
+	maxPages int
+}
+
+func newPrefetchBudget(stats CacheStats) *prefetchBudget {
+	return &prefetchBudget{maxBytes: stats.MaxBytes, maxPages: stats.MaxSize}
+}
+
+// reserve accounts for a page of the given size, reporting whether it fits
+func (b *prefetchBudget) reserve(size int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxBytes > 0 && b.bytes+size > b.maxBytes || b.maxPages > 0 && b.pages+1 > b.maxPages {
+		return false
+	}
+	b.bytes += size
+	b.pages++
+	return true
+}
+
+// PagesAround lists up to radius pages on each side of page, nearest
+// first and the following page before the preceding one. Use a radius of
+// total to order the whole document outward from page.
+func PagesAround(page, radius, total int) []int {
+	var pages []int
+	for d := 1; d <= radius; d++ {
+		if page+d <= total {
+			pages = append(pages, page+d)
+		}
+		if page-d >= 1 {
+			pages = append(pages, page-d)
+		}
+		if page+d > total && page-d < 1 {
+			break
+		}
+	}
+	return pages
+}
+
+// latest is a channel that holds only the most recent value sent on it, so
+// a slow consumer sees current progress rather than a backlog
+type latest[T any] struct {
+	mu sync.Mutex
+	ch chan T
+}
+
+func newLatest[T any]() *latest[T] {
+	return &latest[T]{ch: make(chan T, 1)}
+}
+
+// send replaces a value the consumer has not read yet
+func (l *latest[T]) send(v T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.ch:
+	default:
+	}
+	l.ch <- v
+}
+
+func (l *latest[T]) close() {
+	close(l.ch)
+}
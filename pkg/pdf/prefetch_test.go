@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildPrefetchPDF creates a document with n pages of roughly equal text
+func buildPrefetchPDF(t *testing.T, n int) string {
+	t.Helper()
+	b := newTestPDFBuilder()
+	for i := 1; i <= n; i++ {
+		b.addPage([]string{fmt.Sprintf("Page %02d %s", i, strings.Repeat("x", 40))}, "")
+	}
+	return b.write(t)
+}
+
+// drain waits for a prefetch job to finish and returns its final progress
+func drain(ch <-chan PrefetchProgress) PrefetchProgress {
+	var last PrefetchProgress
+	for p := range ch {
+		last = p
+	}
+	return last
+}
+
+func TestPrefetcher_WarmsPages(t *testing.T) {
+	doc, err := OpenDocument(buildPrefetchPDF(t, 10), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	p := NewPrefetcher(doc, PrefetchOptions{Workers: 3, Radius: 2})
+	final := drain(p.Start(PagesAround(5, p.Radius(), doc.GetPageCount())))
+
+	if !final.Finished || final.Cancelled || final.BudgetFull {
+		t.Errorf("final progress = %+v, want finished normally", final)
+	}
+	if final.Done != 4 || final.Total != 4 {
+		t.Errorf("Done/Total = %d/%d, want 4/4", final.Done, final.Total)
+	}
+	for _, page := range []int{3, 4, 6, 7} {
+		if !doc.cache.Contains(page) {
+			t.Errorf("page %d should be prefetched", page)
+		}
+	}
+	if doc.cache.Contains(5) || doc.cache.Contains(8) {
+		t.Error("only the pages requested should be prefetched")
+	}
+
+	// Prefetched pages are served from the cache
+	before := doc.CacheStats().Hits
+	if _, err := doc.GetPage(6); err != nil {
+		t.Fatalf("GetPage(6) unexpected error: %v", err)
+	}
+	if doc.CacheStats().Hits != before+1 {
+		t.Error("GetPage should hit the cache for a prefetched page")
+	}
+}
+
+func TestPrefetcher_StopsAtBudget(t *testing.T) {
+	path := buildPrefetchPDF(t, 10)
+	doc, err := OpenDocument(path, DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	doc.GetPage(1)
+	pageBytes := doc.CacheStats().Bytes // text and lines
+	doc.Close()
+
+	// Room for three pages
+	budget := pageBytes*3 + 1
+	doc, err = OpenDocument(path, DocumentOptions{CacheBytes: budget})
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	p := NewPrefetcher(doc, PrefetchOptions{Workers: 1})
+	final := drain(p.Start(PagesAround(1, 10, 10)))
+
+	if !final.BudgetFull {
+		t.Errorf("final progress = %+v, want BudgetFull", final)
+	}
+	if stats := doc.CacheStats(); stats.CachedPages != 3 || stats.Bytes > budget {
+		t.Errorf("CacheStats = %+v, want 3 pages within %d bytes", stats, budget)
+	}
+	// Nearest pages win
+	for _, n := range []int{2, 3, 4} {
+		if !doc.cache.Contains(n) {
+			t.Errorf("page %d should be cached", n)
+		}
+	}
+}
+
+func TestPrefetcher_StartCancelsPreviousJob(t *testing.T) {
+	doc, err := OpenDocument(buildPrefetchPDF(t, 40), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	p := NewPrefetcher(doc, PrefetchOptions{Workers: 1})
+	first := p.Start(PagesAround(1, 40, 40))
+	second := p.Start([]int{40})
+
+	// The first job is cancelled unless it already finished
+	if final := drain(first); !final.Finished || !final.Cancelled && final.Done != final.Total {
+		t.Errorf("first job final progress = %+v, want cancelled or complete", final)
+	}
+	if final := drain(second); final.Cancelled || final.Done != 1 {
+		t.Errorf("second job final progress = %+v, want 1 page done", final)
+	}
+	if !doc.cache.Contains(40) {
+		t.Error("page 40 should be prefetched by the second job")
+	}
+
+	p.Cancel()
+}
+
+func TestPagesAround(t *testing.T) {
+	tests := []struct {
+		page, radius, total int
+		want                []int
+	}{
+		{5, 2, 10, []int{6, 4, 7, 3}},
+		{1, 2, 10, []int{2, 3}},
+		{10, 2, 10, []int{9, 8}},
+		{2, 10, 4, []int{3, 1, 4}},
+		{1, 3, 1, nil},
+	}
+
+	for _, tt := range tests {
+		got := PagesAround(tt.page, tt.radius, tt.total)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PagesAround(%d, %d, %d) = %v, want %v", tt.page, tt.radius, tt.total, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -14,6 +15,11 @@ type SearchOptions struct {
 	MaxResults    int    // Maximum number of results (0 = unlimited)
 	StartPage     int    // Start search from this page (1-indexed)
 	EndPage       int    // End search at this page (0 = to end)
+
+	// Ranked searches the document's index for words, "phrases" and
+	// prefix* terms, best page first by BM25. The other matching options
+	// don't apply. Fails with ErrIndexNotReady until the index is built.
+	Ranked bool
 }
 
 // SearchFilter filters results based on criteria
@@ -37,6 +43,196 @@ func (d *Document) AdvancedSearch(query string, opts SearchOptions) ([]SearchRes
 		return []SearchResultAdvanced{}, nil
 	}
 
+	plan, err := d.planSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResultAdvanced
+	for _, pageNum := range plan.pages {
+		if plan.maxResults > 0 && len(results) >= plan.maxResults {
+			break
+		}
+
+		pageResults, err := plan.search(pageNum)
+		if err != nil {
+			continue
+		}
+		results = append(results, limitResults(pageResults, plan.maxResults, len(results))...)
+	}
+
+	return results, nil
+}
+
+// SearchUpdate reports the progress of a streaming search
+type SearchUpdate struct {
+	Results  []SearchResultAdvanced // new results, from a single page
+	Searched int                    // pages searched so far
+	Total    int                    // pages to search
+	Finished bool                   // no more updates will be sent
+}
+
+// SearchStream runs AdvancedSearch in the background, sending the results
+// of each page as soon as it has been searched. Updates arrive in page
+// order, or best page first for ranked searches; the last one has Finished
+// set, after which the channel is closed. Cancelling ctx stops the search
+// and closes the channel without a final update. Invalid options, such as
+// a bad regex, are reported before the search starts.
+func (d *Document) SearchStream(ctx context.Context, query string, opts SearchOptions) (<-chan SearchUpdate, error) {
+	plan, err := d.planSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan SearchUpdate, 16)
+	go func() {
+		defer close(updates)
+
+		send := func(update SearchUpdate) bool {
+			select {
+			case updates <- update:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		pages := plan.pages
+		if query == "" {
+			pages = nil
+		}
+		total := len(pages)
+		found := 0
+		for i, pageNum := range pages {
+			if ctx.Err() != nil {
+				return
+			}
+			if plan.maxResults > 0 && found >= plan.maxResults {
+				break
+			}
+
+			pageResults, _ := plan.search(pageNum)
+			pageResults = limitResults(pageResults, plan.maxResults, found)
+			found += len(pageResults)
+
+			update := SearchUpdate{Results: pageResults, Searched: i + 1, Total: total}
+			if !send(update) {
+				return
+			}
+		}
+
+		send(SearchUpdate{Searched: total, Total: total, Finished: true})
+	}()
+
+	return updates, nil
+}
+
+// searchPlan lists the pages a search visits, in order, and how each one
+// is searched
+type searchPlan struct {
+	pages      []int
+	maxResults int
+	search     func(pageNum int) ([]SearchResultAdvanced, error)
+}
+
+// planSearch validates the options and decides which pages to search.
+// Ranked searches visit the pages the index matched, best first. Other
+// searches visit the pages in range in order, skipping those the index
+// rules out once it is available, and rank results by page with BM25.
+func (d *Document) planSearch(query string, opts SearchOptions) (*searchPlan, error) {
+	opts, regex, err := d.prepareSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+	idx := d.Index()
+	if opts.Ranked {
+		return d.planRankedSearch(idx, query, opts)
+	}
+
+	var scores map[int]float64
+	var best float64
+	if idx != nil && !opts.RegexMode {
+		scores, _ = idx.substringScores(query)
+		for _, score := range scores {
+			best = max(best, score)
+		}
+	}
+
+	plan := &searchPlan{maxResults: opts.MaxResults}
+	for pageNum := opts.StartPage; pageNum <= opts.EndPage; pageNum++ {
+		if _, ok := scores[pageNum]; scores == nil || ok {
+			plan.pages = append(plan.pages, pageNum)
+		}
+	}
+	plan.search = func(pageNum int) ([]SearchResultAdvanced, error) {
+		results, err := d.searchPage(pageNum, query, opts, regex)
+		if best > 0 {
+			for i := range results {
+				results[i].Relevance = scores[pageNum] / best
+			}
+		}
+		return results, err
+	}
+	return plan, nil
+}
+
+// planRankedSearch plans a search of the index, visiting the matching
+// pages best first
+func (d *Document) planRankedSearch(idx *Index, query string, opts SearchOptions) (*searchPlan, error) {
+	if idx == nil {
+		return nil, ErrIndexNotReady
+	}
+
+	plan := &searchPlan{maxResults: opts.MaxResults}
+	hits := make(map[int]IndexHit)
+	var best float64
+	for _, hit := range idx.Search(query) {
+		if hit.Page < opts.StartPage || hit.Page > opts.EndPage {
+			continue
+		}
+		best = max(best, hit.Score)
+		hits[hit.Page] = hit
+		plan.pages = append(plan.pages, hit.Page)
+	}
+
+	plan.search = func(pageNum int) ([]SearchResultAdvanced, error) {
+		page, err := d.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		hit := hits[pageNum]
+		results := make([]SearchResultAdvanced, 0, len(hit.Matches))
+		for _, m := range hit.Matches {
+			if m.End > len(page.Text) {
+				continue
+			}
+			lineNum, colNum := getLineAndColumn(page.Text, m.Start)
+			before := extractContextAdvanced(page.Text, m.Start, -40)
+			after := extractContextAdvanced(page.Text, m.End, 40)
+			results = append(results, SearchResultAdvanced{
+				SearchResult: SearchResult{
+					PageNum:       pageNum,
+					LineNum:       lineNum,
+					ColumnNum:     colNum,
+					MatchText:     page.Text[m.Start:m.End],
+					ContextBefore: before,
+					ContextAfter:  after,
+					Start:         m.Start,
+					End:           m.End,
+				},
+				MatchCount:  len(hit.Matches),
+				Relevance:   hit.Score / best,
+				PreviewText: formatPreview(before, page.Text[m.Start:m.End], after),
+			})
+		}
+		return results, nil
+	}
+	return plan, nil
+}
+
+// prepareSearch validates search options and compiles the regex, if any
+func (d *Document) prepareSearch(query string, opts SearchOptions) (SearchOptions, *regexp.Regexp, error) {
 	// Validate options
 	if opts.MaxResults < 0 {
 		opts.MaxResults = 0
@@ -50,7 +246,7 @@ func (d *Document) AdvancedSearch(query string, opts SearchOptions) ([]SearchRes
 
 	// Compile regex if needed
 	var regex *regexp.Regexp
-	if opts.RegexMode {
+	if opts.RegexMode && !opts.Ranked && query != "" {
 		var err error
 		flags := ""
 		if !opts.CaseSensitive {
@@ -58,50 +254,51 @@ func (d *Document) AdvancedSearch(query string, opts SearchOptions) ([]SearchRes
 		}
 		regex, err = regexp.Compile(flags + query)
 		if err != nil {
-			return nil, fmt.Errorf("invalid regex pattern: %w", err)
+			return opts, nil, fmt.Errorf("invalid regex pattern: %w", err)
 		}
 	}
 
-	var results []SearchResultAdvanced
-	resultCount := 0
-
-	for pageNum := opts.StartPage; pageNum <= opts.EndPage; pageNum++ {
-		if opts.MaxResults > 0 && resultCount >= opts.MaxResults {
-			break
-		}
-
-		page, err := d.GetPage(pageNum)
-		if err != nil {
-			continue
-		}
-
-		pageMatches := findAdvancedMatches(page.Text, query, opts, regex)
-		for _, match := range pageMatches {
-			if opts.MaxResults > 0 && resultCount >= opts.MaxResults {
-				break
-			}
+	return opts, regex, nil
+}
 
-			result := SearchResultAdvanced{
-				SearchResult: SearchResult{
-					PageNum:       pageNum,
-					LineNum:       match.LineNum,
-					ColumnNum:     match.ColumnNum,
-					MatchText:     match.Text,
-					ContextBefore: match.ContextBefore,
-					ContextAfter:  match.ContextAfter,
-				},
-				MatchCount:  len(pageMatches),
-				Relevance:   calculateRelevance(match.Text, query),
-				PreviewText: formatPreview(match.ContextBefore, match.Text, match.ContextAfter),
-			}
-			results = append(results, result)
-			resultCount++
-		}
+// searchPage finds all matches on a single page
+func (d *Document) searchPage(pageNum int, query string, opts SearchOptions, regex *regexp.Regexp) ([]SearchResultAdvanced, error) {
+	page, err := d.GetPage(pageNum)
+	if err != nil {
+		return nil, err
 	}
 
+	pageMatches := findAdvancedMatches(page.Text, query, opts, regex)
+	results := make([]SearchResultAdvanced, 0, len(pageMatches))
+	for _, match := range pageMatches {
+		results = append(results, SearchResultAdvanced{
+			SearchResult: SearchResult{
+				PageNum:       pageNum,
+				LineNum:       match.LineNum,
+				ColumnNum:     match.ColumnNum,
+				MatchText:     match.Text,
+				ContextBefore: match.ContextBefore,
+				ContextAfter:  match.ContextAfter,
+				Start:         match.Start,
+				End:           match.End,
+			},
+			MatchCount:  len(pageMatches),
+			Relevance:   calculateRelevance(match.Text, query),
+			PreviewText: formatPreview(match.ContextBefore, match.Text, match.ContextAfter),
+		})
+	}
 	return results, nil
 }
 
+// limitResults trims results so that no more than maxResults are returned
+// in total, given that found have been returned already (0 = unlimited)
+func limitResults(results []SearchResultAdvanced, maxResults, found int) []SearchResultAdvanced {
+	if maxResults > 0 && found+len(results) > maxResults {
+		return results[:max(maxResults-found, 0)]
+	}
+	return results
+}
+
 // findAdvancedMatches finds matches in text with advanced options
 func findAdvancedMatches(text, query string, opts SearchOptions, regex *regexp.Regexp) []Match {
 	var matches []Match
@@ -129,6 +326,8 @@ func findAdvancedMatches(text, query string, opts SearchOptions, regex *regexp.R
 				ColumnNum:      colNum,
 				ContextBefore:  extractContextAdvanced(text, start, -40),
 				ContextAfter:   extractContextAdvanced(text, end, 40),
+				Start:          start,
+				End:            end,
 			}
 			matches = append(matches, match)
 		}
@@ -149,6 +348,8 @@ func findAdvancedMatches(text, query string, opts SearchOptions, regex *regexp.R
 					ColumnNum:      pos,
 					ContextBefore:  extractLineContext(line.Text, pos, -30),
 					ContextAfter:   extractLineContext(line.Text, pos+len(query), 30),
+					Start:          line.StartPos + pos,
+					End:            line.StartPos + pos + len(query),
 				}
 				matches = append(matches, match)
 			}
@@ -1,6 +1,9 @@
 package pdf
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 	"testing"
@@ -421,3 +424,112 @@ func TestSearchOptions_Validation(t *testing.T) {
 		t.Errorf("StartPage should be normalized to 1, got %d", opts.StartPage)
 	}
 }
+
+func TestSearchStream(t *testing.T) {
+	b := newTestPDFBuilder()
+	for i := 1; i <= 6; i++ {
+		word := "plain"
+		if i%2 == 0 {
+			word = "needle"
+		}
+		b.addPage([]string{fmt.Sprintf("Page %d %s", i, word)}, "")
+	}
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	t.Run("streams results in page order", func(t *testing.T) {
+		ch, err := doc.SearchStream(context.Background(), "NEEDLE", SearchOptions{})
+		if err != nil {
+			t.Fatalf("SearchStream() unexpected error: %v", err)
+		}
+		var pages []int
+		var last SearchUpdate
+		for update := range ch {
+			for _, r := range update.Results {
+				pages = append(pages, r.PageNum)
+			}
+			last = update
+		}
+		if !reflect.DeepEqual(pages, []int{2, 4, 6}) {
+			t.Errorf("result pages = %v, want [2 4 6]", pages)
+		}
+		if !last.Finished || last.Searched != 6 || last.Total != 6 {
+			t.Errorf("final update = %+v, want finished after 6 pages", last)
+		}
+	})
+
+	t.Run("respects options", func(t *testing.T) {
+		opts := SearchOptions{CaseSensitive: true, MaxResults: 1, StartPage: 3}
+		ch, err := doc.SearchStream(context.Background(), "needle", opts)
+		if err != nil {
+			t.Fatalf("SearchStream() unexpected error: %v", err)
+		}
+		var pages []int
+		for update := range ch {
+			for _, r := range update.Results {
+				pages = append(pages, r.PageNum)
+			}
+		}
+		if !reflect.DeepEqual(pages, []int{4}) {
+			t.Errorf("result pages = %v, want [4]", pages)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ch, err := doc.SearchStream(ctx, "needle", SearchOptions{})
+		if err != nil {
+			t.Fatalf("SearchStream() unexpected error: %v", err)
+		}
+		for update := range ch {
+			if update.Finished {
+				t.Error("cancelled search should not report Finished")
+			}
+		}
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		if _, err := doc.SearchStream(context.Background(), "[", SearchOptions{RegexMode: true}); err == nil {
+			t.Error("SearchStream() should reject an invalid regex")
+		}
+	})
+}
+
+// TestFindAdvancedMatches_Offsets verifies match byte ranges in every mode
+func TestFindAdvancedMatches_Offsets(t *testing.T) {
+	text := "alpha betas\nBeta beta gamma"
+	tests := []struct {
+		name  string
+		query string
+		opts  SearchOptions
+		want  []string
+	}{
+		{"case-insensitive", "beta", SearchOptions{}, []string{"beta", "Beta", "beta"}},
+		{"whole word", "beta", SearchOptions{WholeWord: true}, []string{"Beta", "beta"}},
+		{"regex", `b\w+a\b`, SearchOptions{RegexMode: true, CaseSensitive: true}, []string{"beta"}},
+		{"regex across lines", `betas\nbeta`, SearchOptions{RegexMode: true}, []string{"betas\nBeta"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var regex *regexp.Regexp
+			if tt.opts.RegexMode {
+				regex = regexp.MustCompile("(?i)" + tt.query)
+				if tt.opts.CaseSensitive {
+					regex = regexp.MustCompile(tt.query)
+				}
+			}
+			var got []string
+			for _, m := range findAdvancedMatches(text, tt.query, tt.opts, regex) {
+				got = append(got, text[m.Start:m.End])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package pdf
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Table detection
+const (
+	cellGap       = 1.0 // horizontal gap between words, relative to the font size, that separates cells
+	minTableRows  = 3   // fewest rows a table has
+	ruleThickness = 2.0 // rectangles thinner than this, in points, are ruling lines
+)
+
+// Table is a grid of text found on a page
+type Table struct {
+	Rows  [][]TableCell // cells of each row, left to right; every row has one per column
+	Ruled bool          // the table is drawn with ruling lines
+
+	// Start and End delimit the table's lines in the page's
+	// layout-extracted text (see ExtractLayout), or are -1 for documents
+	// extracted in another mode
+	Start, End int
+}
+
+// TableCell is the text in one cell of a table
+type TableCell struct {
+	Text string
+
+	// Offset is the byte offset of Text in the page's layout-extracted
+	// text, or -1 for empty cells and documents extracted in another mode
+	Offset int
+}
+
+// Columns returns the number of columns of the table
+func (t *Table) Columns() int {
+	if len(t.Rows) == 0 {
+		return 0
+	}
+	return len(t.Rows[0])
+}
+
+// Cells returns the text of each cell, row by row
+func (t *Table) Cells() [][]string {
+	cells := make([][]string, len(t.Rows))
+	for i, row := range t.Rows {
+		cells[i] = make([]string, len(row))
+		for j, cell := range row {
+			cells[i][j] = cell.Text
+		}
+	}
+	return cells
+}
+
+// CSV returns the table as comma-separated values
+func (t *Table) CSV() string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.WriteAll(t.Cells()) // writing to a strings.Builder cannot fail
+	return sb.String()
+}
+
+// Markdown returns the table as a Markdown table, with its first row as
+// the header
+func (t *Table) Markdown() string {
+	var sb strings.Builder
+	for i, row := range t.Cells() {
+		for j, cell := range row {
+			row[j] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		fmt.Fprintf(&sb, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", len(row)) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// ruling is a ruling line drawn on a page: a vertical line at x = pos or a
+// horizontal one at y = pos, running from one coordinate to another along
+// the other axis
+type ruling struct {
+	vertical bool
+	pos      float64
+	from, to float64
+}
+
+// rulingsOf finds the ruling lines among the rectangles drawn on a page:
+// thin rectangles are lines, and the edges of larger ones (cell borders
+// and shading) count too. Lines stroked as paths are not reported by the
+// PDF reader and so go unnoticed.
+func rulingsOf(rects []pdf.Rect) []ruling {
+	var rulings []ruling
+	for _, r := range rects {
+		left, right := math.Min(r.Min.X, r.Max.X), math.Max(r.Min.X, r.Max.X)
+		bottom, top := math.Min(r.Min.Y, r.Max.Y), math.Max(r.Min.Y, r.Max.Y)
+		thinX, thinY := right-left <= ruleThickness, top-bottom <= ruleThickness
+		switch {
+		case thinX && thinY:
+		case thinX:
+			rulings = append(rulings, ruling{vertical: true, pos: (left + right) / 2, from: bottom, to: top})
+		case thinY:
+			rulings = append(rulings, ruling{pos: (bottom + top) / 2, from: left, to: right})
+		default:
+			rulings = append(rulings,
+				ruling{vertical: true, pos: left, from: bottom, to: top},
+				ruling{vertical: true, pos: right, from: bottom, to: top},
+				ruling{pos: bottom, from: left, to: right},
+				ruling{pos: top, from: left, to: right})
+		}
+	}
+	return rulings
+}
+
+// lineCell is a cell of a table row with its horizontal extent
+type lineCell struct {
+	TableCell
+	left, right float64
+}
+
+// lineCells splits a line into cells: runs of words separated by gaps
+// wider than cellGap or by vertical ruling lines
+func lineCells(line textLine, rulings []ruling) []lineCell {
+	var cells []lineCell
+	pos := 0 // offset of the word in line.text
+	for i, e := range line.elems {
+		right := e.X + elementWidth(e)
+		if i > 0 {
+			prev := cells[len(cells)-1]
+			size := math.Max(e.FontSize, line.elems[i-1].FontSize)
+			if size <= 0 {
+				size = 1
+			}
+			if e.X-prev.right < cellGap*size && !ruledBetween(rulings, prev.right, e.X, line.y, size) {
+				cells[len(cells)-1].Text += " " + e.Text
+				cells[len(cells)-1].right = math.Max(prev.right, right)
+				pos += len(e.Text) + 1
+				continue
+			}
+		}
+		cell := TableCell{Text: e.Text, Offset: line.offset + pos}
+		cells = append(cells, lineCell{TableCell: cell, left: e.X, right: right})
+		pos += len(e.Text) + 1
+	}
+	return cells
+}
+
+// ruledBetween reports whether a vertical ruling line crosses the line of
+// text on baseline y between the given positions
+func ruledBetween(rulings []ruling, from, to, y, size float64) bool {
+	for _, r := range rulings {
+		if r.vertical && r.pos >= from-1 && r.pos <= to+1 && r.from <= y+size && r.to >= y {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectTables finds tables among text elements from the alignment of
+// their words alone. Document.GetPageTables also uses the ruling lines
+// drawn on the page.
+func (la *LayoutAnalyzer) DetectTables(elements []TextElement) []Table {
+	lines, _ := la.textLines(elements)
+	return la.detectTables(lines, nil)
+}
+
+// detectTables finds tables in the lines of a page: runs of at least
+// minTableRows consecutive lines that break into cells, whose cells line up
+// in two or more columns. Two columns split at a page gutter are taken to
+// be text columns rather than a table unless ruling lines are drawn.
+func (la *LayoutAnalyzer) detectTables(lines []textLine, rulings []ruling) []Table {
+	gutters := la.findGutters(lines)
+
+	var tables []Table
+	var run []textLine
+	var runCells [][]lineCell
+	flush := func() {
+		if table, ok := buildTable(run, runCells, rulings, gutters); ok {
+			tables = append(tables, table)
+		}
+		run, runCells = nil, nil
+	}
+
+	for _, line := range lines {
+		cells := lineCells(line, rulings)
+		if len(cells) < 2 {
+			flush()
+			continue
+		}
+		run = append(run, line)
+		runCells = append(runCells, cells)
+	}
+	flush()
+
+	return tables
+}
+
+// buildTable lays out the cells of consecutive lines as a table, if they
+// form one. The columns are the stretches of the page covered by the
+// cells, separated by gaps that no cell crosses.
+func buildTable(lines []textLine, cells [][]lineCell, rulings []ruling, gutters []float64) (Table, bool) {
+	if len(lines) < minTableRows {
+		return Table{}, false
+	}
+
+	// Merge the extents of the cells into columns
+	var extents [][2]float64
+	for _, row := range cells {
+		for _, cell := range row {
+			extents = append(extents, [2]float64{cell.left, cell.right})
+		}
+	}
+	sort.Slice(extents, func(i, j int) bool { return extents[i][0] < extents[j][0] })
+	columns := [][2]float64{extents[0]}
+	for _, ext := range extents[1:] {
+		last := &columns[len(columns)-1]
+		if ext[0] <= last[1] {
+			last[1] = math.Max(last[1], ext[1])
+		} else {
+			columns = append(columns, ext)
+		}
+	}
+	if len(columns) < 2 {
+		return Table{}, false
+	}
+
+	table := Table{Rows: make([][]TableCell, len(lines))}
+	for i, row := range cells {
+		table.Rows[i] = make([]TableCell, len(columns))
+		for j := range table.Rows[i] {
+			table.Rows[i][j].Offset = -1
+		}
+		occupied := 0
+		for _, cell := range row {
+			col := 0
+			for col+1 < len(columns) && cell.left >= columns[col+1][0] {
+				col++
+			}
+			dst := &table.Rows[i][col]
+			if dst.Text == "" {
+				*dst = cell.TableCell
+				occupied++
+			} else {
+				dst.Text += " " + cell.Text
+			}
+		}
+		if occupied < 2 {
+			return Table{}, false
+		}
+	}
+
+	top, bottom := lines[0].y, lines[len(lines)-1].y
+	size := 0.0
+	for _, line := range lines {
+		for _, e := range line.elems {
+			size = math.Max(size, e.FontSize)
+		}
+	}
+	left, right := columns[0][0], columns[len(columns)-1][1]
+	horizontal := 0
+	for _, r := range rulings {
+		switch {
+		case r.vertical && r.from <= top+size && r.to >= bottom:
+			for c := 1; c < len(columns); c++ {
+				if r.pos >= columns[c-1][1]-1 && r.pos <= columns[c][0]+1 {
+					table.Ruled = true
+				}
+			}
+		case !r.vertical && r.pos >= bottom-2*size && r.pos <= top+2*size && r.from < right && r.to > left:
+			horizontal++
+		}
+	}
+	table.Ruled = table.Ruled || horizontal >= 2
+
+	if !table.Ruled && len(columns) == 2 {
+		for _, g := range gutters {
+			if g > columns[0][1] && g < columns[1][0] {
+				return Table{}, false
+			}
+		}
+	}
+
+	last := lines[len(lines)-1]
+	table.Start, table.End = lines[0].offset, last.offset+len(last.text)
+	return table, true
+}
+
+// setHasTables records whether an extracted page has tables
+func (d *Document) setHasTables(pageNum int, has bool) {
+	d.tablesMu.Lock()
+	defer d.tablesMu.Unlock()
+	if d.tables == nil {
+		d.tables = make(map[int]bool)
+	}
+	d.tables[pageNum] = has
+}
+
+// hasTables reports whether tables were found when the page was extracted
+func (d *Document) hasTables(pageNum int) bool {
+	d.tablesMu.Lock()
+	defer d.tablesMu.Unlock()
+	return d.tables[pageNum]
+}
+
+// GetPageTables detects the tables on a page from the alignment of its
+// words and the ruling lines drawn on it. Cell offsets refer to the page
+// text only when the document uses ExtractLayout.
+func (d *Document) GetPageTables(pageNum int) ([]Table, error) {
+	if pageNum < 1 || pageNum > d.pages {
+		return nil, fmt.Errorf("page number out of range: %d", pageNum)
+	}
+
+	pl, err := d.readPageLines(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	tables := d.layout.detectTables(pl.lines, pl.rulings)
+	d.setHasTables(pageNum, len(tables) > 0)
+
+	if d.extraction != ExtractLayout {
+		for i := range tables {
+			tables[i].Start, tables[i].End = -1, -1
+			for _, row := range tables[i].Rows {
+				for j := range row {
+					row[j].Offset = -1
+				}
+			}
+		}
+	}
+	return tables, nil
+}
@@ -0,0 +1,136 @@
+package pdf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectTables(t *testing.T) {
+	var elements []TextElement
+	add := func(text string, x, y float64) {
+		elements = append(elements, TextElement{Text: text, X: x, Y: y, FontSize: 10, Width: float64(len(text)) * 5})
+	}
+	row := func(y float64, cells ...string) {
+		for i, cell := range cells {
+			x := 10 + 75*float64(i)
+			for _, word := range strings.Fields(cell) {
+				add(word, x, y)
+				x += float64(len(word))*5 + 2
+			}
+		}
+	}
+	add("Register", 10, 400)
+	add("summary", 52, 400)
+	row(380, "Offset", "Name", "Reset value")
+	row(368, "0x00", "CTRL", "0x00000001")
+	row(356, "0x04", "STATUS", "0x00000000")
+	row(344, "0x08", "DATA TX", "0x00000000")
+	add("All", 10, 320)
+	add("registers", 27, 320)
+
+	la := NewLayoutAnalyzer()
+	tables := la.DetectTables(elements)
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(tables))
+	}
+	table := tables[0]
+
+	want := [][]string{
+		{"Offset", "Name", "Reset value"},
+		{"0x00", "CTRL", "0x00000001"},
+		{"0x04", "STATUS", "0x00000000"},
+		{"0x08", "DATA TX", "0x00000000"},
+	}
+	if got := table.Cells(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Cells() = %q, want %q", got, want)
+	}
+	if table.Ruled {
+		t.Error("table drawn without lines should not be ruled")
+	}
+
+	// Offsets point into the layout-extracted text
+	text := la.ExtractText(elements)
+	if got := text[table.Start:table.End]; !strings.HasPrefix(got, "Offset") || !strings.HasSuffix(got, "0x00000000") {
+		t.Errorf("table text = %q", got)
+	}
+	for _, row := range table.Rows {
+		for _, cell := range row {
+			if text[cell.Offset:cell.Offset+len(cell.Text)] != cell.Text {
+				t.Errorf("cell %q has offset %d", cell.Text, cell.Offset)
+			}
+		}
+	}
+
+	if got, want := table.CSV(), "Offset,Name,Reset value\n0x00,CTRL,0x00000001\n0x04,STATUS,0x00000000\n0x08,DATA TX,0x00000000\n"; got != want {
+		t.Errorf("CSV() = %q, want %q", got, want)
+	}
+	if got, want := strings.SplitN(table.Markdown(), "\n", 3)[:2], []string{"| Offset | Name | Reset value |", "| --- | --- | --- |"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Markdown() starts %q, want %q", got, want)
+	}
+}
+
+func TestDetectTables_TextColumns(t *testing.T) {
+	var elements []TextElement
+	for i := 0; i < 5; i++ {
+		y := 280 - float64(i)*12
+		elements = append(elements,
+			TextElement{Text: fmt.Sprintf("left%d", i), X: 10, Y: y, FontSize: 10, Width: 25},
+			TextElement{Text: fmt.Sprintf("right%d", i), X: 200, Y: y, FontSize: 10, Width: 30})
+	}
+	if tables := NewLayoutAnalyzer().DetectTables(elements); len(tables) != 0 {
+		t.Errorf("text columns detected as tables: %+v", tables)
+	}
+}
+
+func TestMarkdownEscapesPipes(t *testing.T) {
+	table := Table{Rows: [][]TableCell{{{Text: "a|b"}, {Text: "c"}}}}
+	if got, want := table.Markdown(), "| a\\|b | c |\n| --- | --- |\n"; got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
+
+func TestGetPageTables(t *testing.T) {
+	texts := []placedText{{x: 72, y: 740, size: 12, text: "Electrical characteristics"}}
+	for i, row := range [][2]string{{"Parameter", "Value"}, {"Supply voltage", "3.3 V"}, {"Current", "12 mA"}, {"Frequency", "48 MHz"}, {"Temperature", "85 C"}} {
+		y := 690 - 14*i
+		texts = append(texts, placedText{x: 72, y: y, size: 12, text: row[0]}, placedText{x: 320, y: y, size: 12, text: row[1]})
+	}
+
+	b := newTestPDFBuilder()
+	b.addPageDrawn(texts, "72 702 400 0.5 re f\n72 630 400 0.5 re f\n", "")
+	b.addPageAt(texts, "") // the same without lines reads as two text columns
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	page, err := doc.GetPage(1)
+	if err != nil {
+		t.Fatalf("GetPage() unexpected error: %v", err)
+	}
+	if !page.HasTables {
+		t.Error("page 1 should have a table")
+	}
+
+	tables, err := doc.GetPageTables(1)
+	if err != nil {
+		t.Fatalf("GetPageTables() unexpected error: %v", err)
+	}
+	if len(tables) != 1 || !tables[0].Ruled || len(tables[0].Rows) != 5 || tables[0].Columns() != 2 {
+		t.Fatalf("tables = %+v, want one ruled table of 5 rows and 2 columns", tables)
+	}
+	if cell := tables[0].Rows[1][1]; cell.Text != "3.3 V" || page.Text[cell.Offset:cell.Offset+len(cell.Text)] != cell.Text {
+		t.Errorf("cell = %+v in %q", cell, page.Text)
+	}
+
+	if page, err := doc.GetPage(2); err != nil || page.HasTables {
+		t.Errorf("page 2 HasTables = %v (err %v), want false", page != nil && page.HasTables, err)
+	}
+	if _, err := doc.GetPageTables(3); err == nil {
+		t.Error("GetPageTables() should reject pages out of range")
+	}
+}
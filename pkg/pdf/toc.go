@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -26,123 +27,38 @@ func (d *Document) ExtractTableOfContents() (*TableOfContents, error) {
 		return nil, fmt.Errorf("document is nil")
 	}
 
-	// Try to extract from PDF metadata first (future enhancement)
-	// For now, generate from detected headings in content
 	toc := &TableOfContents{
 		Entries: []TOCEntry{},
 		Source:  "none",
 	}
 
-	// Scan first 10 pages for heading patterns
-	headingEntries := make(map[int][]TOCEntry)
-	foundHeadings := false
-
-	for pageNum := 1; pageNum <= minInt(10, d.GetPageCount()); pageNum++ {
-		headings := d.FindHeadings(pageNum)
-		if len(headings) > 0 {
-			foundHeadings = true
-			headingEntries[pageNum] = headings
-		}
-	}
-
-	if foundHeadings {
-		// Build hierarchical structure from detected headings
-		for pageNum := 1; pageNum <= d.GetPageCount(); pageNum++ {
-			if headings, ok := headingEntries[pageNum]; ok {
-				for _, heading := range headings {
-					heading.Page = pageNum
-					toc.Entries = append(toc.Entries, heading)
-				}
-			}
-		}
-		toc.Source = "headings"
+	// Prefer the document outline (bookmarks) when the PDF has one
+	if outline, err := d.ExtractOutline(); err == nil && len(outline) > 0 {
+		toc.Entries = outline
+		toc.Source = "metadata"
+		return toc, nil
 	}
 
-	return toc, nil
+	// Fall back to the headings on every page
+	headings, _ := d.scanHeadings(context.Background(), nil)
+	return headingTOC(headings), nil
 }
 
-// FindHeadings analyzes a page and detects potential headings
-// Uses heuristics: line starting with capital letters, short lines, isolated text
+// FindHeadings detects the headings on a page from the size and weight of
+// their fonts and their numbering, with levels assigned among the page's
+// headings alone. Running headers and footers are left out.
 func (d *Document) FindHeadings(pageNum int) []TOCEntry {
-	page, err := d.GetPage(pageNum)
-	if err != nil {
+	if pageNum < 1 || pageNum > d.pages {
 		return []TOCEntry{}
 	}
-
-	var headings []TOCEntry
-	lines := TextToLines(page.Text)
-
-	// Patterns for detecting headings
-	// Capital letters at start, typically short
-	for i, line := range lines {
-		if len(line.Text) == 0 {
-			continue
-		}
-
-		text := strings.TrimSpace(line.Text)
-		if len(text) == 0 {
-			continue
-		}
-
-		// Skip if line is too long (likely body text)
-		if len(text) > 100 {
-			continue
-		}
-
-		// Check if starts with capital letter
-		if len(text) > 0 && text[0] >= 'A' && text[0] <= 'Z' {
-			// Detect level based on indentation or length heuristics
-			level := detectHeadingLevel(text)
-
-			// Skip lines that are just single words (likely not headings)
-			wordCount := len(strings.Fields(text))
-			if wordCount < 2 && level == 1 {
-				continue // Single word at level 1 is probably not a chapter title
-			}
-
-			// Create TOC entry
-			entry := TOCEntry{
-				Title:    text,
-				Page:     pageNum,
-				Level:    level,
-				Children: []TOCEntry{},
-			}
-
-			// Add to headings if not a duplicate of previous
-			if len(headings) == 0 || headings[len(headings)-1].Title != text {
-				headings = append(headings, entry)
-			}
-		}
-
-		// Limit to reasonable number of headings per page
-		if len(headings) >= 5 {
-			break
-		}
-
-		// Stop after scanning first 50 lines
-		if i > 50 {
-			break
-		}
-	}
-
-	return headings
-}
-
-// detectHeadingLevel determines the heading level based on text characteristics
-func detectHeadingLevel(text string) int {
-	// Very short text (< 5 words) → Level 1 (chapter)
-	words := strings.Fields(text)
-	if len(words) <= 2 {
-		return 1
-	}
-
-	// 3-5 words → Level 2 (section)
-	if len(words) <= 5 {
-		return 2
+	pl, err := d.readPageLines(pageNum)
+	if err != nil {
+		return []TOCEntry{}
 	}
 
-	// 6+ words → Level 3 (subsection)
-	return 3
+	body := bodySizes{}
+	body.add(pl.lines)
+	return headingEntries(d.pageHeadings(pageNum, pl.lines, body.size()))
 }
 
 // BuildHierarchy organizes flat TOC entries into a proper hierarchy
@@ -260,6 +176,13 @@ type RegexHeadingDetector struct {
 	patterns []*regexp.Regexp
 }
 
+// sectionNumber matches numbered headings like "3 Results", "2.1. Scope" or
+// "A.2 Tables", capturing the number
+var sectionNumber = regexp.MustCompile(`^((?:\d+|[A-Z])(?:\.\d+)*)\.?\s+\S`)
+
+// partHeading matches top-level headings like "Chapter 3" or "Appendix B"
+var partHeading = regexp.MustCompile(`^(?i:chapter|part|appendix|section)\s+([0-9]+|[IVXLC]+|[A-Z])\b`)
+
 // NewRegexHeadingDetector creates a detector with common heading patterns
 func NewRegexHeadingDetector() *RegexHeadingDetector {
 	return &RegexHeadingDetector{
@@ -280,3 +203,22 @@ func (d *RegexHeadingDetector) MatchesPattern(text string) bool {
 	}
 	return false
 }
+
+// NumberingLevel returns the depth of the section number a heading starts
+// with: 1 for "Chapter 3" or "3 Results", 2 for "3.1 Method", and so on.
+// Returns 0 if the text is not numbered. A lone letter counts as a number
+// only with subsections ("A.1"), so that words like "A" do not.
+func (d *RegexHeadingDetector) NumberingLevel(text string) int {
+	if partHeading.MatchString(text) {
+		return 1
+	}
+	m := sectionNumber.FindStringSubmatch(text)
+	if m == nil {
+		return 0
+	}
+	depth := strings.Count(m[1], ".") + 1
+	if depth == 1 && (m[1][0] < '0' || m[1][0] > '9') {
+		return 0
+	}
+	return depth
+}
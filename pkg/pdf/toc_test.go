@@ -79,40 +79,6 @@ func TestTableOfContents_MultipleEntries(t *testing.T) {
 	}
 }
 
-// TestDetectHeadingLevel_VeryShort detects level 1 for very short text
-func TestDetectHeadingLevel_VeryShort(t *testing.T) {
-	level := detectHeadingLevel("Chapter")
-	if level != 1 {
-		t.Errorf("Expected level 1 for very short text, got %d", level)
-	}
-
-	level = detectHeadingLevel("Part Two")
-	if level != 1 {
-		t.Errorf("Expected level 1 for 2-word text, got %d", level)
-	}
-}
-
-// TestDetectHeadingLevel_Medium detects level 2 for medium text
-func TestDetectHeadingLevel_Medium(t *testing.T) {
-	level := detectHeadingLevel("Chapter One Introduction")
-	if level != 2 {
-		t.Errorf("Expected level 2 for 3-word text, got %d", level)
-	}
-
-	level = detectHeadingLevel("Getting Started with Python")
-	if level != 2 {
-		t.Errorf("Expected level 2 for 4-word text, got %d", level)
-	}
-}
-
-// TestDetectHeadingLevel_Long detects level 3 for long text
-func TestDetectHeadingLevel_Long(t *testing.T) {
-	level := detectHeadingLevel("Advanced Topics in Computer Science and Engineering")
-	if level != 3 {
-		t.Errorf("Expected level 3 for long text, got %d", level)
-	}
-}
-
 // TestBuildHierarchy_Flat converts flat list to hierarchy
 func TestBuildHierarchy_Flat(t *testing.T) {
 	toc := &TableOfContents{
@@ -377,13 +343,3 @@ func BenchmarkFormatTOC(b *testing.B) {
 		_ = toc.FormatTOC()
 	}
 }
-
-// BenchmarkDetectHeadingLevel benchmarks heading level detection
-func BenchmarkDetectHeadingLevel(b *testing.B) {
-	text := "Chapter One Advanced Topics in Computer Science and Engineering Principles"
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_ = detectHeadingLevel(text)
-	}
-}
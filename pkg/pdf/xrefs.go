@@ -0,0 +1,189 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrCrossRefNotFound is returned by ResolveCrossRef when the document has
+// nothing matching a cross-reference
+var ErrCrossRefNotFound = errors.New("cross-reference target not found")
+
+// CrossRefKind is what a cross-reference refers to
+type CrossRefKind int
+
+const (
+	CrossRefPage    CrossRefKind = iota // "page 12", "p. xiv"
+	CrossRefSection                     // "Section 3.2", "Chapter 4", "Appendix B", "§2"
+	CrossRefFigure                      // "Figure 5", "Fig. 2.1"
+	CrossRefTable                       // "Table 1"
+)
+
+// CrossRef is a reference in the text of a page to another part of the
+// document, found by FindCrossRefs. Unlike a Link it is only text, so
+// where it leads is looked up with ResolveCrossRef.
+type CrossRef struct {
+	Kind  CrossRefKind
+	Label string // what is referred to: "12", "3.2", "B"
+	Text  string // the reference as written: "Section 3.2"
+
+	// Start and End delimit the reference in the text it was found in
+	Start, End int
+}
+
+var (
+	crossRefPattern = regexp.MustCompile(
+		`(?:\b(?i:(pages?|pp?\.)|(sections?|secs?\.|chapters?|chaps?\.|appendix|appendices)|(figures?|figs?\.)|(tables?|tabs?\.))|(§))` +
+			`[ \t\x{a0}]*(\d+(?:[.\-]\d+)*|[A-Z](?:\.\d+)*\b|[ivxlc]+\b)`)
+	digitsPattern       = regexp.MustCompile(`^\d+$`)
+	romanPattern        = regexp.MustCompile(`^c{0,3}(?:xc|xl|l?x{0,3})(?:ix|iv|v?i{0,3})$`)
+	sectionLabelPattern = regexp.MustCompile(`^(?:\d+(?:\.\d+)*|[A-Z](?:\.\d+)*)$`)
+	figureLabelPattern  = regexp.MustCompile(`^\d+(?:[.\-]\d+)*$`)
+)
+
+// FindCrossRefs finds the references to pages, sections, figures and
+// tables in text, in order. Captions and headings ("Figure 3: ...",
+// "Chapter 2" alone on its line) are what references lead to, so they are
+// left out.
+func FindCrossRefs(text string) []CrossRef {
+	var refs []CrossRef
+	for _, m := range crossRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		label, end := text[m[12]:m[13]], m[1]
+		var kind CrossRefKind
+		var valid bool
+		switch {
+		case m[2] >= 0:
+			kind = CrossRefPage
+			// Page ranges lead to their first page
+			label, _, _ = strings.Cut(label, "-")
+			end = m[12] + len(label)
+			valid = digitsPattern.MatchString(label) || romanPattern.MatchString(label)
+		case m[4] >= 0, m[10] >= 0:
+			kind = CrossRefSection
+			valid = sectionLabelPattern.MatchString(label)
+		case m[6] >= 0:
+			kind = CrossRefFigure
+			valid = figureLabelPattern.MatchString(label)
+		case m[8] >= 0:
+			kind = CrossRefTable
+			valid = figureLabelPattern.MatchString(label)
+		}
+		if !valid || isCaption(text, m[0], end) {
+			continue
+		}
+		refs = append(refs, CrossRef{Kind: kind, Label: label, Text: text[m[0]:end], Start: m[0], End: end})
+	}
+	return refs
+}
+
+// isCaption reports whether text[start:end] begins its line and is
+// followed by a colon or period or nothing at all, as in captions and
+// headings
+func isCaption(text string, start, end int) bool {
+	lineStart := strings.LastIndexByte(text[:start], '\n') + 1
+	if strings.TrimSpace(text[lineStart:start]) != "" {
+		return false
+	}
+	rest, _, _ := strings.Cut(text[end:], "\n")
+	rest = strings.TrimSpace(rest)
+	return rest == "" || rest[0] == ':' || rest[0] == '.'
+}
+
+// ResolveCrossRef returns the page a cross-reference leads to. Pages are
+// looked up by label. Sections are looked up in the outline, then, like
+// figures and tables, by their heading or caption in the page text.
+// Returns ErrCrossRefNotFound if nothing matches.
+func (d *Document) ResolveCrossRef(ref CrossRef) (int, error) {
+	switch ref.Kind {
+	case CrossRefPage:
+		if pageNum, err := d.ResolvePage(ref.Label); err == nil {
+			return pageNum, nil
+		}
+	case CrossRefSection:
+		if pageNum := d.outlinePage(ref); pageNum > 0 {
+			return pageNum, nil
+		}
+		if pageNum := d.findLine(headingPatterns(ref)); pageNum > 0 {
+			return pageNum, nil
+		}
+	case CrossRefFigure, CrossRefTable:
+		if pageNum := d.findLine(captionPatterns(ref)); pageNum > 0 {
+			return pageNum, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %w", ref.Text, ErrCrossRefNotFound)
+}
+
+// outlinePage returns the page of the outline entry titled with a
+// section's number, or 0
+func (d *Document) outlinePage(ref CrossRef) int {
+	outline, err := d.ExtractOutline()
+	if err != nil {
+		return 0
+	}
+	title := regexp.MustCompile(`(?i)^(?:(?:section|chapter|appendix|§)\s*)?` + regexp.QuoteMeta(ref.Label) + `(?:[.:)]|\s|$)`)
+
+	var find func(entries []TOCEntry) int
+	find = func(entries []TOCEntry) int {
+		for _, entry := range entries {
+			if entry.Page > 0 && title.MatchString(strings.TrimSpace(entry.Title)) {
+				return entry.Page
+			}
+			if pageNum := find(entry.Children); pageNum > 0 {
+				return pageNum
+			}
+		}
+		return 0
+	}
+	return find(outline)
+}
+
+// headingPatterns match the heading of a section, most telling first:
+// "Chapter 3" or "Appendix B" on its own line, then "3.2 Results"
+func headingPatterns(ref CrossRef) []*regexp.Regexp {
+	label := regexp.QuoteMeta(ref.Label)
+	return []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^(?:section|chapter|appendix)\s+` + label + `\.?(?:\s*[:.\x{2014}-]|$)`),
+		regexp.MustCompile(`^(?:(?i:section|chapter|appendix)\s+)?` + label + `\.?\s+\p{Lu}`),
+	}
+}
+
+// captionPatterns match the caption of a figure or table: "Figure 4:" or
+// "Figure 4." first, then any line starting with the figure's name
+func captionPatterns(ref CrossRef) []*regexp.Regexp {
+	noun := `(?:figure|fig\.)`
+	if ref.Kind == CrossRefTable {
+		noun = `(?:table|tab\.)`
+	}
+	prefix := `(?i)^` + noun + `\s*` + regexp.QuoteMeta(ref.Label)
+	return []*regexp.Regexp{
+		regexp.MustCompile(prefix + `\s*[:.](?:\s|$)`),
+		regexp.MustCompile(prefix + `(?:\s|$)`),
+	}
+}
+
+// findLine returns the first page with a line matching the first of the
+// patterns that matches anywhere in the document, or 0
+func (d *Document) findLine(patterns []*regexp.Regexp) int {
+	texts := make([]string, d.pages+1)
+	for pageNum := 1; pageNum <= d.pages; pageNum++ {
+		page, err := d.GetPage(pageNum)
+		if err != nil {
+			continue
+		}
+		texts[pageNum] = page.Text
+	}
+
+	for _, pattern := range patterns {
+		for pageNum := 1; pageNum <= d.pages; pageNum++ {
+			for _, line := range strings.Split(texts[pageNum], "\n") {
+				if pattern.MatchString(strings.TrimSpace(line)) {
+					return pageNum
+				}
+			}
+		}
+	}
+	return 0
+}
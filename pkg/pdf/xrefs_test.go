@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFindCrossRefs(t *testing.T) {
+	text := "As shown in Figure 3 and Fig. 2.1, see Section 4.2 (or §5)\n" +
+		"and Table 1 on page 12, pp. 30-34 and p. xiv.\n" +
+		"Chapter 2 covers Appendix B. The page is mid-way, the ice cream\n" +
+		"Figure 3: A caption\n" +
+		"Chapter 7\n"
+
+	want := []struct {
+		kind  CrossRefKind
+		label string
+		text  string
+	}{
+		{CrossRefFigure, "3", "Figure 3"},
+		{CrossRefFigure, "2.1", "Fig. 2.1"},
+		{CrossRefSection, "4.2", "Section 4.2"},
+		{CrossRefSection, "5", "§5"},
+		{CrossRefTable, "1", "Table 1"},
+		{CrossRefPage, "12", "page 12"},
+		{CrossRefPage, "30", "pp. 30"},
+		{CrossRefPage, "xiv", "p. xiv"},
+		{CrossRefSection, "2", "Chapter 2"},
+		{CrossRefSection, "B", "Appendix B"},
+	}
+
+	refs := FindCrossRefs(text)
+	if len(refs) != len(want) {
+		t.Fatalf("FindCrossRefs() = %d refs, want %d: %+v", len(refs), len(want), refs)
+	}
+	for i, w := range want {
+		ref := refs[i]
+		if ref.Kind != w.kind || ref.Label != w.label || ref.Text != w.text || text[ref.Start:ref.End] != w.text {
+			t.Errorf("ref %d = %+v, want %v %q %q", i, ref, w.kind, w.label, w.text)
+		}
+	}
+}
+
+func TestResolveCrossRef(t *testing.T) {
+	b := newTestPDFBuilder()
+	b.addPage([]string{"See Figure 2, Table 1 and Section 3.1 on page 3."}, "")
+	second := b.addPage([]string{"Figure 2 shows a chart.", "Figure 2: Results of the chart"}, "")
+	b.addPage([]string{"3.1 Methods", "Table 1. Parameters"}, "")
+	b.addPage([]string{"Appendix A", "Proofs"}, "")
+
+	outlines := b.reserve()
+	item := b.add(fmt.Sprintf("<< /Title (Chapter 2 Background) /Parent %s /Dest [%s /Fit] >>", ref(outlines), ref(second)))
+	b.set(outlines, fmt.Sprintf("<< /Type /Outlines /First %s /Last %s /Count 1 >>", ref(item), ref(item)))
+	b.catalog = append(b.catalog, "/Outlines "+ref(outlines))
+
+	doc, err := OpenDocument(b.write(t), DefaultDocumentOptions())
+	if err != nil {
+		t.Fatalf("OpenDocument() unexpected error: %v", err)
+	}
+	defer doc.Close()
+
+	tests := []struct {
+		ref  CrossRef
+		page int
+	}{
+		{CrossRef{Kind: CrossRefPage, Label: "3", Text: "page 3"}, 3},
+		{CrossRef{Kind: CrossRefFigure, Label: "2", Text: "Figure 2"}, 2},
+		{CrossRef{Kind: CrossRefTable, Label: "1", Text: "Table 1"}, 3},
+		{CrossRef{Kind: CrossRefSection, Label: "3.1", Text: "Section 3.1"}, 3},
+		{CrossRef{Kind: CrossRefSection, Label: "2", Text: "Chapter 2"}, 2},
+		{CrossRef{Kind: CrossRefSection, Label: "A", Text: "Appendix A"}, 4},
+	}
+	for _, tt := range tests {
+		got, err := doc.ResolveCrossRef(tt.ref)
+		if err != nil || got != tt.page {
+			t.Errorf("ResolveCrossRef(%q) = %d, %v; want page %d", tt.ref.Text, got, err, tt.page)
+		}
+	}
+
+	_, err = doc.ResolveCrossRef(CrossRef{Kind: CrossRefFigure, Label: "9", Text: "Figure 9"})
+	if !errors.Is(err, ErrCrossRefNotFound) {
+		t.Errorf("ResolveCrossRef(Figure 9) error = %v, want ErrCrossRefNotFound", err)
+	}
+}
@@ -0,0 +1,439 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Action is something keys can be bound to in a mode
+type Action struct {
+	Name  string   // what the [keys] section of the config binds keys to
+	Group string   // heading the key reference lists the action under
+	Help  string   // one-line description
+	Keys  []string // default key sequences, written as in the config
+
+	// Run does the action. count is the number typed before the keys, 0
+	// if none: actions repeat that many times or take it as a page or line
+	// number, as in vim.
+	Run func(m *Model, count int) tea.Cmd
+}
+
+// Key reference headings
+const (
+	groupNavigation = "NAVIGATION"
+	groupLinks      = "LINKS & JUMPS"
+	groupSearch     = "SEARCH & COPY"
+	groupView       = "VIEW"
+	groupThemes     = "THEMES"
+	groupBookmarks  = "MARKS & BOOKMARKS"
+	groupGeneral    = "GENERAL"
+	groupVisual     = "VISUAL MODE (selecting text after v or V)"
+	groupSearchMode = "SEARCH MODE (typing a search)"
+	groupCommand    = "COMMAND LINE (after :)"
+	groupTOC        = "TABLE OF CONTENTS (while it has the focus)"
+	groupHints      = "LINK HINTS (after labelling the links in view)"
+)
+
+// modeActions returns the actions of a mode, in the order the key
+// reference lists them
+func modeActions(mode KeyMode) []Action {
+	switch mode {
+	case KeyModeNormal:
+		return normalActions()
+	case KeyModeVisual:
+		return visualActions()
+	case KeyModeSearch:
+		return searchActions()
+	case KeyModeCommand:
+		return commandActions()
+	case KeyModeTOC:
+		return tocActions()
+	case KeyModeHint:
+		return hintActions()
+	}
+	return nil
+}
+
+// do adapts a method without a command or count to Action.Run
+func do(f func(m *Model)) func(m *Model, count int) tea.Cmd {
+	return func(m *Model, _ int) tea.Cmd {
+		f(m)
+		return nil
+	}
+}
+
+// doCmd adapts a method without a count to Action.Run
+func doCmd(f func(m *Model) tea.Cmd) func(m *Model, count int) tea.Cmd {
+	return func(m *Model, _ int) tea.Cmd {
+		return f(m)
+	}
+}
+
+// times returns the count, or 1 if none was typed
+func times(count int) int {
+	return max(count, 1)
+}
+
+func normalActions() []Action {
+	return []Action{
+		{Name: "scroll-down", Group: groupNavigation, Help: "Scroll down one line", Keys: []string{"j", "down"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineDown(times(count)); return nil }},
+		{Name: "scroll-up", Group: groupNavigation, Help: "Scroll up one line", Keys: []string{"k", "up"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineUp(times(count)); return nil }},
+		{Name: "half-page-down", Group: groupNavigation, Help: "Scroll down half a page, or as many lines as the count", Keys: []string{"d"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineDown(halfPageLines(count)); return nil }},
+		{Name: "half-page-up", Group: groupNavigation, Help: "Scroll up half a page, or as many lines as the count", Keys: []string{"u"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineUp(halfPageLines(count)); return nil }},
+		{Name: "page-down", Group: groupNavigation, Help: "Scroll down a screen", Keys: []string{"ctrl+f"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineDown(times(count) * m.viewport.Height); return nil }},
+		{Name: "page-up", Group: groupNavigation, Help: "Scroll up a screen", Keys: []string{"ctrl+b"},
+			Run: func(m *Model, count int) tea.Cmd { m.viewport.LineUp(times(count) * m.viewport.Height); return nil }},
+		{Name: "first-page", Group: groupNavigation, Help: "Go to the first page, or the page numbered by the count", Keys: []string{"gg"},
+			Run: func(m *Model, count int) tea.Cmd { return m.jumpToPage(min(times(count), m.document.GetPageCount())) }},
+		{Name: "last-page", Group: groupNavigation, Help: "Go to the last page, or the page numbered by the count", Keys: []string{"G"},
+			Run: func(m *Model, count int) tea.Cmd {
+				if count > 0 {
+					return m.jumpToPage(min(count, m.document.GetPageCount()))
+				}
+				return m.goToLastPage()
+			}},
+		{Name: "next-page", Group: groupNavigation, Help: "Go to the next page", Keys: []string{"ctrl+n", "gt"},
+			Run: func(m *Model, count int) tea.Cmd { return m.movePages(times(count)) }},
+		{Name: "previous-page", Group: groupNavigation, Help: "Go to the previous page", Keys: []string{"ctrl+p", "gT"},
+			Run: func(m *Model, count int) tea.Cmd { return m.movePages(-times(count)) }},
+		{Name: "next-section", Group: groupNavigation, Help: "Go to the next section of the table of contents", Keys: []string{"]]"},
+			Run: func(m *Model, count int) tea.Cmd { return m.stepSection(times(count)) }},
+		{Name: "previous-section", Group: groupNavigation, Help: "Go to the previous section of the table of contents", Keys: []string{"[["},
+			Run: func(m *Model, count int) tea.Cmd { return m.stepSection(-times(count)) }},
+		{Name: "center-line", Group: groupNavigation, Help: "Scroll the current match, or the line numbered by the count, to the middle", Keys: []string{"zz"},
+			Run: func(m *Model, count int) tea.Cmd { m.scrollLineTo(count, m.viewport.Height/2); return nil }},
+		{Name: "top-line", Group: groupNavigation, Help: "Scroll the current match, or the line numbered by the count, to the top", Keys: []string{"zt"},
+			Run: func(m *Model, count int) tea.Cmd { m.scrollLineTo(count, 0); return nil }},
+		{Name: "bottom-line", Group: groupNavigation, Help: "Scroll the current match, or the line numbered by the count, to the bottom", Keys: []string{"zb"},
+			Run: func(m *Model, count int) tea.Cmd { m.scrollLineTo(count, m.viewport.Height-1); return nil }},
+
+		{Name: "follow-link", Group: groupLinks, Help: "Follow the first link in view", Keys: []string{"enter"},
+			Run: doCmd((*Model).followLinkInView)},
+		{Name: "link-hints", Group: groupLinks, Help: "Label the links and cross-references in view; type a label to follow it", Keys: []string{"f"},
+			Run: do(func(m *Model) { m.startHints(false) })},
+		{Name: "copy-link-hints", Group: groupLinks, Help: "Label the links in view; type a label to copy where it leads", Keys: []string{"F"},
+			Run: do(func(m *Model) { m.startHints(true) })},
+		{Name: "jump-older", Group: groupLinks, Help: "Go back through the jump list", Keys: []string{"ctrl+o", "backspace"},
+			Run: func(m *Model, count int) tea.Cmd { return m.jumpOlderBy(times(count)) }},
+		{Name: "jump-newer", Group: groupLinks, Help: "Go forward through the jump list (Tab does too after going back)",
+			Run: func(m *Model, count int) tea.Cmd { return m.jumpNewerBy(times(count)) }},
+		{Name: "jump-back", Group: groupLinks, Help: "Go back to where the last jump was made from (also '' and ``)",
+			Run: doCmd((*Model).jumpBack)},
+
+		{Name: "search", Group: groupSearch, Help: "Start a search", Keys: []string{"/"},
+			Run: doCmd((*Model).startSearchInput)},
+		{Name: "next-match", Group: groupSearch, Help: "Go to the next match", Keys: []string{"n"},
+			Run: func(m *Model, count int) tea.Cmd { return m.stepMatch(times(count)) }},
+		{Name: "previous-match", Group: groupSearch, Help: "Go to the previous match", Keys: []string{"N"},
+			Run: func(m *Model, count int) tea.Cmd { return m.stepMatch(-times(count)) }},
+		{Name: "copy-page", Group: groupSearch, Help: "Copy the text of the page", Keys: []string{"y"},
+			Run: doCmd((*Model).copyCurrentPage)},
+		{Name: "copy-table", Group: groupSearch, Help: "Copy the table in view as CSV", Keys: []string{"t"},
+			Run: doCmd(func(m *Model) tea.Cmd { return m.yankTable(false) })},
+		{Name: "copy-table-markdown", Group: groupSearch, Help: "Copy the table in view as Markdown", Keys: []string{"T"},
+			Run: doCmd(func(m *Model) tea.Cmd { return m.yankTable(true) })},
+		{Name: "visual", Group: groupSearch, Help: "Select text from the cursor, a character at a time", Keys: []string{"v"},
+			Run: do(func(m *Model) { m.startVisual(false) })},
+		{Name: "visual-line", Group: groupSearch, Help: "Select whole lines from the cursor", Keys: []string{"V"},
+			Run: do(func(m *Model) { m.startVisual(true) })},
+
+		{Name: "columns", Group: groupView, Help: "Cycle columns: off, reading order, side by side", Keys: []string{"c"},
+			Run: doCmd((*Model).cycleColumnView)},
+		{Name: "headers", Group: groupView, Help: "Hide or show running headers and footers", Keys: []string{"H"},
+			Run: doCmd((*Model).toggleRunning)},
+		{Name: "images", Group: groupView, Help: "Show or hide images", Keys: []string{"i"},
+			Run: doCmd((*Model).toggleImages)},
+		{Name: "toc", Group: groupView, Help: "Show or hide the table of contents", Keys: []string{"ctrl+t"},
+			Run: doCmd((*Model).toggleTOC)},
+		{Name: "next-pane", Group: groupView, Help: "Focus the next pane, or go forward through the jump list after going back", Keys: []string{"tab"},
+			Run: doCmd((*Model).nextPane)},
+		{Name: "previous-pane", Group: groupView, Help: "Focus the previous pane", Keys: []string{"shift+tab"},
+			Run: do(func(m *Model) { m.focusPane((m.activePaneIdx - 1 + 3) % 3) })},
+
+		{Name: "dark-theme", Group: groupThemes, Help: "Switch to the dark theme", Keys: []string{"1"},
+			Run: do(func(m *Model) { m.changeTheme("dark") })},
+		{Name: "light-theme", Group: groupThemes, Help: "Switch to the light theme", Keys: []string{"2"},
+			Run: do(func(m *Model) { m.changeTheme("light") })},
+		{Name: "cycle-themes", Group: groupThemes, Help: "Cycle through the dark themes", Keys: []string{"3"},
+			Run: do((*Model).cycleDarkTheme)},
+
+		{Name: "set-mark", Group: groupBookmarks, Help: "Mark the page and line: m{a-z} in this document, m{A-Z} in any", Keys: []string{"m"},
+			Run: do(func(m *Model) { m.startMark(true) })},
+		{Name: "go-to-mark", Group: groupBookmarks, Help: "Go to a mark, opening its document: '{a-z}, '{A-Z}", Keys: []string{"'", "`"},
+			Run: do(func(m *Model) { m.startMark(false) })},
+		{Name: "bookmark", Group: groupBookmarks, Help: "Bookmark the page, or remove its bookmark", Keys: []string{"b"},
+			Run: do((*Model).toggleBookmark)},
+
+		{Name: "command-line", Group: groupGeneral, Help: "Open the command line", Keys: []string{":"},
+			Run: do((*Model).startCommandLine)},
+		{Name: "help", Group: groupGeneral, Help: "Show or hide this help", Keys: []string{"?"},
+			Run: do(func(m *Model) { m.showHelp = !m.showHelp })},
+		{Name: "quit", Group: groupGeneral, Help: "Quit", Keys: []string{"q", "ctrl+c"},
+			Run: doCmd((*Model).quit)},
+	}
+}
+
+func searchActions() []Action {
+	return []Action{
+		{Name: "run", Group: groupSearchMode, Help: "Search", Keys: []string{"enter"},
+			Run: doCmd((*Model).executeSearch)},
+		{Name: "cancel", Group: groupSearchMode, Help: "Leave search mode", Keys: []string{"esc", "ctrl+c"},
+			Run: do(func(m *Model) {
+				m.keyHandler.Mode = m.normalMode()
+				m.searchActive = false
+			})},
+		{Name: "delete-char", Group: groupSearchMode, Help: "Delete the last character", Keys: []string{"backspace"},
+			Run: do(func(m *Model) {
+				if runes := []rune(m.searchQuery); len(runes) > 0 {
+					m.searchQuery = string(runes[:len(runes)-1])
+				}
+			})},
+		{Name: "toggle-case", Group: groupSearchMode, Help: "Toggle case-sensitive matching", Keys: []string{"ctrl+s"},
+			Run: do(func(m *Model) { m.searchOptionsPane.ToggleCaseSensitive() })},
+		{Name: "toggle-whole-word", Group: groupSearchMode, Help: "Toggle whole-word matching", Keys: []string{"ctrl+w"},
+			Run: do(func(m *Model) { m.searchOptionsPane.ToggleWholeWord() })},
+		{Name: "toggle-regex", Group: groupSearchMode, Help: "Toggle regex matching", Keys: []string{"ctrl+r"},
+			Run: do(func(m *Model) { m.searchOptionsPane.ToggleRegexMode() })},
+		{Name: "toggle-ranked", Group: groupSearchMode, Help: "Toggle ranked search: words, \"phrases\", prefix*, best page first", Keys: []string{"ctrl+t"},
+			Run: do(func(m *Model) { m.searchOptionsPane.ToggleRanked() })},
+	}
+}
+
+func commandActions() []Action {
+	return []Action{
+		{Name: "run", Group: groupCommand, Help: "Run the command; errors show in the status bar", Keys: []string{"enter"},
+			Run: func(m *Model, _ int) tea.Cmd {
+				line := m.commandLine
+				m.endCommandLine()
+				return m.runCommand(line)
+			}},
+		{Name: "cancel", Group: groupCommand, Help: "Leave the command line", Keys: []string{"esc", "ctrl+c"},
+			Run: do((*Model).endCommandLine)},
+		{Name: "delete-char", Group: groupCommand, Help: "Delete the last character, or leave if there is none", Keys: []string{"backspace"},
+			Run: do(func(m *Model) {
+				if runes := []rune(m.commandLine); len(runes) > 0 {
+					m.commandLine = string(runes[:len(runes)-1])
+				} else {
+					m.endCommandLine()
+				}
+			})},
+		{Name: "delete-word", Group: groupCommand, Help: "Delete the word before the cursor", Keys: []string{"ctrl+w"},
+			Run: do(func(m *Model) {
+				line := strings.TrimRight(m.commandLine, " ")
+				m.commandLine = line[:strings.LastIndexByte(line, ' ')+1]
+			})},
+		{Name: "delete-line", Group: groupCommand, Help: "Delete the whole line", Keys: []string{"ctrl+u"},
+			Run: do(func(m *Model) { m.commandLine = "" })},
+		{Name: "complete", Group: groupCommand, Help: "Complete the command or argument, cycling through the candidates", Keys: []string{"tab"},
+			Run: do(func(m *Model) { m.completeCommand(1) })},
+		{Name: "complete-previous", Group: groupCommand, Help: "Cycle back through the candidates", Keys: []string{"shift+tab"},
+			Run: do(func(m *Model) { m.completeCommand(-1) })},
+		{Name: "history-older", Group: groupCommand, Help: "Recall the previous command line starting with what is typed", Keys: []string{"up", "ctrl+p"},
+			Run: do(func(m *Model) { m.recallCommand(-1) })},
+		{Name: "history-newer", Group: groupCommand, Help: "Recall the next command line starting with what is typed", Keys: []string{"down", "ctrl+n"},
+			Run: do(func(m *Model) { m.recallCommand(1) })},
+	}
+}
+
+func tocActions() []Action {
+	actions := []Action{
+		{Name: "next-entry", Group: groupTOC, Help: "Select the next entry", Keys: []string{"j", "down"},
+			Run: func(m *Model, count int) tea.Cmd { m.tocPane.MoveBy(times(count)); return nil }},
+		{Name: "previous-entry", Group: groupTOC, Help: "Select the previous entry", Keys: []string{"k", "up"},
+			Run: func(m *Model, count int) tea.Cmd { m.tocPane.MoveBy(-times(count)); return nil }},
+		{Name: "next-entry-page", Group: groupTOC, Help: "Select the entry a screen down", Keys: []string{"d"},
+			Run: do(func(m *Model) { m.tocPane.MovePageDown() })},
+		{Name: "previous-entry-page", Group: groupTOC, Help: "Select the entry a screen up", Keys: []string{"u"},
+			Run: do(func(m *Model) { m.tocPane.MovePageUp() })},
+		{Name: "first-entry", Group: groupTOC, Help: "Select the first entry", Keys: []string{"gg", "home"},
+			Run: do(func(m *Model) { m.tocPane.MoveToFirst() })},
+		{Name: "last-entry", Group: groupTOC, Help: "Select the last entry", Keys: []string{"G", "end"},
+			Run: do(func(m *Model) { m.tocPane.MoveToLast() })},
+		{Name: "open-entry", Group: groupTOC, Help: "Go to the page of the selected entry", Keys: []string{"enter"},
+			Run: doCmd((*Model).openTOCEntry)},
+		{Name: "collapse", Group: groupTOC, Help: "Collapse the entry, or select its parent", Keys: []string{"h", "left"},
+			Run: do(func(m *Model) { m.tocPane.CollapseOrLeave() })},
+		{Name: "expand", Group: groupTOC, Help: "Expand the entry, or select its first child", Keys: []string{"l", "right"},
+			Run: do(func(m *Model) { m.tocPane.ExpandOrEnter() })},
+		{Name: "toggle-fold", Group: groupTOC, Help: "Expand or collapse the entry", Keys: []string{"za"},
+			Run: do(func(m *Model) { m.tocPane.ToggleExpanded() })},
+		{Name: "open-fold", Group: groupTOC, Help: "Expand the entry", Keys: []string{"zo"},
+			Run: do(func(m *Model) { m.tocPane.Expand() })},
+		{Name: "close-fold", Group: groupTOC, Help: "Collapse the entry", Keys: []string{"zc"},
+			Run: do(func(m *Model) { m.tocPane.Collapse() })},
+		{Name: "open-all-folds", Group: groupTOC, Help: "Expand every entry", Keys: []string{"zR"},
+			Run: do(func(m *Model) { m.tocPane.ExpandAll() })},
+		{Name: "close-all-folds", Group: groupTOC, Help: "Collapse every entry", Keys: []string{"zM"},
+			Run: do(func(m *Model) { m.tocPane.CollapseAll() })},
+	}
+	for level := 1; level <= 9; level++ {
+		actions = append(actions, Action{
+			Name: fmt.Sprintf("fold-level-%d", level), Group: groupTOC,
+			Help: fmt.Sprintf("Expand the entries down to level %d", level), Keys: []string{fmt.Sprintf("z%d", level)},
+			Run: do(func(m *Model) { m.tocPane.ExpandToLevel(level) }),
+		})
+	}
+	return append(actions,
+		Action{Name: "filter", Group: groupTOC, Help: "Fuzzy filter the entries; Enter keeps the filter, Esc clears it", Keys: []string{"/"},
+			Run: do(func(m *Model) { m.tocPane.StartFilter() })},
+		Action{Name: "clear-filter", Group: groupTOC, Help: "Clear the filter", Keys: []string{"esc"},
+			Run: do(func(m *Model) { m.tocPane.ClearFilter() })},
+	)
+}
+
+func hintActions() []Action {
+	return []Action{
+		{Name: "cancel", Group: groupHints, Help: "Leave without picking a link", Keys: []string{"esc", "ctrl+c"},
+			Run: do((*Model).endHints)},
+		{Name: "delete-char", Group: groupHints, Help: "Delete the last letter typed, or leave if there is none", Keys: []string{"backspace"},
+			Run: do(func(m *Model) {
+				if runes := []rune(m.hintTyped); len(runes) > 0 {
+					m.hintTyped = string(runes[:len(runes)-1])
+				} else {
+					m.endHints()
+				}
+			})},
+	}
+}
+
+// runAction runs an action of the keymap with the count typed before its
+// keys
+func (m *Model) runAction(action Action, count int) tea.Cmd {
+	if action.Run == nil {
+		return nil
+	}
+	return action.Run(m, count)
+}
+
+// startSearchInput opens the search prompt
+func (m *Model) startSearchInput() tea.Cmd {
+	m.keyHandler.Mode = KeyModeSearch
+	m.searchActive = true
+	// Warm the whole document while the query is typed
+	return m.startPrefetch(m.document.GetPageCount())
+}
+
+// stepMatch goes to the next (step 1) or previous (step -1) search match,
+// wrapping around
+func (m *Model) stepMatch(step int) tea.Cmd {
+	n := len(m.advancedSearchResults)
+	if n == 0 {
+		return nil
+	}
+	m.currentMatch = ((m.currentMatch+step)%n + n) % n
+	return m.jumpToSearchResult()
+}
+
+// nextPane focuses the next pane. ctrl+i arrives as tab: after ctrl+o it
+// walks forward through the jump list instead.
+func (m *Model) nextPane() tea.Cmd {
+	if m.canJumpNewer() {
+		return m.jumpNewer()
+	}
+	m.focusPane((m.activePaneIdx + 1) % 3)
+	return nil
+}
+
+// toggleBookmark bookmarks the current page, or removes its bookmark
+func (m *Model) toggleBookmark() {
+	if m.cfg.HasBookmark(m.docKey, m.currentPage) {
+		m.cfg.RemoveBookmark(m.docKey, m.currentPage)
+		m.statusMessage = "Bookmark removed"
+	} else {
+		m.cfg.AddBookmark(m.docKey, m.currentPage, "")
+		m.statusMessage = "Bookmarked page " + m.pageName(m.currentPage)
+	}
+	m.saveConfig()
+	m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docKey))
+}
+
+// halfPageLines returns how far d and u scroll: the count if one was
+// typed, as vim's scroll option
+func halfPageLines(count int) int {
+	if count > 0 {
+		return count
+	}
+	return 10
+}
+
+// movePages moves n pages on, or back if n is negative, stopping at the
+// first and last page
+func (m *Model) movePages(n int) tea.Cmd {
+	target := min(max(m.currentPage+n, 1), m.document.GetPageCount())
+	if target == m.currentPage {
+		return nil
+	}
+	m.currentPage = target
+	return m.loadPage(target)
+}
+
+// sectionPages returns the pages the sections of the table of contents
+// start on, in order. Until the table of contents is loaded the outline
+// is read for them.
+func (m *Model) sectionPages() []int {
+	entries := m.tocPane.flatEntries
+	if !m.tocLoaded {
+		outline, err := m.document.ExtractOutline()
+		if err != nil {
+			return nil
+		}
+		entries = m.tocPane.flattenTOC(outline)
+	}
+
+	var pages []int
+	for _, entry := range entries {
+		if entry.Page > 0 {
+			pages = append(pages, entry.Page)
+		}
+	}
+	slices.Sort(pages)
+	return slices.Compact(pages)
+}
+
+// stepSection goes n sections on from the current page, or back if n is
+// negative, stopping at the first and last section
+func (m *Model) stepSection(n int) tea.Cmd {
+	pages := m.sectionPages()
+	if len(pages) == 0 {
+		m.statusMessage = "No sections: the table of contents is empty"
+		return nil
+	}
+
+	if n > 0 {
+		next, _ := slices.BinarySearch(pages, m.currentPage+1)
+		if next == len(pages) {
+			m.statusMessage = "No section after this page"
+			return nil
+		}
+		return m.jumpToPage(pages[min(next+n-1, len(pages)-1)])
+	}
+
+	before, _ := slices.BinarySearch(pages, m.currentPage)
+	if before == 0 {
+		m.statusMessage = "No section before this page"
+		return nil
+	}
+	return m.jumpToPage(pages[max(before+n, 0)])
+}
+
+// scrollLineTo scrolls a line to a row of the view: the line numbered by
+// the count, or else the current match, or else the line in the middle of
+// the view
+func (m *Model) scrollLineTo(count, row int) {
+	line := m.viewport.YOffset + m.viewport.Height/2
+	switch {
+	case count > 0:
+		line = count - 1
+	case m.matchRow >= 0 && len(m.advancedSearchResults) > 0:
+		line = m.matchRow
+	}
+	m.viewport.SetYOffset(max(line-row, 0))
+}
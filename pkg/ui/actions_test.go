@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// newActionsModel opens the five-page fixture at page 1, with key
+// sequences that never time out
+func newActionsModel(t *testing.T) *Model {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	t.Cleanup(func() { doc.Close() })
+
+	model := NewModel(doc)
+	model.keyHandler.Timeout = 0
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.loadPage(1))
+	return model
+}
+
+// typeKeys presses the keys of each word of keys, a named key or runes
+// ("3 ctrl+n", "20G")
+func typeKeys(m *Model, keys string) {
+	named := map[string]tea.KeyType{"ctrl+n": tea.KeyCtrlN, "ctrl+p": tea.KeyCtrlP, "ctrl+o": tea.KeyCtrlO}
+	for _, word := range strings.Fields(keys) {
+		var msgs []tea.KeyMsg
+		if keyType, ok := named[word]; ok {
+			msgs = append(msgs, tea.KeyMsg{Type: keyType})
+		} else {
+			for _, r := range word {
+				msgs = append(msgs, keyPress(string(r)))
+			}
+		}
+		for _, msg := range msgs {
+			_, cmd := m.Update(msg)
+			drive(m, cmd)
+		}
+	}
+}
+
+func TestCounts(t *testing.T) {
+	model := newActionsModel(t)
+
+	tests := []struct {
+		keys string
+		page int
+	}{
+		{"3 ctrl+n", 4},
+		{"2 ctrl+p", 2},
+		{"20G", 5}, // past the last page
+		{"2gg", 2},
+		{"gg", 1},
+		{"G", 5},
+		{"3gT", 2},
+		{"gt", 3},
+	}
+	for _, tt := range tests {
+		typeKeys(model, tt.keys)
+		if model.currentPage != tt.page {
+			t.Errorf("after %s: page %d, want %d", tt.keys, model.currentPage, tt.page)
+		}
+	}
+
+	model.viewport.SetContent(strings.Repeat("line\n", 200))
+	model.viewport.SetYOffset(0)
+	typeKeys(model, "5j")
+	if model.viewport.YOffset != 5 {
+		t.Errorf("after 5j: offset %d, want 5", model.viewport.YOffset)
+	}
+	typeKeys(model, "3d")
+	if model.viewport.YOffset != 8 {
+		t.Errorf("after 3d: offset %d, want 8", model.viewport.YOffset)
+	}
+}
+
+func TestPendingKeysShown(t *testing.T) {
+	model := newActionsModel(t)
+
+	typeKeys(model, "12g")
+	if status := model.renderStatusBar(); !strings.Contains(status, "| 12g |") {
+		t.Errorf("status bar doesn't show the pending keys: %s", status)
+	}
+	typeKeys(model, "g")
+	if model.currentPage != 5 || model.keyHandler.Pending() != "" {
+		t.Errorf("after 12gg: page %d, pending %q", model.currentPage, model.keyHandler.Pending())
+	}
+}
+
+func TestSectionNavigation(t *testing.T) {
+	model := newActionsModel(t)
+
+	if typeKeys(model, "]]"); model.statusMessage != "No sections: the table of contents is empty" {
+		t.Errorf("]] without sections: status %q", model.statusMessage)
+	}
+
+	model.tocPane.SetTableOfContents(&pdf.TableOfContents{Source: "metadata", Entries: []pdf.TOCEntry{
+		{Title: "One", Page: 1, Level: 1, Children: []pdf.TOCEntry{{Title: "One.A", Page: 2, Level: 2}}},
+		{Title: "Two", Page: 4, Level: 1},
+	}})
+	model.tocLoaded = true
+
+	tests := []struct {
+		keys   string
+		page   int
+		status string
+	}{
+		{"]]", 2, ""},
+		{"]]", 4, ""},
+		{"]]", 4, "No section after this page"},
+		{"[[", 2, ""},
+		{"G", 5, ""},
+		{"2[[", 2, ""},
+		{"5[[", 1, ""},
+		{"[[", 1, "No section before this page"},
+		{"9]]", 4, ""},
+	}
+	for _, tt := range tests {
+		model.statusMessage = ""
+		typeKeys(model, tt.keys)
+		if model.currentPage != tt.page || model.statusMessage != tt.status {
+			t.Errorf("after %s: page %d, status %q; want page %d, status %q",
+				tt.keys, model.currentPage, model.statusMessage, tt.page, tt.status)
+		}
+	}
+
+	// Sections are jumps
+	typeKeys(model, "''")
+	if model.currentPage != 1 {
+		t.Errorf("after '': page %d, want 1", model.currentPage)
+	}
+}
+
+func TestScrollLineTo(t *testing.T) {
+	model := newActionsModel(t)
+	model.viewport.SetContent(strings.Repeat("line\n", 200))
+	height := model.viewport.Height
+
+	tests := []struct {
+		keys   string
+		offset int
+	}{
+		{"50zt", 49},
+		{"50zb", 50 - height},
+		{"50zz", 49 - height/2},
+		{"zt", 49}, // the middle line, 50, goes to the top
+	}
+	for _, tt := range tests {
+		typeKeys(model, tt.keys)
+		if model.viewport.YOffset != tt.offset {
+			t.Errorf("after %s: offset %d, want %d", tt.keys, model.viewport.YOffset, tt.offset)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,6 +17,7 @@ type BookmarkPane struct {
 	visible     bool
 	width       int
 	height      int
+	pageLabel   func(int) string // names pages, nil to number them
 }
 
 // NewBookmarkPane creates a new bookmark pane
@@ -38,6 +40,11 @@ func (bp *BookmarkPane) SetBookmarks(bookmarks []config.Bookmark) {
 	}
 }
 
+// SetPageLabels names the bookmarked pages by their labels
+func (bp *BookmarkPane) SetPageLabels(label func(pageNum int) string) {
+	bp.pageLabel = label
+}
+
 // Show makes the pane visible
 func (bp *BookmarkPane) Show() {
 	bp.visible = true
@@ -120,7 +127,13 @@ func (bp *BookmarkPane) View() string {
 			note = fmt.Sprintf(" - %s", bookmark.Note)
 		}
 
-		line := fmt.Sprintf("%s Page %3d%s", marker, bookmark.Page, note)
+		page := fmt.Sprintf("%3d", bookmark.Page)
+		if bp.pageLabel != nil {
+			if label := bp.pageLabel(bookmark.Page); label != strconv.Itoa(bookmark.Page) {
+				page = fmt.Sprintf("%s (%d)", label, bookmark.Page)
+			}
+		}
+		line := fmt.Sprintf("%s Page %s%s", marker, page, note)
 		if i == bp.selectedIdx {
 			content.WriteString(fmt.Sprintf("\033[7m%s\033[0m\n", line))
 		} else {
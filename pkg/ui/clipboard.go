@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	osc52 "github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+)
+
+// ErrNoClipboard is returned when copied text can reach neither the
+// terminal nor a copy command
+var ErrNoClipboard = errors.New("not on a terminal and no copy command found (wl-copy, xclip, xsel or pbcopy)")
+
+// clipboard copies text to the system clipboard: with the OSC 52 escape
+// sequence, which the terminal turns into a copy even over SSH, and with a
+// copy command such as wl-copy or xclip for terminals that ignore it
+type clipboard struct {
+	method  string    // config.ClipboardAuto, ClipboardOSC52 or ClipboardCommand
+	command []string  // copy command and its arguments, nil if none is configured or found
+	tty     io.Writer // terminal OSC 52 is written to, nil if there is none
+	remote  bool      // running over SSH, where the copy command would copy on the wrong machine
+}
+
+// newClipboard sets up copying as the config asks
+func newClipboard(cfg config.UIConfig) clipboard {
+	c := clipboard{
+		method:  cfg.Clipboard,
+		command: strings.Fields(cfg.CopyCommand),
+		remote:  os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "",
+	}
+	if c.method == "" {
+		c.method = config.ClipboardAuto
+	}
+	if len(c.command) == 0 {
+		c.command = findCopyCommand()
+	}
+	// The view is drawn on stdout; stderr reaches the same terminal
+	if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		c.tty = os.Stderr
+	}
+	return c
+}
+
+// copyCommands are the copy commands tried in turn when none is
+// configured, with the variable of the display they need
+var copyCommands = []struct {
+	display string
+	command []string
+}{
+	{"WAYLAND_DISPLAY", []string{"wl-copy"}},
+	{"DISPLAY", []string{"xclip", "-selection", "clipboard"}},
+	{"DISPLAY", []string{"xsel", "--clipboard", "--input"}},
+	{"", []string{"pbcopy"}},
+}
+
+// findCopyCommand returns the first copy command that is installed and
+// has its display, or nil
+func findCopyCommand() []string {
+	for _, c := range copyCommands {
+		if c.display != "" && os.Getenv(c.display) == "" {
+			continue
+		}
+		if _, err := exec.LookPath(c.command[0]); err == nil {
+			return c.command
+		}
+	}
+	return nil
+}
+
+// copy puts text on the clipboard. Whether the terminal acted on OSC 52
+// can't be told, so a failing copy command is only an error if OSC 52
+// wasn't written.
+func (c clipboard) copy(text string) error {
+	wrote := false
+	if c.method != config.ClipboardCommand && c.tty != nil {
+		if err := writeOSC52(c.tty, text); err != nil {
+			return err
+		}
+		wrote = true
+	}
+
+	useCommand := c.method == config.ClipboardCommand || c.method == config.ClipboardAuto && !(wrote && c.remote)
+	if useCommand && len(c.command) > 0 {
+		if err := runCopyCommand(c.command, text); err != nil && !wrote {
+			return err
+		}
+		return nil
+	}
+	if !wrote {
+		return ErrNoClipboard
+	}
+	return nil
+}
+
+// writeOSC52 writes the OSC 52 sequence that copies text, wrapped for
+// tmux or screen if it runs in one
+func writeOSC52(w io.Writer, text string) error {
+	seq := osc52.New(text)
+	switch {
+	case os.Getenv("TMUX") != "":
+		seq = seq.Tmux()
+	case strings.HasPrefix(os.Getenv("TERM"), "screen"):
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(w)
+	return err
+}
+
+// runCopyCommand pipes text to a copy command
+func runCopyCommand(command []string, text string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %v: %s", command[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", command[0], err)
+	}
+	return nil
+}
+
+// CopiedMsg reports the end of a copy to the system clipboard
+type CopiedMsg struct {
+	What string // what was copied, as the status bar names it
+	Err  error
+}
+
+// copyText puts text on the system clipboard in the background, keeping
+// it as the last text copied. what names it if the copy fails.
+func (m *Model) copyText(text, what string) tea.Cmd {
+	m.clipboard = text
+	m.showCopyFeedback = true
+	c := m.systemClipboard
+	return func() tea.Msg {
+		return CopiedMsg{What: what, Err: c.copy(text)}
+	}
+}
+
+// handleCopied reports a copy that didn't reach the system clipboard
+func (m *Model) handleCopied(msg CopiedMsg) {
+	if msg.Err == nil {
+		return
+	}
+	m.showCopyFeedback = false
+	m.statusMessage = fmt.Sprintf("Cannot copy %s to the clipboard: %v", msg.What, msg.Err)
+}
+
+// formatCopy fills in the copy format of the config for text from pages
+// first to last
+func (m *Model) formatCopy(text string, first, last int) string {
+	format := m.cfg.UI.CopyFormat
+	if format == "" {
+		format = config.DefaultCopyFormat
+	}
+	pages := m.document.PageLabel(first)
+	if last != first {
+		pages += "-" + m.document.PageLabel(last)
+	}
+	file := filepath.Base(m.document.Path())
+	title := m.document.GetMetadata().Title
+	if title == "" {
+		title = file
+	}
+	return strings.NewReplacer("{text}", text, "{page}", pages, "{file}", file, "{title}", title).Replace(format)
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luxor/lumos/pkg/config"
+)
+
+func TestClipboardCopy(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+
+	var tty bytes.Buffer
+	if err := (clipboard{method: config.ClipboardOSC52, tty: &tty}).copy("hi"); err != nil {
+		t.Fatalf("copy() with OSC 52 error = %v", err)
+	}
+	if got, want := tty.String(), "\x1b]52;c;aGk=\a"; got != want {
+		t.Errorf("OSC 52 sequence = %q, want %q", got, want)
+	}
+
+	if err := (clipboard{method: config.ClipboardAuto}).copy("hi"); !errors.Is(err, ErrNoClipboard) {
+		t.Errorf("copy() with no terminal or command error = %v, want ErrNoClipboard", err)
+	}
+
+	// Over SSH the copy command would copy on the wrong machine
+	out := filepath.Join(t.TempDir(), "copied")
+	command := []string{"sh", "-c", "cat > " + out}
+	tty.Reset()
+	if err := (clipboard{method: config.ClipboardAuto, command: command, tty: &tty, remote: true}).copy("hi"); err != nil {
+		t.Fatalf("copy() over SSH error = %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) || tty.Len() == 0 {
+		t.Errorf("copy() over SSH ran the copy command or didn't write OSC 52")
+	}
+
+	if err := (clipboard{method: config.ClipboardCommand, command: command, tty: &bytes.Buffer{}}).copy("hi"); err != nil {
+		t.Fatalf("copy() with the copy command error = %v", err)
+	}
+	if got, err := os.ReadFile(out); err != nil || string(got) != "hi" {
+		t.Errorf("copy command got %q, %v; want \"hi\"", got, err)
+	}
+
+	err := (clipboard{method: config.ClipboardCommand, command: []string{"sh", "-c", "echo no display >&2; exit 1"}}).copy("hi")
+	if err == nil || err.Error() != "sh: exit status 1: no display" {
+		t.Errorf("copy() with a failing command error = %v", err)
+	}
+}
+
+func TestFormatCopy(t *testing.T) {
+	model := newActionsModel(t)
+	if got := model.formatCopy("text", 2, 2); got != "text" {
+		t.Errorf("formatCopy() with the default format = %q", got)
+	}
+	model.cfg.UI.CopyFormat = "> {text}\n-- {title}, page {page}"
+	if got, want := model.formatCopy("text", 2, 3), "> text\n-- LUMOS Multi-Page Test, page 2-3"; got != want {
+		t.Errorf("formatCopy() = %q, want %q", got, want)
+	}
+}
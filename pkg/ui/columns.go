@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// minColumnWidth is the narrowest a column is shown side by side
+const minColumnWidth = 30
+
+// columnGap separates columns shown side by side
+const columnGap = " │ "
+
+// fitsSideBySide reports whether n columns fit side by side in width cells
+func fitsSideBySide(n, width int) bool {
+	return n*minColumnWidth+(n-1)*lipgloss.Width(columnGap) <= width
+}
+
+// lineSpans returns the spans that fall in the line of page text at
+// [offset, offset+length), relative to the line, and the index of the
+// current span among them (-1 if it is elsewhere). Spans running past the
+// line are cut at its end.
+func lineSpans(spans []matchSpan, current, offset, length int) ([]matchSpan, int) {
+	var local []matchSpan
+	localCurrent := -1
+	for i, span := range spans {
+		start, end := max(span.start, offset), min(span.end, offset+length)
+		if start >= end {
+			continue
+		}
+		if i == current {
+			localCurrent = len(local)
+		}
+		local = append(local, matchSpan{start: start - offset, end: end - offset})
+	}
+	return local, localCurrent
+}
+
+// layoutRenderer renders page text or a page layout with its search
+// matches highlighted and its links underlined
+type layoutRenderer struct {
+	spans        []matchSpan
+	current      int
+	matchStyle   lipgloss.Style
+	currentStyle lipgloss.Style
+	links        []linkSpan
+	linkStyle    lipgloss.Style
+}
+
+// decorate highlights the matches and links in the page text at offset,
+// returning it with the offset in it of the current match (-1 if it is
+// elsewhere)
+func (r layoutRenderer) decorate(text string, offset int) (string, int) {
+	spans, current := lineSpans(r.spans, r.current, offset, len(text))
+	currentStart := -1
+	if current >= 0 {
+		currentStart = spans[current].start
+	}
+	if links := localLinks(r.links, offset, len(text)); len(links) > 0 {
+		return highlightLinks(text, spans, current, links, r.matchStyle, r.currentStyle, r.linkStyle), currentStart
+	}
+	return highlightMatches(text, spans, current, r.matchStyle, r.currentStyle), currentStart
+}
+
+// line highlights the matches and links on a line, reporting whether the
+// current match is one of them
+func (r layoutRenderer) line(line pdf.LayoutLine) (string, bool) {
+	if line.Offset < 0 {
+		return line.Text, false
+	}
+	text, current := r.decorate(line.Text, line.Offset)
+	return text, current >= 0
+}
+
+// render lays out the page as rows of text, returning them with the row of
+// the current match (-1 if it is not shown). Columns go side by side when
+// sideBySide is set and they fit in width, and one after another
+// otherwise. Running headers and footers are left out when hideRunning.
+func (r layoutRenderer) render(layout *pdf.PageLayout, width int, sideBySide, hideRunning bool) (string, int) {
+	var rows []string
+	currentRow := -1
+
+	section := func() {
+		if len(rows) > 0 {
+			rows = append(rows, "")
+		}
+	}
+	addLines := func(lines []pdf.LayoutLine) {
+		if len(lines) == 0 {
+			return
+		}
+		section()
+		for _, line := range lines {
+			text, current := r.line(line)
+			if current {
+				currentRow = len(rows)
+			}
+			rows = append(rows, text)
+		}
+	}
+
+	if !hideRunning {
+		addLines(layout.Header)
+	}
+	for _, block := range layout.Blocks {
+		n := len(block.Columns)
+		if n < 2 || !sideBySide || !fitsSideBySide(n, width) {
+			for _, column := range block.Columns {
+				addLines(column)
+			}
+			continue
+		}
+
+		section()
+		block, current := r.sideBySide(block, (width-(n-1)*lipgloss.Width(columnGap))/n)
+		if current >= 0 {
+			currentRow = len(rows) + current
+		}
+		rows = append(rows, block...)
+	}
+	if !hideRunning {
+		addLines(layout.Footer)
+	}
+
+	return strings.Join(rows, "\n"), currentRow
+}
+
+// sideBySide renders the columns of a block next to each other, wrapping
+// lines to the column width. It returns the rows with the row of the
+// current match (-1 if it is not in the block).
+func (r layoutRenderer) sideBySide(block pdf.LayoutBlock, width int) ([]string, int) {
+	style := lipgloss.NewStyle().Width(width)
+	columns := make([][]string, len(block.Columns))
+	height, currentRow := 0, -1
+	for i, column := range block.Columns {
+		for _, line := range column {
+			text, current := r.line(line)
+			if current {
+				currentRow = len(columns[i])
+			}
+			columns[i] = append(columns[i], strings.Split(style.Render(text), "\n")...)
+		}
+		height = max(height, len(columns[i]))
+	}
+
+	blank := strings.Repeat(" ", width)
+	rows := make([]string, height)
+	for row := range rows {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = blank
+			if row < len(column) {
+				cells[i] = column[row]
+			}
+		}
+		rows[row] = strings.TrimRight(strings.Join(cells, columnGap), " ")
+	}
+	return rows, currentRow
+}
+
+// bodyRange returns the byte range of the page text between its running
+// header and footer, or false if the layout's lines have no offsets into it
+func bodyRange(layout *pdf.PageLayout, textLen int) (int, int, bool) {
+	start, end := 0, textLen
+	if n := len(layout.Header); n > 0 {
+		last := layout.Header[n-1]
+		if last.Offset < 0 {
+			return 0, 0, false
+		}
+		start = last.Offset + len(last.Text)
+	}
+	if len(layout.Footer) > 0 {
+		if layout.Footer[0].Offset < 0 {
+			return 0, 0, false
+		}
+		end = layout.Footer[0].Offset
+	}
+	if start > end || end > textLen {
+		return 0, 0, false
+	}
+	return start, end, true
+}
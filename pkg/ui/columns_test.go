@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// testLayout lays out a page of text "Head\nleft a\nleft b\nright a\nFoot"
+// as a header, two columns and a footer
+func testLayout() *pdf.PageLayout {
+	return &pdf.PageLayout{
+		Header: []pdf.LayoutLine{{Text: "Head", Offset: 0}},
+		Blocks: []pdf.LayoutBlock{{Columns: [][]pdf.LayoutLine{
+			{{Text: "left a", Offset: 5}, {Text: "left b", Offset: 12}},
+			{{Text: "right a", Offset: 19}},
+		}}},
+		Footer: []pdf.LayoutLine{{Text: "Foot", Offset: 27}},
+	}
+}
+
+func TestRenderLayout(t *testing.T) {
+	r := layoutRenderer{current: -1, matchStyle: markStyle("[", "]"), currentStyle: markStyle("<", ">")}
+
+	got, _ := r.render(testLayout(), 80, false, false)
+	if want := "Head\n\nleft a\nleft b\n\nright a\n\nFoot"; got != want {
+		t.Errorf("stacked = %q, want %q", got, want)
+	}
+
+	got, _ = r.render(testLayout(), 80, true, true)
+	pad := func(s string) string { return s + strings.Repeat(" ", 38-len(s)) } // (80-3)/2
+	if want := pad("left a") + columnGap + "right a\n" + pad("left b") + " │"; got != want {
+		t.Errorf("side by side = %q, want %q", got, want)
+	}
+
+	// Too narrow for two columns
+	got, _ = r.render(testLayout(), 40, true, true)
+	if want := "left a\nleft b\n\nright a"; got != want {
+		t.Errorf("narrow side by side = %q, want %q", got, want)
+	}
+}
+
+func TestRenderLayout_Matches(t *testing.T) {
+	// "right" in the right column is current, "b" in the left one is not
+	r := layoutRenderer{
+		spans:        []matchSpan{{17, 18}, {19, 24}},
+		current:      1,
+		matchStyle:   markStyle("[", "]"),
+		currentStyle: markStyle("<", ">"),
+	}
+
+	got, row := r.render(testLayout(), 80, false, false)
+	if want := "Head\n\nleft a\nleft [b]\n\n<right> a\n\nFoot"; got != want || row != 5 {
+		t.Errorf("stacked = %q, row %d; want %q, row 5", got, row, want)
+	}
+
+	got, row = r.render(testLayout(), 80, true, true)
+	if !strings.HasPrefix(got, "left a") || !strings.Contains(got, "<right> a") || row != 0 {
+		t.Errorf("side by side = %q, row %d; want the current match on row 0", got, row)
+	}
+}
+
+func TestBodyRange(t *testing.T) {
+	text := "Head\nleft a\nleft b\nright a\nFoot"
+	start, end, ok := bodyRange(testLayout(), len(text))
+	if !ok || text[start:end] != "\nleft a\nleft b\nright a\n" {
+		t.Errorf("bodyRange() = %d, %d, %v", start, end, ok)
+	}
+
+	raw := &pdf.PageLayout{Header: []pdf.LayoutLine{{Text: "Head", Offset: -1}}}
+	if _, _, ok := bodyRange(raw, len(text)); ok {
+		t.Error("bodyRange() should fail without offsets into the page text")
+	}
+}
+
+func TestColumnViewKeys(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+
+	model := NewModel(doc)
+	model.currentPage = 2
+	drive(model, model.loadPage(2))
+	if !strings.Contains(model.viewport.View(), "Current Page: 2 of 5") {
+		t.Fatalf("Expected the footer on page 2, got %q", model.viewport.View())
+	}
+
+	// Hiding running text loads the layout and drops header and footer
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	drive(model, cmd)
+	if model.pageLayout == nil {
+		t.Fatal("Expected the page layout to be loaded")
+	}
+	content := model.viewport.View()
+	if strings.Contains(content, "Current Page") || strings.Contains(content, "Test PDF - Page 2") {
+		t.Errorf("Expected header and footer hidden, got %q", content)
+	}
+	if !strings.Contains(content, "This is the second page.") {
+		t.Errorf("Expected the page body, got %q", content)
+	}
+
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	drive(model, cmd)
+	if model.columnView != config.ColumnsStacked || !strings.Contains(model.renderStatusBar(), "Columns") {
+		t.Errorf("Expected the stacked column view, got %q", model.columnView)
+	}
+
+	// The view is remembered for the document
+	restored := NewModel(doc)
+	if restored.columnView != config.ColumnsStacked || !restored.hideRunning {
+		t.Errorf("Expected the view restored, got columns %q, hideRunning %v", restored.columnView, restored.hideRunning)
+	}
+
+	for range 2 {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	}
+	if model.columnView != "" {
+		t.Errorf("Expected columns off after a full cycle, got %q", model.columnView)
+	}
+}
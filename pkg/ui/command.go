@@ -0,0 +1,326 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var (
+	// ErrUnknownCommand is returned by CommandRegistry.Lookup for names no
+	// command has
+	ErrUnknownCommand = errors.New("not a command")
+
+	// ErrAmbiguousCommand is returned by CommandRegistry.Lookup for an
+	// abbreviation of several commands
+	ErrAmbiguousCommand = errors.New("ambiguous command")
+
+	// ErrCommandExists is returned by CommandRegistry.Register for a name
+	// or alias already taken
+	ErrCommandExists = errors.New("command already registered")
+)
+
+// Command is a command run from the ':' command line
+type Command struct {
+	Name    string   // what the command is typed as; any unique abbreviation works too
+	Aliases []string // other names, such as short ones shared with vim
+	Args    string   // argument synopsis for help: "<page>", "[note]"
+	Help    string   // one-line description
+
+	// Run runs the command with the rest of the command line, trimmed. It
+	// reports problems in the status bar.
+	Run func(m *Model, args string) tea.Cmd
+
+	// Force runs the command when it is typed with a "!" after its name,
+	// as vim's :w! is; nil if the command takes no "!"
+	Force func(m *Model, args string) tea.Cmd
+
+	// Complete returns the candidates for the last of the arguments typed
+	// so far, which may be empty; those not starting with it are dropped.
+	// Nil if the command's arguments are not completed.
+	Complete func(m *Model, args []string) []string
+}
+
+// CommandRegistry holds the commands the command line can run
+type CommandRegistry struct {
+	commands []*Command
+	byName   map[string]*Command // by name and alias
+}
+
+// NewCommandRegistry creates an empty command registry
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{byName: make(map[string]*Command)}
+}
+
+// Register adds a command. Returns ErrCommandExists if its name or one of
+// its aliases is taken.
+func (r *CommandRegistry) Register(cmd Command) error {
+	if cmd.Name == "" || cmd.Run == nil {
+		return fmt.Errorf("command %q needs a name and a Run function", cmd.Name)
+	}
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if _, taken := r.byName[name]; taken {
+			return fmt.Errorf("%s: %w", name, ErrCommandExists)
+		}
+	}
+
+	c := &cmd
+	r.commands = append(r.commands, c)
+	sort.Slice(r.commands, func(i, j int) bool { return r.commands[i].Name < r.commands[j].Name })
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		r.byName[name] = c
+	}
+	return nil
+}
+
+// Lookup returns the command with a name or alias, or the only command
+// whose name starts with it
+func (r *CommandRegistry) Lookup(name string) (*Command, error) {
+	if cmd, ok := r.byName[name]; ok {
+		return cmd, nil
+	}
+	matches := r.complete(name)
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%s: %w", name, ErrUnknownCommand)
+	case 1:
+		return r.byName[matches[0]], nil
+	}
+	return nil, fmt.Errorf("%s (%s): %w", name, strings.Join(matches, ", "), ErrAmbiguousCommand)
+}
+
+// lookup returns the command a name typed on the command line runs, and
+// whether it runs the command's Force: the name is followed by a "!" that
+// is not part of an alias, as in ":export!" but not ":q!"
+func (r *CommandRegistry) lookup(name string) (*Command, bool, error) {
+	cmd, err := r.Lookup(name)
+	if err != nil && strings.HasSuffix(name, "!") {
+		if forced, forcedErr := r.Lookup(strings.TrimSuffix(name, "!")); forcedErr == nil && forced.Force != nil {
+			return forced, true, nil
+		}
+	}
+	return cmd, false, err
+}
+
+// Commands returns the registered commands by name
+func (r *CommandRegistry) Commands() []Command {
+	commands := make([]Command, len(r.commands))
+	for i, cmd := range r.commands {
+		commands[i] = *cmd
+	}
+	return commands
+}
+
+// complete returns the names of the commands starting with prefix
+func (r *CommandRegistry) complete(prefix string) []string {
+	var names []string
+	for _, cmd := range r.commands {
+		if strings.HasPrefix(cmd.Name, prefix) {
+			names = append(names, cmd.Name)
+		}
+	}
+	return names
+}
+
+// Usage returns how the command is typed: ":goto <page>"
+func (c Command) Usage() string {
+	name := ":" + c.Name
+	if c.Force != nil {
+		name += "[!]"
+	}
+	if c.Args == "" {
+		return name
+	}
+	return name + " " + c.Args
+}
+
+// maxCommandHistory bounds the command line history
+const maxCommandHistory = 100
+
+// startCommandLine opens the command line
+func (m *Model) startCommandLine() {
+	m.keyHandler.Mode = KeyModeCommand
+	m.commandLine = ""
+	m.historyIndex = len(m.commandHistory)
+	m.endCompletion()
+}
+
+// endCommandLine closes the command line
+func (m *Model) endCommandLine() {
+	m.keyHandler.Mode = m.normalMode()
+	m.commandLine = ""
+	m.endCompletion()
+}
+
+// handleCommandKey edits the command line typed after ':' and runs it on
+// enter. Up and down recall earlier command lines starting with what has
+// been typed; tab completes command names and arguments.
+func (m *Model) handleCommandKey(msg tea.KeyMsg) tea.Cmd {
+	action, count, pending := m.keyHandler.Resolve(msg)
+	if pending {
+		return m.keyHandler.Wait()
+	}
+	if action.Name != "complete" && action.Name != "complete-previous" {
+		m.endCompletion()
+	}
+	if action.Name != "history-older" && action.Name != "history-newer" {
+		m.historyIndex = len(m.commandHistory)
+	}
+	if action.Name == "" {
+		m.commandLine += typedText(msg)
+		return nil
+	}
+	return m.runAction(action, count)
+}
+
+// addCommandHistory records a command line run, moving it to the end if
+// it was run before
+func (m *Model) addCommandHistory(line string) {
+	history := m.commandHistory[:0]
+	for _, old := range m.commandHistory {
+		if old != line {
+			history = append(history, old)
+		}
+	}
+	m.commandHistory = append(history, line)
+	if len(m.commandHistory) > maxCommandHistory {
+		m.commandHistory = m.commandHistory[len(m.commandHistory)-maxCommandHistory:]
+	}
+	m.historyIndex = len(m.commandHistory)
+}
+
+// recallCommand replaces the command line with the previous (step -1) or
+// next (step 1) line of the history that starts with what was typed
+// before recalling. Past the newest one the typed line comes back.
+func (m *Model) recallCommand(step int) {
+	if m.historyIndex == len(m.commandHistory) {
+		m.historyDraft = m.commandLine
+	}
+	for i := m.historyIndex + step; i >= 0 && i <= len(m.commandHistory); i += step {
+		if i == len(m.commandHistory) {
+			m.historyIndex, m.commandLine = i, m.historyDraft
+			return
+		}
+		if strings.HasPrefix(m.commandHistory[i], m.historyDraft) {
+			m.historyIndex, m.commandLine = i, m.commandHistory[i]
+			return
+		}
+	}
+}
+
+// completeCommand completes the word before the cursor: a command name,
+// or an argument of the command. With several candidates the first is
+// put in, and repeating steps through them and back to what was typed.
+func (m *Model) completeCommand(step int) {
+	if m.completions == nil {
+		m.completionBase, m.completionWord, m.completions = m.commandCompletions()
+		m.completionIndex = len(m.completions)
+		if len(m.completions) == 0 {
+			m.completions = nil
+			return
+		}
+	}
+
+	// The word as typed is the last stop of the cycle
+	n := len(m.completions) + 1
+	m.completionIndex = ((m.completionIndex+step)%n + n) % n
+	word := m.completionWord
+	if m.completionIndex < len(m.completions) {
+		word = m.completions[m.completionIndex]
+	}
+	m.commandLine = m.completionBase + word
+	if len(m.completions) == 1 {
+		m.endCompletion()
+	}
+}
+
+// commandCompletions returns the command line up to the word being typed,
+// that word, and the candidates for it
+func (m *Model) commandCompletions() (base, word string, candidates []string) {
+	line := strings.TrimLeft(m.commandLine, " ")
+	name, rest, hasArgs := strings.Cut(line, " ")
+	if !hasArgs {
+		return m.commandLine[:len(m.commandLine)-len(name)], name, m.commands.complete(name)
+	}
+
+	args := strings.Split(rest, " ")
+	word = args[len(args)-1]
+	base = m.commandLine[:len(m.commandLine)-len(word)]
+	cmd, _, err := m.commands.lookup(name)
+	if err != nil || cmd.Complete == nil {
+		return base, word, nil
+	}
+
+	for _, candidate := range cmd.Complete(m, args) {
+		if strings.HasPrefix(candidate, word) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return base, word, candidates
+}
+
+// endCompletion forgets the candidates being cycled through
+func (m *Model) endCompletion() {
+	m.completions, m.completionIndex, m.completionBase, m.completionWord = nil, 0, "", ""
+}
+
+// commandLineView renders the command line, followed by the completion
+// candidates while there are several to cycle through
+func (m *Model) commandLineView() string {
+	view := ":" + m.commandLine + "█"
+	if len(m.completions) > 1 {
+		candidates := make([]string, len(m.completions))
+		for i, candidate := range m.completions {
+			if i == m.completionIndex {
+				candidate = "[" + candidate + "]"
+			}
+			candidates[i] = candidate
+		}
+		view += "  " + strings.Join(candidates, " ")
+	}
+	return view
+}
+
+// runCommand runs a command line: a command and its arguments, or a page
+// on its own. Pages are given by label ("xiv", "A-3") or number ("#12"
+// for the twelfth page whatever its label).
+func (m *Model) runCommand(line string) tea.Cmd {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	m.addCommandHistory(line)
+
+	name, args, _ := strings.Cut(line, " ")
+	cmd, force, err := m.commands.lookup(name)
+	if err != nil {
+		if args == "" {
+			if _, pageErr := m.document.ResolvePage(name); pageErr == nil {
+				return m.goToPageRef(name)
+			}
+		}
+		if errors.Is(err, ErrAmbiguousCommand) {
+			m.statusMessage = fmt.Sprintf("Ambiguous command: %s (%s)", name, strings.Join(m.commands.complete(name), ", "))
+		} else {
+			m.statusMessage = "Not a command: " + name
+		}
+		return nil
+	}
+	if force {
+		return cmd.Force(m, strings.TrimSpace(args))
+	}
+	return cmd.Run(m, strings.TrimSpace(args))
+}
+
+// goToPageRef opens the page a label or page number refers to
+func (m *Model) goToPageRef(ref string) tea.Cmd {
+	pageNum, err := m.document.ResolvePage(ref)
+	if err != nil {
+		m.statusMessage = "Cannot go to page: " + err.Error()
+		return nil
+	}
+	return m.jumpToPage(pageNum)
+}
@@ -0,0 +1,299 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// runCommandLine types a command line after ':' and runs it
+func runCommandLine(m *Model, line string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	for _, r := range line {
+		if r == ' ' {
+			m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+		} else {
+			m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+	}
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(m, cmd)
+}
+
+func TestGotoCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	m := NewModel(doc)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("goto")})
+	if status := m.renderStatusBar(); !strings.Contains(status, ":goto") {
+		t.Errorf("status bar %q should show the command line", status)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if m.keyHandler.Mode != KeyModeNormal || m.commandLine != "" {
+		t.Error("Esc should leave command mode")
+	}
+
+	steps := []struct {
+		line   string
+		page   int
+		status string
+	}{
+		{"goto 3", 3, ""},
+		{"#5", 5, ""},
+		{"2", 2, ""},
+		{"goto 9", 2, "Cannot go to page"},
+		{"goto xiv", 2, "no page labelled"},
+		{"frobnicate", 2, "Not a command: frobnicate"},
+	}
+	for _, step := range steps {
+		m.statusMessage = ""
+		runCommandLine(m, step.line)
+		if m.currentPage != step.page {
+			t.Errorf(":%s went to page %d, want %d", step.line, m.currentPage, step.page)
+		}
+		if !strings.Contains(m.statusMessage, step.status) {
+			t.Errorf(":%s status %q, want %q", step.line, m.statusMessage, step.status)
+		}
+		if m.keyHandler.Mode != KeyModeNormal {
+			t.Errorf(":%s left the command line open", step.line)
+		}
+	}
+	if m.pageTextNum != 2 {
+		t.Errorf("page %d loaded, want 2", m.pageTextNum)
+	}
+}
+
+func TestPaneLabels(t *testing.T) {
+	label := func(pageNum int) string {
+		if pageNum <= 3 {
+			return strings.Repeat("i", pageNum)
+		}
+		return "A-1"
+	}
+
+	toc := NewTOCPane(60, 20)
+	toc.SetPageLabels(label)
+	toc.SetTableOfContents(&pdf.TableOfContents{Source: "metadata", Entries: []pdf.TOCEntry{
+		{Title: "Preface", Page: 2, Level: 1},
+		{Title: "Appendix", Page: 4, Level: 1},
+	}})
+	if row := toc.formatTOCEntry(0); !strings.HasSuffix(strings.TrimSuffix(row, "\033[0m"), "p.ii") {
+		t.Errorf("TOC row %q should end with the page label", row)
+	}
+	if row := toc.formatTOCEntry(1); !strings.HasSuffix(row, "p.A-1") {
+		t.Errorf("TOC row %q should end with the page label", row)
+	}
+
+	bookmarks := NewBookmarkPane(60, 20)
+	bookmarks.SetPageLabels(label)
+	bookmarks.SetBookmarks([]config.Bookmark{{Page: 3}, {Page: 4, Note: "Tables"}})
+	bookmarks.Show()
+	view := bookmarks.View()
+	if !strings.Contains(view, "Page iii (3)") || !strings.Contains(view, "Page A-1 (4) - Tables") {
+		t.Errorf("bookmark pane should name pages by label:\n%s", view)
+	}
+}
+
+func TestCommandRegistry(t *testing.T) {
+	r := NewCommandRegistry()
+	run := func(*Model, string) tea.Cmd { return nil }
+	for _, cmd := range []Command{
+		{Name: "search", Run: run},
+		{Name: "set", Aliases: []string{"se"}, Run: run},
+		{Name: "theme", Run: run},
+	} {
+		if err := r.Register(cmd); err != nil {
+			t.Fatalf("Register(%s) unexpected error: %v", cmd.Name, err)
+		}
+	}
+	if err := r.Register(Command{Name: "sel", Aliases: []string{"se"}, Run: run}); !errors.Is(err, ErrCommandExists) {
+		t.Errorf("registering a taken alias: error %v, want ErrCommandExists", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"theme", "theme", nil},
+		{"th", "theme", nil},
+		{"se", "set", nil},
+		{"sea", "search", nil},
+		{"s", "", ErrAmbiguousCommand},
+		{"quit", "", ErrUnknownCommand},
+	}
+	for _, tt := range tests {
+		cmd, err := r.Lookup(tt.name)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Lookup(%q) error = %v, want %v", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || cmd.Name != tt.want {
+			t.Errorf("Lookup(%q) = %v, %v; want %s", tt.name, cmd, err, tt.want)
+		}
+	}
+}
+
+func TestCommandHistoryAndCompletion(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	m := NewModel(doc)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	runCommandLine(m, "goto 3")
+	runCommandLine(m, "set nocolumns")
+	runCommandLine(m, "goto 4")
+
+	// Up recalls the lines starting with what was typed, newest first
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("go")})
+	for _, want := range []string{"goto 4", "goto 3", "goto 3"} {
+		m.Update(tea.KeyMsg{Type: tea.KeyUp})
+		if m.commandLine != want {
+			t.Errorf("after up: command line %q, want %q", m.commandLine, want)
+		}
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if m.commandLine != "go" {
+		t.Errorf("after going down past the history: command line %q, want what was typed", m.commandLine)
+	}
+
+	// Tab completes a command name, then steps through its arguments
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("th")})
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.commandLine != "theme" {
+		t.Fatalf("after th<tab>: command line %q", m.commandLine)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.commandLine != "theme dark" || !strings.Contains(m.commandLineView(), "[dark] dracula") {
+		t.Errorf("after d<tab>: command line %q, view %q", m.commandLine, m.commandLineView())
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.commandLine != "theme d" {
+		t.Errorf("cycling past the last candidate: command line %q, want what was typed", m.commandLine)
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if m.commandLine != "theme dracula" {
+		t.Errorf("after shift+tab: command line %q", m.commandLine)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlW})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("columns=s")})
+	m.Update(tea.KeyMsg{Type: tea.KeyCtrlW})
+	if m.commandLine != "theme " {
+		t.Errorf("after ctrl+w: command line %q", m.commandLine)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	m := NewModel(doc)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(m, m.loadPage(1))
+
+	runCommandLine(m, "set columns headers! ignorecase?")
+	if m.columnView != config.ColumnsStacked || !m.hideRunning || m.statusMessage != "columns=stacked headers=off ignorecase=on" {
+		t.Errorf(":set columns headers! ignorecase?: columns %q, hidden %v, status %q", m.columnView, m.hideRunning, m.statusMessage)
+	}
+	runCommandLine(m, "se columns=side-by-side noheaders")
+	if m.columnView != config.ColumnsSideBySide || !m.hideRunning {
+		t.Errorf(":se columns=side-by-side: columns %q, hidden %v", m.columnView, m.hideRunning)
+	}
+	runCommandLine(m, "set nocolumns noignorecase")
+	if m.columnView != "" || !m.searchOptionsPane.GetOptions().CaseSensitive {
+		t.Errorf(":set nocolumns noignorecase: columns %q, options %+v", m.columnView, m.searchOptionsPane.GetOptions())
+	}
+	runCommandLine(m, "set columns=sideways")
+	if !strings.Contains(m.statusMessage, "Cannot set columns=sideways: want stacked, side-by-side, off") {
+		t.Errorf(":set columns=sideways: status %q", m.statusMessage)
+	}
+	runCommandLine(m, "set frobs")
+	if m.statusMessage != "Cannot set frobs: unknown option" {
+		t.Errorf(":set frobs: status %q", m.statusMessage)
+	}
+
+	runCommandLine(m, "theme nord")
+	if m.theme.Name != "Nord" || m.cfg.UI.Theme != "nord" {
+		t.Errorf(":theme nord: theme %s, saved %q", m.theme.Name, m.cfg.UI.Theme)
+	}
+	runCommandLine(m, "theme neon")
+	if m.theme.Name != "Nord" || !strings.Contains(m.statusMessage, `Unknown theme "neon"`) {
+		t.Errorf(":theme neon: theme %s, status %q", m.theme.Name, m.statusMessage)
+	}
+
+	runCommandLine(m, "bookmark Read this again")
+	if bookmarks := m.cfg.GetBookmarks(m.docKey); len(bookmarks) != 1 || bookmarks[0].Page != 1 || bookmarks[0].Note != "Read this again" {
+		t.Errorf(":bookmark: bookmarks %+v", bookmarks)
+	}
+
+	out := filepath.Join(t.TempDir(), "out.md")
+	runCommandLine(m, "export md "+out)
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.Contains(string(data), "\n## Page 3\n") || m.statusMessage != "Exported 5 pages to "+out {
+		t.Errorf(":export md: file %q (%v), status %q", data, err, m.statusMessage)
+	}
+	if err := os.WriteFile(out, []byte("notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runCommandLine(m, "export md "+out)
+	if data, _ := os.ReadFile(out); string(data) != "notes" || !strings.Contains(m.statusMessage, "add ! to overwrite") {
+		t.Errorf(":export md over a file: file %q, status %q; want it kept", data, m.statusMessage)
+	}
+	runCommandLine(m, "exp! md "+out)
+	if data, _ := os.ReadFile(out); !strings.Contains(string(data), "\n## Page 3\n") || m.statusMessage != "Exported 5 pages to "+out {
+		t.Errorf(":exp! md over a file: file %q, status %q; want it overwritten", data, m.statusMessage)
+	}
+	runCommandLine(m, "export docx out.docx")
+	if !strings.HasPrefix(m.statusMessage, "Usage: :export") {
+		t.Errorf(":export docx: status %q", m.statusMessage)
+	}
+
+	runCommandLine(m, `search /P\w+ 3/i`)
+	if len(m.advancedSearchResults) == 0 || m.advancedSearchResults[0].PageNum != 3 {
+		t.Errorf(":search /P\\w+ 3/i: results %+v", m.advancedSearchResults)
+	}
+	if opts := m.searchOptionsPane.GetOptions(); opts.RegexMode {
+		t.Error(":search should leave the search options as they are")
+	}
+	runCommandLine(m, "search /x/z")
+	if m.statusMessage != "Unknown search flag 'z' (want i or c)" {
+		t.Errorf(":search /x/z: status %q", m.statusMessage)
+	}
+
+	runCommandLine(m, "s")
+	if m.statusMessage != "Ambiguous command: s (search, set)" {
+		t.Errorf(":s: status %q", m.statusMessage)
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{':'}})
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	if _, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil || cmd() != tea.Quit() {
+		t.Error(":q should quit")
+	}
+}
@@ -0,0 +1,426 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// DefaultCommands returns a registry of the built-in commands
+func DefaultCommands() *CommandRegistry {
+	r := NewCommandRegistry()
+	for _, cmd := range []Command{
+		{
+			Name: "goto", Aliases: []string{"g", "go"}, Args: "<page>",
+			Help: "Go to a page by its label, or #12 by number",
+			Run:  runGoto,
+		},
+		{
+			Name: "set", Aliases: []string{"se"}, Args: "<option>[=value]...",
+			Help:     "Set columns, headers, images, ignorecase, wholeword, regex or ranked",
+			Run:      runSet,
+			Complete: completeSet,
+		},
+		{
+			Name: "theme", Aliases: []string{"colorscheme", "colo"}, Args: "[name]",
+			Help:     "Change the theme, or show the current one",
+			Run:      runTheme,
+			Complete: func(*Model, []string) []string { return config.ThemeKeys() },
+		},
+		{
+			Name: "bookmark", Aliases: []string{"bm"}, Args: "[note]",
+			Help: "Bookmark the current page, with a note",
+			Run:  runBookmark,
+		},
+		{
+			Name: "marks", Args: "[names]",
+			Help: "List the marks of this document and the global marks, or those named",
+			Run:  runMarks,
+		},
+		{
+			Name: "export", Args: "<md|txt> [file]",
+			Help:     "Write the text of the document to a new file; with ! overwrite one",
+			Run:      func(m *Model, args string) tea.Cmd { return runExport(m, args, false) },
+			Force:    func(m *Model, args string) tea.Cmd { return runExport(m, args, true) },
+			Complete: completeExport,
+		},
+		{
+			Name: "search", Args: "/regexp/[flags] | <text>",
+			Help: "Search for a regexp (flags i, c: ignore, match case) or text",
+			Run:  runSearch,
+		},
+		{
+			Name: "help", Aliases: []string{"h"},
+			Help: "Show or hide the help screen",
+			Run: func(m *Model, _ string) tea.Cmd {
+				m.showHelp = !m.showHelp
+				return nil
+			},
+		},
+		{
+			Name: "quit", Aliases: []string{"q", "q!", "qa", "x"},
+			Help: "Quit, saving the reading position",
+			Run:  func(m *Model, _ string) tea.Cmd { return m.quit() },
+		},
+	} {
+		if err := r.Register(cmd); err != nil {
+			// The built-in commands have distinct names
+			panic(err)
+		}
+	}
+	return r
+}
+
+// runGoto opens the page a label or page number refers to
+func runGoto(m *Model, args string) tea.Cmd {
+	if args == "" {
+		m.statusMessage = "Usage: :goto <page>"
+		return nil
+	}
+	return m.goToPageRef(args)
+}
+
+// setting is an option of the :set command
+type setting struct {
+	name   string
+	values []string // the first is what ":set name" picks; "off" is what ":set noname" picks
+	get    func(m *Model) string
+	set    func(m *Model, value string) tea.Cmd
+}
+
+// onOff is the value of a setting that is on or off
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// settings returns the options of the :set command
+func settings() []setting {
+	onOffValues := []string{"on", "off"}
+	searchOption := func(name string, get func(pdf.SearchOptions) bool, toggle func(*SearchOptionsPane)) setting {
+		return setting{
+			name: name, values: onOffValues,
+			get: func(m *Model) string { return onOff(get(m.searchOptionsPane.GetOptions())) },
+			set: func(m *Model, value string) tea.Cmd {
+				if onOff(get(m.searchOptionsPane.GetOptions())) != value {
+					toggle(m.searchOptionsPane)
+				}
+				return nil
+			},
+		}
+	}
+
+	return []setting{
+		{
+			name: "columns", values: []string{config.ColumnsStacked, config.ColumnsSideBySide, "off"},
+			get: func(m *Model) string {
+				if m.columnView == "" {
+					return "off"
+				}
+				return m.columnView
+			},
+			set: func(m *Model, value string) tea.Cmd {
+				if value == "off" {
+					value = ""
+				}
+				m.columnView = value
+				return m.changeView()
+			},
+		},
+		{
+			name: "headers", values: onOffValues,
+			get: func(m *Model) string { return onOff(!m.hideRunning) },
+			set: func(m *Model, value string) tea.Cmd {
+				m.hideRunning = value == "off"
+				return m.changeView()
+			},
+		},
+		{
+			name: "images", values: onOffValues,
+			get: func(m *Model) string { return onOff(m.showImages) },
+			// Only set when the value changes
+			set: func(m *Model, _ string) tea.Cmd { return m.toggleImages() },
+		},
+		searchOption("ignorecase", func(o pdf.SearchOptions) bool { return !o.CaseSensitive }, (*SearchOptionsPane).ToggleCaseSensitive),
+		searchOption("wholeword", func(o pdf.SearchOptions) bool { return o.WholeWord }, (*SearchOptionsPane).ToggleWholeWord),
+		searchOption("regex", func(o pdf.SearchOptions) bool { return o.RegexMode }, (*SearchOptionsPane).ToggleRegexMode),
+		searchOption("ranked", func(o pdf.SearchOptions) bool { return o.Ranked }, (*SearchOptionsPane).ToggleRanked),
+	}
+}
+
+// findSetting returns the setting with a name, or nil
+func findSetting(name string) *setting {
+	for _, s := range settings() {
+		if s.name == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+// runSet sets options the way vim's :set does: "columns" picks an
+// option's first value, "nocolumns" turns it off, "columns!" moves on to
+// its next value, "columns=side-by-side" sets it and "columns?" shows it.
+// Without arguments every option is shown.
+func runSet(m *Model, args string) tea.Cmd {
+	if args == "" {
+		var shown []string
+		for _, s := range settings() {
+			shown = append(shown, s.name+"="+s.get(m))
+		}
+		m.statusMessage = strings.Join(shown, " ")
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	var shown []string
+	for _, arg := range strings.Fields(args) {
+		s, value, err := parseSetting(m, arg)
+		if err != nil {
+			m.statusMessage = fmt.Sprintf("Cannot set %s: %v", arg, err)
+			return tea.Batch(cmds...)
+		}
+		if value != "" && value != s.get(m) {
+			cmds = append(cmds, s.set(m, value))
+		}
+		shown = append(shown, s.name+"="+s.get(m))
+	}
+	m.statusMessage = strings.Join(shown, " ")
+	return tea.Batch(cmds...)
+}
+
+// parseSetting returns the setting an argument of :set names and the value
+// it asks for, which is empty if the setting is only to be shown
+func parseSetting(m *Model, arg string) (*setting, string, error) {
+	name, value, hasValue := strings.Cut(arg, "=")
+	switch {
+	case hasValue:
+	case strings.HasSuffix(name, "?"):
+		name = strings.TrimSuffix(name, "?")
+	case strings.HasSuffix(name, "!"):
+		name = strings.TrimSuffix(name, "!")
+		if s := findSetting(name); s != nil {
+			current := s.get(m)
+			for i, v := range s.values {
+				if v == current {
+					return s, s.values[(i+1)%len(s.values)], nil
+				}
+			}
+			return s, s.values[0], nil
+		}
+	case findSetting(name) == nil && strings.HasPrefix(name, "no"):
+		name, value = strings.TrimPrefix(name, "no"), "off"
+		if s := findSetting(name); s != nil && !slices.Contains(s.values, "off") {
+			return nil, "", errors.New("cannot be turned off")
+		}
+	default:
+		if s := findSetting(name); s != nil {
+			value = s.values[0]
+		}
+	}
+
+	s := findSetting(name)
+	if s == nil {
+		return nil, "", errors.New("unknown option")
+	}
+	if value != "" && !slices.Contains(s.values, value) {
+		return nil, "", fmt.Errorf("want %s", strings.Join(s.values, ", "))
+	}
+	return s, value, nil
+}
+
+// completeSet completes option names, and values after "name="
+func completeSet(_ *Model, args []string) []string {
+	word := args[len(args)-1]
+	var candidates []string
+	for _, s := range settings() {
+		if name, _, hasValue := strings.Cut(word, "="); hasValue {
+			if name == s.name {
+				for _, v := range s.values {
+					candidates = append(candidates, name+"="+v)
+				}
+			}
+			continue
+		}
+		candidates = append(candidates, s.name)
+		if slices.Contains(s.values, "off") {
+			candidates = append(candidates, "no"+s.name)
+		}
+	}
+	return candidates
+}
+
+// runTheme changes the theme, remembering it for the next session
+func runTheme(m *Model, args string) tea.Cmd {
+	switch {
+	case args == "":
+		m.statusMessage = fmt.Sprintf("Theme: %s (available: %s)", config.ThemeKey(m.theme), strings.Join(config.ThemeKeys(), ", "))
+	case !config.IsValidTheme(args):
+		m.statusMessage = fmt.Sprintf("Unknown theme %q (available: %s)", args, strings.Join(config.ThemeKeys(), ", "))
+	default:
+		m.changeTheme(args)
+		m.statusMessage = "Theme: " + m.theme.Name
+	}
+	return nil
+}
+
+// runBookmark bookmarks the current page, replacing the note of a bookmark
+// already there
+func runBookmark(m *Model, note string) tea.Cmd {
+	if m.docKey == "" {
+		m.statusMessage = "Cannot bookmark: the document is not identified"
+		return nil
+	}
+	m.cfg.AddBookmark(m.docKey, m.currentPage, note)
+	m.saveConfig()
+	m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docKey))
+	m.statusMessage = "Bookmarked page " + m.pageName(m.currentPage)
+	return nil
+}
+
+// ExportedMsg reports the end of an export started by :export
+type ExportedMsg struct {
+	Path  string
+	Pages int
+	Err   error
+}
+
+// runExport writes the text of the document to a file in the background.
+// Without a file name the document's name is used, with the format's
+// extension, in the current directory. A file already there is only
+// overwritten if overwrite is set, by :export!.
+func runExport(m *Model, args string, overwrite bool) tea.Cmd {
+	name, path, _ := strings.Cut(args, " ")
+	format, err := pdf.ParseExportFormat(name)
+	if err != nil {
+		m.statusMessage = "Usage: :export[!] <md|txt> [file]"
+		return nil
+	}
+	path = expandHome(strings.TrimSpace(path))
+	if path == "" {
+		base := filepath.Base(m.document.Path())
+		path = strings.TrimSuffix(base, filepath.Ext(base)) + "." + string(format)
+	}
+
+	doc := m.document
+	m.statusMessage = "Exporting to " + path
+	return func() tea.Msg {
+		return ExportedMsg{Path: path, Pages: doc.GetPageCount(), Err: exportDocument(doc, path, format, overwrite)}
+	}
+}
+
+// exportDocument writes the text of a document to a file, failing with
+// fs.ErrExist if the file exists and overwrite is not set
+func exportDocument(doc *pdf.Document, path string, format pdf.ExportFormat, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := doc.Export(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// handleExported reports how an export went
+func (m *Model) handleExported(msg ExportedMsg) {
+	if errors.Is(msg.Err, fs.ErrExist) {
+		m.statusMessage = fmt.Sprintf("Not exported: %s exists (add ! to overwrite)", msg.Path)
+		return
+	}
+	if msg.Err != nil {
+		m.statusMessage = fmt.Sprintf("Cannot export to %s: %v", msg.Path, msg.Err)
+		return
+	}
+	m.statusMessage = fmt.Sprintf("Exported %d pages to %s", msg.Pages, msg.Path)
+}
+
+// completeExport completes the format, then file names
+func completeExport(_ *Model, args []string) []string {
+	if len(args) == 1 {
+		var formats []string
+		for _, format := range pdf.ExportFormats() {
+			formats = append(formats, string(format))
+		}
+		return formats
+	}
+	return completePath(args[len(args)-1])
+}
+
+// completePath returns the files and directories whose path starts with
+// prefix, directories ending with a slash
+func completePath(prefix string) []string {
+	matches, _ := filepath.Glob(expandHome(prefix) + "*")
+	paths := make([]string, 0, len(matches))
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			match += string(filepath.Separator)
+		}
+		if strings.HasPrefix(prefix, "~") {
+			home, _ := os.UserHomeDir()
+			match = "~" + strings.TrimPrefix(match, home)
+		}
+		paths = append(paths, match)
+	}
+	return paths
+}
+
+// expandHome replaces a leading ~ in a path with the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+// runSearch searches for a regular expression written /like this/ and
+// followed by flags, or for text with the search options as they are
+func runSearch(m *Model, args string) tea.Cmd {
+	if args == "" {
+		m.statusMessage = "Usage: :search /regexp/[flags] or :search <text>"
+		return nil
+	}
+	opts := m.searchOptionsPane.GetOptions()
+	query := args
+	if strings.HasPrefix(args, "/") {
+		end := strings.LastIndexByte(args, '/')
+		if end == 0 {
+			end = len(args)
+			args += "/"
+		}
+		query = args[1:end]
+		opts.RegexMode, opts.WholeWord, opts.Ranked = true, false, false
+		for _, flag := range args[end+1:] {
+			switch flag {
+			case 'i':
+				opts.CaseSensitive = false
+			case 'c':
+				opts.CaseSensitive = true
+			default:
+				m.statusMessage = fmt.Sprintf("Unknown search flag %q (want i or c)", flag)
+				return nil
+			}
+		}
+	}
+	m.searchQuery = query
+	return m.startSearch(query, opts)
+}
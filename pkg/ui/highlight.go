@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// matchSpan is the byte range of a search match in a page's text
+type matchSpan struct {
+	start, end int
+}
+
+// pageMatchSpans returns the spans of the search results on a page and the
+// index of the current match among them (-1 if it is on another page)
+func pageMatchSpans(results []pdf.SearchResultAdvanced, page, currentMatch int) ([]matchSpan, int) {
+	var spans []matchSpan
+	current := -1
+	for i, result := range results {
+		if result.PageNum != page {
+			continue
+		}
+		if i == currentMatch {
+			current = len(spans)
+		}
+		spans = append(spans, matchSpan{start: result.Start, end: result.End})
+	}
+	return spans, current
+}
+
+// highlightMatches styles the given spans of text, using currentStyle for
+// the span at index current and matchStyle for the others. Spans must be
+// in order; spans that overlap an earlier one or fall outside the text are
+// skipped. Each line of a span is styled separately so that a match
+// spanning lines does not disturb the layout.
+func highlightMatches(text string, spans []matchSpan, current int, matchStyle, currentStyle lipgloss.Style) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for i, span := range spans {
+		if span.start < last || span.start >= span.end || span.end > len(text) {
+			continue
+		}
+		sb.WriteString(text[last:span.start])
+
+		style := matchStyle
+		if i == current {
+			style = currentStyle
+		}
+		for j, part := range strings.Split(text[span.start:span.end], "\n") {
+			if j > 0 {
+				sb.WriteByte('\n')
+			}
+			if part != "" {
+				sb.WriteString(style.Render(part))
+			}
+		}
+		last = span.end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// markStyle renders text between the given markers, independent of the
+// terminal's color support
+func markStyle(open, close string) lipgloss.Style {
+	return lipgloss.NewStyle().Transform(func(s string) string {
+		return open + s + close
+	})
+}
+
+func TestHighlightMatches(t *testing.T) {
+	match, current := markStyle("[", "]"), markStyle("<", ">")
+	tests := []struct {
+		name    string
+		text    string
+		spans   []matchSpan
+		current int
+		want    string
+	}{
+		{"no spans", "plain text", nil, -1, "plain text"},
+		{"current and others", "one two one", []matchSpan{{0, 3}, {8, 11}}, 1, "[one] two <one>"},
+		{"spans lines", "ab\ncd", []matchSpan{{1, 4}}, 0, "a<b>\n<c>d"},
+		{"overlapping", "aaaa", []matchSpan{{0, 2}, {1, 3}, {2, 4}}, -1, "[aa][aa]"},
+		{"out of range", "short", []matchSpan{{2, 40}}, -1, "short"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := highlightMatches(tt.text, tt.spans, tt.current, match, current)
+			if got != tt.want {
+				t.Errorf("highlightMatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPageMatchSpans(t *testing.T) {
+	result := func(page, start, end int) pdf.SearchResultAdvanced {
+		return pdf.SearchResultAdvanced{SearchResult: pdf.SearchResult{PageNum: page, Start: start, End: end}}
+	}
+	results := []pdf.SearchResultAdvanced{result(1, 0, 2), result(2, 5, 7), result(2, 9, 11), result(3, 1, 2)}
+
+	spans, current := pageMatchSpans(results, 2, 2)
+	if len(spans) != 2 || spans[0] != (matchSpan{5, 7}) || current != 1 {
+		t.Errorf("pageMatchSpans(page 2) = %v, %d; want two spans with current 1", spans, current)
+	}
+	if _, current := pageMatchSpans(results, 2, 0); current != -1 {
+		t.Errorf("current = %d, want -1 when the current match is on another page", current)
+	}
+}
+
+func TestViewerHighlightsMatches(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+	model.handleWindowResize(tea.WindowSizeMsg{Width: 400, Height: 20})
+
+	search(model, "page")
+	model.styles.Accent, model.styles.CurrentMatch = markStyle("[", "]"), markStyle("<", ">")
+	model.renderPageContent()
+
+	view := model.viewport.View()
+	if !strings.Contains(view, "<Page>") {
+		t.Errorf("Expected the current match marked in the viewer, got %q", view)
+	}
+	if !strings.Contains(view, "[page]") {
+		t.Errorf("Expected other matches marked in the viewer, got %q", view)
+	}
+}
+
+func TestRevealCurrentMatch(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+	model.viewport.Height = 10
+	model.viewport.SetContent(strings.Repeat("line\n", 100))
+	model.advancedSearchResults = []pdf.SearchResultAdvanced{
+		{SearchResult: pdf.SearchResult{PageNum: 1, LineNum: 50}},
+		{SearchResult: pdf.SearchResult{PageNum: 1, LineNum: 52}},
+	}
+
+	model.currentMatch = 0
+	model.revealCurrentMatch()
+	if model.viewport.YOffset != 46 {
+		t.Errorf("YOffset = %d, want 46 (match a third of the way down)", model.viewport.YOffset)
+	}
+
+	// A match that is already visible does not move the viewport
+	model.currentMatch = 1
+	model.revealCurrentMatch()
+	if model.viewport.YOffset != 46 {
+		t.Errorf("YOffset = %d, want 46 unchanged", model.viewport.YOffset)
+	}
+}
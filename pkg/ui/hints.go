@@ -0,0 +1,230 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/luxor/lumos/pkg/config"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// hint labels a link or cross-reference in view while in hint mode
+type hint struct {
+	label  string
+	cell   linkCell
+	target int // index of the link among the page's links, then its cross-references
+}
+
+// hintLabels returns n labels made of chars, all of the same length so
+// that none is the start of another
+func hintLabels(n int, chars string) []string {
+	alphabet := []rune(chars)
+	length := 1
+	for total := len(alphabet); total < n; total *= len(alphabet) {
+		length++
+	}
+
+	labels := make([]string, n)
+	for i := range labels {
+		label := make([]rune, length)
+		for j, k := length-1, i; j >= 0; j, k = j-1, k/len(alphabet) {
+			label[j] = alphabet[k%len(alphabet)]
+		}
+		labels[i] = string(label)
+	}
+	return labels
+}
+
+// startHints labels the links and cross-references in the viewport and
+// waits for one of the labels to be typed. In copy mode only links are
+// labelled, to copy where they lead.
+func (m *Model) startHints(copyMode bool) {
+	if m.pageTextNum != m.currentPage {
+		return
+	}
+
+	var hints []hint
+	for i, cell := range m.linkCells {
+		if copyMode && i >= len(m.pageLinks) {
+			break
+		}
+		if cell.row >= m.viewport.YOffset && cell.row < m.viewport.YOffset+m.viewport.Height {
+			hints = append(hints, hint{cell: cell, target: i})
+		}
+	}
+	if len(hints) == 0 {
+		m.statusMessage = "No links in view"
+		return
+	}
+	sort.SliceStable(hints, func(i, j int) bool {
+		a, b := hints[i].cell, hints[j].cell
+		return a.row < b.row || (a.row == b.row && a.col < b.col)
+	})
+
+	chars := m.cfg.UI.HintChars
+	if chars == "" {
+		chars = config.DefaultHintChars
+	}
+	for i, label := range hintLabels(len(hints), chars) {
+		hints[i].label = label
+	}
+	m.hints, m.hintTyped, m.hintCopy = hints, "", copyMode
+	m.statusMessage = ""
+	m.keyHandler.Mode = KeyModeHint
+}
+
+// endHints leaves hint mode
+func (m *Model) endHints() {
+	m.hints, m.hintTyped = nil, ""
+	m.keyHandler.Mode = m.normalMode()
+}
+
+// typeHint narrows the hints down to the labels starting with what has
+// been typed, following the link once a whole label is typed
+func (m *Model) typeHint(msg tea.KeyMsg) tea.Cmd {
+	if msg.Type != tea.KeyRunes {
+		return nil
+	}
+	typed := m.hintTyped + string(msg.Runes)
+	matched := -1
+	for i, h := range m.hints {
+		if strings.HasPrefix(h.label, typed) {
+			matched = i
+			if h.label == typed {
+				break
+			}
+		}
+	}
+	if matched < 0 {
+		m.statusMessage = "No hint " + typed
+		return nil
+	}
+	m.hintTyped = typed
+	if h := m.hints[matched]; h.label == typed {
+		copyMode := m.hintCopy
+		m.endHints()
+		return m.followHint(h.target, copyMode)
+	}
+	return nil
+}
+
+// followHint follows the link or cross-reference a hint labels, or copies
+// where the link leads
+func (m *Model) followHint(target int, copyMode bool) tea.Cmd {
+	if target >= len(m.pageLinks) {
+		xref := m.pageXRefs[target-len(m.pageLinks)]
+		m.statusMessage = "Looking up " + xref.Text
+		return resolveCrossRef(m.document, xref)
+	}
+
+	link := m.pageLinks[target]
+	if !copyMode {
+		return m.followLink(link)
+	}
+	copied := link.Target()
+	switch link.Kind {
+	case pdf.LinkURI:
+		copied = link.URI
+	case pdf.LinkRemote:
+		copied = link.File
+	}
+	m.statusMessage = "Copied " + pdf.EscapeControls(copied)
+	return m.copyText(copied, "the link")
+}
+
+// CrossRefResolvedMsg carries the page a cross-reference leads to
+type CrossRefResolvedMsg struct {
+	Ref  pdf.CrossRef
+	Page int
+	Err  error
+}
+
+// resolveCrossRef looks up where a cross-reference leads in the background;
+// figures and tables may need every page's text
+func resolveCrossRef(doc *pdf.Document, ref pdf.CrossRef) tea.Cmd {
+	return func() tea.Msg {
+		page, err := doc.ResolveCrossRef(ref)
+		return CrossRefResolvedMsg{Ref: ref, Page: page, Err: err}
+	}
+}
+
+// handleCrossRefResolved follows a cross-reference to its page like a link
+func (m *Model) handleCrossRefResolved(msg CrossRefResolvedMsg) tea.Cmd {
+	if msg.Err != nil {
+		m.statusMessage = "Cannot find " + msg.Ref.Text
+		return nil
+	}
+	cmd := m.followLink(pdf.Link{Kind: pdf.LinkPage, Page: msg.Page})
+	m.statusMessage = fmt.Sprintf("Followed %s to page %s (backspace to go back)", msg.Ref.Text, m.document.PageLabel(msg.Page))
+	return cmd
+}
+
+// hintView returns the viewport with the hint labels still matching what
+// has been typed drawn over the start of their links
+func (m *Model) hintView() string {
+	rows := strings.Split(m.viewport.View(), "\n")
+	for _, h := range m.hints {
+		row := h.cell.row - m.viewport.YOffset
+		if row < 0 || row >= len(rows) || !strings.HasPrefix(h.label, m.hintTyped) {
+			continue
+		}
+		label := m.styles.Hint.Render(h.label[len(m.hintTyped):])
+		if m.hintTyped != "" {
+			label = m.styles.HintTyped.Render(m.hintTyped) + label
+		}
+		rows[row] = overlay(rows[row], h.cell.col, label, ansi.StringWidth(h.label))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// overlay draws label, width cells wide, over a line of styled text from
+// column col on. The styling in effect there is restored after the label.
+func overlay(line string, col int, label string, width int) string {
+	var sb, sgr strings.Builder
+	var state byte
+	pos, drawn := 0, false
+	draw := func() {
+		sb.WriteString(strings.Repeat(" ", max(col-pos, 0)))
+		if sgr.Len() > 0 {
+			sb.WriteString("\x1b[0m")
+		}
+		sb.WriteString(label)
+		sb.WriteString(sgr.String())
+		drawn = true
+	}
+
+	for len(line) > 0 {
+		seq, w, n, newState := ansi.DecodeSequence(line, state, nil)
+		state, line = newState, line[n:]
+		if w == 0 {
+			sb.WriteString(seq)
+			if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+				if seq == "\x1b[0m" || seq == "\x1b[m" {
+					sgr.Reset()
+				} else {
+					sgr.WriteString(seq)
+				}
+			}
+			continue
+		}
+
+		switch {
+		case pos+w <= col || pos >= col+width:
+			sb.WriteString(seq)
+		case !drawn:
+			// The label covers this cell, or starts inside a wide one
+			draw()
+			sb.WriteString(strings.Repeat(" ", max(pos+w-(col+width), 0)))
+		default:
+			sb.WriteString(strings.Repeat(" ", max(pos+w-(col+width), 0)))
+		}
+		pos += w
+	}
+	if !drawn {
+		draw()
+	}
+	return sb.String()
+}
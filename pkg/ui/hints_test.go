@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+func TestHintLabels(t *testing.T) {
+	tests := []struct {
+		n     int
+		chars string
+		want  []string
+	}{
+		{0, "asd", []string{}},
+		{3, "asd", []string{"a", "s", "d"}},
+		{5, "asd", []string{"aa", "as", "ad", "sa", "ss"}},
+	}
+	for _, tt := range tests {
+		if got := hintLabels(tt.n, tt.chars); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("hintLabels(%d, %q) = %q, want %q", tt.n, tt.chars, got, tt.want)
+		}
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	tests := []struct {
+		line  string
+		col   int
+		label string
+		width int
+		want  string
+	}{
+		{"hello world", 6, "ab", 2, "hello abrld"},
+		{"ab", 4, "X", 1, "ab  X"},
+		{"日本", 1, "X", 1, " X本"},
+		// The style the label interrupts carries on after it
+		{"\x1b[4mlink\x1b[0m text", 0, "X", 1, "\x1b[4m\x1b[0mX\x1b[4mink\x1b[0m text"},
+	}
+	for _, tt := range tests {
+		if got := overlay(tt.line, tt.col, tt.label, tt.width); got != tt.want {
+			t.Errorf("overlay(%q, %d, %q) = %q, want %q", tt.line, tt.col, tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestHintMode(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(PageLoadedMsg{Page: 1, Content: "See Chapter Two\nonline docs\nmore on page 3", Links: testLinks()})
+
+	// Hints label the links, then the cross-reference, in reading order
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	if model.keyHandler.Mode != KeyModeHint || len(model.hints) != 3 {
+		t.Fatalf("after f: mode %v, hints %+v", model.keyHandler.Mode, model.hints)
+	}
+	view := ansi.Strip(model.hintView())
+	for _, want := range []string{"See ahapter Two", "snline docs", "more on dage 3"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("hint view should contain %q, got:\n%s", want, view)
+		}
+	}
+
+	// Typing something no label starts with changes nothing
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	if model.keyHandler.Mode != KeyModeHint || model.hintTyped != "" {
+		t.Fatalf("after x: mode %v, typed %q", model.keyHandler.Mode, model.hintTyped)
+	}
+
+	// The cross-reference is followed like a link
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'d'}})
+	drive(model, cmd)
+	if model.keyHandler.Mode != KeyModeNormal || model.currentPage != 3 {
+		t.Fatalf("after d: mode %v, page %d; want page 3", model.keyHandler.Mode, model.currentPage)
+	}
+	drive(model, model.jumpOlder())
+	model.Update(PageLoadedMsg{Page: 1, Content: "See Chapter Two\nonline docs\nmore on page 3", Links: testLinks()})
+
+	// Copy hints label links only
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'F'}})
+	if len(model.hints) != 2 {
+		t.Fatalf("after F: hints %+v, want 2", model.hints)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if model.GetClipboard() != "https://example.com" || model.keyHandler.Mode != KeyModeNormal {
+		t.Errorf("after F s: clipboard %q, mode %v", model.GetClipboard(), model.keyHandler.Mode)
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if model.keyHandler.Mode != KeyModeNormal || model.hints != nil {
+		t.Errorf("after esc: mode %v, hints %+v", model.keyHandler.Mode, model.hints)
+	}
+}
+
+func TestFollowLinkToAnotherDocument(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+	other, err := filepath.Abs("../../test/fixtures/simple.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.loadPage(1))
+
+	drive(model, model.followLink(pdf.Link{Kind: pdf.LinkRemote, File: other, Page: 1}))
+	if model.document.Path() != other || model.currentPage != 1 {
+		t.Fatalf("after following: document %s, page %d", model.document.Path(), model.currentPage)
+	}
+	if model.pageTextNum != 1 || !strings.Contains(model.statusMessage, "simple.pdf") {
+		t.Errorf("after following: page %d loaded, status %q", model.pageTextNum, model.statusMessage)
+	}
+
+	drive(model, model.jumpOlder())
+	if filepath.Base(model.document.Path()) != "multipage.pdf" || model.currentPage != 1 {
+		t.Errorf("after going back: document %s, page %d", model.document.Path(), model.currentPage)
+	}
+
+	// Forward again through the jump list
+	drive(model, model.jumpNewer())
+	if model.document.Path() != other {
+		t.Errorf("after going forward: document %s", model.document.Path())
+	}
+
+	drive(model, model.followLink(pdf.Link{Kind: pdf.LinkRemote, File: "missing.pdf"}))
+	if !strings.Contains(model.statusMessage, "Cannot open missing.pdf") {
+		t.Errorf("following a link to a missing file: status %q", model.statusMessage)
+	}
+}
@@ -0,0 +1,148 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+)
+
+// maxJumps bounds the jump list
+const maxJumps = 100
+
+// pagePosition is a page and scroll offset to return to
+type pagePosition struct {
+	file         string // path of the document, if it may be another one
+	page, scroll int
+}
+
+// here returns the current position
+func (m *Model) here() pagePosition {
+	return pagePosition{file: m.docPath, page: m.currentPage, scroll: m.viewport.YOffset}
+}
+
+// recordJump adds the current position to the jump list before jumping
+// away from it. Like vim, a page is in the list once, where it was last
+// jumped from, and recording a jump ends any walk through the list.
+func (m *Model) recordJump() {
+	m.addJump(m.here())
+	m.jumpIndex = len(m.jumps)
+	m.jumpsDirty = true
+}
+
+// addJump appends a position to the jump list, dropping the older entry
+// for its page and the oldest entries beyond maxJumps
+func (m *Model) addJump(pos pagePosition) {
+	kept := m.jumps[:0]
+	for _, jump := range m.jumps {
+		if jump.file != pos.file || jump.page != pos.page {
+			kept = append(kept, jump)
+		}
+	}
+	m.jumps = append(kept, pos)
+	if len(m.jumps) > maxJumps {
+		m.jumps = m.jumps[len(m.jumps)-maxJumps:]
+	}
+}
+
+// jumpToPage shows a page, recording the jump if it leaves the current one
+func (m *Model) jumpToPage(page int) tea.Cmd {
+	if page != m.currentPage {
+		m.recordJump()
+	}
+	m.currentPage = page
+	return m.loadPage(m.currentPage)
+}
+
+// jumpOlder goes to the previous position in the jump list. Starting a
+// walk back records where it started, so jumpNewer can return there.
+func (m *Model) jumpOlder() tea.Cmd {
+	return m.jumpOlderBy(1)
+}
+
+// jumpOlderBy goes n positions back in the jump list, stopping at the
+// oldest
+func (m *Model) jumpOlderBy(n int) tea.Cmd {
+	if m.jumpIndex >= len(m.jumps) {
+		m.addJump(m.here())
+		m.jumpIndex = len(m.jumps) - 1
+	}
+	if m.jumpIndex == 0 {
+		m.statusMessage = "At the oldest jump"
+		return nil
+	}
+	m.jumpIndex = max(m.jumpIndex-n, 0)
+	return m.goToJump(m.jumps[m.jumpIndex])
+}
+
+// jumpNewer goes to the next position in the jump list after jumpOlder
+func (m *Model) jumpNewer() tea.Cmd {
+	return m.jumpNewerBy(1)
+}
+
+// jumpNewerBy goes n positions forward in the jump list, stopping at the
+// newest
+func (m *Model) jumpNewerBy(n int) tea.Cmd {
+	if m.jumpIndex+1 >= len(m.jumps) {
+		m.statusMessage = "At the newest jump"
+		return nil
+	}
+	m.jumpIndex = min(m.jumpIndex+n, len(m.jumps)-1)
+	return m.goToJump(m.jumps[m.jumpIndex])
+}
+
+// canJumpNewer reports whether a walk back through the jump list is under
+// way, so there is a newer position to go to
+func (m *Model) canJumpNewer() bool {
+	return m.jumpIndex+1 < len(m.jumps)
+}
+
+// jumpBack goes to where the last jump was made from, like vim's previous
+// context mark.
+// That is a jump itself, so repeating it goes back and forth.
+func (m *Model) jumpBack() tea.Cmd {
+	here := m.here()
+	for i := len(m.jumps) - 1; i >= 0; i-- {
+		if target := m.jumps[i]; target.file != here.file || target.page != here.page {
+			m.recordJump()
+			return m.goToJump(target)
+		}
+	}
+	m.statusMessage = "No previous jump"
+	return nil
+}
+
+// goToJump shows a position of the jump list, reopening its document if
+// it is in another one
+func (m *Model) goToJump(pos pagePosition) tea.Cmd {
+	if pos.file != "" && pos.file != m.docPath {
+		return m.openDocument(pos.file, DocumentOpenedMsg{Page: pos.page, Scroll: pos.scroll, Jump: true})
+	}
+	m.currentPage = min(max(pos.page, 1), m.document.GetPageCount())
+	m.pendingScroll = pos.scroll
+	return m.loadPage(m.currentPage)
+}
+
+// restoreJumps loads the jump list saved for the document
+func (m *Model) restoreJumps() {
+	state, ok := m.cfg.GetDocState(m.docKey)
+	if !ok {
+		return
+	}
+	for _, jump := range state.Jumps {
+		if jump.Page <= m.document.GetPageCount() {
+			m.addJump(pagePosition{file: m.docPath, page: jump.Page, scroll: jump.Scroll})
+		}
+	}
+	m.jumpIndex = len(m.jumps)
+}
+
+// docJumps returns the positions of the jump list in the current
+// document, for saving
+func (m *Model) docJumps() []config.Jump {
+	var jumps []config.Jump
+	for _, jump := range m.jumps {
+		if jump.file == m.docPath {
+			jumps = append(jumps, config.Jump{Page: jump.page, Scroll: jump.scroll})
+		}
+	}
+	return jumps
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+func keyPress(s string) tea.KeyMsg {
+	switch s {
+	case "ctrl+o":
+		return tea.KeyMsg{Type: tea.KeyCtrlO}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestJumpList(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	model := NewModel(doc)
+	model.keyHandler.Timeout = 0 // drive would wait the timeout out
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.loadPage(1))
+
+	// Jumps from page 1 to 3 to 5; moving a page on is not a jump
+	drive(model, model.goToPageRef("3"))
+	drive(model, model.goToNextPage())
+	drive(model, model.goToLastPage())
+	if model.currentPage != 5 || len(model.jumps) != 2 {
+		t.Fatalf("after jumping: page %d, jumps %v", model.currentPage, model.jumps)
+	}
+
+	press := func(keys ...string) {
+		for _, k := range keys {
+			_, cmd := model.Update(keyPress(k))
+			drive(model, cmd)
+		}
+	}
+
+	press("ctrl+o")
+	if model.currentPage != 4 {
+		t.Errorf("after ctrl+o: page %d, want 4", model.currentPage)
+	}
+	press("ctrl+o", "ctrl+o")
+	if model.currentPage != 1 || model.statusMessage != "At the oldest jump" {
+		t.Errorf("after ctrl+o twice more: page %d, status %q; want page 1", model.currentPage, model.statusMessage)
+	}
+	press("tab", "tab")
+	if model.currentPage != 5 || model.activePaneIdx != 1 {
+		t.Errorf("after tab twice: page %d, pane %d; want page 5", model.currentPage, model.activePaneIdx)
+	}
+
+	// At the newest jump tab cycles the panes again
+	press("tab")
+	if model.currentPage != 5 || model.activePaneIdx != 2 {
+		t.Errorf("after tab at the newest jump: page %d, pane %d", model.currentPage, model.activePaneIdx)
+	}
+	model.activePaneIdx = 1
+
+	// '' goes back and forth
+	press("'", "'")
+	if model.currentPage != 4 {
+		t.Errorf("after '': page %d, want 4", model.currentPage)
+	}
+	press("`", "`")
+	if model.currentPage != 5 {
+		t.Errorf("after ``: page %d, want 5", model.currentPage)
+	}
+	press("'", "1")
+	if model.currentPage != 5 || model.keyHandler.Mode != KeyModeNormal || !strings.HasPrefix(model.statusMessage, "Not a mark") {
+		t.Errorf("after '1: page %d, mode %v, status %q", model.currentPage, model.keyHandler.Mode, model.statusMessage)
+	}
+}
+
+func TestJumpListLimit(t *testing.T) {
+	model := &Model{}
+	for page := 1; page <= maxJumps+10; page++ {
+		model.addJump(pagePosition{page: page})
+	}
+	model.addJump(pagePosition{page: maxJumps + 5})
+	if len(model.jumps) != maxJumps {
+		t.Fatalf("jump list has %d entries, want %d", len(model.jumps), maxJumps)
+	}
+	if first, last := model.jumps[0].page, model.jumps[maxJumps-1].page; first != 11 || last != maxJumps+5 {
+		t.Errorf("jump list runs from page %d to %d, want 11 to %d", first, last, maxJumps+5)
+	}
+}
+
+func TestJumpListSaved(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.loadPage(1))
+	drive(model, model.goToPageRef("2"))
+	drive(model, model.goToPageRef("4"))
+	model.saveReadingState()
+
+	resumed := NewModel(doc)
+	resumed.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	if len(resumed.jumps) != 2 || resumed.jumps[0].page != 1 || resumed.jumps[1].page != 2 {
+		t.Fatalf("restored jumps %v, want pages 1 and 2", resumed.jumps)
+	}
+
+	// '' works across sessions
+	drive(resumed, resumed.loadPage(resumed.currentPage))
+	drive(resumed, resumed.jumpBack())
+	if resumed.currentPage != 2 {
+		t.Errorf("after '' in a new session: page %d, want 2", resumed.currentPage)
+	}
+}
@@ -1,10 +1,33 @@
 package ui
 
-import "github.com/charmbracelet/bubbletea"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
-// KeyHandler handles vim-style keybindings
+	"github.com/charmbracelet/bubbletea"
+)
+
+// DefaultKeyTimeout is how long a key sequence waits for its next key
+// unless configured otherwise
+const DefaultKeyTimeout = time.Second
+
+// maxCountDigits bounds the count typed before a key
+const maxCountDigits = 6
+
+// KeyHandler turns key presses into the actions the keymap binds them to
+// in the current mode. In normal and visual mode and the table of
+// contents a count can be typed first ("5j", "20G"). Keys of a sequence or
+// count left unfinished for Timeout are dropped.
 type KeyHandler struct {
-	Mode KeyMode
+	Mode    KeyMode
+	Keymap  *Keymap
+	Timeout time.Duration // zero waits for the next key however long it takes
+
+	pending []string // keys typed so far of a longer sequence
+	count   string   // digits of the count typed so far
+	timer   int      // identifies the latest timeout started by Wait
 }
 
 // KeyMode represents the current key handling mode
@@ -14,240 +37,146 @@ const (
 	KeyModeNormal KeyMode = iota
 	KeyModeSearch
 	KeyModeCommand
+	KeyModeHint   // picking a link by the label drawn over it
+	KeyModeMark   // naming the mark to set or go to
+	KeyModeVisual // moving the text cursor to select text
+	KeyModeTOC    // moving through the table of contents while it has the focus
 )
 
-// NewKeyHandler creates a new key handler
-func NewKeyHandler() *KeyHandler {
-	return &KeyHandler{
-		Mode: KeyModeNormal,
-	}
-}
+var keyModeNames = []string{"normal", "search", "command", "hint", "mark", "visual", "toc"}
 
-// HandleKey processes a key press
-func (kh *KeyHandler) HandleKey(msg tea.KeyMsg) tea.Cmd {
-	switch kh.Mode {
-	case KeyModeNormal:
-		return kh.handleNormalKey(msg)
-	case KeyModeSearch:
-		return kh.handleSearchKey(msg)
-	case KeyModeCommand:
-		return kh.handleCommandKey(msg)
-	}
-	return nil
-}
+// countModes are the modes a count can be typed in before a key
+var countModes = map[KeyMode]bool{KeyModeNormal: true, KeyModeVisual: true, KeyModeTOC: true}
 
-// handleNormalKey processes keys in normal mode
-func (kh *KeyHandler) handleNormalKey(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	// Navigation
-	case "j", "down":
-		return ScrollDown
-	case "k", "up":
-		return ScrollUp
-	case "d":
-		return HalfPageDown
-	case "u":
-		return HalfPageUp
-	case "g":
-		return GoToTop
-	case "G", "shift+g":
-		return GoToBottom
-
-	// Page navigation
-	case "ctrl+n":
-		return NextPage
-	case "ctrl+p":
-		return PrevPage
-
-	// Search
-	case "/":
-		return EnterSearch
-	case "ctrl+\\", "ctrl+/":
-		return ToggleSearchOptions
-	case "ctrl+h":
-		return ToggleSearchHistory
-
-	// UI
-	case "tab":
-		return CyclePane
-	case "shift+tab":
-		return CyclePanePrev
-	case "1":
-		return ToggleDarkMode
-	case "2":
-		return ToggleLightMode
-	case "3":
-		return CycleDarkTheme
-	case "ctrl+t":
-		return ToggleTOC
-	case "m":
-		return ToggleBookmark
-	case "'", "`":
-		return ToggleBookmarkList
-	case "i":
-		return ToggleImages
-	case "?":
-		return ToggleHelp
-
-	// Exit
-	case "q", "ctrl+c":
-		return Exit
-
-	// Commands
-	case ":":
-		return EnterCommand
+// String returns the name of the mode, as in the [keys] section of the
+// config
+func (mode KeyMode) String() string {
+	if mode < 0 || int(mode) >= len(keyModeNames) {
+		return fmt.Sprintf("KeyMode(%d)", int(mode))
 	}
-
-	return nil
+	return keyModeNames[mode]
 }
 
-// handleSearchKey processes keys in search mode
-func (kh *KeyHandler) handleSearchKey(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "enter":
-		return ExecuteSearch
-	case "escape", "ctrl+c":
-		return ExitSearch
-	case "n":
-		return NextMatch
-	case "N", "shift+n":
-		return PrevMatch
+// parseKeyMode returns the mode with the given name
+func parseKeyMode(name string) (KeyMode, bool) {
+	for i, modeName := range keyModeNames {
+		if name == modeName {
+			return KeyMode(i), true
+		}
 	}
-
-	return nil
+	return 0, false
 }
 
-// handleCommandKey processes keys in command mode
-func (kh *KeyHandler) handleCommandKey(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "enter":
-		return ExecuteCommand
-	case "escape", "ctrl+c":
-		return ExitCommand
+// NewKeyHandler creates a key handler with the default keymap
+func NewKeyHandler() *KeyHandler {
+	return &KeyHandler{
+		Mode:    KeyModeNormal,
+		Keymap:  DefaultKeymap(),
+		Timeout: DefaultKeyTimeout,
 	}
-
-	return nil
 }
 
-// Command functions for key bindings
-
-var (
-	ScrollDown = func() tea.Msg {
-		return ScrollMsg{Direction: "down", Amount: 1}
-	}
-
-	ScrollUp = func() tea.Msg {
-		return ScrollMsg{Direction: "up", Amount: 1}
-	}
-
-	HalfPageDown = func() tea.Msg {
-		return ScrollMsg{Direction: "down", Amount: 10}
-	}
-
-	HalfPageUp = func() tea.Msg {
-		return ScrollMsg{Direction: "up", Amount: 10}
-	}
-
-	GoToTop = func() tea.Msg {
-		return NavigateMsg{Type: "first_page"}
-	}
-
-	GoToBottom = func() tea.Msg {
-		return NavigateMsg{Type: "last_page"}
-	}
-
-	NextPage = func() tea.Msg {
-		return NavigateMsg{Type: "next_page"}
-	}
-
-	PrevPage = func() tea.Msg {
-		return NavigateMsg{Type: "prev_page"}
-	}
-
-	EnterSearch = func() tea.Msg {
-		return ModeChangeMsg{Mode: KeyModeSearch}
-	}
-
-	ExitSearch = func() tea.Msg {
-		return ModeChangeMsg{Mode: KeyModeNormal}
-	}
-
-	ExecuteSearch = func() tea.Msg {
-		return SearchMsg{}
-	}
-
-	NextMatch = func() tea.Msg {
-		return SearchMsg{Direction: "next"}
-	}
-
-	PrevMatch = func() tea.Msg {
-		return SearchMsg{Direction: "prev"}
-	}
-
-	EnterCommand = func() tea.Msg {
-		return ModeChangeMsg{Mode: KeyModeCommand}
-	}
-
-	ExitCommand = func() tea.Msg {
-		return ModeChangeMsg{Mode: KeyModeNormal}
-	}
-
-	ExecuteCommand = func() tea.Msg {
-		return CommandMsg{}
-	}
-
-	CyclePane = func() tea.Msg {
-		return PaneChangeMsg{Direction: "next"}
-	}
-
-	CyclePanePrev = func() tea.Msg {
-		return PaneChangeMsg{Direction: "prev"}
-	}
-
-	ToggleDarkMode = func() tea.Msg {
-		return ThemeChangeMsg{Theme: "dark"}
+// keyName returns the name of a key press as the keymap has it
+func keyName(msg tea.KeyMsg) string {
+	if msg.Type == tea.KeySpace {
+		return "space"
 	}
+	return msg.String()
+}
 
-	ToggleLightMode = func() tea.Msg {
-		return ThemeChangeMsg{Theme: "light"}
-	}
+// Resolve returns the action a key press completes in the current mode
+// and the count typed before it, 0 if none. pending is true when the key
+// is part of a count or starts or continues a longer sequence; with
+// neither, the key is bound to nothing. Keys that don't continue the
+// sequence typed so far drop it, and its count, and count on their own.
+func (kh *KeyHandler) Resolve(msg tea.KeyMsg) (action Action, count int, pending bool) {
+	key := keyName(msg)
+	if countModes[kh.Mode] && len(kh.pending) == 0 && len(kh.count) < maxCountDigits &&
+		len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || kh.count != "") {
+		kh.count += key
+		return Action{}, 0, true
+	}
+
+	seq := strings.Join(append(kh.pending, key), " ")
+	action, longer := kh.Keymap.lookup(kh.Mode, seq)
+	if longer {
+		kh.pending = append(kh.pending, key)
+		return Action{}, 0, true
+	}
+
+	started := len(kh.pending) > 0
+	count, _ = strconv.Atoi(kh.count)
+	kh.Reset()
+	if action.Name == "" && started {
+		return kh.Resolve(msg)
+	}
+	return action, count, false
+}
 
-	CycleDarkTheme = func() tea.Msg {
-		return ThemeChangeMsg{Theme: "cycle"}
+// Pending returns the count and keys typed so far of a longer sequence,
+// as the help shows them ("5g"), or "" if there are none
+func (kh *KeyHandler) Pending() string {
+	if len(kh.pending) == 0 {
+		return kh.count
 	}
+	return kh.count + formatKeySequence(strings.Join(kh.pending, " "))
+}
 
-	ToggleTOC = func() tea.Msg {
-		return ToggleTOCMsg{}
-	}
+// Reset drops the count and keys typed so far of a longer sequence
+func (kh *KeyHandler) Reset() {
+	kh.pending, kh.count = nil, ""
+}
 
-	ToggleSearchOptions = func() tea.Msg {
-		return ToggleSearchOptionsMsg{}
-	}
+// KeyTimeoutMsg ends the key sequence being typed if nothing was typed
+// since the timeout started
+type KeyTimeoutMsg struct {
+	Timer int
+}
 
-	ToggleSearchHistory = func() tea.Msg {
-		return ToggleSearchHistoryMsg{}
-	}
+// Wait returns a command that reports the timeout of the sequence being
+// typed, or nil if there is none or it never times out
+func (kh *KeyHandler) Wait() tea.Cmd {
+	if kh.Pending() == "" || kh.Timeout <= 0 {
+		return nil
+	}
+	kh.timer++
+	timer := kh.timer
+	return tea.Tick(kh.Timeout, func(time.Time) tea.Msg {
+		return KeyTimeoutMsg{Timer: timer}
+	})
+}
 
-	ToggleBookmark = func() tea.Msg {
-		return ToggleBookmarkMsg{}
+// Expire drops the sequence being typed if msg is its timeout. A lone
+// digit that was waiting to see if a count followed is a key after all:
+// its action is returned, if it has one.
+func (kh *KeyHandler) Expire(msg KeyTimeoutMsg) Action {
+	if msg.Timer != kh.timer || kh.Pending() == "" {
+		return Action{}
 	}
-
-	ToggleBookmarkList = func() tea.Msg {
-		return ToggleBookmarkListMsg{}
+	digit := ""
+	if len(kh.pending) == 0 && len(kh.count) == 1 {
+		digit = kh.count
 	}
-
-	ToggleHelp = func() tea.Msg {
-		return ToggleHelpMsg{}
+	kh.Reset()
+	if digit == "" {
+		return Action{}
 	}
+	action, _ := kh.Keymap.lookup(kh.Mode, digit)
+	return action
+}
 
-	ToggleImages = func() tea.Msg {
-		return ToggleImagesMsg{}
+// HandleKey processes a key press, returning a command that reports the
+// action it completes as an ActionMsg, or nil if it completes none
+func (kh *KeyHandler) HandleKey(msg tea.KeyMsg) tea.Cmd {
+	action, count, _ := kh.Resolve(msg)
+	if action.Name == "" {
+		return nil
 	}
-
-	Exit = func() tea.Msg {
-		return tea.QuitMsg{}
+	mode := kh.Mode
+	return func() tea.Msg {
+		return ActionMsg{Mode: mode, Action: action.Name, Count: count}
 	}
-)
+}
 
 // UI Messages
 
@@ -265,6 +194,13 @@ type SearchMsg struct {
 	Query     string
 }
 
+// ActionMsg runs an action of the keymap
+type ActionMsg struct {
+	Mode   KeyMode
+	Action string
+	Count  int // count typed before the keys, 0 if none
+}
+
 type ModeChangeMsg struct {
 	Mode KeyMode
 }
@@ -273,6 +209,11 @@ type CommandMsg struct {
 	Command string
 }
 
+// HintMsg labels the links in view to pick one
+type HintMsg struct {
+	Copy bool // copy where the picked link leads instead of following it
+}
+
 type PaneChangeMsg struct {
 	Direction string // "next" or "prev"
 }
@@ -295,53 +236,58 @@ type ToggleBookmarkListMsg struct{}
 
 type ToggleImagesMsg struct{}
 
-// VimKeybindingReference provides a reference of all keybindings
-var VimKeybindingReference = map[string]string{
-	// Navigation - Line scrolling
-	"j/↓":           "Scroll down one line",
-	"k/↑":           "Scroll up one line",
-	"h/←":           "Scroll left (wide PDFs)",
-	"l/→":           "Scroll right (wide PDFs)",
-
-	// Navigation - Page scrolling
-	"d":             "Scroll down half page",
-	"u":             "Scroll up half page",
-	"Ctrl+F":        "Scroll down full page",
-	"Ctrl+B":        "Scroll up full page",
-
-	// Navigation - Page jumps
-	"g":             "Go to first page",
-	"G":             "Go to last page",
-	"Ctrl+N":        "Go to next page",
-	"Ctrl+P":        "Go to previous page",
-
-	// Search & Copy
-	"/":             "Start search",
-	"Ctrl+\\":       "Toggle search options (regex, case, whole-word)",
-	"Ctrl+H":        "Toggle search history",
-	"n":             "Go to next match",
-	"N":             "Go to previous match",
-	"y":             "Copy current page text",
-	"Esc":           "Exit search",
-
-	// Themes (Dark mode - core feature)
-	"1":             "Switch to dark theme",
-	"2":             "Switch to light theme",
-	"3":             "Cycle through dark themes",
-
-	// Bookmarks
-	"m":             "Add/toggle bookmark on current page",
-	"'/backtick":    "Show bookmark list",
-
-	// Images (Phase 3)
-	"i":             "Toggle images on/off",
-
-	// UI Controls
-	"Tab":           "Cycle through panes (forward)",
-	"Shift+Tab":     "Cycle through panes (backward)",
-	"Ctrl+T":        "Toggle table of contents",
-	"?":             "Toggle help screen",
-
-	// General
-	"q/Ctrl+C":      "Quit application",
+// VimKeybindingReference maps the default keys of the normal mode actions,
+// as the help shows them, to what they do: each key sequence on its own
+// ("Ctrl+O") and all of an action's together ("Ctrl+O/Backspace")
+var VimKeybindingReference = DefaultKeymap().reference(KeyModeNormal)
+
+// reference maps the keys bound to each action of a mode to its help
+func (km *Keymap) reference(mode KeyMode) map[string]string {
+	ref := make(map[string]string)
+	for _, action := range km.Actions(mode) {
+		keys := km.Keys(mode, action.Name)
+		for _, key := range keys {
+			ref[key] = action.Help
+		}
+		if len(keys) > 1 {
+			ref[strings.Join(keys, "/")] = action.Help
+		}
+	}
+	return ref
+}
+
+// fixedKeyReference explains counts, and what keys bound to nothing do
+// in the table of contents and in link hints
+const fixedKeyReference = `COUNTS AND SEQUENCES
+  5j, 20G, 3 Ctrl+N  A number typed first repeats the key, or is the page or line
+                     it goes to. Keys waiting for the rest of a sequence show in
+                     the status bar, and are dropped after key_timeout_ms; a
+                     digit nothing follows is taken as a key then
+  1, 2, 3            The theme keys are digits, so they take effect only after
+                     key_timeout_ms, and a key typed sooner makes them a count:
+                     2j scrolls two lines
+  Other keys         Keys the table of contents doesn't bind do what they do in
+                     normal mode; in link hints they type the label of a link
+`
+
+// KeyReference lists the actions of each mode by heading, with the keys
+// bound to them in km and the name the config binds keys to them by,
+// followed by fixedKeyReference
+func KeyReference(km *Keymap) string {
+	var b strings.Builder
+	for _, mode := range keymapModes {
+		group := ""
+		for _, action := range km.Actions(mode) {
+			if action.Group != group {
+				if group = action.Group; b.Len() > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString(group + "\n")
+			}
+			keys := strings.Join(km.Keys(mode, action.Name), "/")
+			fmt.Fprintf(&b, "  %-18s %s (%s)\n", keys, action.Help, action.Name)
+		}
+	}
+	b.WriteString("\n" + fixedKeyReference)
+	return b.String()
 }
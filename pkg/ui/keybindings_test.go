@@ -2,6 +2,7 @@ package ui
 
 import (
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -54,15 +55,18 @@ func TestKeyHandler_HalfPageUp(t *testing.T) {
 	}
 }
 
-// TestKeyHandler_GoToFirstPage tests g key
+// TestKeyHandler_GoToFirstPage tests gg: g alone waits for the second g
 func TestKeyHandler_GoToFirstPage(t *testing.T) {
 	kh := NewKeyHandler()
 
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'g'}}
+	if cmd := kh.HandleKey(msg); cmd != nil {
+		t.Error("Expected 'g' alone to wait for the rest of the sequence")
+	}
 	cmd := kh.HandleKey(msg)
 
 	if cmd == nil {
-		t.Error("Expected command for 'g' key, got nil")
+		t.Error("Expected command for 'gg', got nil")
 	}
 }
 
@@ -235,15 +239,17 @@ func TestKeyHandler_AllNavigationKeys(t *testing.T) {
 		{"scroll up", []rune{'k'}},
 		{"half page down", []rune{'d'}},
 		{"half page up", []rune{'u'}},
-		{"first page", []rune{'g'}},
+		{"first page", []rune{'g', 'g'}},
 		{"last page", []rune{'G'}},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			kh := NewKeyHandler()
-			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: tt.keys}
-			cmd := kh.HandleKey(msg)
+			var cmd tea.Cmd
+			for _, key := range tt.keys {
+				cmd = kh.HandleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{key}})
+			}
 
 			if cmd == nil {
 				t.Errorf("Expected command for %s, got nil", tt.name)
@@ -324,7 +330,7 @@ func TestVimKeybindingReference_AllKeysPresent(t *testing.T) {
 		"k/↑",
 		"d",
 		"u",
-		"g",
+		"gg",
 		"G",
 		"Ctrl+N",
 		"Ctrl+P",
@@ -370,3 +376,81 @@ func BenchmarkKeyHandler_MultiKey(b *testing.B) {
 		_ = kh.HandleKey(msg2)
 	}
 }
+
+// TestKeyHandler_Counts tests numbers typed before keys
+func TestKeyHandler_Counts(t *testing.T) {
+	kh := NewKeyHandler()
+
+	tests := []struct {
+		keys   string
+		action string
+		count  int
+	}{
+		{"5j", "scroll-down", 5},
+		{"20G", "last-page", 20},
+		{"3gg", "first-page", 3},
+		{"10n", "next-match", 10},
+		{"j", "scroll-down", 0},
+		{"0j", "scroll-down", 0}, // 0 doesn't start a count
+		{"5gx", "", 0},           // an unfinished sequence drops its count
+	}
+	for _, tt := range tests {
+		var action Action
+		var count int
+		for _, r := range tt.keys {
+			action, count, _ = kh.Resolve(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		if action.Name != tt.action || count != tt.count {
+			t.Errorf("%s resolved to %q with count %d, want %q with %d", tt.keys, action.Name, count, tt.action, tt.count)
+		}
+	}
+
+	kh.Resolve(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'3'}})
+	action, count, _ := kh.Resolve(tea.KeyMsg{Type: tea.KeyCtrlN})
+	if action.Name != "next-page" || count != 3 {
+		t.Errorf("3 Ctrl+N resolved to %q with count %d", action.Name, count)
+	}
+
+	// Digits are typed as they are on the command line
+	kh.Mode = KeyModeCommand
+	if _, _, pending := kh.Resolve(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'5'}}); pending {
+		t.Error("5 started a count in command mode")
+	}
+}
+
+// TestKeyHandler_Timeout tests unfinished sequences expire
+func TestKeyHandler_Timeout(t *testing.T) {
+	kh := NewKeyHandler()
+	kh.Timeout = time.Millisecond
+
+	press := func(r rune) tea.Cmd {
+		kh.Resolve(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		return kh.Wait()
+	}
+
+	// A stale timeout leaves the sequence alone
+	stale := press('1')
+	press('2')
+	if action := kh.Expire(stale().(KeyTimeoutMsg)); action.Name != "" || kh.Pending() != "12" {
+		t.Errorf("stale timeout ran %q, pending %q", action.Name, kh.Pending())
+	}
+
+	// The latest one drops it
+	kh.Reset()
+	timeout := press('g')
+	if action := kh.Expire(timeout().(KeyTimeoutMsg)); action.Name != "" || kh.Pending() != "" {
+		t.Errorf("timed out g ran %q, pending %q", action.Name, kh.Pending())
+	}
+
+	// A digit that no count followed is a key after all
+	timeout = press('2')
+	if action := kh.Expire(timeout().(KeyTimeoutMsg)); action.Name != "light-theme" {
+		t.Errorf("timed out 2 ran %q, want light-theme", action.Name)
+	}
+
+	kh.Timeout = 0
+	press('g')
+	if cmd := kh.Wait(); cmd != nil {
+		t.Error("Wait() without a timeout returned a command")
+	}
+}
@@ -0,0 +1,339 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrKeyConflict is wrapped by the errors of NewKeymap for a binding
+	// that starts with another binding of its mode, or that another starts
+	// with, so one of them could never be typed
+	ErrKeyConflict = errors.New("conflicting key binding")
+
+	// ErrUnknownAction is wrapped by the errors of NewKeymap for a binding
+	// to an action its mode doesn't have
+	ErrUnknownAction = errors.New("unknown action")
+)
+
+// Keymap binds key sequences to the actions of each mode. Sequences are
+// stored as the key names bubbletea gives key presses, separated by
+// spaces ("g g", "ctrl+w j"); the space bar is "space".
+type Keymap struct {
+	actions  map[KeyMode]map[string]Action
+	bindings map[KeyMode]map[string]string // key sequence to action name
+	order    map[KeyMode][]string          // key sequences in the order they were bound, for listing
+}
+
+// keymapModes are the modes with key bindings, in the order the key
+// reference lists them
+var keymapModes = []KeyMode{KeyModeNormal, KeyModeVisual, KeyModeSearch, KeyModeCommand, KeyModeTOC, KeyModeHint}
+
+// keymapFallbacks are the modes whose keys, where a mode binds none, do
+// what they do in another: the table of contents leaves the keys it
+// doesn't use to the viewer
+var keymapFallbacks = map[KeyMode]KeyMode{KeyModeTOC: KeyModeNormal}
+
+// DefaultKeymap returns the built-in key bindings
+func DefaultKeymap() *Keymap {
+	km := &Keymap{
+		actions:  make(map[KeyMode]map[string]Action),
+		bindings: make(map[KeyMode]map[string]string),
+		order:    make(map[KeyMode][]string),
+	}
+	for _, mode := range keymapModes {
+		km.actions[mode] = make(map[string]Action)
+		km.bindings[mode] = make(map[string]string)
+		for _, action := range modeActions(mode) {
+			km.actions[mode][action.Name] = action
+			for _, keys := range action.Keys {
+				seq, err := parseKeySequence(keys)
+				if err != nil {
+					// The built-in bindings are valid
+					panic(err)
+				}
+				km.bind(mode, seq, action.Name)
+			}
+		}
+	}
+	return km
+}
+
+// NewKeymap returns the built-in key bindings changed by the [keys]
+// section of the config: key sequences bound to action names by mode, an
+// empty name unbinding the keys. Sequences are keys separated by spaces;
+// a word that isn't the name of a key ("gg") is a key per character.
+// Bindings that are invalid or conflict with others are left out and
+// reported in the returned error.
+func NewKeymap(overrides map[string]map[string]string) (*Keymap, error) {
+	km := DefaultKeymap()
+	var errs []error
+
+	modeNames := make([]string, 0, len(overrides))
+	for name := range overrides {
+		modeNames = append(modeNames, name)
+	}
+	sort.Strings(modeNames)
+
+	for _, name := range modeNames {
+		mode, ok := parseKeyMode(name)
+		if !ok || km.actions[mode] == nil {
+			errs = append(errs, fmt.Errorf("keys.%s: unknown mode (want normal, visual, search, command, toc or hint)", name))
+			continue
+		}
+
+		type binding struct{ keys, seq, action string }
+		var unbind, bind []binding
+		for keys, action := range overrides[name] {
+			seq, err := parseKeySequence(keys)
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("keys.%s.%q: %w", name, keys, err))
+			case action == "":
+				unbind = append(unbind, binding{keys, seq, action})
+			case km.actions[mode][action].Name == "":
+				errs = append(errs, fmt.Errorf("keys.%s.%q: %s: %w", name, keys, action, ErrUnknownAction))
+			default:
+				bind = append(bind, binding{keys, seq, action})
+			}
+		}
+		sort.Slice(bind, func(i, j int) bool { return bind[i].seq < bind[j].seq })
+
+		// Keys are freed first, so they can be bound to something else
+		for _, b := range unbind {
+			km.unbind(mode, b.seq)
+		}
+		for _, b := range bind {
+			if other, action, ok := km.conflict(mode, b.seq); ok {
+				errs = append(errs, fmt.Errorf("keys.%s.%q: %w with %s (%s); unbind one of them with = \"\"",
+					name, b.keys, ErrKeyConflict, formatKeySequence(other), action))
+				continue
+			}
+			km.bind(mode, b.seq, b.action)
+		}
+	}
+	return km, errors.Join(errs...)
+}
+
+// bind binds a key sequence, replacing its binding if it has one
+func (km *Keymap) bind(mode KeyMode, seq, action string) {
+	if _, bound := km.bindings[mode][seq]; !bound {
+		km.order[mode] = append(km.order[mode], seq)
+	}
+	km.bindings[mode][seq] = action
+}
+
+// unbind removes the binding of a key sequence
+func (km *Keymap) unbind(mode KeyMode, seq string) {
+	delete(km.bindings[mode], seq)
+	order := km.order[mode][:0]
+	for _, s := range km.order[mode] {
+		if s != seq {
+			order = append(order, s)
+		}
+	}
+	km.order[mode] = order
+}
+
+// conflict returns another sequence bound in mode that seq starts with or
+// that starts with seq, and its action
+func (km *Keymap) conflict(mode KeyMode, seq string) (string, string, bool) {
+	for _, other := range km.order[mode] {
+		if strings.HasPrefix(seq, other+" ") || strings.HasPrefix(other, seq+" ") {
+			return other, km.bindings[mode][other], true
+		}
+	}
+	return "", "", false
+}
+
+// lookup returns the action a key sequence is bound to in mode, or in the
+// mode it falls back to if mode binds it to nothing, and whether longer
+// sequences start with it
+func (km *Keymap) lookup(mode KeyMode, seq string) (Action, bool) {
+	action := km.actions[mode][km.bindings[mode][seq]]
+	longer := false
+	for _, other := range km.order[mode] {
+		if strings.HasPrefix(other, seq+" ") {
+			longer = true
+			break
+		}
+	}
+	if fallback, ok := keymapFallbacks[mode]; ok && action.Name == "" {
+		action, fallbackLonger := km.lookup(fallback, seq)
+		return action, longer || fallbackLonger
+	}
+	return action, longer
+}
+
+// Action returns the action of a mode, or of the mode it falls back to,
+// with the given name
+func (km *Keymap) Action(mode KeyMode, name string) (Action, bool) {
+	if action, ok := km.actions[mode][name]; ok {
+		return action, true
+	}
+	if fallback, ok := keymapFallbacks[mode]; ok {
+		return km.Action(fallback, name)
+	}
+	return Action{}, false
+}
+
+// Keys returns the key sequences bound to an action, as they are shown in
+// the help
+func (km *Keymap) Keys(mode KeyMode, action string) []string {
+	var keys []string
+	for _, seq := range km.order[mode] {
+		if km.bindings[mode][seq] == action {
+			keys = append(keys, formatKeySequence(seq))
+		}
+	}
+	return keys
+}
+
+// Actions returns the actions of a mode in the order the help lists them
+func (km *Keymap) Actions(mode KeyMode) []Action {
+	return modeActions(mode)
+}
+
+// namedKeys are the names of keys that aren't characters, with the name
+// bubbletea gives them
+var namedKeys = map[string]string{
+	"enter": "enter", "return": "enter", "esc": "esc", "escape": "esc",
+	"tab": "tab", "backspace": "backspace", "delete": "delete", "del": "delete",
+	"insert": "insert", "space": "space", "up": "up", "down": "down",
+	"left": "left", "right": "right", "home": "home", "end": "end",
+	"pgup": "pgup", "pageup": "pgup", "pgdown": "pgdown", "pagedown": "pgdown",
+}
+
+// ctrlAliases are control keys terminals send as other keys
+var ctrlAliases = map[string]string{"ctrl+i": "tab", "ctrl+m": "enter", "ctrl+[": "esc"}
+
+// parseKeySequence turns the keys of a binding in the config into the key
+// names bubbletea gives the key presses, separated by spaces
+func parseKeySequence(s string) (string, error) {
+	var keys []string
+	for _, word := range strings.Fields(s) {
+		if key, err := parseKey(word); err == nil {
+			keys = append(keys, key)
+			continue
+		} else if strings.Contains(word, "+") || isKeyName(word) {
+			return "", err
+		}
+		// A word of plain characters is a key per character
+		for _, r := range word {
+			keys = append(keys, string(r))
+		}
+	}
+	if len(keys) == 0 {
+		return "", errors.New("no keys")
+	}
+	return strings.Join(keys, " "), nil
+}
+
+// isKeyName reports whether a word looks like the name of a key, such as
+// "f5", rather than characters
+func isKeyName(word string) bool {
+	return len(word) > 1 && len(word) <= 3 && (word[0] == 'f' || word[0] == 'F') && strings.Trim(word[1:], "0123456789") == ""
+}
+
+// parseKey turns a key as written in the config ("Ctrl+O", "shift+g",
+// "enter", "x") into the name bubbletea gives it
+func parseKey(word string) (string, error) {
+	parts := strings.Split(word, "+")
+	if word == "+" || strings.HasSuffix(word, "++") {
+		// The + key itself, as in "ctrl++"
+		parts = append(parts[:len(parts)-2], "+")
+	}
+	base := parts[len(parts)-1]
+
+	var alt, ctrl, shift bool
+	for _, mod := range parts[:len(parts)-1] {
+		switch strings.ToLower(mod) {
+		case "alt", "meta":
+			alt = true
+		case "ctrl", "control":
+			ctrl = true
+		case "shift":
+			shift = true
+		default:
+			return "", fmt.Errorf("unknown modifier %q in %q", mod, word)
+		}
+	}
+
+	key := base
+	switch name, named := namedKeys[strings.ToLower(base)]; {
+	case named:
+		key = name
+	case utf8.RuneCountInString(base) == 1:
+		if ctrl {
+			key = strings.ToLower(base)
+		} else if shift {
+			key, shift = strings.ToUpper(base), false
+		}
+	case isKeyName(base):
+		key = strings.ToLower(base)
+	default:
+		return "", fmt.Errorf("unknown key %q", word)
+	}
+
+	if shift {
+		key = "shift+" + key
+	}
+	if ctrl {
+		key = "ctrl+" + key
+		if alias, ok := ctrlAliases[key]; ok {
+			key = alias
+		}
+	}
+	if alt {
+		key = "alt+" + key
+	}
+	return key, nil
+}
+
+// keyDisplayNames are how keys are shown in the help
+var keyDisplayNames = map[string]string{
+	"space": "Space", "up": "↑", "down": "↓", "left": "←", "right": "→",
+	"enter": "Enter", "esc": "Esc", "tab": "Tab", "backspace": "Backspace",
+	"delete": "Delete", "insert": "Insert", "home": "Home", "end": "End",
+	"pgup": "PgUp", "pgdown": "PgDn",
+}
+
+// formatKey shows a key name the way the help does: "Ctrl+O", "↓", "g"
+func formatKey(key string) string {
+	if name, ok := keyDisplayNames[key]; ok {
+		return name
+	}
+	if utf8.RuneCountInString(key) == 1 || !strings.Contains(key[:len(key)-1], "+") {
+		return key
+	}
+
+	mods := strings.Split(key[:strings.LastIndexByte(key[:len(key)-1], '+')], "+")
+	base := key[len(strings.Join(mods, "+"))+1:]
+	for i, mod := range mods {
+		mods[i] = strings.ToUpper(mod[:1]) + mod[1:]
+	}
+	if name, ok := keyDisplayNames[base]; ok {
+		base = name
+	} else if mods[len(mods)-1] == "Ctrl" {
+		base = strings.ToUpper(base)
+	}
+	return strings.Join(mods, "+") + "+" + base
+}
+
+// formatKeySequence shows a key sequence the way the help does: "gg",
+// "Ctrl+W j"
+func formatKeySequence(seq string) string {
+	keys := strings.Split(seq, " ")
+	plain := true
+	for i, key := range keys {
+		keys[i] = formatKey(key)
+		plain = plain && utf8.RuneCountInString(keys[i]) == 1
+	}
+	if plain {
+		return strings.Join(keys, "")
+	}
+	return strings.Join(keys, " ")
+}
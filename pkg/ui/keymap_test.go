@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+func TestParseKeySequence(t *testing.T) {
+	tests := []struct {
+		keys, want string
+	}{
+		{"j", "j"},
+		{"gg", "g g"},
+		{"g g", "g g"},
+		{"''", "' '"},
+		{"Ctrl+O", "ctrl+o"},
+		{"ctrl+i", "tab"},
+		{"shift+g", "G"},
+		{"shift+tab", "shift+tab"},
+		{"ctrl+w j", "ctrl+w j"},
+		{"Escape", "esc"},
+		{"space", "space"},
+		{"alt+x", "alt+x"},
+		{"ctrl++", "ctrl++"},
+		{"F5", "f5"},
+	}
+	for _, tt := range tests {
+		got, err := parseKeySequence(tt.keys)
+		if err != nil || got != tt.want {
+			t.Errorf("parseKeySequence(%q) = %q, %v; want %q", tt.keys, got, err, tt.want)
+		}
+	}
+
+	for _, keys := range []string{"", " ", "hyper+x", "ctrl+nope"} {
+		if got, err := parseKeySequence(keys); err == nil {
+			t.Errorf("parseKeySequence(%q) = %q, want an error", keys, got)
+		}
+	}
+}
+
+func TestFormatKeySequence(t *testing.T) {
+	tests := map[string]string{
+		"g g":           "gg",
+		"ctrl+o":        "Ctrl+O",
+		"down":          "↓",
+		"shift+tab":     "Shift+Tab",
+		"ctrl+w j":      "Ctrl+W j",
+		"space":         "Space",
+		"alt+ctrl+a":    "Alt+Ctrl+A",
+		"ctrl+\\":       "Ctrl+\\",
+		"ctrl+shift+up": "Ctrl+Shift+↑",
+	}
+	for seq, want := range tests {
+		if got := formatKeySequence(seq); got != want {
+			t.Errorf("formatKeySequence(%q) = %q, want %q", seq, got, want)
+		}
+	}
+}
+
+func TestNewKeymap(t *testing.T) {
+	km, err := NewKeymap(map[string]map[string]string{
+		"normal": {
+			"g":        "", // frees g for the sequences below
+			"gg":       "first-page",
+			"g t":      "next-page",
+			"ctrl+w j": "next-page",
+			"x":        "quit",
+			"ctrl+w":   "help",      // bound first, so ctrl+w j conflicts with it
+			"'":        "",          // unbinds go-to-mark
+			"' a":      "jump-back", // conflicts with nothing once ' is gone
+			"z":        "no-such-action",
+		},
+		"command": {"ctrl+g": "cancel"},
+		"insert":  {"v": "quit"},
+	})
+
+	if !errors.Is(err, ErrKeyConflict) || !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("NewKeymap() error = %v, want a conflict and an unknown action", err)
+	}
+	for _, want := range []string{`keys.normal."ctrl+w j"`, `keys.normal."z"`, "keys.insert: unknown mode"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("NewKeymap() error = %v, want it to mention %s", err, want)
+		}
+	}
+
+	tests := []struct {
+		mode KeyMode
+		name string
+		want []string
+	}{
+		{KeyModeNormal, "first-page", []string{"gg"}},
+		{KeyModeNormal, "next-page", []string{"Ctrl+N", "gt"}},
+		{KeyModeNormal, "quit", []string{"q", "Ctrl+C", "x"}},
+		{KeyModeNormal, "help", []string{"?", "Ctrl+W"}},
+		{KeyModeNormal, "jump-back", []string{"'a"}},
+		{KeyModeNormal, "go-to-mark", []string{"`"}},
+		{KeyModeCommand, "cancel", []string{"Esc", "Ctrl+C", "Ctrl+G"}},
+	}
+	for _, tt := range tests {
+		if got := km.Keys(tt.mode, tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("Keys(%v, %s) = %q, want %q", tt.mode, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTOCAndHintKeymaps(t *testing.T) {
+	km, err := NewKeymap(map[string]map[string]string{
+		"toc":  {"J": "next-entry", "z": "collapse", "x": "quit"},
+		"hint": {"ctrl+g": "cancel"},
+	})
+	if !errors.Is(err, ErrKeyConflict) || !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("NewKeymap() error = %v, want a conflict with the z folds and quit unknown", err)
+	}
+	if got := km.Keys(KeyModeTOC, "next-entry"); !slices.Equal(got, []string{"j", "↓", "J"}) {
+		t.Errorf("Keys(toc, next-entry) = %q", got)
+	}
+	if got := km.Keys(KeyModeHint, "cancel"); !slices.Equal(got, []string{"Esc", "Ctrl+C", "Ctrl+G"}) {
+		t.Errorf("Keys(hint, cancel) = %q", got)
+	}
+
+	// Keys the table of contents doesn't bind are the viewer's
+	kh := &KeyHandler{Mode: KeyModeTOC, Keymap: km}
+	for key, want := range map[string]string{"J": "next-entry", "q": "quit", "n": "next-match"} {
+		if action, _, _ := kh.Resolve(keyPress(key)); action.Name != want {
+			t.Errorf("toc key %s resolved to %q, want %s", key, action.Name, want)
+		}
+	}
+	for _, action := range km.Actions(KeyModeTOC) {
+		if _, ok := km.actions[KeyModeNormal][action.Name]; ok {
+			t.Errorf("toc action %s hides the normal mode action of that name", action.Name)
+		}
+	}
+
+	ref := KeyReference(km)
+	for _, want := range []string{
+		"TABLE OF CONTENTS (while it has the focus)",
+		"za                 Expand or collapse the entry (toggle-fold)",
+		"z3                 Expand the entries down to level 3 (fold-level-3)",
+		"Esc/Ctrl+C/Ctrl+G  Leave without picking a link (cancel)",
+	} {
+		if !strings.Contains(ref, want) {
+			t.Errorf("KeyReference() lacks %q:\n%s", want, ref)
+		}
+	}
+}
+
+// TestKeyHandlerSequences types key sequences through the handler
+func TestKeyHandlerSequences(t *testing.T) {
+	km, err := NewKeymap(map[string]map[string]string{"normal": {"g": "", "gg": "first-page", "gt": "next-page"}})
+	if err != nil {
+		t.Fatalf("NewKeymap() error = %v", err)
+	}
+	kh := &KeyHandler{Mode: KeyModeNormal, Keymap: km}
+
+	resolve := func(key string) string {
+		action, _, pending := kh.Resolve(keyPress(key))
+		if pending {
+			return "…"
+		}
+		return action.Name
+	}
+
+	var got []string
+	for _, key := range []string{"g", "g", "g", "t", "g", "j", "ctrl+o", "x"} {
+		got = append(got, resolve(key))
+	}
+	want := []string{"…", "first-page", "…", "next-page", "…", "scroll-down", "jump-older", ""}
+	if !slices.Equal(got, want) {
+		t.Errorf("resolved %q, want %q", got, want)
+	}
+
+	resolve("g")
+	if kh.Pending() != "g" {
+		t.Errorf("Pending() = %q, want g", kh.Pending())
+	}
+	kh.Reset()
+	if kh.Pending() != "" {
+		t.Errorf("Pending() after Reset = %q", kh.Pending())
+	}
+}
+
+// TestConfiguredKeys binds keys in the config and checks the model and
+// its help follow them
+func TestConfiguredKeys(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	cfgFile := filepath.Join(dir, "lumos", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(cfgFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := `version = 3
+
+[keys.normal]
+"ctrl+n" = ""
+"J" = "next-page"
+"q" = "nope"
+
+[keys.search]
+"ctrl+g" = "cancel"
+`
+	if err := os.WriteFile(cfgFile, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.loadPage(1))
+	if !strings.Contains(model.statusMessage, `Key binding ignored: keys.normal."q"`) {
+		t.Errorf("status = %q, want the bad binding reported", model.statusMessage)
+	}
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyCtrlN})
+	drive(model, cmd)
+	if model.currentPage != 1 {
+		t.Errorf("unbound ctrl+n moved to page %d", model.currentPage)
+	}
+	_, cmd = model.Update(keyPress("J"))
+	drive(model, cmd)
+	if model.currentPage != 2 {
+		t.Errorf("after J: page %d, want 2", model.currentPage)
+	}
+
+	// Keys bound to nothing are typed into the search
+	for _, k := range []string{"/", "q", "J"} {
+		_, cmd = model.Update(keyPress(k))
+		drive(model, cmd)
+	}
+	if model.keyHandler.Mode != KeyModeSearch || model.searchQuery != "qJ" {
+		t.Errorf("mode %v, query %q; want search mode with qJ typed", model.keyHandler.Mode, model.searchQuery)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	if model.keyHandler.Mode != KeyModeNormal {
+		t.Errorf("ctrl+g left mode %v, want normal", model.keyHandler.Mode)
+	}
+
+	help := model.renderHelp()
+	if !strings.Contains(help, "gt/J               Go to the next page (next-page)") {
+		t.Errorf("help does not show the configured keys:\n%s", help)
+	}
+	if strings.Contains(help, "Ctrl+N             Go to the next page") {
+		t.Error("help still shows the unbound ctrl+n")
+	}
+}
@@ -0,0 +1,370 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// linkSpan is the byte range of a link's text in a page's text
+type linkSpan struct {
+	start, end int
+	link       int    // index of the link among the page's links, then its cross-references
+	uri        string // web address, made a terminal hyperlink
+	plain      bool   // a cross-reference: marked for hints but not styled
+}
+
+// pageLinkSpans returns the spans of the links that cover text, in order,
+// and of the cross-references that no link covers, numbered after the
+// links. Links overlapping an earlier one are left out.
+func pageLinkSpans(links []pdf.Link, xrefs []pdf.CrossRef) []linkSpan {
+	var spans []linkSpan
+	for i, link := range links {
+		if link.Start < 0 || link.End <= link.Start {
+			continue
+		}
+		span := linkSpan{start: link.Start, end: link.End, link: i}
+		if link.Kind == pdf.LinkURI {
+			span.uri = link.URI
+		}
+		spans = append(spans, span)
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	kept := spans[:0]
+	for _, span := range spans {
+		if len(kept) > 0 && span.start < kept[len(kept)-1].end {
+			continue
+		}
+		kept = append(kept, span)
+	}
+	if len(xrefs) == 0 {
+		return kept
+	}
+
+	// Cross-references are often links already, or part of one
+	all := append([]linkSpan(nil), kept...)
+	for i, xref := range xrefs {
+		covered := false
+		for _, span := range kept {
+			covered = covered || (xref.Start < span.end && span.start < xref.End)
+		}
+		if !covered {
+			all = append(all, linkSpan{start: xref.Start, end: xref.End, link: len(links) + i, plain: true})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].start < all[j].start })
+	return all
+}
+
+// hyperlink makes text an OSC 8 terminal hyperlink to uri. The URI comes
+// from the document, and is escaped again in case it was not read by the
+// pdf package.
+func hyperlink(uri, text string) string {
+	return ansi.SetHyperlink(pdf.EscapeControls(uri)) + text + ansi.ResetHyperlink()
+}
+
+// linkMarker marks where a link starts in rendered page content. It is a
+// private OSC sequence, so it takes no room when text is measured or
+// wrapped, and extractLinkMarkers removes it once the content is laid out.
+func linkMarker(link int) string {
+	return "\x1b]7771;" + strconv.Itoa(link) + "\a"
+}
+
+var linkMarkerPattern = regexp.MustCompile("\x1b\\]7771;(\\d+)\a")
+
+// linkCell is where a link starts in the viewport content
+type linkCell struct {
+	row, col int
+}
+
+// extractLinkMarkers removes the link markers from rendered content and
+// returns where each of n links starts, with row -1 for links not shown
+func extractLinkMarkers(content string, n int) (string, []linkCell) {
+	cells := make([]linkCell, n)
+	for i := range cells {
+		cells[i] = linkCell{-1, -1}
+	}
+	if !strings.Contains(content, "\x1b]7771;") {
+		return content, cells
+	}
+
+	rows := strings.Split(content, "\n")
+	for row, text := range rows {
+		matches := linkMarkerPattern.FindAllStringSubmatchIndex(text, -1)
+		if matches == nil {
+			continue
+		}
+		var sb strings.Builder
+		last := 0
+		for _, m := range matches {
+			sb.WriteString(text[last:m[0]])
+			if link, err := strconv.Atoi(text[m[2]:m[3]]); err == nil && link < n && cells[link].row < 0 {
+				cells[link] = linkCell{row, ansi.StringWidth(sb.String())}
+			}
+			last = m[1]
+		}
+		sb.WriteString(text[last:])
+		rows[row] = sb.String()
+	}
+	return strings.Join(rows, "\n"), cells
+}
+
+// highlightLinks styles text like highlightMatches, and also underlines
+// the link spans, making those with a URI terminal hyperlinks. The start
+// of each link is marked with linkMarker; plain spans are only marked.
+// Spans of either kind must be in order and not overlap each other.
+func highlightLinks(text string, spans []matchSpan, current int, links []linkSpan, matchStyle, currentStyle, linkStyle lipgloss.Style) string {
+	cuts := []int{0, len(text)}
+	for _, span := range spans {
+		cuts = append(cuts, span.start, span.end)
+	}
+	for _, link := range links {
+		cuts = append(cuts, link.start, link.end)
+	}
+	sort.Ints(cuts)
+
+	var sb strings.Builder
+	match, link := 0, 0
+	for i := 0; i+1 < len(cuts); i++ {
+		from, to := cuts[i], cuts[i+1]
+		if from >= to || from < 0 || to > len(text) {
+			continue
+		}
+		for match < len(spans) && spans[match].end <= from {
+			match++
+		}
+		for link < len(links) && links[link].end <= from {
+			link++
+		}
+		inMatch := match < len(spans) && spans[match].start <= from
+		inSpan := link < len(links) && links[link].start <= from
+		if inSpan && links[link].start == from {
+			sb.WriteString(linkMarker(links[link].link))
+		}
+		inLink := inSpan && !links[link].plain
+
+		var style lipgloss.Style
+		styled := inMatch || inLink
+		switch {
+		case inMatch && match == current:
+			style = currentStyle
+		case inMatch:
+			style = matchStyle
+		case inLink:
+			style = linkStyle
+		}
+		if inLink {
+			style = style.Underline(true)
+		}
+
+		for j, part := range strings.Split(text[from:to], "\n") {
+			if j > 0 {
+				sb.WriteByte('\n')
+			}
+			if part == "" {
+				continue
+			}
+			if styled {
+				part = style.Render(part)
+			}
+			if inLink && links[link].uri != "" {
+				part = hyperlink(links[link].uri, part)
+			}
+			sb.WriteString(part)
+		}
+	}
+	return sb.String()
+}
+
+// localLinks returns the link spans that fall in the page text at
+// [offset, offset+length), relative to it
+func localLinks(links []linkSpan, offset, length int) []linkSpan {
+	var local []linkSpan
+	for _, link := range links {
+		start, end := max(link.start, offset), min(link.end, offset+length)
+		if start >= end {
+			continue
+		}
+		link.start, link.end = start-offset, end-offset
+		local = append(local, link)
+	}
+	return local
+}
+
+// linkInView returns the first link on the current page that starts in
+// the viewport, or -1
+func (m *Model) linkInView() int {
+	if m.pageTextNum != m.currentPage {
+		return -1
+	}
+	best := -1
+	for i, cell := range m.linkCells[:min(len(m.pageLinks), len(m.linkCells))] {
+		if cell.row < m.viewport.YOffset || cell.row >= m.viewport.YOffset+m.viewport.Height {
+			continue
+		}
+		if best < 0 || cell.row < m.linkCells[best].row || (cell.row == m.linkCells[best].row && cell.col < m.linkCells[best].col) {
+			best = i
+		}
+	}
+	return best
+}
+
+// followLinkInView follows the first link in the viewport
+func (m *Model) followLinkInView() tea.Cmd {
+	i := m.linkInView()
+	if i < 0 {
+		m.statusMessage = "No link in view"
+		return nil
+	}
+	return m.followLink(m.pageLinks[i])
+}
+
+// followLink follows a link: to its page within the document or to
+// another document, recording the jump, or to a web address, which is
+// opened with the configured opener
+func (m *Model) followLink(link pdf.Link) tea.Cmd {
+	switch {
+	case link.Kind == pdf.LinkURI:
+		return m.openURI(link.URI)
+	case link.Kind == pdf.LinkRemote:
+		return m.openLinkedDocument(link)
+	case link.Page < 1 || link.Page > m.document.GetPageCount():
+		m.statusMessage = "Link leads nowhere"
+		return nil
+	}
+
+	m.recordJump()
+	m.currentPage = link.Page
+	m.pendingScroll = 0
+	m.statusMessage = fmt.Sprintf("Followed link to page %s (backspace to go back)", m.document.PageLabel(link.Page))
+	return m.loadPage(m.currentPage)
+}
+
+// URIOpenedMsg reports the end of the opener command run for a web link
+type URIOpenedMsg struct {
+	URI string
+	Err error
+}
+
+// defaultOpener is the command that opens web links unless one is
+// configured
+func defaultOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	}
+	return []string{"xdg-open"}
+}
+
+// openSchemes are the URI schemes handed to the opener command; any
+// other address in a document could run a program or reach a local file
+var openSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// openURI opens a web address with the opener command, the address
+// appended to its arguments. Addresses of other schemes are copied instead
+func (m *Model) openURI(uri string) tea.Cmd {
+	if u, err := url.Parse(uri); err != nil || !openSchemes[strings.ToLower(u.Scheme)] {
+		m.statusMessage = fmt.Sprintf("Not opening %s (only http, https and mailto links are); address copied", pdf.EscapeControls(uri))
+		return m.copyText(uri, "the address")
+	}
+	opener := strings.Fields(m.cfg.UI.Opener)
+	if len(opener) == 0 {
+		opener = defaultOpener()
+	}
+	m.statusMessage = "Opening " + pdf.EscapeControls(uri)
+	return func() tea.Msg {
+		// Without stdin or output the command cannot disturb the terminal
+		err := exec.Command(opener[0], append(opener[1:], uri)...).Run()
+		return URIOpenedMsg{URI: uri, Err: err}
+	}
+}
+
+// handleURIOpened reports an opener that failed, copying the address
+// instead so it can be opened by hand
+func (m *Model) handleURIOpened(msg URIOpenedMsg) tea.Cmd {
+	if msg.Err == nil {
+		return nil
+	}
+	m.statusMessage = fmt.Sprintf("Cannot open %s (%v); address copied", pdf.EscapeControls(msg.URI), msg.Err)
+	return m.copyText(msg.URI, "the address")
+}
+
+// DocumentOpenedMsg carries a document opened to follow a link into it, or
+// to return to it through the jump list
+type DocumentOpenedMsg struct {
+	Doc    *pdf.Document
+	Path   string
+	Page   int    // page to show, 1 if unknown
+	Dest   string // named destination to show instead of Page
+	Scroll int
+	Jump   bool // moving through the jump list, so not recorded in it
+	Err    error
+}
+
+// openLinkedDocument opens the document a link to another file leads to
+func (m *Model) openLinkedDocument(link pdf.Link) tea.Cmd {
+	if link.File == "" {
+		m.statusMessage = "Link leads nowhere"
+		return nil
+	}
+	return m.openDocument(link.File, DocumentOpenedMsg{Page: link.Page, Dest: link.Dest})
+}
+
+// openDocument opens a document in the background, to be shown in place
+// of the current one as described by target
+func (m *Model) openDocument(path string, target DocumentOpenedMsg) tea.Cmd {
+	m.statusMessage = "Opening " + pdf.EscapeControls(filepath.Base(path))
+	opts := m.docOptions
+	return func() tea.Msg {
+		target.Path = path
+		target.Doc, target.Err = pdf.OpenDocument(path, opts)
+		return target
+	}
+}
+
+// handleDocumentOpened shows a document opened by openDocument
+func (m *Model) handleDocumentOpened(msg DocumentOpenedMsg) tea.Cmd {
+	name := pdf.EscapeControls(filepath.Base(msg.Path))
+	switch {
+	case errors.Is(msg.Err, pdf.ErrPasswordRequired):
+		m.statusMessage = name + " is encrypted; open it with lumos --password"
+		return nil
+	case msg.Err != nil:
+		m.statusMessage = fmt.Sprintf("Cannot open %s: %v", name, msg.Err)
+		return nil
+	}
+
+	if !msg.Jump {
+		m.recordJump()
+	}
+	page := msg.Page
+	if msg.Dest != "" {
+		page = msg.Doc.DestinationPage(msg.Dest)
+	}
+	if page < 1 || page > msg.Doc.GetPageCount() {
+		page = 1
+	}
+
+	cmd := m.switchDocument(msg.Doc, page, msg.Scroll)
+	if msg.Jump {
+		m.statusMessage = fmt.Sprintf("Jumped to %s, page %s", name, msg.Doc.PageLabel(page))
+	} else {
+		m.statusMessage = fmt.Sprintf("Opened %s at page %s (backspace to go back)", name, msg.Doc.PageLabel(page))
+	}
+	return cmd
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// testLinks are links in the page text "See Chapter Two\nonline docs"
+func testLinks() []pdf.Link {
+	return []pdf.Link{
+		{Kind: pdf.LinkURI, URI: "https://example.com", Text: "online", Start: 16, End: 22},
+		{Kind: pdf.LinkPage, Page: 3, Text: "Chapter Two", Start: 4, End: 15},
+		{Kind: pdf.LinkPage, Page: 2, Start: -1, End: -1},
+	}
+}
+
+func TestHighlightLinks(t *testing.T) {
+	text := "See Chapter Two\nonline docs"
+	spans := pageLinkSpans(testLinks(), nil)
+	if len(spans) != 2 || spans[0].link != 1 || spans[1].uri != "https://example.com" {
+		t.Fatalf("pageLinkSpans() = %+v", spans)
+	}
+
+	got := highlightLinks(text, []matchSpan{{8, 11}}, 0, spans,
+		markStyle("[", "]"), markStyle("<", ">"), markStyle("_", "_"))
+	got, cells := extractLinkMarkers(got, 3)
+
+	want := "See _Chap_<ter>_ Two_\n\x1b]8;;https://example.com\a_online_\x1b]8;;\a docs"
+	if got != want {
+		t.Errorf("highlightLinks() = %q, want %q", got, want)
+	}
+	if cells[0] != (linkCell{1, 0}) || cells[1] != (linkCell{0, 4}) || cells[2].row != -1 {
+		t.Errorf("link cells = %v", cells)
+	}
+}
+
+func TestFollowLinks(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(PageLoadedMsg{Page: 1, Content: "See Chapter Two\nonline docs", Links: testLinks()})
+	if !strings.Contains(model.viewport.View(), "\x1b]8;;https://example.com\a") {
+		t.Errorf("Expected the web link as a terminal hyperlink, got %q", model.viewport.View())
+	}
+
+	// The link to page 3 comes first in view
+	model.viewport.SetYOffset(0)
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(model, cmd)
+	if model.currentPage != 3 || model.jumps[len(model.jumps)-1].page != 1 {
+		t.Fatalf("after enter: page %d, jumps %v; want page 3", model.currentPage, model.jumps)
+	}
+
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	drive(model, cmd)
+	if model.currentPage != 1 {
+		t.Errorf("after backspace: page %d; want page 1", model.currentPage)
+	}
+
+	// Web links are shown, not followed
+	model.followLink(testLinks()[0])
+	if model.currentPage != 1 || !strings.Contains(model.statusMessage, "https://example.com") {
+		t.Errorf("following a web link: page %d, status %q", model.currentPage, model.statusMessage)
+	}
+}
+
+func TestLinksEscapeControls(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+
+	// A URI that ends the hyperlink early and sets the clipboard
+	evil := "https://example.com/\a\x1b]52;c;cm0gLXJm\a"
+	links := []pdf.Link{{Kind: pdf.LinkURI, URI: evil, Text: "online", Start: 16, End: 22}}
+	model := NewModel(doc)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model.Update(PageLoadedMsg{Page: 1, Content: "See Chapter Two\nonline docs", Links: links})
+	if want := "\x1b]8;;https://example.com/%07%1B]52;c;cm0gLXJm%07\a"; !strings.Contains(model.viewport.View(), want) {
+		t.Errorf("Expected the URI escaped in the hyperlink, got %q", model.viewport.View())
+	}
+
+	steps := []func(){
+		func() { model.followLink(links[0]) },
+		func() { model.handleURIOpened(URIOpenedMsg{URI: evil, Err: errors.New("no opener")}) },
+		func() { model.followHint(0, true) },
+	}
+	for i, step := range steps {
+		step()
+		if strings.ContainsAny(model.statusMessage, "\a\x1b") {
+			t.Errorf("step %d: status %q has control characters", i, model.statusMessage)
+		}
+	}
+}
+
+func TestOpenURISchemes(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	defer doc.Close()
+	model := NewModel(doc)
+
+	for _, uri := range []string{"https://example.com", "HTTP://example.com", "mailto:someone@example.com"} {
+		model.clipboard = ""
+		if model.openURI(uri) == nil || !strings.HasPrefix(model.statusMessage, "Opening") || model.clipboard != "" {
+			t.Errorf("openURI(%q): status %q, clipboard %q; want it opened", uri, model.statusMessage, model.clipboard)
+		}
+	}
+	for _, uri := range []string{"file:///etc/passwd", "smb://host/share", "-x", "javascript:alert(1)"} {
+		model.clipboard = ""
+		if model.openURI(uri) == nil || !strings.HasPrefix(model.statusMessage, "Not opening") || model.clipboard != uri {
+			t.Errorf("openURI(%q): status %q, clipboard %q; want it copied", uri, model.statusMessage, model.clipboard)
+		}
+	}
+}
+
+func TestSwitchDocumentDropsStalePages(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	open := func() *pdf.Document {
+		doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+		if err != nil {
+			t.Fatalf("Failed to load test PDF: %v", err)
+		}
+		return doc
+	}
+	old, doc := open(), open()
+	model := NewModel(old)
+	model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	drive(model, model.switchDocument(doc, 1, 0))
+	defer model.document.Close()
+
+	// A page of the closed document arrives after the switch
+	model.Update(PageLoadedMsg{Doc: old, Page: 1, Content: "Error loading page: closed"})
+	if model.pageTextNum != 1 || strings.Contains(model.pageText, "Error") {
+		t.Errorf("page %d %q shown; want the stale page dropped", model.pageTextNum, model.pageText)
+	}
+}
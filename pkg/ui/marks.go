@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
+)
+
+// markNames are the names of marks in the order :marks lists them: the
+// marks of the document, then the global ones
+const markNames = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// startMark waits for the name of a mark to set, or to go to
+func (m *Model) startMark(set bool) {
+	m.markSet = set
+	m.keyHandler.Mode = KeyModeMark
+}
+
+// handleMarkKey takes the key typed after m or ' as the name of a mark.
+// As in vim, ' and ` name the position the last jump was made from.
+func (m *Model) handleMarkKey(msg tea.KeyMsg) tea.Cmd {
+	m.keyHandler.Mode = m.normalMode()
+	name := typedText(msg)
+	switch {
+	case msg.Type == tea.KeyEscape || msg.Type == tea.KeyCtrlC:
+		return nil
+	case !m.markSet && (name == "'" || name == "`"):
+		return m.jumpBack()
+	case !config.IsLocalMark(name) && !config.IsGlobalMark(name):
+		m.statusMessage = fmt.Sprintf("Not a mark: %s (marks are a-z, and A-Z across documents)", formatKey(keyName(msg)))
+		return nil
+	case m.markSet:
+		m.setMark(name)
+		return nil
+	}
+	return m.goToMark(name)
+}
+
+// setMark marks the current page and scroll line. Marks a-z belong to the
+// document; marks A-Z remember its path, so they lead back from any other.
+func (m *Model) setMark(name string) {
+	if m.docKey == "" {
+		m.statusMessage = "Cannot set a mark: the document is not identified"
+		return
+	}
+	m.cfg.SetMark(m.docKey, name, config.Mark{Path: m.docPath, Page: m.currentPage, Scroll: m.viewport.YOffset})
+	m.statusMessage = fmt.Sprintf("Mark %s set on page %s", name, m.pageName(m.currentPage))
+	m.saveConfig()
+}
+
+// goToMark jumps to a mark, opening its document if it is a global mark
+// set in another one
+func (m *Model) goToMark(name string) tea.Cmd {
+	mark, ok := m.cfg.GetMark(m.docKey, name)
+	if !ok {
+		m.statusMessage = "Mark " + name + " is not set"
+		return nil
+	}
+	if mark.Path != "" && mark.Path != m.docPath {
+		return m.openDocument(mark.Path, DocumentOpenedMsg{Page: mark.Page, Scroll: mark.Scroll})
+	}
+	m.recordJump()
+	return m.goToJump(pagePosition{file: m.docPath, page: mark.Page, scroll: mark.Scroll})
+}
+
+// runMarks lists the marks of the document and the global marks, or only
+// those named in args
+func runMarks(m *Model, args string) tea.Cmd {
+	var listed []string
+	for _, r := range markNames {
+		name := string(r)
+		if args != "" && !strings.Contains(args, name) {
+			continue
+		}
+		if mark, ok := m.cfg.GetMark(m.docKey, name); ok {
+			listed = append(listed, name+": "+m.describeMark(mark))
+		}
+	}
+	if len(listed) == 0 {
+		m.statusMessage = "No marks set"
+		return nil
+	}
+	m.statusMessage = "Marks " + strings.Join(listed, "; ")
+	return nil
+}
+
+// describeMark says where a mark is: "page xiv (14), line 41", preceded
+// by the file name of a global mark in another document
+func (m *Model) describeMark(mark config.Mark) string {
+	where := "page " + m.pageName(mark.Page)
+	if mark.Path != "" && mark.Path != m.docPath {
+		where = fmt.Sprintf("%s page %d", filepath.Base(mark.Path), mark.Page)
+	}
+	if mark.Scroll > 0 {
+		where += fmt.Sprintf(", line %d", mark.Scroll+1)
+	}
+	return where
+}
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luxor/lumos/pkg/config"
+)
+
+func TestMarks(t *testing.T) {
+	model := newActionsModel(t)
+	typeKeys(model, "2gt")
+	model.viewport.SetContent(strings.Repeat("line\n", 200))
+
+	typeKeys(model, "40j ma G")
+	if model.keyHandler.Mode != KeyModeNormal || model.currentPage != 5 {
+		t.Fatalf("after setting a mark: mode %v, page %d", model.keyHandler.Mode, model.currentPage)
+	}
+
+	typeKeys(model, "'a")
+	if model.currentPage != 3 {
+		t.Errorf("after 'a: page %d, want 3", model.currentPage)
+	}
+
+	// Going to a mark is a jump, so '' goes back
+	typeKeys(model, "''")
+	if model.currentPage != 5 {
+		t.Errorf("after '': page %d, want 5", model.currentPage)
+	}
+
+	typeKeys(model, "'b")
+	if model.statusMessage != "Mark b is not set" {
+		t.Errorf("after 'b: status %q", model.statusMessage)
+	}
+	typeKeys(model, "m1")
+	if !strings.HasPrefix(model.statusMessage, "Not a mark: 1") {
+		t.Errorf("after m1: status %q", model.statusMessage)
+	}
+
+	// Marks are saved in the config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if mark, ok := cfg.GetMark(model.docKey, "a"); !ok || mark != (config.Mark{Page: 3, Scroll: 40}) {
+		t.Errorf("saved mark a = %+v, %v; want page 3, scroll 40", mark, ok)
+	}
+
+	runCommandLine(model, "marks")
+	if model.statusMessage != "Marks a: page 3, line 41" {
+		t.Errorf(":marks status = %q", model.statusMessage)
+	}
+}
+
+func TestMarkStatus(t *testing.T) {
+	model := newActionsModel(t)
+
+	typeKeys(model, "m")
+	if status := model.renderStatusBar(); !strings.Contains(status, "Mark to set") {
+		t.Errorf("status bar doesn't ask for the mark: %s", status)
+	}
+	typeKeys(model, "'")
+	if model.keyHandler.Mode != KeyModeNormal {
+		t.Errorf("mode %v after naming the mark, want normal", model.keyHandler.Mode)
+	}
+}
+
+// TestGlobalMarks goes to a mark set in another document
+func TestGlobalMarks(t *testing.T) {
+	model := newActionsModel(t)
+	typeKeys(model, "4gg mA")
+
+	path, err := filepath.Abs("../../test/fixtures/multipage.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := filepath.Abs("../../test/fixtures/simple.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.cfg.SetMark("", "B", config.Mark{Path: other, Page: 1})
+
+	typeKeys(model, "'B")
+	if model.docPath != other {
+		t.Fatalf("after 'B: document %s, want %s (status %q)", model.docPath, other, model.statusMessage)
+	}
+
+	runCommandLine(model, "marks AB")
+	if model.statusMessage != "Marks A: multipage.pdf page 4; B: page 1" {
+		t.Errorf(":marks AB status = %q", model.statusMessage)
+	}
+
+	typeKeys(model, "'A")
+	if model.docPath != path || model.currentPage != 4 {
+		t.Errorf("after 'A: document %s, page %d; want page 4 of %s", model.docPath, model.currentPage, path)
+	}
+}
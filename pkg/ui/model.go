@@ -1,11 +1,17 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/luxor/lumos/pkg/config"
 	"github.com/luxor/lumos/pkg/pdf"
 )
@@ -14,7 +20,6 @@ import (
 type Model struct {
 	// Document
 	document *pdf.Document
-	cache    *pdf.LRUCache
 
 	// UI State
 	currentPage       int
@@ -24,12 +29,22 @@ type Model struct {
 	showHelp          bool
 	searchActive      bool
 	searchQuery       string
-	searchResults     []pdf.SearchResult
 	currentMatch      int
 	activePaneIdx     int
 	themeIndex        int // For cycling through themes
 	clipboard         string // Store copied text
+	systemClipboard   clipboard // how copied text reaches the system clipboard
 	showCopyFeedback  bool // Show copy confirmation
+	statusMessage     string // Transient message shown in the status bar
+	commandLine       string // command typed after ':'
+	commands          *CommandRegistry
+	commandHistory    []string // command lines run, oldest first
+	historyIndex      int      // line of the history recalled, len(commandHistory) when none
+	historyDraft      string   // what was typed before recalling the history
+	completions       []string // candidates cycled through by tab
+	completionIndex   int      // candidate put in, len(completions) for the word as typed
+	completionBase    string   // command line before the word completed
+	completionWord    string   // word completed, as typed
 
 	// Phase 2: Table of Contents
 	tocPane      *TOCPane
@@ -43,12 +58,50 @@ type Model struct {
 	advancedSearchResults []pdf.SearchResultAdvanced
 	showSearchOptions    bool
 	showSearchHistory    bool
+	searchCancel         context.CancelFunc
+	searchCh             <-chan pdf.SearchUpdate // updates of the running search
+	searchProgress       pdf.SearchUpdate
+	searchEntry          pdf.SearchHistoryEntry // running search, recorded when it ends
+	revealMatch          bool                   // scroll the current match into view once its page loads
 
 	// Phase 2.3: Bookmarks & Configuration
 	cfg           *config.Config
+	cfgErr        error // load failure; saving is disabled so the file isn't clobbered
 	bookmarkPane  *BookmarkPane
 	showBookmarks bool
 	docPath       string // Full path to current document
+	docKey        string // Content hash keying this document's saved state
+	docOptions    pdf.DocumentOptions // how documents that links lead to are opened
+
+	// Background prefetching
+	prefetcher       *pdf.Prefetcher
+	prefetchCh       <-chan pdf.PrefetchProgress // progress of the running job
+	prefetchProgress pdf.PrefetchProgress
+
+	// Background indexing for fast and ranked search
+	indexer       *pdf.Indexer
+	indexCh       <-chan pdf.IndexProgress // progress of the running job
+	indexProgress pdf.IndexProgress
+
+	// Background heading scan, building the TOC of documents without an outline
+	headingScanner  *pdf.HeadingScanner
+	headingCh       <-chan pdf.HeadingProgress // progress of the running scan
+	headingProgress pdf.HeadingProgress
+
+	// Reading position persistence
+	pendingScroll int // scroll offset to apply once the current page loads (-1 = none)
+	savedPage     int
+	savedScroll   int
+
+	// Jump list
+	jumps      []pagePosition // positions jumped from, oldest first
+	jumpIndex  int            // position shown while walking the list, len(jumps) when not
+	jumpsDirty bool           // jumps changed since the last save
+	markSet    bool           // the mark being named is to be set, not gone to
+
+	// Page view
+	columnView  string // config.ColumnsStacked, config.ColumnsSideBySide or "" for plain text
+	hideRunning bool   // hide running headers and footers
 
 	// Phase 3: Image Support
 	imageCache     *pdf.ImagePageCache
@@ -58,21 +111,66 @@ type Model struct {
 	imageLoading   bool              // Loading state
 
 	// Viewport
+	pageText    string          // text of the page shown in the viewport, before highlighting
+	pageTextNum int             // page pageText belongs to
+	pageLayout  *pdf.PageLayout // layout of the page, when the page view needs it
+	pageTables  []pdf.Table     // tables on the page
+	pageLinks   []pdf.Link      // links on the page
+	pageXRefs   []pdf.CrossRef  // cross-references in the page text
+	linkCells   []linkCell      // where each link, then each cross-reference, starts in the viewport content
+	hints       []hint          // labelled links while picking one in hint mode
+	hintTyped   string          // what has been typed of a hint's label
+	hintCopy    bool            // copy the address of the picked link instead of following it
+	pageLines   []string        // viewport content without styling, a line per row
+	tableRows   []rowRange      // viewport rows of each table drawn as a grid
+	matchRow    int             // viewport row of the current match as rendered (-1 = unknown)
 	viewport    viewport.Model
 	metadataView viewport.Model
 	searchView  viewport.Model
 
+	// Visual mode
+	cursor      textPos          // the text cursor
+	visualStart textPos          // the end of the selection the cursor didn't move from
+	visualLines bool             // the selection is of whole lines (V)
+	cursorToEnd bool             // the cursor goes to the last line of its page once it is shown
+	shownLines  map[int][]string // pageLines of the pages shown since visual mode started, to copy from
+
 	// Dimensions
 	width  int
 	height int
 }
 
+// ModelOptions configures a new Model
+type ModelOptions struct {
+	Resume          bool                // restore the last reading position of the document
+	DocumentOptions pdf.DocumentOptions // how documents that links lead to are opened
+	Commands        *CommandRegistry    // commands of the ':' command line
+}
+
+// DefaultModelOptions returns the default model options
+func DefaultModelOptions() ModelOptions {
+	return ModelOptions{
+		Resume:          true,
+		DocumentOptions: pdf.DefaultDocumentOptions(),
+		Commands:        DefaultCommands(),
+	}
+}
+
+// saveStateInterval is how often the reading position is saved while reading
+const saveStateInterval = 30 * time.Second
+
 // NewModel creates a new application model
 func NewModel(document *pdf.Document) *Model {
-	cache := pdf.NewLRUCache(5)
+	return NewModelWithOptions(document, DefaultModelOptions())
+}
 
-	// Load persistent configuration
-	cfg, _ := config.LoadConfig()
+// NewModelWithOptions creates a new application model with custom options
+func NewModelWithOptions(document *pdf.Document, opts ModelOptions) *Model {
+	// Load persistent configuration, falling back to defaults if it is broken
+	cfg, cfgErr := config.LoadConfig()
+	if cfgErr != nil {
+		cfg = config.DefaultConfig()
+	}
 	theme := config.GetTheme(cfg.UI.Theme)
 	styles := config.NewStyles(theme)
 
@@ -97,7 +195,6 @@ func NewModel(document *pdf.Document) *Model {
 
 	m := &Model{
 		document:             document,
-		cache:                cache,
 		currentPage:          1,
 		theme:                theme,
 		styles:               styles,
@@ -115,8 +212,13 @@ func NewModel(document *pdf.Document) *Model {
 		showSearchOptions:    false,
 		showSearchHistory:    false,
 		cfg:                  cfg,
+		cfgErr:               cfgErr,
 		bookmarkPane:         bookmarkPane,
 		showBookmarks:        false,
+		docOptions:           opts.DocumentOptions,
+		commands:             opts.Commands,
+		pendingScroll:        -1,
+		matchRow:             -1,
 		// Phase 3: Image Support
 		imageCache:     imageCache,
 		showImages:     true, // Enable images by default
@@ -124,24 +226,198 @@ func NewModel(document *pdf.Document) *Model {
 		imageRenderCfg: imageRenderCfg,
 		imageLoading:   false,
 	}
+	if m.commands == nil {
+		m.commands = DefaultCommands()
+	}
+	m.applyTheme(theme)
+	m.identifyDocument()
+	if document != nil && document.HasPageLabels() {
+		tocPane.SetPageLabels(document.PageLabel)
+		bookmarkPane.SetPageLabels(document.PageLabel)
+	}
+	if document != nil {
+		m.prefetcher = pdf.NewPrefetcher(document, pdf.DefaultPrefetchOptions())
+		m.indexer = pdf.NewIndexer(document, pdf.DefaultIndexOptions())
+		m.headingScanner = pdf.NewHeadingScanner(document)
+	}
+
+	keymap, keysErr := NewKeymap(cfg.Keys)
+	m.keyHandler.Keymap = keymap
+	if cfg.UI.KeyTimeout > 0 {
+		m.keyHandler.Timeout = time.Duration(cfg.UI.KeyTimeout) * time.Millisecond
+	}
+	m.systemClipboard = newClipboard(cfg.UI)
+
+	if cfgErr != nil {
+		// Joined validation errors span several lines; show the first
+		msg, _, _ := strings.Cut(cfgErr.Error(), "\n")
+		m.statusMessage = "Config not loaded: " + msg
+	} else {
+		if keysErr != nil {
+			msg, _, _ := strings.Cut(keysErr.Error(), "\n")
+			m.statusMessage = "Key binding ignored: " + msg
+		}
+		m.restoreView()
+		m.restoreJumps()
+		if opts.Resume {
+			m.restorePosition()
+		}
+	}
 
 	return m
 }
 
+// identifyDocument derives the key under which the document's state is
+// saved. The content hash lets state survive renames and moves; the path
+// is used only if the file cannot be read.
+func (m *Model) identifyDocument() {
+	if m.document == nil {
+		return
+	}
+	m.docPath = m.document.Path()
+	if abs, err := filepath.Abs(m.docPath); err == nil {
+		m.docPath = abs
+	}
+
+	m.docKey = m.docPath
+	if hash, err := m.document.ContentHash(); err == nil {
+		m.docKey = hash
+	}
+	m.cfg.TrackDocument(m.docKey, m.docPath)
+}
+
+// restorePosition jumps to the page and scroll offset saved for the
+// document in a previous session
+func (m *Model) restorePosition() {
+	state, ok := m.cfg.GetDocState(m.docKey)
+	if !ok || state.LastPage < 1 {
+		return
+	}
+
+	m.currentPage = min(state.LastPage, m.document.GetPageCount())
+	if state.LastScroll > 0 {
+		m.pendingScroll = state.LastScroll
+	}
+	m.savedPage, m.savedScroll = state.LastPage, state.LastScroll
+	if m.currentPage > 1 || state.LastScroll > 0 {
+		m.statusMessage = fmt.Sprintf("Resumed at page %d", m.currentPage)
+	}
+}
+
+// restoreView applies the column view and header hiding saved for the
+// document
+func (m *Model) restoreView() {
+	if state, ok := m.cfg.GetDocState(m.docKey); ok {
+		m.columnView, m.hideRunning = state.Columns, state.HideRunning
+	}
+}
+
+// switchDocument shows another document in place of the current one, which
+// is closed, at the given page and scroll offset
+func (m *Model) switchDocument(doc *pdf.Document, page, scroll int) tea.Cmd {
+	m.saveReadingState()
+	m.stopBackgroundJobs()
+	m.document.Close()
+
+	m.document = doc
+	m.docPath, m.docKey = "", ""
+	m.identifyDocument()
+	m.savedPage, m.savedScroll = 0, 0
+	m.columnView, m.hideRunning = "", false
+	m.restoreView()
+
+	m.prefetcher = pdf.NewPrefetcher(doc, pdf.DefaultPrefetchOptions())
+	m.indexer = pdf.NewIndexer(doc, pdf.DefaultIndexOptions())
+	m.headingScanner = pdf.NewHeadingScanner(doc)
+	m.prefetchCh, m.indexCh, m.headingCh = nil, nil, nil
+	m.prefetchProgress, m.headingProgress = pdf.PrefetchProgress{}, pdf.HeadingProgress{}
+
+	// Nothing shown belongs to the new document
+	m.advancedSearchResults, m.currentMatch = []pdf.SearchResultAdvanced{}, 0
+	m.pageText, m.pageTextNum, m.pageLayout = "", 0, nil
+	m.pageTables, m.pageLinks, m.pageXRefs, m.linkCells = nil, nil, nil, nil
+	m.imagesOnPage = []pdf.PageImage{}
+	m.imageCache = pdf.NewImagePageCache(10)
+	m.viewport.SetContent("")
+
+	m.tocPane = NewTOCPane(80, 20)
+	m.tocPane.SetSize(max(m.width/5-2, 1), max(m.height-5, 1))
+	m.tocLoaded, m.showTOC = false, false
+	if m.activePaneIdx == 0 {
+		m.focusPane(1)
+	}
+	m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docKey))
+	m.bookmarkPane.SetPageLabels(nil)
+	if doc.HasPageLabels() {
+		m.tocPane.SetPageLabels(doc.PageLabel)
+		m.bookmarkPane.SetPageLabels(doc.PageLabel)
+	}
+
+	m.currentPage = page
+	m.pendingScroll = scroll
+	return tea.Batch(m.loadPage(m.currentPage), m.startIndexing())
+}
+
+// stopBackgroundJobs cancels the search and the prefetching, indexing and
+// heading scan running for the document
+func (m *Model) stopBackgroundJobs() {
+	m.cancelSearch()
+	if m.prefetcher != nil {
+		m.prefetcher.Cancel()
+	}
+	if m.indexer != nil {
+		m.indexer.Cancel()
+	}
+	if m.headingScanner != nil {
+		m.headingScanner.Cancel()
+	}
+}
+
+// saveReadingState stores the current page and scroll offset, and the
+// jump list, if they changed since the last save
+func (m *Model) saveReadingState() {
+	if m.docKey == "" {
+		return
+	}
+	page, scroll := m.currentPage, m.viewport.YOffset
+	if page == m.savedPage && scroll == m.savedScroll && !m.jumpsDirty {
+		return
+	}
+	m.cfg.UpdateDocState(m.docKey, page, scroll)
+	if m.jumpsDirty {
+		m.cfg.SetJumps(m.docKey, m.docJumps())
+	}
+	m.cfg.TrackDocument(m.docKey, m.docPath)
+	m.saveConfig()
+	m.savedPage, m.savedScroll = page, scroll
+	m.jumpsDirty = false
+}
+
+// SaveStateTickMsg triggers a periodic save of the reading position
+type SaveStateTickMsg struct{}
+
+func saveStateTick() tea.Cmd {
+	return tea.Tick(saveStateInterval, func(time.Time) tea.Msg {
+		return SaveStateTickMsg{}
+	})
+}
+
 // Init initializes the model
 func (m *Model) Init() tea.Cmd {
-	// Load the initial page
-	return LoadPageCmd(m.document, m.currentPage)
+	// Load the initial page, start saving the reading position and index
+	// the document in the background
+	return tea.Batch(m.loadPage(m.currentPage), saveStateTick(), m.startIndexing())
 }
 
-// LoadTOC loads the table of contents from the document
+// LoadTOC loads the table of contents from the document outline. Without
+// one the TOC is left empty, to be filled by a heading scan.
 func (m *Model) LoadTOC() tea.Cmd {
 	return func() tea.Msg {
 		if m.document != nil {
-			toc, err := m.document.ExtractTableOfContents()
-			if err == nil && toc != nil {
+			outline, err := m.document.ExtractOutline()
+			if err == nil && len(outline) > 0 {
 				return TOCLoadedMsg{
-					TOC: toc,
+					TOC: &pdf.TableOfContents{Entries: outline, Source: "metadata"},
 				}
 			}
 		}
@@ -161,6 +437,42 @@ func (m *Model) ToggleTOC() {
 		m.LoadTOC()
 	}
 	m.showTOC = !m.showTOC
+	m.focusPane(m.activePaneIdx)
+}
+
+// toggleTOC shows or hides the table of contents in the left pane, loading
+// it the first time. The pane takes the focus while it is shown.
+func (m *Model) toggleTOC() tea.Cmd {
+	if !m.tocLoaded {
+		return m.LoadTOC()
+	}
+	m.showTOC = !m.showTOC
+	if m.showTOC {
+		m.focusPane(0)
+	} else if m.activePaneIdx == 0 {
+		m.focusPane(1)
+	}
+	return nil
+}
+
+// normalMode returns the mode keys are read in when none of the others is
+// entered: the table of contents' while it has the focus
+func (m *Model) normalMode() KeyMode {
+	if m.showTOC && m.activePaneIdx == 0 {
+		return KeyModeTOC
+	}
+	return KeyModeNormal
+}
+
+// focusPane gives a pane the focus, and with it the keys if it is the
+// table of contents
+func (m *Model) focusPane(idx int) {
+	m.activePaneIdx = idx
+	if mode := m.keyHandler.Mode; mode == KeyModeNormal || mode == KeyModeTOC {
+		if m.keyHandler.Mode = m.normalMode(); m.keyHandler.Mode != mode {
+			m.keyHandler.Reset()
+		}
+	}
 }
 
 // Update handles messages
@@ -174,8 +486,29 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		cmd = m.handleKeyPress(msg)
 
+	case ActionMsg:
+		if action, ok := m.keyHandler.Keymap.Action(msg.Mode, msg.Action); ok {
+			cmd = m.runAction(action, msg.Count)
+		}
+
+	case KeyTimeoutMsg:
+		cmd = m.runAction(m.keyHandler.Expire(msg), 0)
+
 	case PageLoadedMsg:
-		m.handlePageLoaded(msg)
+		cmd = m.handlePageLoaded(msg)
+
+	case PrefetchProgressMsg:
+		cmd = m.handlePrefetchProgress(msg)
+
+	case IndexProgressMsg:
+		cmd = m.handleIndexProgress(msg)
+
+	case SearchResultsMsg:
+		cmd = m.handleSearchResults(msg)
+
+	case SaveStateTickMsg:
+		m.saveReadingState()
+		cmd = saveStateTick()
 
 	case ScrollMsg:
 		m.viewport.LineDown(msg.Amount)
@@ -190,16 +523,20 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.showHelp = !m.showHelp
 
 	case ToggleTOCMsg:
-		if !m.tocLoaded {
-			cmd = m.LoadTOC()
-		} else {
-			m.showTOC = !m.showTOC
-		}
+		cmd = m.toggleTOC()
 
 	case TOCLoadedMsg:
 		m.tocPane.SetTableOfContents(msg.TOC)
+		m.tocPane.SetCurrentPage(m.currentPage)
 		m.tocLoaded = true
 		m.showTOC = true
+		m.focusPane(0)
+		if msg.TOC.Source == "none" {
+			cmd = m.startHeadingScan()
+		}
+
+	case HeadingProgressMsg:
+		cmd = m.handleHeadingProgress(msg)
 
 	case SearchMsg:
 		cmd = m.handleSearch(msg)
@@ -221,37 +558,45 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case ToggleBookmarkMsg:
-		// Add or remove bookmark on current page
-		if m.cfg.HasBookmark(m.docPath, m.currentPage) {
-			m.cfg.RemoveBookmark(m.docPath, m.currentPage)
-		} else {
-			m.cfg.AddBookmark(m.docPath, m.currentPage, "")
+		m.toggleBookmark()
+
+	case JumpToBookmarkMsg:
+		if msg.Page >= 1 && msg.Page <= m.document.GetPageCount() {
+			cmd = m.jumpToPage(msg.Page)
 		}
-		m.cfg.Save()
-		// Update bookmark pane with latest bookmarks
-		m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docPath))
 
 	case ToggleBookmarkListMsg:
 		m.showBookmarks = !m.showBookmarks
 		if m.showBookmarks {
-			m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docPath))
+			m.bookmarkPane.SetBookmarks(m.cfg.GetBookmarks(m.docKey))
 			m.bookmarkPane.Show()
 		} else {
 			m.bookmarkPane.Hide()
 		}
 
 	case ToggleImagesMsg:
-		// Toggle image display on/off
-		m.showImages = !m.showImages
-		// Load images when toggled on
-		if m.showImages {
-			m.imageLoading = true
-			opts := pdf.DefaultImageExtractionOptions()
-			cmd = LoadPageImagesCmd(m.document, m.currentPage, opts)
-		}
+		cmd = m.toggleImages()
 
 	case PageImagesLoadedMsg:
 		m.handlePageImagesLoaded(msg)
+
+	case ExportedMsg:
+		m.handleExported(msg)
+
+	case HintMsg:
+		m.startHints(msg.Copy)
+
+	case CrossRefResolvedMsg:
+		cmd = m.handleCrossRefResolved(msg)
+
+	case URIOpenedMsg:
+		cmd = m.handleURIOpened(msg)
+
+	case CopiedMsg:
+		m.handleCopied(msg)
+
+	case DocumentOpenedMsg:
+		cmd = m.handleDocumentOpened(msg)
 	}
 
 	return m, cmd
@@ -268,8 +613,12 @@ func (m *Model) View() string {
 	viewerWidth := (m.width / 10) * 6
 	searchWidth := m.width - metadataWidth - viewerWidth
 
-	// Render panes
+	// Render panes, with the table of contents in place of the metadata
+	// while it is shown
 	metadataPane := m.renderMetadataPane(metadataWidth, m.height - 2)
+	if m.showTOC {
+		metadataPane = m.renderTOCPane(metadataWidth, m.height-2)
+	}
 	viewerPane := m.renderViewerPane(viewerWidth, m.height - 2)
 	searchPane := m.renderSearchPane(searchWidth, m.height - 2)
 
@@ -301,107 +650,84 @@ func (m *Model) handleWindowResize(msg tea.WindowSizeMsg) {
 	// Resize viewport
 	m.viewport.Width = msg.Width / 2
 	m.viewport.Height = msg.Height - 2
+
+	// The TOC fills the left pane inside its border and title
+	m.tocPane.SetSize(max(msg.Width/5-2, 1), max(msg.Height-5, 1))
+
+	// Side-by-side columns are laid out to the viewport width
+	if m.columnView == config.ColumnsSideBySide {
+		m.renderPageContent()
+	}
 }
 
+// handleKeyPress runs the action the keymap binds a key to in the current
+// mode. Keys bound to nothing are typed into the search, the command line,
+// the filter of the table of contents and the label of a link hint.
 func (m *Model) handleKeyPress(msg tea.KeyMsg) tea.Cmd {
-	if m.keyHandler.Mode == KeyModeNormal {
-		switch msg.String() {
-		case "q", "ctrl+c":
-			return tea.Quit
-		case "?":
-			m.showHelp = !m.showHelp
-			return nil
-		case "1":
-			m.changeTheme("dark")
-			return nil
-		case "2":
-			m.changeTheme("light")
-			return nil
-		case "3":
-			// Cycle through dark themes
-			m.cycleDarkTheme()
-			return nil
-		case "/":
-			m.keyHandler.Mode = KeyModeSearch
-			m.searchActive = true
-			return nil
-		case "y":
-			// Copy current page text to clipboard
-			m.copyCurrentPage()
-			return nil
-		case "tab":
-			m.activePaneIdx = (m.activePaneIdx + 1) % 3
-			return nil
-		case "shift+tab":
-			m.activePaneIdx = (m.activePaneIdx - 1 + 3) % 3
-			return nil
-		case "n":
-			if len(m.searchResults) > 0 {
-				m.currentMatch = (m.currentMatch + 1) % len(m.searchResults)
-				m.jumpToSearchResult()
-				return nil
-			}
-		case "N":
-			if len(m.searchResults) > 0 {
-				m.currentMatch--
-				if m.currentMatch < 0 {
-					m.currentMatch = len(m.searchResults) - 1
-				}
-				m.jumpToSearchResult()
-				return nil
-			}
-		case "j", "down":
-			m.viewport.LineDown(1)
-			return nil
-		case "k", "up":
-			m.viewport.LineUp(1)
-			return nil
-		case "d":
-			m.viewport.LineDown(10)
-			return nil
-		case "u":
-			m.viewport.LineUp(10)
+	switch m.keyHandler.Mode {
+	case KeyModeMark:
+		return m.handleMarkKey(msg)
+	case KeyModeCommand:
+		return m.handleCommandKey(msg)
+	case KeyModeTOC:
+		if m.tocPane.IsFiltering() {
+			m.tocPane.HandleKey(msg)
 			return nil
-		case "g":
-			return m.goToFirstPage()
-		case "G":
-			return m.goToLastPage()
-		case "ctrl+f":
-			m.viewport.LineDown(m.viewport.Height)
-			return nil
-		case "ctrl+b":
-			m.viewport.LineUp(m.viewport.Height)
-			return nil
-		case "ctrl+n":
-			return m.goToNextPage()
-		case "ctrl+p":
-			return m.goToPreviousPage()
 		}
-	} else if m.keyHandler.Mode == KeyModeSearch {
-		switch msg.Type {
-		case tea.KeyEscape, tea.KeyCtrlC:
-			m.keyHandler.Mode = KeyModeNormal
-			m.searchActive = false
-			return nil
-		case tea.KeyEnter:
-			// Execute search
-			return m.executeSearch()
-		case tea.KeyBackspace:
-			if len(m.searchQuery) > 0 {
-				m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
-			}
-			return nil
-		case tea.KeyRunes:
-			m.searchQuery += string(msg.Runes)
-			return nil
+	}
+
+	action, count, pending := m.keyHandler.Resolve(msg)
+	if pending {
+		return m.keyHandler.Wait()
+	}
+	if action.Name == "" {
+		switch m.keyHandler.Mode {
+		case KeyModeSearch:
+			m.searchQuery += typedText(msg)
+		case KeyModeHint:
+			return m.typeHint(msg)
 		}
+		return nil
 	}
+	return m.runAction(action, count)
+}
 
-	return nil
+// typedText returns the text a key press types, if any
+func typedText(msg tea.KeyMsg) string {
+	switch msg.Type {
+	case tea.KeySpace:
+		return " "
+	case tea.KeyRunes:
+		return string(msg.Runes)
+	}
+	return ""
 }
 
-func (m *Model) handlePageLoaded(msg PageLoadedMsg) {
-	m.viewport.SetContent(msg.Content)
+func (m *Model) handlePageLoaded(msg PageLoadedMsg) tea.Cmd {
+	// A page read from a document since closed would replace the page
+	// shown, and one read after the close is an error
+	if msg.Doc != nil && msg.Doc != m.document {
+		return nil
+	}
+	m.pageText, m.pageTextNum, m.pageLayout = msg.Content, msg.Page, msg.Layout
+	m.pageTables, m.pageLinks = msg.Tables, msg.Links
+	m.pageXRefs = pdf.FindCrossRefs(msg.Content)
+	m.renderPageContent()
+
+	if m.pendingScroll >= 0 && msg.Page == m.currentPage {
+		m.viewport.SetYOffset(m.pendingScroll)
+		m.pendingScroll = -1
+	}
+	if m.revealMatch && msg.Page == m.currentPage {
+		m.revealCurrentMatch()
+		m.revealMatch = false
+	}
+	if m.keyHandler.Mode == KeyModeVisual && msg.Page == m.currentPage {
+		m.visualPageShown()
+	}
+	if m.tocLoaded && msg.Page == m.currentPage {
+		m.tocPane.SetCurrentPage(msg.Page)
+	}
 
 	// Load images for the page if image display is enabled
 	if m.showImages {
@@ -413,6 +739,151 @@ func (m *Model) handlePageLoaded(msg PageLoadedMsg) {
 		// Clear images when page changes and images are not shown
 		m.imagesOnPage = []pdf.PageImage{}
 	}
+
+	// Warm the neighbouring pages so flipping pages doesn't block
+	if msg.Page != m.currentPage || m.prefetcher == nil {
+		return nil
+	}
+	return m.startPrefetch(m.prefetcher.Radius())
+}
+
+// PrefetchProgressMsg carries progress of a background prefetch job
+type PrefetchProgressMsg struct {
+	Progress pdf.PrefetchProgress
+	ch       <-chan pdf.PrefetchProgress
+}
+
+// startPrefetch warms up to radius pages around the current page,
+// cancelling whatever job was running before
+func (m *Model) startPrefetch(radius int) tea.Cmd {
+	if m.prefetcher == nil {
+		return nil
+	}
+	pages := pdf.PagesAround(m.currentPage, radius, m.document.GetPageCount())
+	if len(pages) == 0 {
+		return nil
+	}
+	m.prefetchCh = m.prefetcher.Start(pages)
+	m.prefetchProgress = pdf.PrefetchProgress{Total: len(pages)}
+	return waitForPrefetch(m.prefetchCh)
+}
+
+// waitForPrefetch waits for the next progress update of a prefetch job
+func waitForPrefetch(ch <-chan pdf.PrefetchProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return PrefetchProgressMsg{Progress: progress, ch: ch}
+	}
+}
+
+func (m *Model) handlePrefetchProgress(msg PrefetchProgressMsg) tea.Cmd {
+	// Ignore updates from jobs that have been replaced
+	if msg.ch != m.prefetchCh {
+		return nil
+	}
+	m.prefetchProgress = msg.Progress
+	if msg.Progress.Finished {
+		m.prefetchCh = nil
+		return nil
+	}
+	return waitForPrefetch(msg.ch)
+}
+
+// IndexProgressMsg carries progress of background indexing
+type IndexProgressMsg struct {
+	Progress pdf.IndexProgress
+	ch       <-chan pdf.IndexProgress
+}
+
+// startIndexing loads or builds the document's search index
+func (m *Model) startIndexing() tea.Cmd {
+	if m.indexer == nil {
+		return nil
+	}
+	m.indexCh = m.indexer.Start()
+	m.indexProgress = pdf.IndexProgress{Total: m.document.GetPageCount()}
+	return waitForIndex(m.indexCh)
+}
+
+// waitForIndex waits for the next progress update of an indexing job
+func waitForIndex(ch <-chan pdf.IndexProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return IndexProgressMsg{Progress: progress, ch: ch}
+	}
+}
+
+func (m *Model) handleIndexProgress(msg IndexProgressMsg) tea.Cmd {
+	if msg.ch != m.indexCh {
+		return nil
+	}
+	m.indexProgress = msg.Progress
+	if !msg.Progress.Finished {
+		return waitForIndex(msg.ch)
+	}
+
+	m.indexCh = nil
+	if msg.Progress.Err != nil {
+		m.statusMessage = "Search index not saved: " + msg.Progress.Err.Error()
+	}
+	return nil
+}
+
+// HeadingProgressMsg carries the TOC found so far by a heading scan
+type HeadingProgressMsg struct {
+	Progress pdf.HeadingProgress
+	ch       <-chan pdf.HeadingProgress
+}
+
+// startHeadingScan builds the TOC from the headings on every page
+func (m *Model) startHeadingScan() tea.Cmd {
+	if m.headingScanner == nil {
+		return nil
+	}
+	m.headingCh = m.headingScanner.Start()
+	m.headingProgress = pdf.HeadingProgress{Total: m.document.GetPageCount()}
+	return waitForHeadings(m.headingCh)
+}
+
+// waitForHeadings waits for the next progress update of a heading scan
+func waitForHeadings(ch <-chan pdf.HeadingProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return HeadingProgressMsg{Progress: progress, ch: ch}
+	}
+}
+
+func (m *Model) handleHeadingProgress(msg HeadingProgressMsg) tea.Cmd {
+	if msg.ch != m.headingCh {
+		return nil
+	}
+	m.headingProgress = msg.Progress
+	if msg.Progress.TOC != nil {
+		m.tocPane.UpdateTableOfContents(msg.Progress.TOC)
+	}
+	if msg.Progress.Finished {
+		m.headingCh = nil
+		return nil
+	}
+	return waitForHeadings(msg.ch)
+}
+
+// openTOCEntry goes to the page of the entry selected in the table of
+// contents
+func (m *Model) openTOCEntry() tea.Cmd {
+	if m.tocPane.GetSelectedEntry() == nil {
+		return nil
+	}
+	return m.jumpToPage(min(max(m.tocPane.GetSelectedPage(), 1), m.document.GetPageCount()))
 }
 
 func (m *Model) handleNavigation(msg NavigateMsg) tea.Cmd {
@@ -431,9 +902,9 @@ func (m *Model) handleNavigation(msg NavigateMsg) tea.Cmd {
 
 func (m *Model) handleSearch(msg SearchMsg) tea.Cmd {
 	if msg.Direction == "next" {
-		if len(m.searchResults) > 0 {
-			m.currentMatch = (m.currentMatch + 1) % len(m.searchResults)
-			m.jumpToSearchResult()
+		if len(m.advancedSearchResults) > 0 {
+			m.currentMatch = (m.currentMatch + 1) % len(m.advancedSearchResults)
+			return m.jumpToSearchResult()
 		}
 	}
 	return nil
@@ -442,19 +913,17 @@ func (m *Model) handleSearch(msg SearchMsg) tea.Cmd {
 // Navigation helpers
 
 func (m *Model) goToFirstPage() tea.Cmd {
-	m.currentPage = 1
-	return LoadPageCmd(m.document, m.currentPage)
+	return m.jumpToPage(1)
 }
 
 func (m *Model) goToLastPage() tea.Cmd {
-	m.currentPage = m.document.GetPageCount()
-	return LoadPageCmd(m.document, m.currentPage)
+	return m.jumpToPage(m.document.GetPageCount())
 }
 
 func (m *Model) goToNextPage() tea.Cmd {
 	if m.currentPage < m.document.GetPageCount() {
 		m.currentPage++
-		return LoadPageCmd(m.document, m.currentPage)
+		return m.loadPage(m.currentPage)
 	}
 	return nil
 }
@@ -462,7 +931,7 @@ func (m *Model) goToNextPage() tea.Cmd {
 func (m *Model) goToPreviousPage() tea.Cmd {
 	if m.currentPage > 1 {
 		m.currentPage--
-		return LoadPageCmd(m.document, m.currentPage)
+		return m.loadPage(m.currentPage)
 	}
 	return nil
 }
@@ -470,8 +939,20 @@ func (m *Model) goToPreviousPage() tea.Cmd {
 // Theme
 
 func (m *Model) changeTheme(themeName string) {
-	m.theme = config.GetTheme(themeName)
+	m.applyTheme(config.GetTheme(themeName))
+	m.rememberTheme()
+}
+
+func (m *Model) cycleDarkTheme() {
+	m.themeIndex = (m.themeIndex + 1) % len(config.AvailableThemes)
+	m.applyTheme(config.AvailableThemes[m.themeIndex])
+	m.rememberTheme()
+}
+
+func (m *Model) applyTheme(theme config.Theme) {
+	m.theme = theme
 	m.styles = config.NewStyles(m.theme)
+	m.renderPageContent()
 
 	// Update theme index for cycling
 	for i, t := range config.AvailableThemes {
@@ -482,26 +963,264 @@ func (m *Model) changeTheme(themeName string) {
 	}
 }
 
-func (m *Model) cycleDarkTheme() {
-	m.themeIndex = (m.themeIndex + 1) % len(config.AvailableThemes)
-	m.theme = config.AvailableThemes[m.themeIndex]
-	m.styles = config.NewStyles(m.theme)
+// rememberTheme persists the current theme for the next session
+func (m *Model) rememberTheme() {
+	m.cfg.UI.Theme = config.ThemeKey(m.theme)
+	m.saveConfig()
+}
+
+// saveConfig writes the configuration, reporting failures in the status
+// bar. A config that failed to load is never overwritten.
+func (m *Model) saveConfig() {
+	if m.cfgErr != nil {
+		return
+	}
+	if err := m.cfg.Save(); err != nil {
+		m.statusMessage = "Config not saved: " + err.Error()
+	}
 }
 
 // Search
 
+// SearchResultsMsg carries an update from a running search
+type SearchResultsMsg struct {
+	Update pdf.SearchUpdate
+	ch     <-chan pdf.SearchUpdate
+}
+
+// executeSearch starts searching the document for the typed query with
+// the options from the search options pane, replacing any running search.
+// Results stream into the search pane; the first one is jumped to.
 func (m *Model) executeSearch() tea.Cmd {
-	// This will be implemented in search handling
+	m.keyHandler.Mode = m.normalMode()
+	m.searchActive = false
+	return m.startSearch(m.searchQuery, m.searchOptionsPane.GetOptions())
+}
+
+// startSearch starts searching the document for query with opts,
+// replacing any running search
+func (m *Model) startSearch(query string, opts pdf.SearchOptions) tea.Cmd {
+	m.cancelSearch()
+	if query == "" {
+		return nil
+	}
+	m.searchOptionsPane.SetQuery(query)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := m.document.SearchStream(ctx, query, opts)
+	if err != nil {
+		cancel()
+		m.statusMessage = "Search failed: " + err.Error()
+		return nil
+	}
+
+	m.searchCancel = cancel
+	m.searchCh = ch
+	m.searchProgress = pdf.SearchUpdate{}
+	m.searchEntry = pdf.SearchHistoryEntry{
+		Query:     query,
+		Options:   opts,
+		Timestamp: time.Now().Unix(),
+	}
+	m.advancedSearchResults = []pdf.SearchResultAdvanced{}
+	m.currentMatch = 0
+	m.statusMessage = ""
+	m.renderPageContent()
+	return waitForSearch(ch)
+}
+
+// waitForSearch waits for the next update of a running search
+func waitForSearch(ch <-chan pdf.SearchUpdate) tea.Cmd {
+	return func() tea.Msg {
+		update, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return SearchResultsMsg{Update: update, ch: ch}
+	}
+}
+
+func (m *Model) handleSearchResults(msg SearchResultsMsg) tea.Cmd {
+	// Ignore updates from searches that have been replaced
+	if msg.ch != m.searchCh {
+		return nil
+	}
+
+	first := len(m.advancedSearchResults) == 0
+	m.advancedSearchResults = append(m.advancedSearchResults, msg.Update.Results...)
+	m.searchProgress = msg.Update
+	if len(msg.Update.Results) > 0 && msg.Update.Results[0].PageNum == m.pageTextNum {
+		m.renderPageContent()
+	}
+
+	var jump tea.Cmd
+	if first && len(m.advancedSearchResults) > 0 {
+		m.currentMatch = 0
+		jump = m.jumpToSearchResult()
+	}
+
+	if msg.Update.Finished {
+		if len(m.advancedSearchResults) == 0 {
+			m.statusMessage = "Pattern not found: " + m.searchEntry.Query
+		}
+		m.endSearch()
+		return jump
+	}
+	return tea.Batch(jump, waitForSearch(msg.ch))
+}
+
+// cancelSearch stops the running search, if any
+func (m *Model) cancelSearch() {
+	if m.searchCancel != nil {
+		m.searchCancel()
+	}
+	m.endSearch()
+}
+
+// endSearch records the running search in the history once it has
+// finished or been cancelled
+func (m *Model) endSearch() {
+	if m.searchCh == nil {
+		return
+	}
+	m.searchEntry.ResultCount = len(m.advancedSearchResults)
+	m.searchHistoryManager.Add(m.searchEntry)
+	m.searchCh = nil
+	m.searchCancel = nil
+}
+
+// needsLayout reports whether pages are shown from their layout rather
+// than their plain text
+func (m *Model) needsLayout() bool {
+	return m.columnView != "" || m.hideRunning
+}
+
+// loadPage loads a page, with its layout if the page view needs it
+func (m *Model) loadPage(pageNum int) tea.Cmd {
+	if m.needsLayout() {
+		return LoadPageWithLayoutCmd(m.document, pageNum)
+	}
+	return LoadPageCmd(m.document, pageNum)
+}
+
+// cycleColumnView switches between plain text, columns in reading order
+// and columns side by side, remembering the choice for the document
+func (m *Model) cycleColumnView() tea.Cmd {
+	switch m.columnView {
+	case "":
+		m.columnView = config.ColumnsStacked
+		m.statusMessage = "Columns in reading order"
+	case config.ColumnsStacked:
+		m.columnView = config.ColumnsSideBySide
+		m.statusMessage = "Columns side by side"
+	default:
+		m.columnView = ""
+		m.statusMessage = "Columns off"
+	}
+	return m.changeView()
+}
+
+// toggleRunning hides or shows running headers and footers, remembering
+// the choice for the document
+func (m *Model) toggleRunning() tea.Cmd {
+	m.hideRunning = !m.hideRunning
+	if m.hideRunning {
+		m.statusMessage = "Headers and footers hidden"
+	} else {
+		m.statusMessage = "Headers and footers shown"
+	}
+	return m.changeView()
+}
+
+// changeView saves the page view and redraws the current page in it,
+// loading its layout first if it is needed and missing
+func (m *Model) changeView() tea.Cmd {
+	if m.docKey != "" {
+		m.cfg.SetDocView(m.docKey, m.columnView, m.hideRunning)
+		m.saveConfig()
+	}
+	if m.needsLayout() && (m.pageLayout == nil || m.pageTextNum != m.currentPage) {
+		return m.loadPage(m.currentPage)
+	}
+	m.renderPageContent()
 	return nil
 }
 
-func (m *Model) jumpToSearchResult() {
-	if len(m.searchResults) > 0 && m.currentMatch < len(m.searchResults) {
-		result := m.searchResults[m.currentMatch]
-		m.currentPage = result.PageNum
-		// Load page and scroll to result
-		_ = LoadPageCmd(m.document, m.currentPage)
+// jumpToSearchResult shows the page of the current match and scrolls the
+// match into view once the page has loaded
+func (m *Model) jumpToSearchResult() tea.Cmd {
+	if m.currentMatch < 0 || m.currentMatch >= len(m.advancedSearchResults) {
+		return nil
+	}
+	page := m.advancedSearchResults[m.currentMatch].PageNum
+	if page != m.currentPage {
+		m.recordJump()
+	}
+	m.currentPage = page
+	m.revealMatch = true
+	return m.loadPage(m.currentPage)
+}
+
+// renderPageContent puts the current page text in the viewport with the
+// search matches on it highlighted and its tables drawn as grids
+func (m *Model) renderPageContent() {
+	if m.pageTextNum == 0 {
+		return
+	}
+	spans, current := pageMatchSpans(m.advancedSearchResults, m.pageTextNum, m.currentMatch)
+	r := layoutRenderer{
+		spans: spans, current: current, matchStyle: m.styles.Accent, currentStyle: m.styles.CurrentMatch,
+		links: pageLinkSpans(m.pageLinks, m.pageXRefs), linkStyle: m.styles.Link,
+	}
+	m.matchRow, m.tableRows = -1, nil
+
+	layout := m.pageLayout
+	if !m.needsLayout() {
+		layout = nil
+	}
+	if layout != nil && m.columnView != "" {
+		content, row := r.render(layout, m.viewport.Width, m.columnView == config.ColumnsSideBySide, m.hideRunning)
+		content, m.linkCells = extractLinkMarkers(content, len(m.pageLinks)+len(m.pageXRefs))
+		m.setPageContent(content)
+		m.matchRow = row
+		return
+	}
+
+	text, base := m.pageText, 0
+	if layout != nil {
+		// Cut the running header and footer out of the page text
+		if start, end, ok := bodyRange(layout, len(text)); ok {
+			text = strings.Trim(text[start:end], "\n")
+			base = start + strings.Index(m.pageText[start:], text)
+		}
 	}
+	content, row, tableRows := r.plain(text, base, m.pageTables)
+	content, m.linkCells = extractLinkMarkers(content, len(m.pageLinks)+len(m.pageXRefs))
+	m.setPageContent(content)
+	m.matchRow, m.tableRows = row, tableRows
+}
+
+// setPageContent shows the rendered page in the viewport, keeping its
+// text for the cursor of visual mode
+func (m *Model) setPageContent(content string) {
+	m.viewport.SetContent(content)
+	m.pageLines = strings.Split(ansi.Strip(content), "\n")
+}
+
+// revealCurrentMatch scrolls the viewport so the current match's line is
+// visible, a third of the way down, unless it is visible already
+func (m *Model) revealCurrentMatch() {
+	if m.currentMatch < 0 || m.currentMatch >= len(m.advancedSearchResults) {
+		return
+	}
+	line := m.matchRow
+	if line < 0 {
+		line = m.advancedSearchResults[m.currentMatch].LineNum - 1
+	}
+	if line >= m.viewport.YOffset && line < m.viewport.YOffset+m.viewport.Height {
+		return
+	}
+	m.viewport.SetYOffset(max(line-m.viewport.Height/3, 0))
 }
 
 // Image Loading and Rendering (Phase 3)
@@ -534,17 +1253,66 @@ func (m *Model) handlePageImagesLoaded(msg PageImagesLoadedMsg) {
 	}
 }
 
+// toggleImages shows or hides the images of the page, loading them when
+// they are shown
+func (m *Model) toggleImages() tea.Cmd {
+	m.showImages = !m.showImages
+	if !m.showImages {
+		return nil
+	}
+	m.imageLoading = true
+	return LoadPageImagesCmd(m.document, m.currentPage, pdf.DefaultImageExtractionOptions())
+}
+
+// quit saves the reading position, stops the background jobs and quits
+func (m *Model) quit() tea.Cmd {
+	m.saveReadingState()
+	m.stopBackgroundJobs()
+	return tea.Quit
+}
+
 // Copy - "y" key functionality
 
-func (m *Model) copyCurrentPage() {
-	pageNum := m.currentPage
-	pageContent := m.viewport.View()
+// copyCurrentPage copies the text of the page as the viewer shows it, in
+// the copy format of the config
+func (m *Model) copyCurrentPage() tea.Cmd {
+	if m.pageTextNum != m.currentPage {
+		m.statusMessage = "The page is still loading"
+		return nil
+	}
+	lines := make([]string, len(m.pageLines))
+	for i, line := range m.pageLines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	text := strings.Trim(strings.Join(lines, "\n"), "\n")
+	m.statusMessage = "Copied page " + m.pageName(m.currentPage)
+	return m.copyText(m.formatCopy(text, m.currentPage, m.currentPage), "the page")
+}
 
-	// Store in clipboard (in-memory for now, can integrate with system clipboard)
-	m.clipboard = fmt.Sprintf("=== Page %d ===\n%s", pageNum, pageContent)
+// yankTable copies a table on the current page as CSV or Markdown: the
+// first one showing in the viewport, or else the first on the page
+func (m *Model) yankTable(markdown bool) tea.Cmd {
+	if len(m.pageTables) == 0 || m.pageTextNum != m.currentPage {
+		m.statusMessage = "No table on this page"
+		return nil
+	}
+
+	i := 0
+	for j, rows := range m.tableRows {
+		if rows.first >= 0 && rows.last >= m.viewport.YOffset && rows.first < m.viewport.YOffset+m.viewport.Height {
+			i = j
+			break
+		}
+	}
 
-	// Show feedback (briefly display copy confirmation)
-	m.showCopyFeedback = true
+	table, text, format := m.pageTables[i], "", "CSV"
+	if markdown {
+		text, format = table.Markdown(), "Markdown"
+	} else {
+		text = table.CSV()
+	}
+	m.statusMessage = fmt.Sprintf("Table %d/%d copied as %s", i+1, len(m.pageTables), format)
+	return m.copyText(text, "the table")
 }
 
 // GetClipboard returns the currently copied text
@@ -564,13 +1332,68 @@ func (m *Model) renderMetadataPane(width, height int) string {
 	if meta.Author != "" {
 		content += "Author: " + meta.Author + "\n"
 	}
+	if meta.Subject != "" {
+		content += "Subject: " + meta.Subject + "\n"
+	}
+	if meta.Keywords != "" {
+		content += "Keywords: " + meta.Keywords + "\n"
+	}
+	if meta.Creator != "" {
+		content += "Creator: " + meta.Creator + "\n"
+	}
+	if meta.Producer != "" {
+		content += "Producer: " + meta.Producer + "\n"
+	}
+	if !meta.CreationDate.IsZero() {
+		content += "Created: " + meta.CreationDate.Format("2006-01-02 15:04") + "\n"
+	}
+	if !meta.ModDate.IsZero() {
+		content += "Modified: " + meta.ModDate.Format("2006-01-02 15:04") + "\n"
+	}
+	if meta.PDFVersion != "" {
+		content += "PDF: " + meta.PDFVersion + "\n"
+	}
+	if size := meta.FormatPageSize(); size != "" {
+		content += "Size: " + size + "\n"
+	}
+	if meta.Encrypted {
+		content += "Encrypted: yes\n"
+	}
 
 	paneStyle := m.styles.PaneBorder.Width(width).Height(height)
 	return paneStyle.Render(content)
 }
 
+// renderTOCPane renders the table of contents in the left pane
+func (m *Model) renderTOCPane(width, height int) string {
+	title := "📑 Contents"
+	if p := m.headingProgress; m.headingCh != nil && p.Total > 0 {
+		title += fmt.Sprintf(" (%d/%d)", p.Done, p.Total)
+	}
+	content := m.tocPane.viewport.View()
+	if m.tocPane.IsEmpty() {
+		content = "No table of contents"
+		if m.headingCh != nil {
+			content = "Looking for headings..."
+		}
+	}
+
+	paneStyle := m.styles.PaneBorder.Width(width).Height(height)
+	return paneStyle.Render(m.styles.PaneTitle.Render(title) + "\n" + content)
+}
+
+// pageName names a page by its label, adding the page number when the
+// two differ ("xiv (14)")
+func (m *Model) pageName(pageNum int) string {
+	number := strconv.Itoa(pageNum)
+	if label := m.document.PageLabel(pageNum); label != number {
+		return label + " (" + number + ")"
+	}
+	return number
+}
+
 func (m *Model) renderViewerPane(width, height int) string {
-	title := m.styles.PaneTitle.Render(fmt.Sprintf("📖 Viewer - Page %d", m.currentPage))
+	title := m.styles.PaneTitle.Render("📖 Viewer - Page " + m.pageName(m.currentPage))
 
 	// Prepare content
 	var content string
@@ -580,6 +1403,10 @@ func (m *Model) renderViewerPane(width, height int) string {
 	} else if m.showImages && m.imageLoading {
 		// Show loading indicator while images load
 		content = m.viewport.View() + "\n[Loading images...]"
+	} else if m.keyHandler.Mode == KeyModeHint {
+		content = m.hintView()
+	} else if m.keyHandler.Mode == KeyModeVisual {
+		content = m.visualView()
 	} else {
 		// Just text content
 		content = m.viewport.View()
@@ -625,9 +1452,33 @@ func (m *Model) renderPageWithImages(width, height int) string {
 
 func (m *Model) renderSearchPane(width, height int) string {
 	title := m.styles.PaneTitle.Render("🔍 Search")
-	content := fmt.Sprintf("Results: %d\n", len(m.searchResults))
-	if m.searchActive {
-		content += "Query: " + m.searchQuery + "\n"
+	content := fmt.Sprintf("Results: %d", len(m.advancedSearchResults))
+	if p := m.searchProgress; m.searchCh != nil && p.Total > 0 {
+		content += fmt.Sprintf(" (searching %d/%d)", p.Searched, p.Total)
+	}
+	content += "\n"
+	if m.searchActive || m.searchQuery != "" {
+		content += "Query: " + m.searchQuery
+		if flags := m.searchOptionsPane.GetStatusLine(); flags != "" {
+			content += " " + flags
+		}
+		content += "\n"
+	}
+	content += "\n"
+
+	// List the results around the current match that fit the pane
+	rows := max(height-4, 1)
+	start := max(min(m.currentMatch-rows/2, len(m.advancedSearchResults)-rows), 0)
+	for i := start; i < len(m.advancedSearchResults) && i < start+rows; i++ {
+		result := m.advancedSearchResults[i]
+		snippet := strings.Join([]string{result.ContextBefore, result.MatchText, result.ContextAfter}, " ")
+		line := fmt.Sprintf("p%d:%d %s", result.PageNum, result.LineNum, strings.TrimSpace(snippet))
+		line = strings.ReplaceAll(line, "\n", " ")
+		line = lipgloss.NewStyle().MaxWidth(max(width-4, 1)).Render(line)
+		if i == m.currentMatch {
+			line = m.styles.SelectedItem.Render(line)
+		}
+		content += line + "\n"
 	}
 
 	paneStyle := m.styles.PaneBorder.Width(width).Height(height)
@@ -635,13 +1486,79 @@ func (m *Model) renderSearchPane(width, height int) string {
 }
 
 func (m *Model) renderStatusBar() string {
+	if m.keyHandler.Mode == KeyModeCommand {
+		return m.styles.StatusBar.Width(m.width).Render(m.commandLineView())
+	}
+	if m.keyHandler.Mode == KeyModeHint {
+		action := "follow"
+		if m.hintCopy {
+			action = "copy"
+		}
+		status := fmt.Sprintf("Type a hint to %s a link: %s█ (Esc to cancel)", action, m.hintTyped)
+		if m.statusMessage != "" {
+			status += " | " + m.statusMessage
+		}
+		return m.styles.StatusBar.Width(m.width).Render(status)
+	}
+
 	status := fmt.Sprintf("Page %d/%d", m.currentPage, m.document.GetPageCount())
+	if label := m.document.PageLabel(m.currentPage); label != strconv.Itoa(m.currentPage) {
+		status = fmt.Sprintf("Page %s (%d/%d)", label, m.currentPage, m.document.GetPageCount())
+	}
 	status += " | Theme: " + m.theme.Name
 
 	if m.showCopyFeedback {
 		status += " | [✓] Copied!"
 	}
 
+	if n := len(m.advancedSearchResults); n > 0 {
+		status += fmt.Sprintf(" | Match %d/%d", m.currentMatch+1, n)
+	}
+
+	if p := m.indexProgress; m.indexCh != nil && p.Total > 0 {
+		status += fmt.Sprintf(" | Indexing %d/%d", p.Done, p.Total)
+	}
+
+	if p := m.headingProgress; m.headingCh != nil && p.Total > 0 {
+		status += fmt.Sprintf(" | Scanning headings %d/%d", p.Done, p.Total)
+	}
+
+	if p := m.prefetchProgress; p.Total > 0 && !p.Finished {
+		status += fmt.Sprintf(" | Prefetching %d/%d", p.Done, p.Total)
+	}
+
+	switch m.columnView {
+	case config.ColumnsStacked:
+		status += " | Columns"
+	case config.ColumnsSideBySide:
+		status += " | Columns side by side"
+	}
+	if m.hideRunning {
+		status += " | Headers hidden"
+	}
+	if n := len(m.pageTables); n > 0 && m.pageTextNum == m.currentPage {
+		status += fmt.Sprintf(" | Tables: %d", n)
+	}
+
+	if m.statusMessage != "" {
+		status += " | " + m.statusMessage
+	}
+
+	// The keys of a sequence typed so far, like vim's showcmd
+	if pending := m.keyHandler.Pending(); pending != "" {
+		status += " | " + pending
+	}
+	if m.keyHandler.Mode == KeyModeVisual {
+		status += " | " + m.visualStatus()
+	}
+	if m.keyHandler.Mode == KeyModeMark {
+		if m.markSet {
+			status += " | Mark to set: a-z, A-Z"
+		} else {
+			status += " | Mark to go to: a-z, A-Z, '"
+		}
+	}
+
 	status += " | [?] Help [q] Quit"
 
 	return m.styles.StatusBar.Width(m.width).Render(status)
@@ -649,27 +1566,14 @@ func (m *Model) renderStatusBar() string {
 
 func (m *Model) renderHelp() string {
 	helpText := "LUMOS - Dark Mode PDF Reader for Developers\n\n"
-	helpText += "NAVIGATION\n"
-	helpText += "  j/k or ↑/↓  - Scroll line up/down\n"
-	helpText += "  d/u         - Scroll half page up/down\n"
-	helpText += "  Ctrl+F/B    - Full page down/up\n"
-	helpText += "  gg/G        - Go to first/last page\n"
-	helpText += "  Ctrl+N/P    - Next/previous page\n\n"
-	helpText += "SEARCH & COPY\n"
-	helpText += "  /           - Start search\n"
-	helpText += "  n/N         - Next/previous match\n"
-	helpText += "  y           - Copy current page\n"
-	helpText += "  Esc         - Exit search\n\n"
-	helpText += "THEMES (Professional Dark Modes)\n"
-	helpText += "  1           - Switch to dark theme\n"
-	helpText += "  2           - Switch to light theme\n"
-	helpText += "  3           - Cycle through dark themes\n"
-	helpText += "    Available: LUMOS Dark, Tokyo Night, Dracula, Solarized, Nord\n\n"
-	helpText += "UI CONTROLS\n"
-	helpText += "  Tab/Shift+Tab - Cycle panes forward/backward\n"
-	helpText += "  ?           - Toggle this help screen\n"
-	helpText += "  q/Ctrl+C    - Quit\n\n"
-	helpText += "Press ? to close this help"
+	helpText += KeyReference(m.keyHandler.Keymap)
+	helpText += "\nCOMMANDS (:xiv goes to a page by its label, :#12 by number)\n"
+	for _, cmd := range m.commands.Commands() {
+		helpText += fmt.Sprintf("  %-32s - %s\n", cmd.Usage(), cmd.Help)
+	}
+	if keys := m.keyHandler.Keymap.Keys(KeyModeNormal, "help"); len(keys) > 0 {
+		helpText += "\nPress " + keys[0] + " to close this help"
+	}
 
 	return m.styles.Background.Width(m.width).Height(m.height).Render(helpText)
 }
@@ -677,15 +1581,42 @@ func (m *Model) renderHelp() string {
 // Command definitions
 
 type PageLoadedMsg struct {
+	Doc     *pdf.Document // document the page was read from
+	Page    int
 	Content string
+	Layout  *pdf.PageLayout // set by LoadPageWithLayoutCmd
+	Tables  []pdf.Table     // tables on the page, if any
+	Links   []pdf.Link      // links on the page, if any
 }
 
 func LoadPageCmd(doc *pdf.Document, pageNum int) tea.Cmd {
 	return func() tea.Msg {
-		page, err := doc.GetPage(pageNum)
-		if err != nil {
-			return PageLoadedMsg{Content: "Error loading page: " + err.Error()}
-		}
-		return PageLoadedMsg{Content: page.Text}
+		return loadPage(doc, pageNum, false)
+	}
+}
+
+// LoadPageWithLayoutCmd loads a page together with its column layout and
+// running headers and footers
+func LoadPageWithLayoutCmd(doc *pdf.Document, pageNum int) tea.Cmd {
+	return func() tea.Msg {
+		return loadPage(doc, pageNum, true)
+	}
+}
+
+// loadPage reads a page and the tables and links on it, and its layout if
+// asked. Tables, links and layout are left out if they cannot be read.
+func loadPage(doc *pdf.Document, pageNum int, withLayout bool) PageLoadedMsg {
+	page, err := doc.GetPage(pageNum)
+	if err != nil {
+		return PageLoadedMsg{Doc: doc, Page: pageNum, Content: "Error loading page: " + err.Error()}
+	}
+	msg := PageLoadedMsg{Doc: doc, Page: pageNum, Content: page.Text}
+	if page.HasTables {
+		msg.Tables, _ = doc.GetPageTables(pageNum)
+	}
+	msg.Links, _ = doc.GetPageLinks(pageNum)
+	if withLayout {
+		msg.Layout, _ = doc.GetPageLayout(pageNum)
 	}
+	return msg
 }
@@ -1,12 +1,30 @@
 package ui
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/config"
 	"github.com/luxor/lumos/pkg/pdf"
 )
 
+// TestMain keeps the models under test from reading or writing the real
+// user configuration and cache
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "lumos-ui-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("HOME", dir)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("XDG_CACHE_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func TestModelInit(t *testing.T) {
 	// Create a test document
 	doc, err := pdf.NewDocument("../../test/fixtures/simple.pdf", 5)
@@ -102,9 +120,14 @@ func TestUpdate_ThemeChange(t *testing.T) {
 		t.Errorf("Expected LUMOS Dark theme initially, got %s", model.theme.Name)
 	}
 
+	// Digits may start a count, so '2' switches themes once nothing
+	// follows it within the key timeout
+	model.keyHandler.Timeout = time.Millisecond
+
 	// Press '2' for light mode
 	msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'2'}}
-	_, _ = model.Update(msg)
+	_, cmd := model.Update(msg)
+	drive(model, cmd)
 
 	// Should be light theme now
 	if model.theme.Name != "Light" {
@@ -113,7 +136,8 @@ func TestUpdate_ThemeChange(t *testing.T) {
 
 	// Press '1' for dark mode
 	msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'1'}}
-	_, _ = model.Update(msg)
+	_, cmd = model.Update(msg)
+	drive(model, cmd)
 
 	// Should be LUMOS Dark theme again
 	if model.theme.Name != "LUMOS Dark" {
@@ -187,13 +211,17 @@ func TestUpdate_PageNavigation(t *testing.T) {
 		t.Errorf("Expected page %d after previous, got %d", initialPage, model.currentPage)
 	}
 
-	// Test go to first page (g)
+	// Test go to first page (gg)
 	model.currentPage = 5 // Set to middle page
 	msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'g'}}
 	_, _ = model.Update(msg)
+	if model.currentPage != 5 {
+		t.Errorf("Expected 'g' alone to wait for the second g, got page %d", model.currentPage)
+	}
+	_, _ = model.Update(msg)
 
 	if model.currentPage != 1 {
-		t.Errorf("Expected page 1 after 'g', got %d", model.currentPage)
+		t.Errorf("Expected page 1 after 'gg', got %d", model.currentPage)
 	}
 
 	// Test go to last page (G)
@@ -298,3 +326,227 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestThemeChangePersists(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/simple.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+	model.keyHandler.Timeout = time.Millisecond
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'3'}})
+	drive(model, cmd)
+	if model.cfg.UI.Theme != "tokyo-night" {
+		t.Errorf("Expected config theme tokyo-night after cycling, got %q", model.cfg.UI.Theme)
+	}
+
+	// A fresh model picks the saved theme up
+	if reloaded := NewModel(doc); reloaded.theme.Name != "Tokyo Night" {
+		t.Errorf("Expected saved theme to be restored, got %s", reloaded.theme.Name)
+	}
+}
+
+func TestResumePosition(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+
+	model := NewModel(doc)
+	model.currentPage = 3
+	model.saveReadingState()
+
+	resumed := NewModel(doc)
+	if resumed.currentPage != 3 {
+		t.Errorf("Expected to resume at page 3, got %d", resumed.currentPage)
+	}
+
+	opts := DefaultModelOptions()
+	opts.Resume = false
+	if fresh := NewModelWithOptions(doc, opts); fresh.currentPage != 1 {
+		t.Errorf("Expected page 1 with resume disabled, got %d", fresh.currentPage)
+	}
+}
+
+func TestQuitSavesPosition(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+
+	model := NewModel(doc)
+	model.currentPage = 2
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	state, ok := cfg.GetDocState(model.docKey)
+	if !ok || state.LastPage != 2 {
+		t.Errorf("Expected saved page 2, got %+v (found=%v)", state, ok)
+	}
+	if state.Path != model.docPath {
+		t.Errorf("Expected saved path %q, got %q", model.docPath, state.Path)
+	}
+}
+
+func TestPageLoadStartsPrefetch(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+
+	_, cmd := model.Update(PageLoadedMsg{Page: model.currentPage, Content: "text"})
+	if cmd == nil {
+		t.Fatal("Expected a prefetch command after the page loaded")
+	}
+
+	// Follow progress until the job finishes
+	for cmd != nil {
+		msg, ok := cmd().(PrefetchProgressMsg)
+		if !ok {
+			t.Fatalf("Expected PrefetchProgressMsg, got %T", msg)
+		}
+		_, cmd = model.Update(msg)
+	}
+	if !model.prefetchProgress.Finished {
+		t.Errorf("Expected finished prefetch, got %+v", model.prefetchProgress)
+	}
+	if stats := doc.CacheStats(); stats.CachedPages == 0 {
+		t.Error("Expected neighbouring pages in the cache")
+	}
+}
+
+// drive runs cmd and every command that follows from it, feeding the
+// resulting messages back into the model
+func drive(m *Model, cmd tea.Cmd) {
+	for queue := []tea.Cmd{cmd}; len(queue) > 0; queue = queue[1:] {
+		if queue[0] == nil {
+			continue
+		}
+		switch msg := queue[0]().(type) {
+		case nil:
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		default:
+			_, next := m.Update(msg)
+			queue = append(queue, next)
+		}
+	}
+}
+
+// search types a query in search mode and runs it to completion
+func search(m *Model, query string) {
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m.searchQuery = ""
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(query)})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(m, cmd)
+}
+
+func TestSearchExecution(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+
+	search(model, "fourth")
+	if model.keyHandler.Mode != KeyModeNormal {
+		t.Error("Enter should leave search mode")
+	}
+	if len(model.advancedSearchResults) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(model.advancedSearchResults))
+	}
+	if model.currentPage != 4 {
+		t.Errorf("Expected jump to page 4, got %d", model.currentPage)
+	}
+	if model.searchCh != nil {
+		t.Error("Search should be finished")
+	}
+
+	history := model.searchHistoryManager.GetHistory()
+	if len(history) != 1 || history[0].Query != "fourth" || history[0].ResultCount != 1 {
+		t.Errorf("Expected the search in history, got %+v", history)
+	}
+
+	// N wraps from the first match to the last one
+	search(model, "Current Page")
+	if len(model.advancedSearchResults) != 4 || model.currentPage != 2 {
+		t.Fatalf("Expected 4 results starting on page 2, got %d on page %d",
+			len(model.advancedSearchResults), model.currentPage)
+	}
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'N'}})
+	drive(model, cmd)
+	if model.currentPage != 5 || model.currentMatch != 3 {
+		t.Errorf("Expected last match on page 5, got match %d on page %d", model.currentMatch, model.currentPage)
+	}
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	drive(model, cmd)
+	if model.currentPage != 2 || model.currentMatch != 0 {
+		t.Errorf("Expected first match on page 2, got match %d on page %d", model.currentMatch, model.currentPage)
+	}
+	if model.searchHistoryManager.Size() != 2 {
+		t.Errorf("Expected 2 searches in history, got %d", model.searchHistoryManager.Size())
+	}
+}
+
+func TestSearchExecution_Options(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+
+	// Case-sensitive matching is toggled from search mode
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	search(model, "FOURTH")
+	if len(model.advancedSearchResults) != 0 {
+		t.Errorf("Expected no case-sensitive results, got %d", len(model.advancedSearchResults))
+	}
+	if !contains(model.statusMessage, "not found") {
+		t.Errorf("Expected a not-found message, got %q", model.statusMessage)
+	}
+
+	model.searchOptionsPane.ToggleRegexMode()
+	search(model, "[")
+	if !contains(model.statusMessage, "Search failed") {
+		t.Errorf("Expected an invalid regex error, got %q", model.statusMessage)
+	}
+}
+
+func TestIndexingEnablesRankedSearch(t *testing.T) {
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	model := NewModel(doc)
+
+	// Ranked search needs the index
+	model.searchOptionsPane.ToggleRanked()
+	search(model, "page")
+	if !contains(model.statusMessage, "not ready") {
+		t.Errorf("Expected an index-not-ready message, got %q", model.statusMessage)
+	}
+
+	drive(model, model.startIndexing())
+	if !model.indexProgress.Finished || model.indexProgress.Err != nil || doc.Index() == nil {
+		t.Fatalf("Expected the index to be built, got %+v", model.indexProgress)
+	}
+
+	search(model, `"second page"`)
+	if len(model.advancedSearchResults) != 1 || model.currentPage != 2 {
+		t.Errorf("Expected one ranked match on page 2, got %d on page %d",
+			len(model.advancedSearchResults), model.currentPage)
+	}
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/config"
+)
+
+// PasswordPrompt asks for the password of an encrypted document before it
+// is opened. The password is masked as it is typed.
+type PasswordPrompt struct {
+	file      string
+	problem   string // why the password is asked again, if it is
+	password  []rune
+	styles    config.Styles
+	width     int
+	height    int
+	done      bool
+	cancelled bool
+}
+
+// NewPasswordPrompt creates a prompt for the password of a document. The
+// problem, if any, is shown above the input ("Wrong password").
+func NewPasswordPrompt(path, problem string) *PasswordPrompt {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	return &PasswordPrompt{
+		file:    filepath.Base(path),
+		problem: problem,
+		styles:  config.NewStyles(config.GetTheme(cfg.UI.Theme)),
+		width:   80,
+		height:  24,
+	}
+}
+
+// PromptPassword shows a password prompt in the terminal. It returns false
+// if the user gave up.
+func PromptPassword(path, problem string) (string, bool, error) {
+	prompt := NewPasswordPrompt(path, problem)
+	if _, err := tea.NewProgram(prompt, tea.WithAltScreen()).Run(); err != nil {
+		return "", false, err
+	}
+	password, ok := prompt.Password()
+	return password, ok, nil
+}
+
+// Password returns the password entered, or false if the prompt was
+// cancelled
+func (p *PasswordPrompt) Password() (string, bool) {
+	return string(p.password), p.done && !p.cancelled
+}
+
+// Init implements tea.Model
+func (p *PasswordPrompt) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model
+func (p *PasswordPrompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width, p.height = msg.Width, msg.Height
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEscape, tea.KeyCtrlC:
+			p.done, p.cancelled = true, true
+			return p, tea.Quit
+		case tea.KeyEnter:
+			p.done = true
+			return p, tea.Quit
+		case tea.KeyBackspace:
+			if len(p.password) > 0 {
+				p.password = p.password[:len(p.password)-1]
+			}
+		case tea.KeyCtrlU:
+			p.password = nil
+		case tea.KeySpace:
+			p.password = append(p.password, ' ')
+		case tea.KeyRunes:
+			p.password = append(p.password, msg.Runes...)
+		}
+	}
+	return p, nil
+}
+
+// View implements tea.Model
+func (p *PasswordPrompt) View() string {
+	var b strings.Builder
+	b.WriteString(p.styles.Accent.Render("🔒 " + p.file + " is encrypted"))
+	b.WriteString("\n\n")
+	if p.problem != "" {
+		b.WriteString(p.styles.Error.Render(p.problem))
+		b.WriteString("\n\n")
+	}
+	b.WriteString(p.styles.Text.Render("Password: " + strings.Repeat("•", len(p.password)) + "█"))
+	b.WriteString("\n\n")
+	b.WriteString(p.styles.HelpText.Render("Enter to open • Esc to cancel"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(p.styles.Theme.Accent)).
+		Padding(1, 3).
+		Render(b.String())
+	return lipgloss.Place(p.width, p.height, lipgloss.Center, lipgloss.Center, box)
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPasswordPrompt(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	p := NewPasswordPrompt("/tmp/secret.pdf", "Wrong password, try again")
+	for _, msg := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("hunter")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("23")},
+		{Type: tea.KeyBackspace},
+	} {
+		p.Update(msg)
+	}
+
+	view := p.View()
+	if strings.Contains(view, "hunter") {
+		t.Error("View() shows the password")
+	}
+	for _, want := range []string{"secret.pdf", "Wrong password", "••••••••█"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() is missing %q", want)
+		}
+	}
+	if _, ok := p.Password(); ok {
+		t.Error("Password() ok before enter")
+	}
+
+	if _, cmd := p.Update(tea.KeyMsg{Type: tea.KeyEnter}); cmd == nil {
+		t.Error("enter doesn't quit the prompt")
+	}
+	if password, ok := p.Password(); !ok || password != "hunter 2" {
+		t.Errorf("Password() = %q, %v, want \"hunter 2\", true", password, ok)
+	}
+
+	p = NewPasswordPrompt("secret.pdf", "")
+	p.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	p.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if _, ok := p.Password(); ok {
+		t.Error("Password() ok after esc")
+	}
+}
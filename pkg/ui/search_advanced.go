@@ -69,9 +69,19 @@ func (sop *SearchOptionsPane) ToggleWholeWord() {
 // ToggleRegexMode toggles regex matching
 func (sop *SearchOptionsPane) ToggleRegexMode() {
 	sop.options.RegexMode = !sop.options.RegexMode
-	// Disable whole-word when regex is enabled
+	// Disable whole-word and ranked search when regex is enabled
 	if sop.options.RegexMode {
 		sop.options.WholeWord = false
+		sop.options.Ranked = false
+	}
+}
+
+// ToggleRanked toggles ranked search of the document index
+func (sop *SearchOptionsPane) ToggleRanked() {
+	sop.options.Ranked = !sop.options.Ranked
+	// Ranked queries have their own syntax
+	if sop.options.Ranked {
+		sop.options.RegexMode = false
 	}
 }
 
@@ -111,7 +121,7 @@ func (sop *SearchOptionsPane) MoveUp() {
 
 // MoveDown moves selection down
 func (sop *SearchOptionsPane) MoveDown() {
-	optionCount := 3 // case-sensitive, whole-word, regex
+	optionCount := 4 // case-sensitive, whole-word, regex, ranked
 	if sop.selectedOptionIdx < optionCount-1 {
 		sop.selectedOptionIdx++
 	}
@@ -126,6 +136,8 @@ func (sop *SearchOptionsPane) SelectOption() {
 		sop.ToggleWholeWord()
 	case 2:
 		sop.ToggleRegexMode()
+	case 3:
+		sop.ToggleRanked()
 	}
 }
 
@@ -160,6 +172,12 @@ func (sop *SearchOptionsPane) View() string {
 			Value:       sop.options.RegexMode,
 			Key:         "r",
 		},
+		{
+			Name:        "Ranked",
+			Description: "Indexed words, \"phrases\" and prefix*, best page first (Ctrl+T)",
+			Value:       sop.options.Ranked,
+			Key:         "t",
+		},
 	}
 
 	var content strings.Builder
@@ -230,6 +248,8 @@ func (sop *SearchOptionsPane) HandleKey(key tea.KeyMsg) {
 			sop.ToggleWholeWord()
 		case 'r':
 			sop.ToggleRegexMode()
+		case 't':
+			sop.ToggleRanked()
 		}
 	}
 }
@@ -246,6 +266,9 @@ func (sop *SearchOptionsPane) GetStatusLine() string {
 	if sop.options.RegexMode {
 		flags = append(flags, ".*")
 	}
+	if sop.options.Ranked {
+		flags = append(flags, "BM25")
+	}
 
 	if len(flags) == 0 {
 		return ""
@@ -164,8 +164,13 @@ func TestSearchOptionsPane_MoveDown(t *testing.T) {
 	}
 
 	pane.MoveDown()
-	if pane.selectedOptionIdx != 2 {
-		t.Errorf("Should stay at index 2, got %d", pane.selectedOptionIdx)
+	if pane.selectedOptionIdx != 3 {
+		t.Errorf("Should move to index 3, got %d", pane.selectedOptionIdx)
+	}
+
+	pane.MoveDown()
+	if pane.selectedOptionIdx != 3 {
+		t.Errorf("Should stay at index 3, got %d", pane.selectedOptionIdx)
 	}
 }
 
@@ -195,6 +200,13 @@ func TestSearchOptionsPane_SelectOption(t *testing.T) {
 	if !pane.options.RegexMode {
 		t.Error("Regex should be enabled")
 	}
+
+	// Move to ranked (index 3), which turns regex off
+	pane.MoveDown()
+	pane.SelectOption()
+	if !pane.options.Ranked || pane.options.RegexMode {
+		t.Error("Ranked should be enabled and regex disabled")
+	}
 }
 
 // TestSearchOptionsPane_SetPageRange sets page range
@@ -275,6 +287,12 @@ func TestSearchOptionsPane_GetStatusLine(t *testing.T) {
 	if !strings.Contains(status, ".*") {
 		t.Errorf("Status should contain '.*', got %s", status)
 	}
+
+	pane.ToggleRanked()
+	status = pane.GetStatusLine()
+	if !strings.Contains(status, "BM25") || strings.Contains(status, ".*") {
+		t.Errorf("Status should contain 'BM25' but not '.*', got %s", status)
+	}
 }
 
 // TestSearchOptionsPane_HandleKey processes keyboard
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// rowRange is the first and last viewport row of something drawn there
+// (-1 if it is not drawn)
+type rowRange struct {
+	first, last int
+}
+
+// plain renders page text, which starts at byte offset base of the page,
+// drawing the tables in it as grids. It returns the content with the row
+// of the current match (-1 if it is not shown) and the rows of each table.
+// Tables without offsets into the text are left as text.
+func (r layoutRenderer) plain(text string, base int, tables []pdf.Table) (string, int, []rowRange) {
+	var sb strings.Builder
+	row, currentRow := 0, -1
+	pos := 0 // offset in text of what is left to render
+
+	segment := func(from, to int) {
+		part := text[from:to]
+		decorated, current := r.decorate(part, base+from)
+		if current >= 0 {
+			currentRow = row + strings.Count(part[:current], "\n")
+		}
+		sb.WriteString(decorated)
+		row += strings.Count(part, "\n")
+	}
+
+	var tableRows []rowRange
+	for _, table := range tables {
+		start, end := table.Start-base, table.End-base
+		if table.Start < 0 || start < pos || end > len(text) {
+			tableRows = append(tableRows, rowRange{-1, -1})
+			continue
+		}
+		segment(pos, start)
+
+		grid, current := r.table(table)
+		if current >= 0 {
+			currentRow = row + current
+		}
+		sb.WriteString(strings.Join(grid, "\n"))
+		tableRows = append(tableRows, rowRange{row, row + len(grid) - 1})
+		row += len(grid) - 1
+		pos = end
+	}
+	segment(pos, len(text))
+
+	return sb.String(), currentRow, tableRows
+}
+
+// table draws a table as a grid with box-drawing characters, its first row
+// set apart as the header. It returns the rows of the grid with the row of
+// the current match (-1 if it is not in the table).
+func (r layoutRenderer) table(table pdf.Table) ([]string, int) {
+	widths := make([]int, table.Columns())
+	for _, row := range table.Rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell.Text))
+		}
+	}
+	border := func(left, mid, right string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return left + strings.Join(parts, mid) + right
+	}
+
+	grid := []string{border("┌", "┬", "┐")}
+	currentRow := -1
+	for i, row := range table.Rows {
+		if i == 1 {
+			grid = append(grid, border("├", "┼", "┤"))
+		}
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			text, current := r.line(pdf.LayoutLine{Text: cell.Text, Offset: cell.Offset})
+			if current {
+				currentRow = len(grid)
+			}
+			cells[j] = text + strings.Repeat(" ", widths[j]-lipgloss.Width(cell.Text))
+		}
+		grid = append(grid, "│ "+strings.Join(cells, " │ ")+" │")
+	}
+	grid = append(grid, border("└", "┴", "┘"))
+
+	return grid, currentRow
+}
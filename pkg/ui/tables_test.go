@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/luxor/lumos/pkg/pdf"
+)
+
+// testTable is the table in the page text "Regs\nName Addr\nCTRL 0x00\nDATA 0x04\nEnd"
+func testTable() pdf.Table {
+	return pdf.Table{
+		Rows: [][]pdf.TableCell{
+			{{Text: "Name", Offset: 5}, {Text: "Addr", Offset: 10}},
+			{{Text: "CTRL", Offset: 15}, {Text: "0x00", Offset: 20}},
+			{{Text: "DATA", Offset: 25}, {Text: "0x04", Offset: 30}},
+		},
+		Start: 5,
+		End:   34,
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	r := layoutRenderer{current: -1}
+	grid, current := r.table(testTable())
+
+	want := []string{
+		"┌──────┬──────┐",
+		"│ Name │ Addr │",
+		"├──────┼──────┤",
+		"│ CTRL │ 0x00 │",
+		"│ DATA │ 0x04 │",
+		"└──────┴──────┘",
+	}
+	if strings.Join(grid, "\n") != strings.Join(want, "\n") || current != -1 {
+		t.Errorf("table() = %q, %d; want %q", grid, current, want)
+	}
+}
+
+func TestRenderPlainWithTables(t *testing.T) {
+	text := "Regs\nName Addr\nCTRL 0x00\nDATA 0x04\nEnd"
+	r := layoutRenderer{
+		spans:        []matchSpan{{0, 4}, {30, 34}, {35, 38}},
+		current:      1,
+		matchStyle:   markStyle("[", "]"),
+		currentStyle: markStyle("<", ">"),
+	}
+
+	got, row, tables := r.plain(text, 0, []pdf.Table{testTable()})
+	lines := strings.Split(got, "\n")
+	if len(lines) != 8 || lines[0] != "[Regs]" || lines[5] != "│ DATA │ <0x04> │" || lines[7] != "[End]" {
+		t.Errorf("plain() = %q", lines)
+	}
+	if row != 5 {
+		t.Errorf("current match row = %d, want 5", row)
+	}
+	if len(tables) != 1 || tables[0] != (rowRange{1, 6}) {
+		t.Errorf("table rows = %v, want [{1 6}]", tables)
+	}
+
+	// Tables outside the text stay as text
+	got, _, tables = r.plain(text[:12], 0, []pdf.Table{testTable()})
+	if got != "[Regs]\nName Ad" || tables[0] != (rowRange{-1, -1}) {
+		t.Errorf("plain() of a cut page = %q, %v", got, tables)
+	}
+}
+
+func TestYankTable(t *testing.T) {
+	model := NewModel(nil)
+	model.Update(PageLoadedMsg{Page: 1, Content: "Regs\nName Addr\nCTRL 0x00\nDATA 0x04\nEnd", Tables: []pdf.Table{testTable()}})
+	if !strings.Contains(model.viewport.View(), "│ CTRL │ 0x00 │") {
+		t.Errorf("Expected the table drawn as a grid, got %q", model.viewport.View())
+	}
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	if got, want := model.GetClipboard(), "Name,Addr\nCTRL,0x00\nDATA,0x04\n"; got != want {
+		t.Errorf("CSV clipboard = %q, want %q", got, want)
+	}
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'T'}})
+	if got := model.GetClipboard(); !strings.HasPrefix(got, "| Name | Addr |\n| --- | --- |\n") {
+		t.Errorf("Markdown clipboard = %q", got)
+	}
+
+	model.Update(PageLoadedMsg{Page: 1, Content: "No tables here"})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	if model.statusMessage != "No table on this page" {
+		t.Errorf("status = %q, want a note that there is no table", model.statusMessage)
+	}
+}
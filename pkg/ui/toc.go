@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -10,15 +11,53 @@ import (
 	"github.com/luxor/lumos/pkg/pdf"
 )
 
+// DefaultTOCExpandLevel is the number of TOC levels shown when it is
+// loaded (1 shows only top-level entries)
+const DefaultTOCExpandLevel = 1
+
+// tocHeaderRows is the number of rows above the entries: title and rule
+const tocHeaderRows = 2
+
+// tocNode is an entry of the TOC tree, in the flattened order
+type tocNode struct {
+	depth    int // 0 for top-level entries
+	parent   int // index of the parent node, -1 for top-level entries
+	children int // number of direct children
+}
+
+// tocKey identifies an entry across updates of the TOC, to keep its
+// expansion and selection
+type tocKey struct {
+	title       string
+	page, depth int
+}
+
 // TOCPane manages the table of contents display and navigation
 type TOCPane struct {
 	toc           *pdf.TableOfContents
-	selectedIdx   int
+	selectedIdx   int            // row of the selection among the visible entries
 	flatEntries   []pdf.TOCEntry // Flattened TOC for easier navigation
+	nodes         []tocNode      // tree structure of flatEntries
+	visible       []int          // flatEntries shown, given expansion and filter
 	viewport      viewport.Model
 	width         int
 	height        int
 	selectedEntry *pdf.TOCEntry
+
+	// Expansion
+	expandLevel int             // levels shown unless expanded or collapsed by hand
+	expanded    map[tocKey]bool // entries expanded or collapsed by hand
+
+	// Section being read
+	currentPage int
+	currentNode int // -1 if the page comes before every entry
+
+	// Fuzzy filter
+	filter    string
+	filtering bool    // the filter is being typed
+	matches   [][]int // matched rune positions in each entry's title, nil if it doesn't match
+
+	pageLabel func(int) string // names pages, nil to number them
 }
 
 // NewTOCPane creates a new TOC pane
@@ -30,14 +69,67 @@ func NewTOCPane(width, height int) *TOCPane {
 		width:       width,
 		height:      height,
 		selectedIdx: 0,
+		expandLevel: DefaultTOCExpandLevel,
+		expanded:    make(map[tocKey]bool),
+		currentNode: -1,
 	}
 }
 
 // SetTableOfContents sets the TOC to display
 func (tp *TOCPane) SetTableOfContents(toc *pdf.TableOfContents) {
+	tp.expandLevel = DefaultTOCExpandLevel
+	clear(tp.expanded)
+	tp.setTOC(toc)
+	tp.selectedIdx = 0
+	tp.updateViewport()
+}
+
+// UpdateTableOfContents replaces the TOC with a newer version of it, such
+// as one growing while headings are scanned, keeping the selected entry
+// selected and expanded entries expanded
+func (tp *TOCPane) UpdateTableOfContents(toc *pdf.TableOfContents) {
+	selected := tp.selectedNode()
+	var key tocKey
+	if selected >= 0 {
+		key = tp.key(selected)
+	}
+	tp.setTOC(toc)
+
+	if selected >= 0 {
+		for row, i := range tp.visible {
+			if tp.key(i) == key {
+				tp.selectedIdx = row
+				break
+			}
+		}
+	}
+	tp.selectedIdx = max(min(tp.selectedIdx, len(tp.visible)-1), 0)
+	tp.updateViewport()
+}
+
+// setTOC rebuilds the tree and the visible entries for a TOC
+func (tp *TOCPane) setTOC(toc *pdf.TableOfContents) {
 	tp.toc = toc
 	tp.flatEntries = tp.flattenTOC(toc.Entries)
-	tp.selectedIdx = 0
+
+	tp.nodes = tp.nodes[:0]
+	var walk func(entries []pdf.TOCEntry, depth, parent int)
+	walk = func(entries []pdf.TOCEntry, depth, parent int) {
+		for _, entry := range entries {
+			tp.nodes = append(tp.nodes, tocNode{depth: depth, parent: parent, children: len(entry.Children)})
+			walk(entry.Children, depth+1, len(tp.nodes)-1)
+		}
+	}
+	walk(toc.Entries, 0, -1)
+
+	tp.currentNode = tp.sectionAt(tp.currentPage)
+	tp.matchFilter()
+	tp.refresh()
+}
+
+// SetPageLabels names pages in the TOC by their labels
+func (tp *TOCPane) SetPageLabels(label func(pageNum int) string) {
+	tp.pageLabel = label
 	tp.updateViewport()
 }
 
@@ -59,6 +151,88 @@ func (tp *TOCPane) flattenTOC(entries []pdf.TOCEntry) []pdf.TOCEntry {
 	return flat
 }
 
+// key identifies an entry across updates of the TOC
+func (tp *TOCPane) key(i int) tocKey {
+	return tocKey{title: tp.flatEntries[i].Title, page: tp.flatEntries[i].Page, depth: tp.nodes[i].depth}
+}
+
+// isExpanded reports whether an entry shows its children. A lone top-level
+// entry, often the document title, is always expanded by default.
+func (tp *TOCPane) isExpanded(i int) bool {
+	if expanded, ok := tp.expanded[tp.key(i)]; ok {
+		return expanded
+	}
+	return tp.nodes[i].depth+1 < tp.expandLevel || (tp.nodes[i].depth == 0 && len(tp.toc.Entries) == 1)
+}
+
+// setExpanded expands or collapses an entry
+func (tp *TOCPane) setExpanded(i int, expanded bool) {
+	if tp.nodes[i].children > 0 {
+		tp.expanded[tp.key(i)] = expanded
+	}
+}
+
+// refresh works out the visible entries: those under expanded entries or,
+// while filtering, those matching the filter with their ancestors
+func (tp *TOCPane) refresh() {
+	tp.visible = tp.visible[:0]
+	if tp.filter != "" {
+		shown := make([]bool, len(tp.nodes))
+		for i := range tp.nodes {
+			if tp.matches[i] != nil {
+				for j := i; j >= 0 && !shown[j]; j = tp.nodes[j].parent {
+					shown[j] = true
+				}
+			}
+		}
+		for i, show := range shown {
+			if show {
+				tp.visible = append(tp.visible, i)
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(tp.nodes); i++ {
+		tp.visible = append(tp.visible, i)
+		if !tp.isExpanded(i) {
+			i = tp.subtreeEnd(i) - 1
+		}
+	}
+}
+
+// subtreeEnd returns the index after the last descendant of an entry
+func (tp *TOCPane) subtreeEnd(i int) int {
+	j := i + 1
+	for j < len(tp.nodes) && tp.nodes[j].depth > tp.nodes[i].depth {
+		j++
+	}
+	return j
+}
+
+// selectedNode returns the index of the selected entry, or -1 if none
+func (tp *TOCPane) selectedNode() int {
+	if tp.selectedIdx < 0 || tp.selectedIdx >= len(tp.visible) {
+		return -1
+	}
+	return tp.visible[tp.selectedIdx]
+}
+
+// selectNode selects an entry, keeping the selection on the same entry
+// after the visible entries change. A hidden entry selects its nearest
+// visible ancestor.
+func (tp *TOCPane) selectNode(i int) {
+	for ; i >= 0; i = tp.nodes[i].parent {
+		for row, v := range tp.visible {
+			if v == i {
+				tp.selectedIdx = row
+				return
+			}
+		}
+	}
+	tp.selectedIdx = max(min(tp.selectedIdx, len(tp.visible)-1), 0)
+}
+
 // MoveUp moves selection up in TOC
 func (tp *TOCPane) MoveUp() {
 	if tp.selectedIdx > 0 {
@@ -69,12 +243,36 @@ func (tp *TOCPane) MoveUp() {
 
 // MoveDown moves selection down in TOC
 func (tp *TOCPane) MoveDown() {
-	if tp.selectedIdx < len(tp.flatEntries)-1 {
+	if tp.selectedIdx < len(tp.visible)-1 {
 		tp.selectedIdx++
 		tp.updateViewport()
 	}
 }
 
+// MoveBy moves the selection n entries down, or up if n is negative,
+// stopping at the first and last entry
+func (tp *TOCPane) MoveBy(n int) {
+	if len(tp.visible) == 0 {
+		return
+	}
+	tp.selectedIdx = min(max(tp.selectedIdx+n, 0), len(tp.visible)-1)
+	tp.updateViewport()
+}
+
+// MoveToFirst selects the first entry shown
+func (tp *TOCPane) MoveToFirst() {
+	tp.selectedIdx = 0
+	tp.updateViewport()
+}
+
+// MoveToLast selects the last entry shown
+func (tp *TOCPane) MoveToLast() {
+	if len(tp.visible) > 0 {
+		tp.selectedIdx = len(tp.visible) - 1
+		tp.updateViewport()
+	}
+}
+
 // MovePageUp moves selection up by page
 func (tp *TOCPane) MovePageUp() {
 	newIdx := tp.selectedIdx - (tp.height / 2)
@@ -88,19 +286,109 @@ func (tp *TOCPane) MovePageUp() {
 // MovePageDown moves selection down by page
 func (tp *TOCPane) MovePageDown() {
 	newIdx := tp.selectedIdx + (tp.height / 2)
-	if newIdx >= len(tp.flatEntries) {
-		newIdx = len(tp.flatEntries) - 1
+	if newIdx >= len(tp.visible) {
+		newIdx = len(tp.visible) - 1
 	}
-	tp.selectedIdx = newIdx
+	tp.selectedIdx = max(newIdx, 0)
+	tp.updateViewport()
+}
+
+// Expand shows the children of the selected entry
+func (tp *TOCPane) Expand() {
+	if i := tp.selectedNode(); i >= 0 {
+		tp.setExpanded(i, true)
+		tp.refreshSelected()
+	}
+}
+
+// Collapse hides the children of the selected entry
+func (tp *TOCPane) Collapse() {
+	if i := tp.selectedNode(); i >= 0 {
+		tp.setExpanded(i, false)
+		tp.refreshSelected()
+	}
+}
+
+// ToggleExpanded expands the selected entry if it is collapsed, and
+// collapses it otherwise
+func (tp *TOCPane) ToggleExpanded() {
+	if i := tp.selectedNode(); i >= 0 {
+		tp.setExpanded(i, !tp.isExpanded(i))
+		tp.refreshSelected()
+	}
+}
+
+// ExpandOrEnter expands the selected entry, or moves to its first child if
+// it is expanded already
+func (tp *TOCPane) ExpandOrEnter() {
+	i := tp.selectedNode()
+	if i < 0 || tp.nodes[i].children == 0 {
+		return
+	}
+	if tp.isExpanded(i) && tp.filter == "" {
+		tp.MoveDown()
+		return
+	}
+	tp.Expand()
+}
+
+// CollapseOrLeave collapses the selected entry, or moves to its parent if
+// it is collapsed already or has no children
+func (tp *TOCPane) CollapseOrLeave() {
+	i := tp.selectedNode()
+	if i < 0 {
+		return
+	}
+	if tp.nodes[i].children > 0 && tp.isExpanded(i) && tp.filter == "" {
+		tp.Collapse()
+		return
+	}
+	if parent := tp.nodes[i].parent; parent >= 0 {
+		tp.selectNode(parent)
+		tp.updateViewport()
+	}
+}
+
+// ExpandToLevel shows the given number of levels (1 shows only top-level
+// entries), discarding expansions made by hand
+func (tp *TOCPane) ExpandToLevel(level int) {
+	tp.expandLevel = max(level, 1)
+	clear(tp.expanded)
+	tp.refreshSelected()
+}
+
+// ExpandAll expands every entry
+func (tp *TOCPane) ExpandAll() {
+	depth := 0
+	for _, node := range tp.nodes {
+		depth = max(depth, node.depth)
+	}
+	tp.ExpandToLevel(depth + 2)
+}
+
+// CollapseAll collapses every entry
+func (tp *TOCPane) CollapseAll() {
+	tp.expandLevel = 0
+	clear(tp.expanded)
+	tp.refreshSelected()
+}
+
+// refreshSelected recomputes the visible entries, keeping the selection on
+// the selected entry or the ancestor hiding it
+func (tp *TOCPane) refreshSelected() {
+	selected := tp.selectedNode()
+	tp.refresh()
+	tp.selectNode(selected)
 	tp.updateViewport()
 }
 
 // GetSelectedEntry returns the currently selected TOC entry
 func (tp *TOCPane) GetSelectedEntry() *pdf.TOCEntry {
-	if tp.selectedIdx < 0 || tp.selectedIdx >= len(tp.flatEntries) {
+	i := tp.selectedNode()
+	if i < 0 {
 		return nil
 	}
-	return &tp.flatEntries[tp.selectedIdx]
+	return &tp.flatEntries[i]
 }
 
 // GetSelectedPage returns the page number of the selected entry
@@ -112,10 +400,156 @@ func (tp *TOCPane) GetSelectedPage() int {
 	return 1 // Default to first page
 }
 
-// updateViewport updates the viewport content
+// sectionAt returns the entry for the section a page belongs to: the last
+// entry starting on or before the page, or -1 if there is none
+func (tp *TOCPane) sectionAt(pageNum int) int {
+	section := -1
+	for i, entry := range tp.flatEntries {
+		if entry.Page > 0 && entry.Page <= pageNum && (section < 0 || entry.Page >= tp.flatEntries[section].Page) {
+			section = i
+		}
+	}
+	return section
+}
+
+// SetCurrentPage marks the section the page belongs to as the one being
+// read, and follows it: its ancestors are expanded and it is selected
+func (tp *TOCPane) SetCurrentPage(pageNum int) {
+	if pageNum == tp.currentPage && tp.sectionAt(pageNum) == tp.currentNode {
+		return
+	}
+	tp.currentPage = pageNum
+	tp.currentNode = tp.sectionAt(pageNum)
+	if tp.currentNode < 0 {
+		tp.updateViewport()
+		return
+	}
+
+	// An entry picked on the same page stays selected
+	if entry := tp.GetSelectedEntry(); entry != nil && entry.Page == tp.flatEntries[tp.currentNode].Page {
+		tp.updateViewport()
+		return
+	}
+
+	tp.reveal(tp.currentNode)
+	tp.updateViewport()
+}
+
+// reveal expands the ancestors of an entry and selects it. While
+// filtering, only the selection changes.
+func (tp *TOCPane) reveal(i int) {
+	if tp.filter == "" {
+		for j := tp.nodes[i].parent; j >= 0; j = tp.nodes[j].parent {
+			if !tp.isExpanded(j) {
+				tp.setExpanded(j, true)
+			}
+		}
+		tp.refresh()
+	}
+	tp.selectNode(i)
+}
+
+// CurrentEntry returns the entry for the section being read, or nil if
+// the current page comes before every entry
+func (tp *TOCPane) CurrentEntry() *pdf.TOCEntry {
+	if tp.currentNode < 0 || tp.currentNode >= len(tp.flatEntries) {
+		return nil
+	}
+	return &tp.flatEntries[tp.currentNode]
+}
+
+// StartFilter starts typing a filter
+func (tp *TOCPane) StartFilter() {
+	tp.filtering = true
+	tp.updateViewport()
+}
+
+// IsFiltering reports whether a filter is being typed
+func (tp *TOCPane) IsFiltering() bool {
+	return tp.filtering
+}
+
+// SetFilter shows only the entries whose titles fuzzily match the query,
+// with their ancestors for context. An empty query shows every entry.
+func (tp *TOCPane) SetFilter(query string) {
+	selected := tp.selectedNode()
+	tp.filter = query
+	tp.matchFilter()
+	tp.refresh()
+
+	// Keep the selection if it still matches, else take the first match
+	if selected >= 0 && (query == "" || tp.matches[selected] != nil) {
+		tp.selectNode(selected)
+	} else {
+		tp.selectedIdx = 0
+		for row, i := range tp.visible {
+			if tp.matches[i] != nil {
+				tp.selectedIdx = row
+				break
+			}
+		}
+	}
+	tp.updateViewport()
+}
+
+// ClearFilter stops filtering and shows the entries as they were, with
+// the selected entry revealed
+func (tp *TOCPane) ClearFilter() {
+	selected := tp.selectedNode()
+	tp.filtering = false
+	tp.SetFilter("")
+	if selected >= 0 {
+		tp.reveal(selected)
+		tp.updateViewport()
+	}
+}
+
+// matchFilter matches every entry against the filter
+func (tp *TOCPane) matchFilter() {
+	tp.matches = nil
+	if tp.filter == "" {
+		return
+	}
+	tp.matches = make([][]int, len(tp.flatEntries))
+	for i, entry := range tp.flatEntries {
+		tp.matches[i] = fuzzyMatch(tp.filter, entry.Title)
+	}
+}
+
+// fuzzyMatch reports where the runes of a query appear in order in a
+// title, ignoring case and spaces in the query, or nil if they don't
+func fuzzyMatch(query, title string) []int {
+	positions := []int{}
+	runes := []rune(title)
+	pos := 0
+	for _, q := range strings.ToLower(query) {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		for pos < len(runes) && unicode.ToLower(runes[pos]) != q {
+			pos++
+		}
+		if pos == len(runes) {
+			return nil
+		}
+		positions = append(positions, pos)
+		pos++
+	}
+	return positions
+}
+
+// updateViewport updates the viewport content and scrolls the selection
+// into view
 func (tp *TOCPane) updateViewport() {
 	content := tp.renderTOC()
 	tp.viewport.SetContent(content)
+
+	row := tp.selectedIdx + tocHeaderRows
+	if row < tp.viewport.YOffset+tocHeaderRows {
+		tp.viewport.SetYOffset(max(row-tocHeaderRows, 0))
+	} else if row >= tp.viewport.YOffset+tp.viewport.Height {
+		tp.viewport.SetYOffset(row - tp.viewport.Height + 1)
+	}
 }
 
 // renderTOC renders the TOC with proper formatting and highlighting
@@ -127,51 +561,117 @@ func (tp *TOCPane) renderTOC() string {
 		return buf.String()
 	}
 
-	buf.WriteString(fmt.Sprintf("Table of Contents (%s)\n", tp.toc.Source))
+	switch {
+	case tp.filtering:
+		buf.WriteString("Filter: " + tp.filter + "█\n")
+	case tp.filter != "":
+		buf.WriteString(fmt.Sprintf("Filter: %s (Esc clears)\n", tp.filter))
+	default:
+		buf.WriteString(fmt.Sprintf("Table of Contents (%s)\n", tp.toc.Source))
+	}
 	buf.WriteString(strings.Repeat("─", tp.width) + "\n")
 
-	for i, entry := range tp.flatEntries {
-		line := tp.formatTOCEntry(entry, i)
-		buf.WriteString(line + "\n")
+	if len(tp.visible) == 0 {
+		buf.WriteString("No matching entries\n")
+	}
+	for row := range tp.visible {
+		buf.WriteString(tp.formatTOCEntry(row) + "\n")
 	}
 
 	return buf.String()
 }
 
-// formatTOCEntry formats a single TOC entry with indentation and selection indicator
-func (tp *TOCPane) formatTOCEntry(entry pdf.TOCEntry, idx int) string {
-	// Indentation based on level
-	indent := strings.Repeat("  ", entry.Level-1)
+// formatTOCEntry formats a visible entry with indentation, its fold state
+// and indicators for the selection and the section being read
+func (tp *TOCPane) formatTOCEntry(row int) string {
+	i := tp.visible[row]
+	entry, node := tp.flatEntries[i], tp.nodes[i]
+
+	// Indentation based on depth in the tree
+	indent := strings.Repeat("  ", node.depth)
+
+	// Fold state
+	collapsed := node.children > 0 && tp.filter == "" && !tp.isExpanded(i)
+	fold := " "
+	switch {
+	case collapsed:
+		fold = "▸"
+	case node.children > 0:
+		fold = "▾"
+	}
 
-	// Selection indicator
+	// Selection indicator, or • on the section being read (or the
+	// collapsed entry hiding it)
+	current := i == tp.currentNode || (collapsed && tp.currentNode >= 0 && tp.isAncestor(i, tp.currentNode))
 	marker := " "
-	if idx == tp.selectedIdx {
+	if row == tp.selectedIdx {
 		marker = "›"
+	} else if current {
+		marker = "•"
 	}
 
-	// Format: > Chapter Title ........................... Page 42
-	title := entry.Title
-	if len(title) > tp.width-30 {
-		title = title[:tp.width-30]
+	// Titles are cut to leave room for the page number, even in a narrow pane
+	pageNum := fmt.Sprintf("p.%d", entry.Page)
+	if tp.pageLabel != nil && entry.Page > 0 {
+		pageNum = "p." + tp.pageLabel(entry.Page)
+	}
+	title := []rune(entry.Title)
+	room := max(tp.width-len(indent)-lipgloss.Width(pageNum)-6, 8)
+	shown := tp.highlightTitle(i, title)
+	width := len(title)
+	if width > room {
+		shown, width = tp.highlightTitle(i, title[:room-1])+"…", room
 	}
 
-	pageNum := fmt.Sprintf("p.%d", entry.Page)
-	dots := tp.width - len(indent) - 2 - len(title) - len(pageNum) - 2
+	dots := tp.width - len(indent) - 3 - width - lipgloss.Width(pageNum) - 2
 	if dots < 1 {
 		dots = 1
 	}
 
-	line := fmt.Sprintf("%s%s %s %s %s",
-		indent, marker, title, strings.Repeat(".", dots), pageNum)
+	line := fmt.Sprintf("%s%s%s %s %s %s",
+		indent, marker, fold, shown, strings.Repeat(".", dots), pageNum)
 
 	// Highlight selected entry
-	if idx == tp.selectedIdx {
+	if row == tp.selectedIdx {
 		return fmt.Sprintf("\033[7m%s\033[0m", line) // Reverse video for selection
 	}
+	if current {
+		return fmt.Sprintf("\033[1m%s\033[0m", line) // Bold for the section being read
+	}
 
 	return line
 }
 
+// highlightTitle underlines the runes of a title that match the filter
+func (tp *TOCPane) highlightTitle(i int, title []rune) string {
+	if tp.matches == nil || tp.matches[i] == nil {
+		return string(title)
+	}
+	matched := make(map[int]bool, len(tp.matches[i]))
+	for _, pos := range tp.matches[i] {
+		matched[pos] = true
+	}
+	var sb strings.Builder
+	for pos, r := range title {
+		if matched[pos] {
+			sb.WriteString("\033[4m" + string(r) + "\033[24m")
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// isAncestor reports whether entry a contains entry b
+func (tp *TOCPane) isAncestor(a, b int) bool {
+	for i := tp.nodes[b].parent; i >= 0; i = tp.nodes[i].parent {
+		if i == a {
+			return true
+		}
+	}
+	return false
+}
+
 // SetSize updates pane size
 func (tp *TOCPane) SetSize(width, height int) {
 	tp.width = width
@@ -200,43 +700,30 @@ func (tp *TOCPane) View() string {
 		Render(tp.viewport.View())
 }
 
-// HandleKey processes keyboard input
+// HandleKey edits the filter being typed: Enter keeps it, Esc clears it.
+// The other keys of the pane are actions of the toc mode of the keymap.
 func (tp *TOCPane) HandleKey(key tea.KeyMsg) {
+	if !tp.filtering {
+		return
+	}
 	switch key.Type {
-	case tea.KeyUp:
-		tp.MoveUp()
-	case tea.KeyDown:
-		tp.MoveDown()
-	case tea.KeyHome:
-		tp.selectedIdx = 0
+	case tea.KeyEnter:
+		tp.filtering = false
 		tp.updateViewport()
-	case tea.KeyEnd:
-		if len(tp.flatEntries) > 0 {
-			tp.selectedIdx = len(tp.flatEntries) - 1
-			tp.updateViewport()
-		}
-	}
-
-	// Handle rune keys for vim navigation
-	if key.Type == tea.KeyRunes && len(key.Runes) > 0 {
-		switch key.Runes[0] {
-		case 'k':
-			tp.MoveUp()
-		case 'j':
-			tp.MoveDown()
-		case 'u':
-			tp.MovePageUp()
-		case 'd':
-			tp.MovePageDown()
-		case 'g':
-			tp.selectedIdx = 0
-			tp.updateViewport()
-		case 'G':
-			if len(tp.flatEntries) > 0 {
-				tp.selectedIdx = len(tp.flatEntries) - 1
-				tp.updateViewport()
-			}
+	case tea.KeyEscape, tea.KeyCtrlC:
+		tp.ClearFilter()
+	case tea.KeyBackspace:
+		if runes := []rune(tp.filter); len(runes) > 0 {
+			tp.SetFilter(string(runes[:len(runes)-1]))
 		}
+	case tea.KeySpace:
+		tp.SetFilter(tp.filter + " ")
+	case tea.KeyRunes:
+		tp.SetFilter(tp.filter + string(key.Runes))
+	case tea.KeyUp, tea.KeyCtrlP:
+		tp.MoveUp()
+	case tea.KeyDown, tea.KeyCtrlN:
+		tp.MoveDown()
 	}
 }
 
@@ -250,13 +737,13 @@ type TOCChangedMsg struct {
 	TOC *pdf.TableOfContents
 }
 
-// SearchTOC searches the TOC for entries matching a query
+// SearchTOC searches the TOC for entries whose titles fuzzily match a
+// query: its letters appear in the title in order, ignoring case
 func (tp *TOCPane) SearchTOC(query string) []pdf.TOCEntry {
 	var results []pdf.TOCEntry
 
-	query = strings.ToLower(query)
 	for _, entry := range tp.flatEntries {
-		if strings.Contains(strings.ToLower(entry.Title), query) {
+		if fuzzyMatch(query, entry.Title) != nil {
 			results = append(results, entry)
 		}
 	}
@@ -269,7 +756,7 @@ func (tp *TOCPane) JumpToPage(pageNum int) {
 	// Find entries for this page
 	for i, entry := range tp.flatEntries {
 		if entry.Page == pageNum {
-			tp.selectedIdx = i
+			tp.reveal(i)
 			tp.updateViewport()
 			return
 		}
@@ -355,31 +355,32 @@ func TestTOCPane_HandleKey(t *testing.T) {
 		Source: "metadata",
 	}
 	pane.SetTableOfContents(toc)
+	m := tocModel(pane)
 
 	// Test down arrow key
 	msg := tea.KeyMsg{Type: tea.KeyDown}
-	pane.HandleKey(msg)
+	m.handleKeyPress(msg)
 	if pane.selectedIdx != 1 {
 		t.Errorf("Down arrow should move to 1, got %d", pane.selectedIdx)
 	}
 
 	// Test up arrow key
 	msg = tea.KeyMsg{Type: tea.KeyUp}
-	pane.HandleKey(msg)
+	m.handleKeyPress(msg)
 	if pane.selectedIdx != 0 {
 		t.Errorf("Up arrow should move to 0, got %d", pane.selectedIdx)
 	}
 
 	// Test 'j' key (vim down)
 	msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}}
-	pane.HandleKey(msg)
+	m.handleKeyPress(msg)
 	if pane.selectedIdx != 1 {
 		t.Errorf("'j' should move down, got %d", pane.selectedIdx)
 	}
 
 	// Test 'k' key (vim up)
 	msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}}
-	pane.HandleKey(msg)
+	m.handleKeyPress(msg)
 	if pane.selectedIdx != 0 {
 		t.Errorf("'k' should move up, got %d", pane.selectedIdx)
 	}
@@ -432,15 +433,15 @@ func TestTOCPane_MovePageDown(t *testing.T) {
 // BenchmarkTOCPane_FormatEntry benchmarks entry formatting
 func BenchmarkTOCPane_FormatEntry(b *testing.B) {
 	pane := NewTOCPane(80, 24)
-	entry := pdf.TOCEntry{
+	pane.SetTableOfContents(&pdf.TableOfContents{Entries: []pdf.TOCEntry{{
 		Title: "Chapter One Advanced Topics in Computer Science",
 		Page:  42,
 		Level: 1,
-	}
+	}}})
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = pane.formatTOCEntry(entry, i%10)
+		_ = pane.formatTOCEntry(0)
 	}
 }
 
@@ -468,3 +469,298 @@ func BenchmarkTOCPane_Flatten(b *testing.B) {
 
 // Ensure code compiles
 var _ = fmt.Sprintf
+
+// TestModelTOCFromHeadings builds the TOC of a document without an outline
+// from its headings, and opens pages from it
+func TestModelTOCFromHeadings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	doc, err := pdf.NewDocument("../../test/fixtures/search_test.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	m := NewModel(doc)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	drive(m, cmd)
+
+	if !m.showTOC || m.activePaneIdx != 0 {
+		t.Fatalf("TOC shown = %v with pane %d focused, want it shown and focused", m.showTOC, m.activePaneIdx)
+	}
+	if m.headingCh != nil || !m.headingProgress.Finished {
+		t.Errorf("heading scan still running: %+v", m.headingProgress)
+	}
+	if m.tocPane.GetSourceType() != "headings" || m.tocPane.IsEmpty() {
+		t.Fatalf("TOC source %q with %d entries, want headings", m.tocPane.GetSourceType(), m.tocPane.GetEntryCount())
+	}
+	if entry := m.tocPane.GetSelectedEntry(); entry.Title != "LUMOS Search Test PDF" {
+		t.Errorf("first entry = %q", entry.Title)
+	}
+	if view := m.View(); !strings.Contains(view, "Contents") || !strings.Contains(view, "LUMOS Search") {
+		t.Errorf("TOC not shown in the left pane:\n%s", view)
+	}
+
+	// Enter opens the selected entry's page; j moves the selection, not
+	// the viewer
+	m.currentPage = 2
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(m, cmd)
+	if m.currentPage != 1 {
+		t.Errorf("currentPage = %d after opening the entry, want 1", m.currentPage)
+	}
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	drive(m, cmd)
+	if m.showTOC || m.activePaneIdx != 1 {
+		t.Errorf("TOC shown = %v with pane %d focused after hiding it", m.showTOC, m.activePaneIdx)
+	}
+}
+
+func TestTOCPane_UpdateTableOfContents(t *testing.T) {
+	pane := NewTOCPane(80, 24)
+	pane.SetTableOfContents(&pdf.TableOfContents{Entries: []pdf.TOCEntry{
+		{Title: "Intro", Page: 1, Level: 1},
+		{Title: "Setup", Page: 3, Level: 1},
+	}})
+	pane.MoveDown()
+
+	pane.UpdateTableOfContents(&pdf.TableOfContents{Entries: []pdf.TOCEntry{
+		{Title: "Intro", Page: 1, Level: 1, Children: []pdf.TOCEntry{{Title: "Scope", Page: 2, Level: 2}}},
+		{Title: "Setup", Page: 3, Level: 1},
+	}})
+	if entry := pane.GetSelectedEntry(); entry == nil || entry.Title != "Setup" {
+		t.Errorf("selected %+v after the update, want Setup", entry)
+	}
+
+	pane.UpdateTableOfContents(&pdf.TableOfContents{Entries: []pdf.TOCEntry{}})
+	if pane.selectedIdx != 0 || pane.GetSelectedEntry() != nil {
+		t.Errorf("selection %d in an empty TOC", pane.selectedIdx)
+	}
+}
+
+// bookTOC is a three-level outline
+func bookTOC() *pdf.TableOfContents {
+	return &pdf.TableOfContents{Source: "metadata", Entries: []pdf.TOCEntry{
+		{Title: "Getting Started", Page: 1, Level: 1, Children: []pdf.TOCEntry{
+			{Title: "Installation", Page: 2, Level: 2},
+			{Title: "First Steps", Page: 4, Level: 2},
+		}},
+		{Title: "Configuration", Page: 10, Level: 1, Children: []pdf.TOCEntry{
+			{Title: "Key Bindings", Page: 12, Level: 2, Children: []pdf.TOCEntry{
+				{Title: "Vim Mode", Page: 13, Level: 3},
+			}},
+			{Title: "Themes", Page: 15, Level: 2},
+		}},
+		{Title: "Reference", Page: 20, Level: 1},
+	}}
+}
+
+// visibleTitles lists the titles of the entries shown
+func visibleTitles(pane *TOCPane) []string {
+	var titles []string
+	for _, i := range pane.visible {
+		titles = append(titles, pane.flatEntries[i].Title)
+	}
+	return titles
+}
+
+// tocModel returns a viewer around the pane with the table of contents
+// focused, to send it keys through the keymap
+func tocModel(pane *TOCPane) *Model {
+	m := &Model{tocPane: pane, keyHandler: NewKeyHandler(), showTOC: true}
+	m.keyHandler.Mode = KeyModeTOC
+	return m
+}
+
+// pressTOC sends keys to the pane, one rune at a time
+func pressTOC(pane *TOCPane, keys string) {
+	m := tocModel(pane)
+	for _, r := range keys {
+		m.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestTOCPane_Sequences(t *testing.T) {
+	pane := NewTOCPane(80, 24)
+	pane.SetTableOfContents(bookTOC())
+	pane.ExpandAll()
+
+	steps := []struct {
+		keys     string
+		selected string
+	}{
+		{"G", "Reference"},
+		{"g", "Reference"}, // waits for the rest of gg
+		{"gg", "Getting Started"},
+		{"3j", "Configuration"},
+		{"2k", "Installation"},
+	}
+	for _, step := range steps {
+		pressTOC(pane, step.keys)
+		if got := pane.GetSelectedEntry().Title; got != step.selected {
+			t.Errorf("after %q selected %q, want %q", step.keys, got, step.selected)
+		}
+	}
+}
+
+func TestTOCPane_Folding(t *testing.T) {
+	pane := NewTOCPane(80, 24)
+	pane.SetTableOfContents(bookTOC())
+
+	if got := strings.Join(visibleTitles(pane), ","); got != "Getting Started,Configuration,Reference" {
+		t.Fatalf("initially shown %q, want the top level", got)
+	}
+
+	steps := []struct {
+		keys     string
+		visible  string
+		selected string
+	}{
+		{"jl", "Getting Started,Configuration,Key Bindings,Themes,Reference", "Configuration"},
+		{"l", "Getting Started,Configuration,Key Bindings,Themes,Reference", "Key Bindings"},
+		{"zo", "Getting Started,Configuration,Key Bindings,Vim Mode,Themes,Reference", "Key Bindings"},
+		{"jh", "Getting Started,Configuration,Key Bindings,Vim Mode,Themes,Reference", "Key Bindings"},
+		{"hh", "Getting Started,Configuration,Key Bindings,Themes,Reference", "Configuration"},
+		{"za", "Getting Started,Configuration,Reference", "Configuration"},
+		{"za", "Getting Started,Configuration,Key Bindings,Themes,Reference", "Configuration"},
+		{"zM", "Getting Started,Configuration,Reference", "Configuration"},
+		{"z2", "Getting Started,Installation,First Steps,Configuration,Key Bindings,Themes,Reference", "Configuration"},
+		{"zR", "Getting Started,Installation,First Steps,Configuration,Key Bindings,Vim Mode,Themes,Reference", "Configuration"},
+		{"zc", "Getting Started,Installation,First Steps,Configuration,Reference", "Configuration"},
+		{"zx", "Getting Started,Installation,First Steps,Configuration,Reference", "Configuration"},
+	}
+	for _, step := range steps {
+		pressTOC(pane, step.keys)
+		if got := strings.Join(visibleTitles(pane), ","); got != step.visible {
+			t.Errorf("after %q shown %q, want %q", step.keys, got, step.visible)
+		}
+		if got := pane.GetSelectedEntry().Title; got != step.selected {
+			t.Errorf("after %q selected %q, want %q", step.keys, got, step.selected)
+		}
+	}
+
+	// Collapsing hides the selection under its parent
+	pane.ExpandAll()
+	pane.JumpToPage(13)
+	pane.CollapseAll()
+	if got := pane.GetSelectedEntry().Title; got != "Configuration" {
+		t.Errorf("selected %q after collapsing, want its top-level ancestor", got)
+	}
+}
+
+func TestTOCPane_FollowsCurrentPage(t *testing.T) {
+	pane := NewTOCPane(80, 24)
+	pane.SetTableOfContents(bookTOC())
+
+	pane.SetCurrentPage(14)
+	if entry := pane.CurrentEntry(); entry == nil || entry.Title != "Vim Mode" {
+		t.Fatalf("current section = %+v, want Vim Mode", entry)
+	}
+	if got := pane.GetSelectedEntry().Title; got != "Vim Mode" {
+		t.Errorf("selected %q, want the current section revealed and selected", got)
+	}
+
+	// Collapsed, the section's ancestor carries the indicator
+	pane.CollapseAll()
+	if row := pane.formatTOCEntry(1); !strings.Contains(row, "▸") || !strings.Contains(row, "Configuration") {
+		t.Errorf("row %q should be the collapsed current section", row)
+	}
+	pressTOC(pane, "k")
+	if row := pane.formatTOCEntry(1); !strings.Contains(row, "•") {
+		t.Errorf("row %q should mark the section being read", row)
+	}
+
+	// Picking an entry on the same page as the section keeps it selected
+	pane.ExpandAll()
+	pane.JumpToPage(1)
+	pane.SetCurrentPage(1)
+	if got := pane.GetSelectedEntry().Title; got != "Getting Started" {
+		t.Errorf("selected %q after opening page 1", got)
+	}
+
+	pane.SetCurrentPage(0)
+	if pane.CurrentEntry() != nil {
+		t.Error("no section should be current before the first entry")
+	}
+}
+
+func TestTOCPane_Filter(t *testing.T) {
+	pane := NewTOCPane(80, 24)
+	pane.SetTableOfContents(bookTOC())
+
+	pressTOC(pane, "/vim")
+	if !pane.IsFiltering() {
+		t.Fatal("'/' should start typing a filter")
+	}
+	if got := strings.Join(visibleTitles(pane), ","); got != "Configuration,Key Bindings,Vim Mode" {
+		t.Errorf("filtered %q, want the match with its ancestors", got)
+	}
+	if got := pane.GetSelectedEntry().Title; got != "Vim Mode" {
+		t.Errorf("selected %q, want the first match", got)
+	}
+
+	// Fuzzy: letters in order, spaces ignored
+	pane.HandleKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	pane.HandleKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	pane.HandleKey(tea.KeyMsg{Type: tea.KeyBackspace})
+	pressTOC(pane, "kbd")
+	if got := strings.Join(visibleTitles(pane), ","); got != "Configuration,Key Bindings" {
+		t.Errorf("filtered %q for \"kbd\"", got)
+	}
+
+	pane.HandleKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if pane.IsFiltering() || pane.filter != "kbd" {
+		t.Errorf("Enter should keep the filter %q", pane.filter)
+	}
+	tocModel(pane).handleKeyPress(tea.KeyMsg{Type: tea.KeyEscape})
+	if got := strings.Join(visibleTitles(pane), ","); pane.filter != "" || got != "Getting Started,Configuration,Key Bindings,Themes,Reference" {
+		t.Errorf("Esc should clear the filter and reveal the selection, shown %q", got)
+	}
+	if got := pane.GetSelectedEntry().Title; got != "Key Bindings" {
+		t.Errorf("selected %q after clearing, want the match kept", got)
+	}
+
+	if results := pane.SearchTOC("gs"); len(results) != 2 {
+		t.Errorf("SearchTOC(\"gs\") found %d entries, want 2", len(results))
+	}
+}
+
+func TestModelTOCFollowsPages(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	doc, err := pdf.NewDocument("../../test/fixtures/multipage.pdf", 5)
+	if err != nil {
+		t.Fatalf("Failed to load test PDF: %v", err)
+	}
+	m := NewModel(doc)
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	m.Update(TOCLoadedMsg{TOC: &pdf.TableOfContents{Source: "metadata", Entries: []pdf.TOCEntry{
+		{Title: "Start", Page: 1, Level: 1},
+		{Title: "Middle", Page: 3, Level: 1},
+	}}})
+
+	drive(m, m.goToLastPage())
+	if entry := m.tocPane.CurrentEntry(); entry == nil || entry.Title != "Middle" {
+		t.Errorf("current section on the last page = %+v, want Middle", entry)
+	}
+
+	// Keys go to the pane while it has the focus
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	drive(m, cmd)
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	drive(m, cmd)
+	if m.currentPage != 1 {
+		t.Errorf("currentPage = %d after opening Start, want 1", m.currentPage)
+	}
+
+	// They come back to it after the command line, and leave with the focus
+	m.Update(keyPress(":"))
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.keyHandler.Mode != KeyModeTOC {
+		t.Errorf("mode %v after the command line, want toc", m.keyHandler.Mode)
+	}
+	_, cmd = m.Update(keyPress("tab"))
+	drive(m, cmd)
+	if m.keyHandler.Mode != KeyModeNormal {
+		t.Errorf("mode %v after focusing the viewer, want normal", m.keyHandler.Mode)
+	}
+}
@@ -0,0 +1,537 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// textPos is a place in the text of a page as the viewer shows it: a line
+// of the viewport content and a rune of that line
+type textPos struct {
+	page, line, col int
+}
+
+// before reports whether p comes before q in the document
+func (p textPos) before(q textPos) bool {
+	if p.page != q.page {
+		return p.page < q.page
+	}
+	if p.line != q.line {
+		return p.line < q.line
+	}
+	return p.col < q.col
+}
+
+func visualActions() []Action {
+	return []Action{
+		{Name: "left", Group: groupVisual, Help: "Move the cursor left", Keys: []string{"h", "left"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorCol(m.cursor.col - times(count)); return nil }},
+		{Name: "right", Group: groupVisual, Help: "Move the cursor right", Keys: []string{"l", "right"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorCol(m.cursor.col + times(count)); return nil }},
+		{Name: "down", Group: groupVisual, Help: "Move the cursor down, on to the next page from the last line", Keys: []string{"j", "down"},
+			Run: func(m *Model, count int) tea.Cmd { return m.moveCursorLines(times(count)) }},
+		{Name: "up", Group: groupVisual, Help: "Move the cursor up, back to the previous page from the first line", Keys: []string{"k", "up"},
+			Run: func(m *Model, count int) tea.Cmd { return m.moveCursorLines(-times(count)) }},
+		{Name: "word-forward", Group: groupVisual, Help: "Move to the start of the next word", Keys: []string{"w"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorWords(times(count), nextWordStart); return nil }},
+		{Name: "word-backward", Group: groupVisual, Help: "Move to the start of the word", Keys: []string{"b"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorWords(times(count), prevWordStart); return nil }},
+		{Name: "word-end", Group: groupVisual, Help: "Move to the end of the word", Keys: []string{"e"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorWords(times(count), nextWordEnd); return nil }},
+		{Name: "line-start", Group: groupVisual, Help: "Move to the start of the line", Keys: []string{"0", "home"},
+			Run: do(func(m *Model) { m.moveCursorCol(0) })},
+		{Name: "line-text-start", Group: groupVisual, Help: "Move to the first character of the line that isn't a space", Keys: []string{"^"},
+			Run: do(func(m *Model) {
+				line := []rune(m.cursorLine())
+				col := 0
+				for col < len(line) && unicode.IsSpace(line[col]) {
+					col++
+				}
+				m.moveCursorCol(col)
+			})},
+		{Name: "line-end", Group: groupVisual, Help: "Move to the end of the line", Keys: []string{"$", "end"},
+			Run: do(func(m *Model) { m.moveCursorCol(len([]rune(m.cursorLine()))) })},
+		{Name: "half-page-down", Group: groupVisual, Help: "Move the cursor down half a screen", Keys: []string{"ctrl+d"},
+			Run: func(m *Model, count int) tea.Cmd {
+				return m.moveCursorLines(times(count) * max(m.viewport.Height/2, 1))
+			}},
+		{Name: "half-page-up", Group: groupVisual, Help: "Move the cursor up half a screen", Keys: []string{"ctrl+u"},
+			Run: func(m *Model, count int) tea.Cmd {
+				return m.moveCursorLines(-times(count) * max(m.viewport.Height/2, 1))
+			}},
+		{Name: "first-line", Group: groupVisual, Help: "Move to the first line of the page, or the line numbered by the count", Keys: []string{"gg"},
+			Run: func(m *Model, count int) tea.Cmd { m.moveCursorTo(times(count)-1, 0); return nil }},
+		{Name: "last-line", Group: groupVisual, Help: "Move to the last line of the page, or the line numbered by the count", Keys: []string{"G"},
+			Run: func(m *Model, count int) tea.Cmd {
+				if count == 0 {
+					count = len(m.pageLines)
+				}
+				m.moveCursorTo(count-1, 0)
+				return nil
+			}},
+		{Name: "next-page", Group: groupVisual, Help: "Move the cursor to the top of the next page", Keys: []string{"ctrl+n"},
+			Run: doCmd(func(m *Model) tea.Cmd { return m.moveCursorPage(1) })},
+		{Name: "previous-page", Group: groupVisual, Help: "Move the cursor to the top of the previous page", Keys: []string{"ctrl+p"},
+			Run: doCmd(func(m *Model) tea.Cmd { return m.moveCursorPage(-1) })},
+		{Name: "other-end", Group: groupVisual, Help: "Move the cursor to the other end of the selection", Keys: []string{"o"},
+			Run: doCmd((*Model).swapSelectionEnds)},
+		{Name: "visual", Group: groupVisual, Help: "Select characters, or leave visual mode if they are selected", Keys: []string{"v"},
+			Run: do(func(m *Model) { m.switchVisual(false) })},
+		{Name: "visual-line", Group: groupVisual, Help: "Select whole lines, or leave visual mode if they are selected", Keys: []string{"V"},
+			Run: do(func(m *Model) { m.switchVisual(true) })},
+		{Name: "yank", Group: groupVisual, Help: "Copy the selection to the clipboard and leave visual mode", Keys: []string{"y"},
+			Run: doCmd((*Model).yankSelection)},
+		{Name: "cancel", Group: groupVisual, Help: "Leave visual mode", Keys: []string{"esc", "ctrl+c"},
+			Run: do((*Model).endVisual)},
+	}
+}
+
+// startVisual starts selecting text, a character or a line at a time,
+// from the cursor. The cursor stays where it was last left on the page if
+// that is in view, and is otherwise put on the current match or the top
+// line of the view.
+func (m *Model) startVisual(lines bool) {
+	if m.pageTextNum != m.currentPage {
+		m.statusMessage = "The page is still loading"
+		return
+	}
+	if m.cursor.page != m.currentPage || !m.lineInView(m.cursor.line) {
+		m.cursor = textPos{page: m.currentPage, line: m.viewport.YOffset}
+		if len(m.advancedSearchResults) > 0 && m.lineInView(m.matchRow) {
+			m.cursor.line = m.matchRow
+		}
+	}
+	m.clampCursor()
+	m.visualStart, m.visualLines = m.cursor, lines
+	m.shownLines = map[int][]string{m.currentPage: m.pageLines}
+	m.keyHandler.Mode = KeyModeVisual
+}
+
+// endVisual leaves visual mode, the cursor staying where it is
+func (m *Model) endVisual() {
+	m.keyHandler.Mode = m.normalMode()
+	m.shownLines = nil
+	m.cursorToEnd = false
+}
+
+// switchVisual selects characters or whole lines, leaving visual mode if
+// that is what is selected already, as v and V do in vim
+func (m *Model) switchVisual(lines bool) {
+	if m.visualLines == lines {
+		m.endVisual()
+		return
+	}
+	m.visualLines = lines
+}
+
+// visualPageShown keeps the text of a page shown in visual mode for
+// copying, and brings the cursor into view if it has moved on to the page
+func (m *Model) visualPageShown() {
+	m.shownLines[m.pageTextNum] = m.pageLines
+	if m.cursor.page != m.pageTextNum {
+		return
+	}
+	if m.cursorToEnd {
+		m.cursor.line, m.cursorToEnd = len(m.pageLines)-1, false
+	}
+	m.clampCursor()
+	m.revealCursor()
+}
+
+// lineInView reports whether a line of the page is in the viewport
+func (m *Model) lineInView(line int) bool {
+	return line >= m.viewport.YOffset && line < m.viewport.YOffset+m.viewport.Height && line < len(m.pageLines)
+}
+
+// cursorLine returns the line of the page the cursor is on
+func (m *Model) cursorLine() string {
+	if m.cursor.line < 0 || m.cursor.line >= len(m.pageLines) {
+		return ""
+	}
+	return m.pageLines[m.cursor.line]
+}
+
+// clampCursor keeps the cursor on a character of the page, or at the
+// start of an empty line
+func (m *Model) clampCursor() {
+	m.cursor.line = min(max(m.cursor.line, 0), max(len(m.pageLines)-1, 0))
+	m.cursor.col = min(max(m.cursor.col, 0), max(len([]rune(m.cursorLine()))-1, 0))
+}
+
+// revealCursor scrolls the line of the cursor into view
+func (m *Model) revealCursor() {
+	switch {
+	case m.cursor.line < m.viewport.YOffset:
+		m.viewport.SetYOffset(m.cursor.line)
+	case m.cursor.line >= m.viewport.YOffset+m.viewport.Height:
+		m.viewport.SetYOffset(m.cursor.line - m.viewport.Height + 1)
+	}
+}
+
+// cursorShown reports whether the page the cursor is on is the one shown,
+// so it can move; it isn't while the next page loads
+func (m *Model) cursorShown() bool {
+	return m.cursor.page == m.pageTextNum && m.pageTextNum == m.currentPage
+}
+
+// moveCursorCol moves the cursor to a column of its line
+func (m *Model) moveCursorCol(col int) {
+	if m.cursorShown() {
+		m.cursor.col = col
+		m.clampCursor()
+	}
+}
+
+// moveCursorTo moves the cursor to a line and column of its page
+func (m *Model) moveCursorTo(line, col int) {
+	if m.cursorShown() {
+		m.cursor.line, m.cursor.col = line, col
+		m.clampCursor()
+		m.revealCursor()
+	}
+}
+
+// moveCursorLines moves the cursor n lines down, or up if n is negative.
+// Moving past the last or first line of the page goes on to the top of
+// the next page or the bottom of the previous one.
+func (m *Model) moveCursorLines(n int) tea.Cmd {
+	if !m.cursorShown() {
+		return nil
+	}
+	line := m.cursor.line + n
+	switch {
+	case line >= len(m.pageLines) && m.cursor.line == len(m.pageLines)-1:
+		return m.moveCursorPage(1)
+	case line < 0 && m.cursor.line == 0:
+		if cmd := m.moveCursorPage(-1); cmd != nil {
+			m.cursorToEnd = true
+			return cmd
+		}
+		return nil
+	}
+	m.moveCursorTo(line, m.cursor.col)
+	return nil
+}
+
+// moveCursorPage moves the cursor to the top of the page n pages on, or
+// back if n is negative, which is shown once it is loaded
+func (m *Model) moveCursorPage(n int) tea.Cmd {
+	page := m.cursor.page + n
+	if !m.cursorShown() || page < 1 || page > m.document.GetPageCount() {
+		return nil
+	}
+	m.cursor = textPos{page: page}
+	m.currentPage = page
+	m.pendingScroll = 0
+	return m.loadPage(page)
+}
+
+// swapSelectionEnds moves the cursor to the other end of the selection,
+// showing its page if it is another one
+func (m *Model) swapSelectionEnds() tea.Cmd {
+	if !m.cursorShown() {
+		return nil
+	}
+	m.cursor, m.visualStart = m.visualStart, m.cursor
+	if m.cursor.page != m.currentPage {
+		m.currentPage = m.cursor.page
+		return m.loadPage(m.currentPage)
+	}
+	m.revealCursor()
+	return nil
+}
+
+// Character classes words are made of
+const (
+	classSpace = iota
+	classWord  // letters, digits and underscores
+	classPunct // anything else
+)
+
+func charClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return classSpace
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return classWord
+	}
+	return classPunct
+}
+
+// wordWalker steps through the runes of a page, the end of each line
+// counting as a space
+type wordWalker struct {
+	lines [][]rune
+	pos   textPos
+}
+
+// class returns the character class of the rune at the position
+func (w *wordWalker) class() int {
+	if line := w.lines[w.pos.line]; w.pos.col < len(line) {
+		return charClass(line[w.pos.col])
+	}
+	return classSpace
+}
+
+// step moves one rune on, or back if dir is negative, returning false at
+// the ends of the page
+func (w *wordWalker) step(dir int) bool {
+	p := &w.pos
+	switch {
+	case dir > 0 && p.col < len(w.lines[p.line]):
+		p.col++
+	case dir > 0 && p.line+1 < len(w.lines):
+		p.line, p.col = p.line+1, 0
+	case dir < 0 && p.col > 0:
+		p.col--
+	case dir < 0 && p.line > 0:
+		p.line--
+		p.col = len(w.lines[p.line])
+	default:
+		return false
+	}
+	return true
+}
+
+// skip steps over runes of a class
+func (w *wordWalker) skip(class, dir int) {
+	for w.class() == class && w.step(dir) {
+	}
+}
+
+// toRunEnd steps to the last rune of the run of the class it is on
+func (w *wordWalker) toRunEnd(dir int) {
+	class := w.class()
+	for {
+		prev := w.pos
+		if !w.step(dir) || w.class() != class {
+			w.pos = prev
+			return
+		}
+	}
+}
+
+// nextWordStart moves to the start of the next word, as vim's w
+func nextWordStart(w *wordWalker) {
+	if class := w.class(); class != classSpace {
+		w.skip(class, 1)
+	}
+	w.skip(classSpace, 1)
+}
+
+// prevWordStart moves to the start of the word, or of the previous one,
+// as vim's b
+func prevWordStart(w *wordWalker) {
+	if w.step(-1) {
+		w.skip(classSpace, -1)
+		w.toRunEnd(-1)
+	}
+}
+
+// nextWordEnd moves to the end of the word, or of the next one, as vim's e
+func nextWordEnd(w *wordWalker) {
+	if w.step(1) {
+		w.skip(classSpace, 1)
+		w.toRunEnd(1)
+	}
+}
+
+// moveCursorWords moves the cursor n words with a word motion, within
+// its page
+func (m *Model) moveCursorWords(n int, motion func(*wordWalker)) {
+	if !m.cursorShown() || len(m.pageLines) == 0 {
+		return
+	}
+	w := &wordWalker{lines: make([][]rune, len(m.pageLines)), pos: m.cursor}
+	for i, line := range m.pageLines {
+		w.lines[i] = []rune(line)
+	}
+	for range n {
+		motion(w)
+	}
+	m.moveCursorTo(w.pos.line, w.pos.col)
+}
+
+// selection returns the ends of the selection in document order
+func (m *Model) selection() (start, end textPos) {
+	start, end = m.visualStart, m.cursor
+	if end.before(start) {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// selectedRange returns the runes of a line the selection covers, from
+// from up to to, to being -1 for the end of the line
+func (m *Model) selectedRange(page, line int) (from, to int, ok bool) {
+	start, end := m.selection()
+	at, first, last := textPos{page: page, line: line}, textPos{page: start.page, line: start.line}, textPos{page: end.page, line: end.line}
+	if at.before(first) || last.before(at) {
+		return 0, 0, false
+	}
+	from, to = 0, -1
+	if !m.visualLines {
+		if page == start.page && line == start.line {
+			from = start.col
+		}
+		if page == end.page && line == end.line {
+			to = end.col + 1
+		}
+	}
+	return from, to, true
+}
+
+// linesOf returns the lines of a page as the viewer showed them, or, for
+// a page skipped over, as the document has them
+func (m *Model) linesOf(page int) []string {
+	if lines, ok := m.shownLines[page]; ok {
+		return lines
+	}
+	info, err := m.document.GetPage(page)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(info.Text, "\n")
+}
+
+// selectedText returns the text of the selection, a line of text per
+// line of the view with trailing spaces dropped
+func (m *Model) selectedText() string {
+	start, end := m.selection()
+	var text []string
+	for page := start.page; page <= end.page; page++ {
+		lines := m.linesOf(page)
+		first, last := 0, len(lines)-1
+		if page == start.page {
+			first = start.line
+		}
+		if page == end.page {
+			last = min(end.line, last)
+		}
+		for line := first; line <= last; line++ {
+			runes := []rune(lines[line])
+			from, to, _ := m.selectedRange(page, line)
+			if to < 0 || to > len(runes) {
+				to = len(runes)
+			}
+			text = append(text, strings.TrimRight(string(runes[min(from, to):to]), " "))
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// yankSelection copies the selection in the copy format of the config and
+// leaves visual mode, the cursor going to the start of the selection
+func (m *Model) yankSelection() tea.Cmd {
+	start, end := m.selection()
+	text := m.selectedText()
+	m.endVisual()
+
+	if n := strings.Count(text, "\n") + 1; n > 1 {
+		m.statusMessage = fmt.Sprintf("Copied %d lines", n)
+	} else {
+		m.statusMessage = fmt.Sprintf("Copied %d characters", len([]rune(text)))
+	}
+	copyCmd := m.copyText(m.formatCopy(text, start.page, end.page), "the selection")
+	m.cursor = start
+	if start.page == m.currentPage {
+		m.revealCursor()
+		return copyCmd
+	}
+	m.currentPage = start.page
+	return tea.Batch(m.loadPage(m.currentPage), copyCmd)
+}
+
+// visualStatus describes the selection for the status bar
+func (m *Model) visualStatus() string {
+	if m.visualLines {
+		return "-- VISUAL LINE -- (y to copy, Esc to leave)"
+	}
+	return "-- VISUAL -- (y to copy, Esc to leave)"
+}
+
+// visualView draws the selection and the cursor over the view
+func (m *Model) visualView() string {
+	rows := strings.Split(m.viewport.View(), "\n")
+	for row := range rows {
+		line := m.viewport.YOffset + row
+		if line >= len(m.pageLines) {
+			break
+		}
+		runes := []rune(m.pageLines[line])
+		cells := func(col int) int { return ansi.StringWidth(string(runes[:min(max(col, 0), len(runes))])) }
+
+		if from, to, ok := m.selectedRange(m.pageTextNum, line); ok {
+			if to < 0 {
+				to = len(runes)
+			}
+			start, end := cells(from), cells(to)
+			rows[row] = restyle(rows[row], start, max(end, start+1), m.styles.Selection)
+		}
+		if m.cursor.page == m.pageTextNum && m.cursor.line == line {
+			start := cells(m.cursor.col)
+			rows[row] = restyle(rows[row], start, max(cells(m.cursor.col+1), start+1), m.styles.Cursor)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// restyle draws the cells of a line of styled text from column from up to
+// column to in style instead, padding the line with spaces to reach to.
+// The styling in effect there is restored after them.
+func restyle(line string, from, to int, style lipgloss.Style) string {
+	var sb, sgr, cells strings.Builder
+	var state byte
+	pos, drawn := 0, false
+	draw := func() {
+		if sgr.Len() > 0 {
+			sb.WriteString("\x1b[0m")
+		}
+		sb.WriteString(style.Render(cells.String()))
+		sb.WriteString(sgr.String())
+		drawn = true
+	}
+
+	for len(line) > 0 {
+		seq, w, n, newState := ansi.DecodeSequence(line, state, nil)
+		state, line = newState, line[n:]
+		inside := pos >= from && pos < to
+		if w == 0 {
+			if strings.HasPrefix(seq, "\x1b[") && strings.HasSuffix(seq, "m") {
+				if seq == "\x1b[0m" || seq == "\x1b[m" {
+					sgr.Reset()
+				} else {
+					sgr.WriteString(seq)
+				}
+				if inside {
+					// The style of the range replaces it
+					continue
+				}
+			}
+			sb.WriteString(seq)
+			continue
+		}
+
+		switch {
+		case pos+w > from && pos < to:
+			cells.WriteString(seq)
+		case pos >= to && !drawn:
+			draw()
+			sb.WriteString(seq)
+		default:
+			sb.WriteString(seq)
+		}
+		pos += w
+	}
+	if !drawn {
+		sb.WriteString(strings.Repeat(" ", max(from-pos, 0)))
+		cells.WriteString(strings.Repeat(" ", max(to-max(pos, from), 0)))
+		draw()
+	}
+	return sb.String()
+}
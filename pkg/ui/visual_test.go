@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/luxor/lumos/pkg/config"
+)
+
+func TestVisualSelection(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm")
+	model := newActionsModel(t)
+	var tty bytes.Buffer
+	model.systemClipboard = clipboard{method: config.ClipboardOSC52, tty: &tty}
+
+	typeKeys(model, "v")
+	if model.keyHandler.Mode != KeyModeVisual || model.cursor != (textPos{page: 1}) {
+		t.Fatalf("after v: mode %v, cursor %+v", model.keyHandler.Mode, model.cursor)
+	}
+	if status := model.renderStatusBar(); !strings.Contains(status, "-- VISUAL --") {
+		t.Errorf("status bar doesn't show visual mode: %s", status)
+	}
+	typeKeys(model, "ey")
+	if model.keyHandler.Mode != KeyModeNormal || model.GetClipboard() != "LUMOS" || model.statusMessage != "Copied 5 characters" {
+		t.Errorf("after v e y: mode %v, clipboard %q, status %q", model.keyHandler.Mode, model.GetClipboard(), model.statusMessage)
+	}
+
+	typeKeys(model, "Vjy")
+	if want := "LUMOS Multi-Page Test PDF - Page 1\n"; model.GetClipboard() != want || model.statusMessage != "Copied 2 lines" {
+		t.Errorf("after V j y: clipboard %q, status %q", model.GetClipboard(), model.statusMessage)
+	}
+
+	// The selection goes on to the next page, and is copied in the copy
+	// format of the config
+	model.cfg.UI.CopyFormat = "{text} ({file} p. {page})"
+	typeKeys(model, "v9jv vjj")
+	if model.currentPage != 2 || model.cursor != (textPos{page: 2, line: 1}) {
+		t.Fatalf("after v 9j v v j j: page %d, cursor %+v", model.currentPage, model.cursor)
+	}
+	typeKeys(model, "key")
+	want := "Total Pages: 5\nLUMOS (multipage.pdf p. 1-2)"
+	if model.GetClipboard() != want || model.currentPage != 1 || model.cursor != (textPos{page: 1, line: 9}) {
+		t.Errorf("after k e y: clipboard %q, page %d, cursor %+v", model.GetClipboard(), model.currentPage, model.cursor)
+	}
+	if !strings.Contains(tty.String(), "\x1b]52;c;"+base64.StdEncoding.EncodeToString([]byte(want))) {
+		t.Errorf("OSC 52 not written for the selection: %q", tty.String())
+	}
+}
+
+func TestVisualWordMotions(t *testing.T) {
+	model := newActionsModel(t)
+
+	// "This is the first page of a multi-page test document."
+	typeKeys(model, "v2j")
+	for _, tt := range []struct {
+		keys string
+		col  int
+	}{
+		{"w", 5}, {"2w", 12}, {"e", 16}, {"b", 12}, {"4w", 28}, {"w", 33}, {"$", 52}, {"^", 0}, {"3l", 3},
+	} {
+		typeKeys(model, tt.keys)
+		if model.cursor.col != tt.col {
+			t.Errorf("after %s: column %d, want %d", tt.keys, model.cursor.col, tt.col)
+		}
+	}
+
+	typeKeys(model, "o")
+	if model.cursor != (textPos{page: 1}) || model.visualStart != (textPos{page: 1, line: 2, col: 3}) {
+		t.Errorf("after o: cursor %+v, other end %+v", model.cursor, model.visualStart)
+	}
+	typeKeys(model, "v")
+	if model.keyHandler.Mode != KeyModeNormal {
+		t.Errorf("mode %v after v in visual mode, want normal", model.keyHandler.Mode)
+	}
+}
+
+func TestRestyle(t *testing.T) {
+	plain := lipgloss.NewStyle()
+	tests := []struct {
+		line     string
+		from, to int
+		want     string
+	}{
+		{"abcd", 1, 3, "abcd"},
+		{"\x1b[1mabcd", 1, 2, "\x1b[1ma\x1b[0mb\x1b[1mcd"},
+		{"\x1b[1mab\x1b[0mcd", 1, 3, "\x1b[1mabcd"},
+		{"ab", 3, 5, "ab   "},
+		{"日本", 2, 4, "日本"},
+	}
+	for _, tt := range tests {
+		if got := restyle(tt.line, tt.from, tt.to, plain); got != tt.want {
+			t.Errorf("restyle(%q, %d, %d) = %q, want %q", tt.line, tt.from, tt.to, got, tt.want)
+		}
+	}
+}